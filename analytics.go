@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// topErrorsShown limits the console error ranking; analytics.json keeps all signatures
+const topErrorsShown = 10
+
+// payloadShown is the share of the payload limit from which states are
+// printed; analytics.json keeps every state
+const payloadShown = 0.25
+
+// topResourcesShown limits the console latency ranking; analytics.json keeps every resource
+const topResourcesShown = 15
+
+func processAnalytics(stateMachines []stepfunctions.StateMachine, outputDir string) {
+	report := stepfunctions.BuildAnalyticsReport(stateMachines)
+
+	adviceTable := newTable()
+	adviceTable.SetHeader([]string{"State Machine", "Current", "Recommended", "Executions", "Avg Duration", "Avg Payload", "Standard Cost", "Express Cost", "Savings", "Blockers"})
+	for _, advice := range report.WorkflowTypeAdvice {
+		adviceTable.Append([]string{
+			advice.StateMachineName,
+			advice.CurrentType,
+			advice.RecommendedType,
+			fmt.Sprintf("%d", advice.Executions),
+			fmt.Sprintf("%.1fs", advice.AvgDurationSeconds),
+			fmt.Sprintf("%dB", advice.AvgPayloadBytes),
+			fmt.Sprintf("$%.6f", advice.StandardCost),
+			fmt.Sprintf("$%.6f", advice.ExpressCost),
+			fmt.Sprintf("$%.6f", advice.CostDelta),
+			strings.Join(advice.Blockers, "\n"),
+		})
+	}
+	fmt.Println("Workflow Type Advice (estimated cost of observed executions):")
+	adviceTable.Render()
+	fmt.Println()
+
+	if len(report.TransitionStats) > 0 {
+		transitionTable := newTable()
+		transitionTable.SetHeader([]string{"State Machine", "Executions", "Transitions", "Avg", "Max", "Retries", "Est. Cost", "Loop Factor", "Busiest States"})
+		for _, stats := range report.TransitionStats {
+			loopFactor := fmt.Sprintf("%.1f", stats.LoopFactor)
+			if stats.LoopHeavy {
+				loopFactor += " (loop-heavy)"
+			}
+			transitionTable.Append([]string{
+				stats.StateMachineName,
+				fmt.Sprintf("%d", stats.Executions),
+				fmt.Sprintf("%d", stats.TotalTransitions),
+				fmt.Sprintf("%.1f", stats.AvgTransitions),
+				fmt.Sprintf("%d", stats.MaxTransitions),
+				fmt.Sprintf("%d", stats.Retries),
+				fmt.Sprintf("$%.6f", stats.EstimatedCost),
+				loopFactor,
+				strings.Join(stats.TopStates(3), ", "),
+			})
+		}
+		fmt.Println("State Transitions (billing units):")
+		transitionTable.Render()
+		fmt.Println()
+	}
+
+	if len(report.TopErrors) > 0 {
+		errorTable := newTable()
+		errorTable.SetHeader([]string{"#", "State Machine", "State", "Error", "Count", "Example Cause", "Example Execution"})
+		for i, sig := range report.TopErrors {
+			if i == topErrorsShown {
+				break
+			}
+			cause := ""
+			if len(sig.ExampleCauses) > 0 {
+				cause = sig.ExampleCauses[0]
+				if len(cause) > 80 {
+					cause = cause[:77] + "..."
+				}
+			}
+			errorTable.Append([]string{
+				fmt.Sprintf("%d", i+1),
+				sig.StateMachineName,
+				sig.StateName,
+				sig.Error,
+				fmt.Sprintf("%d", sig.Count),
+				cause,
+				sig.ExecutionArns[0],
+			})
+		}
+		fmt.Println("Top Errors:")
+		errorTable.Render()
+		fmt.Println()
+	}
+
+	retryTable := newTable()
+	retryTable.SetHeader([]string{"State Machine", "State", "Entries", "Retry Rate", "Retries", "Avg Attempts", "Recovery Rate", "Retry Errors"})
+	for _, stats := range report.RetryStats {
+		if stats.Retries == 0 {
+			continue
+		}
+		var retryErrors []string
+		for errorCode, count := range stats.RetryErrors {
+			retryErrors = append(retryErrors, fmt.Sprintf("%s (%d)", errorCode, count))
+		}
+		sort.Strings(retryErrors)
+		retryTable.Append([]string{
+			stats.StateMachineName,
+			stats.StateName,
+			fmt.Sprintf("%d", stats.Entries),
+			fmt.Sprintf("%.0f%%", stats.RetryRate*100),
+			fmt.Sprintf("%d", stats.Retries),
+			fmt.Sprintf("%.1f", stats.AvgAttempts),
+			fmt.Sprintf("%.0f%%", stats.RecoveryRate*100),
+			strings.Join(retryErrors, "\n"),
+		})
+	}
+	if retryTable.NumLines() > 0 {
+		fmt.Println("Retry Behavior:")
+		retryTable.Render()
+		fmt.Println()
+	}
+
+	if len(report.ChoiceStats) > 0 {
+		choiceTable := newTable()
+		choiceTable.SetHeader([]string{"State Machine", "Choice State", "Rules", "Next", "Taken", "Share"})
+		for _, stats := range report.ChoiceStats {
+			for _, branch := range stats.Branches {
+				share := "never taken"
+				if !branch.Unexercised {
+					share = fmt.Sprintf("%.0f%%", float64(branch.Taken)/float64(stats.Evaluations)*100)
+				}
+				choiceTable.Append([]string{
+					stats.StateMachineName,
+					stats.ChoiceState,
+					strings.Join(branch.Rules, ", "),
+					branch.Next,
+					fmt.Sprintf("%d", branch.Taken),
+					share,
+				})
+			}
+		}
+		fmt.Println("Choice Branch Coverage:")
+		choiceTable.Render()
+		fmt.Println()
+	}
+
+	if len(report.IdleTime) > 0 {
+		idleTable := newTable()
+		idleTable.SetHeader([]string{"State Machine", "Executions", "Total", "Productive", "Wait", "Callback", "Idle Share"})
+		for _, stats := range report.IdleTime {
+			idle := stats.WaitSeconds + stats.CallbackSeconds
+			share := 0.0
+			if stats.TotalSeconds > 0 {
+				share = idle / stats.TotalSeconds * 100
+			}
+			idleTable.Append([]string{
+				stats.StateMachineName,
+				fmt.Sprintf("%d", stats.Executions),
+				fmt.Sprintf("%.1fs", stats.TotalSeconds),
+				fmt.Sprintf("%.1fs", stats.ProductiveSeconds),
+				fmt.Sprintf("%.1fs", stats.WaitSeconds),
+				fmt.Sprintf("%.1fs", stats.CallbackSeconds),
+				fmt.Sprintf("%.0f%%", share),
+			})
+		}
+		fmt.Println("Productive vs Idle Time:")
+		idleTable.Render()
+		fmt.Println()
+	}
+
+	if len(report.ResourceLatency) > 0 {
+		latencyTable := newTable()
+		latencyTable.SetHeader([]string{"Resource", "Integration", "Calls", "Failed", "Total", "Avg", "P50", "P90", "P99", "Max", "State Machines"})
+		for i, latency := range report.ResourceLatency {
+			if i == topResourcesShown {
+				break
+			}
+			latencyTable.Append([]string{
+				latency.Resource,
+				latency.Integration,
+				fmt.Sprintf("%d", latency.Calls),
+				fmt.Sprintf("%d", latency.Failed),
+				fmt.Sprintf("%.1fs", latency.TotalSeconds),
+				fmt.Sprintf("%.2fs", latency.AvgSeconds),
+				fmt.Sprintf("%.2fs", latency.P50Seconds),
+				fmt.Sprintf("%.2fs", latency.P90Seconds),
+				fmt.Sprintf("%.2fs", latency.P99Seconds),
+				fmt.Sprintf("%.2fs", latency.MaxSeconds),
+				strings.Join(latency.StateMachines, "\n"),
+			})
+		}
+		fmt.Println("Resource Latency (slowest in total first):")
+		latencyTable.Render()
+		fmt.Println()
+	}
+
+	payloadTable := newTable()
+	payloadTable.SetHeader([]string{"State Machine", "State", "Entries", "Avg Input", "Max Input", "Max Output", "Limit Share"})
+	for _, stats := range report.PayloadSizes {
+		if stats.LimitShare < payloadShown {
+			continue
+		}
+		share := fmt.Sprintf("%.0f%%", stats.LimitShare*100)
+		if stats.NearLimit {
+			share += " (near limit)"
+		}
+		payloadTable.Append([]string{
+			stats.StateMachineName,
+			stats.StateName,
+			fmt.Sprintf("%d", stats.Samples),
+			fmt.Sprintf("%dB", stats.AvgInputBytes),
+			fmt.Sprintf("%dB", stats.MaxInputBytes),
+			fmt.Sprintf("%dB", stats.MaxOutputBytes),
+			share,
+		})
+	}
+	if payloadTable.NumLines() > 0 {
+		fmt.Printf("Payload Sizes (states above %.0f%% of the %dKB limit):\n", payloadShown*100, stepfunctions.PayloadLimitBytes/1024)
+		payloadTable.Render()
+		for _, candidate := range report.S3Offload {
+			fmt.Printf("Consider passing %s payloads through S3 (largest %dB in %s)\n", candidate.StateMachineName, candidate.MaxBytes, strings.Join(candidate.States, ", "))
+		}
+		fmt.Println()
+	}
+
+	heartbeatTable := newTable()
+	heartbeatTable.SetHeader([]string{"State Machine", "Resource", "States", "HeartbeatSeconds", "Attempts", "Heartbeat Timeouts", "Timeout Rate", "Executions"})
+	for _, stats := range report.Heartbeats {
+		if stats.HeartbeatTimeouts == 0 {
+			continue
+		}
+		var seconds []string
+		for _, s := range stats.HeartbeatSeconds {
+			seconds = append(seconds, fmt.Sprintf("%d", s))
+		}
+		heartbeatTable.Append([]string{
+			stats.StateMachineName,
+			stats.Resource,
+			strings.Join(stats.States, "\n"),
+			strings.Join(seconds, ", "),
+			fmt.Sprintf("%d", stats.Attempts),
+			fmt.Sprintf("%d", stats.HeartbeatTimeouts),
+			fmt.Sprintf("%.0f%%", stats.TimeoutRate*100),
+			fmt.Sprintf("%d", stats.Executions),
+		})
+	}
+	if heartbeatTable.NumLines() > 0 {
+		fmt.Println("Heartbeat Timeouts:")
+		heartbeatTable.Render()
+		fmt.Println()
+	}
+
+	data, err := stepfunctions.MarshalDocument(report, "")
+	if err != nil {
+		log.Printf("Failed to marshal analytics report: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "analytics.json"), data, 0644); err != nil {
+		log.Printf("Failed to save analytics report: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// readARNFile reads state machine ARNs from path, or from stdin when path is
+// "-". ARNs are separated by whitespace; blank lines and lines starting with
+// # are ignored.
+func readARNFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ARN file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var arns []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		for _, field := range strings.Fields(text) {
+			if err := validateStateMachineARN(field); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if !seen[field] {
+				seen[field] = true
+				arns = append(arns, field)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ARN file: %w", err)
+	}
+	if len(arns) == 0 {
+		return nil, fmt.Errorf("no state machine ARNs in %s", path)
+	}
+	return arns, nil
+}
+
+func validateStateMachineARN(s string) error {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid ARN %q: %w", s, err)
+	}
+	if parsed.Service != "states" || !strings.HasPrefix(parsed.Resource, "stateMachine:") {
+		return fmt.Errorf("%q is not a state machine ARN", s)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// bucketSizes are the --buckets intervals
+var bucketSizes = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+}
+
+// processTimeBuckets aggregates every state machine's executions into time
+// buckets, prints the peak bucket of each and saves the series to
+// buckets.json and buckets.csv
+func processTimeBuckets(stateMachines []stepfunctions.StateMachine, outputDir, interval string) {
+	var buckets []stepfunctions.TimeBucket
+	peakTable := newTable()
+	peakTable.SetHeader([]string{"State Machine", "Buckets", "Busiest", "Executions", "Worst Failure Rate", "Failed"})
+	for _, sm := range stateMachines {
+		series := stepfunctions.ComputeTimeBuckets(sm, bucketSizes[interval])
+		if len(series) == 0 {
+			continue
+		}
+		buckets = append(buckets, series...)
+		busiest, worst := series[0], series[0]
+		for _, b := range series {
+			if b.Executions > busiest.Executions {
+				busiest = b
+			}
+			if b.FailureRate > worst.FailureRate {
+				worst = b
+			}
+		}
+		failed := "-"
+		if worst.FailureRate > 0 {
+			failed = fmt.Sprintf("%.1f%% at %s", worst.FailureRate*100, formatTime(worst.Start))
+		}
+		peakTable.Append([]string{
+			sm.Name,
+			strconv.Itoa(len(series)),
+			formatTime(busiest.Start),
+			strconv.Itoa(busiest.Executions),
+			failed,
+			strconv.Itoa(worst.Failed + worst.Aborted),
+		})
+	}
+	if len(buckets) == 0 {
+		fmt.Println("No executions with a start time to bucket.")
+		return
+	}
+	fmt.Printf("Executions per %s bucket (UTC):\n", bucketSizes[interval])
+	peakTable.Render()
+	fmt.Println()
+
+	data, err := stepfunctions.MarshalDocument(map[string]interface{}{"interval": interval, "buckets": buckets}, "")
+	if err != nil {
+		log.Printf("Failed to marshal time buckets: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "buckets.json"), data, 0644); err != nil {
+		log.Printf("Failed to save buckets.json: %v", err)
+	}
+
+	file, err := os.Create(filepath.Join(outputDir, "buckets.csv"))
+	if err != nil {
+		log.Printf("Failed to save buckets.csv: %v", err)
+		return
+	}
+	defer file.Close()
+	if err := writeBucketsCSV(file, buckets); err != nil {
+		log.Printf("Failed to save buckets.csv: %v", err)
+	}
+}
+
+// writeBucketsCSV writes one row per bucket, for spreadsheets and plotting
+func writeBucketsCSV(w io.Writer, buckets []stepfunctions.TimeBucket) error {
+	seconds := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"state_machine", "start", "executions", "succeeded", "failed", "aborted", "running", "failure_rate", "avg_seconds", "p90_seconds", "max_seconds"})
+	for _, b := range buckets {
+		cw.Write([]string{
+			b.StateMachineName,
+			b.Start.Format(time.RFC3339),
+			strconv.Itoa(b.Executions),
+			strconv.Itoa(b.Succeeded),
+			strconv.Itoa(b.Failed),
+			strconv.Itoa(b.Aborted),
+			strconv.Itoa(b.Running),
+			strconv.FormatFloat(b.FailureRate, 'f', 4, 64),
+			seconds(b.AvgSeconds),
+			seconds(b.P90Seconds),
+			seconds(b.MaxSeconds),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
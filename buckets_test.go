@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestWriteBucketsCSV(t *testing.T) {
+	var sb strings.Builder
+	err := writeBucketsCSV(&sb, []stepfunctions.TimeBucket{{
+		StateMachineName: "orders",
+		Start:            time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Executions:       2,
+		Succeeded:        1,
+		Failed:           1,
+		FailureRate:      0.5,
+		AvgSeconds:       20,
+		P90Seconds:       30,
+		MaxSeconds:       30,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "state_machine,start,executions,succeeded,failed,aborted,running,failure_rate,avg_seconds,p90_seconds,max_seconds\n" +
+		"orders,2024-03-01T10:00:00Z,2,1,1,0,0,0.5000,20.000,30.000,30.000\n"
+	if sb.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", sb.String(), want)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// processCallbacks prints the running executions blocked on a task token,
+// longest waiting first, and saves them to callbacks.json
+func processCallbacks(stateMachines []stepfunctions.StateMachine, outputDir string, now time.Time) {
+	var waits []stepfunctions.CallbackWait
+	for _, sm := range stateMachines {
+		waits = append(waits, stepfunctions.PendingCallbacks(sm, now)...)
+	}
+	if len(waits) == 0 {
+		fmt.Println("No executions are waiting on a task token callback.")
+	} else {
+		callbackTable := newTable()
+		callbackTable.SetHeader([]string{"State Machine", "Execution", "State", "Resource", "Waiting Since", "Waiting", "Timeout"})
+		for _, wait := range waits {
+			timeout := "none"
+			if wait.TimeoutSeconds > 0 {
+				timeout = (time.Duration(wait.TimeoutSeconds) * time.Second).String()
+			}
+			callbackTable.Append([]string{
+				wait.StateMachineName,
+				wait.ExecutionArn[strings.LastIndex(wait.ExecutionArn, ":")+1:],
+				wait.State,
+				wait.Resource,
+				formatTime(wait.Since),
+				wait.Waiting.Round(time.Second).String(),
+				timeout,
+			})
+		}
+		fmt.Println("Waiting on Task Token Callbacks:")
+		callbackTable.Render()
+		fmt.Println()
+	}
+
+	data, err := stepfunctions.MarshalDocument(waits, "callbacks")
+	if err != nil {
+		log.Printf("Failed to marshal callbacks: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "callbacks.json"), data, 0644); err != nil {
+		log.Printf("Failed to save callbacks.json: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCDK(t *testing.T) {
+	sms := iacFixture()
+	sms[0].Definition = `{"StartAt":"Route","States":{
+		"Route":{"Type":"Choice","Choices":[{"Variable":"$.total","NumericGreaterThan":100,"Next":"Review"}],"Default":"Fan"},
+		"Review":{"Type":"Pass","Result":{"manual":true},"Next":"Fan"},
+		"Fan":{"Type":"Parallel","Branches":[{"StartAt":"Charge","States":{"Charge":{"Type":"Task","Resource":"arn:aws:states:::lambda:invoke","End":true}}}],"Next":"Done"},
+		"Done":{"Type":"Succeed"},
+		"Orphan":{"Type":"Fail","Error":"Unused"}}}`
+	dir := t.TempDir()
+	if err := writeCDK(sms, dir, iacOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "order_flow.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filepath.Join("cdk", "order_flow.go.golden"), data)
+}
+
+func TestExportedName(t *testing.T) {
+	tests := map[string]string{
+		"order-flow": "OrderFlow",
+		"Charge":     "Charge",
+		"2fa check":  "State2faCheck",
+	}
+	for name, want := range tests {
+		if got := exportedName(name); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// writeCloudFormation renders the state machines as a CloudFormation template,
+// template.json, with one AWS::StepFunctions::StateMachine per state machine
+// and each execution role as a parameter defaulting to the current role.
+// Definitions are inlined as DefinitionString, or with opts.definitionS3
+// referenced as DefinitionS3Location and written to definitions/ for upload.
+func writeCloudFormation(stateMachines []stepfunctions.StateMachine, dir string, opts iacOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cloudformation directory: %w", err)
+	}
+	sort.Slice(stateMachines, func(i, j int) bool { return stateMachines[i].Name < stateMachines[j].Name })
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(opts.definitionS3, "s3://"), "/")
+	parameters := make(map[string]interface{})
+	resources := make(map[string]interface{})
+	roleParameters := make(map[string]string) // Role ARN -> parameter name
+	logicalIDs := make(map[string]bool)       // Resources and parameters share one namespace
+	for _, sm := range stateMachines {
+		logicalID := uniqueName(logicalIDs, cfnLogicalID(sm.Name), "")
+		roleParameter, ok := roleParameters[sm.RoleARN]
+		if !ok {
+			roleParameter = uniqueName(logicalIDs, logicalID+"RoleArn", "")
+			roleParameters[sm.RoleARN] = roleParameter
+			parameters[roleParameter] = map[string]interface{}{
+				"Type":        "String",
+				"Default":     sm.RoleARN,
+				"Description": fmt.Sprintf("Execution role of %s", sm.Name),
+			}
+		}
+
+		properties := map[string]interface{}{
+			"StateMachineName": sm.Name,
+			"StateMachineType": sm.Type,
+			"RoleArn":          map[string]interface{}{"Ref": roleParameter},
+		}
+		if opts.definitionS3 == "" {
+			properties["DefinitionString"] = map[string]interface{}{"Fn::Sub": subDefinition(sm)}
+		} else {
+			file := definitionFile(sm.Name)
+			if err := os.MkdirAll(filepath.Join(dir, "definitions"), 0755); err != nil {
+				return fmt.Errorf("failed to create definitions directory: %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "definitions", file), []byte(sm.Definition), 0644); err != nil {
+				return fmt.Errorf("failed to save definition for %s: %w", sm.Name, err)
+			}
+			properties["DefinitionS3Location"] = map[string]interface{}{"Bucket": bucket, "Key": path.Join(prefix, file)}
+		}
+		if sm.Logging.Level != "" && sm.Logging.Level != "OFF" {
+			var destinations []interface{}
+			for _, dest := range sm.Logging.Destinations {
+				destinations = append(destinations, map[string]interface{}{
+					"CloudWatchLogsLogGroup": map[string]interface{}{"LogGroupArn": dest},
+				})
+			}
+			properties["LoggingConfiguration"] = map[string]interface{}{
+				"Level":                sm.Logging.Level,
+				"IncludeExecutionData": sm.Logging.IncludeExecutionData,
+				"Destinations":         destinations,
+			}
+		}
+		if sm.Tracing {
+			properties["TracingConfiguration"] = map[string]interface{}{"Enabled": true}
+		}
+		if sm.Encryption.Type == "CUSTOMER_MANAGED_KMS_KEY" {
+			encryption := map[string]interface{}{"Type": sm.Encryption.Type, "KmsKeyId": sm.Encryption.KMSKeyID}
+			if sm.Encryption.DataKeyReusePeriodSeconds > 0 {
+				encryption["KmsDataKeyReusePeriodSeconds"] = sm.Encryption.DataKeyReusePeriodSeconds
+			}
+			properties["EncryptionConfiguration"] = encryption
+		}
+		resources[logicalID] = map[string]interface{}{
+			"Type":       "AWS::StepFunctions::StateMachine",
+			"Properties": properties,
+		}
+	}
+
+	template := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "Generated by stepfunction-fetcher export cloudformation",
+		"Parameters":               parameters,
+		"Resources":                resources,
+	}
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "template.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save template.json: %w", err)
+	}
+	return nil
+}
+
+// subDefinition escapes the definition for Fn::Sub and replaces the state
+// machine's account ID and region with the pseudo parameters
+func subDefinition(sm stepfunctions.StateMachine) string {
+	definition := strings.ReplaceAll(sm.Definition, "${", "${!")
+	parsed, err := arn.Parse(sm.ARN)
+	if err != nil {
+		return definition
+	}
+	if parsed.AccountID != "" {
+		definition = strings.ReplaceAll(definition, ":"+parsed.AccountID+":", ":${AWS::AccountId}:")
+	}
+	if parsed.Region != "" {
+		definition = strings.ReplaceAll(definition, ":"+parsed.Region+":", ":${AWS::Region}:")
+	}
+	return definition
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// cfnLogicalID turns a state machine name into an alphanumeric logical ID,
+// e.g. order-flow becomes OrderFlow
+func cfnLogicalID(name string) string {
+	var id strings.Builder
+	for _, part := range nonAlphanumeric.Split(name, -1) {
+		if part != "" {
+			id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	if id.Len() == 0 || (id.String()[0] >= '0' && id.String()[0] <= '9') {
+		return "StateMachine" + id.String()
+	}
+	return id.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestWriteCloudFormation(t *testing.T) {
+	for name, opts := range map[string]iacOptions{
+		"inline.json": {},
+		"s3.json":     {definitionS3: "s3://templates/stepfunctions"},
+	} {
+		dir := t.TempDir()
+		if err := writeCloudFormation(iacFixture(), dir, opts); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "template.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, filepath.Join("cloudformation", name), data)
+	}
+}
+
+func TestCFNLogicalID(t *testing.T) {
+	tests := map[string]string{
+		"orders":       "Orders",
+		"order-flow":   "OrderFlow",
+		"2fa_check":    "StateMachine2faCheck",
+		"billing.v2-x": "BillingV2X",
+	}
+	for name, want := range tests {
+		if got := cfnLogicalID(name); got != want {
+			t.Errorf("cfnLogicalID(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteCloudFormationCollidingNames(t *testing.T) {
+	dir := t.TempDir()
+	first, second := iacFixture()[0], iacFixture()[0]
+	second.Name, second.ARN = "order_flow", "arn:aws:states:us-east-1:123456789012:stateMachine:order_flow"
+	second.RoleARN = "arn:aws:iam::123456789012:role/order_flow"
+	if err := writeCloudFormation([]stepfunctions.StateMachine{first, second}, dir, iacOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "template.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template struct{ Parameters, Resources map[string]json.RawMessage }
+	if err := json.Unmarshal(data, &template); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"OrderFlow", "OrderFlow2"} {
+		if _, ok := template.Resources[id]; !ok {
+			t.Errorf("got resources %v, want %s", template.Resources, id)
+		}
+		if _, ok := template.Parameters[id+"RoleArn"]; !ok {
+			t.Errorf("got parameters %v, want %sRoleArn", template.Parameters, id)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// colorEnabled is off with --no-color, when NO_COLOR is set (https://no-color.org)
+// or when stdout is not a terminal
+var colorEnabled = false
+
+func initColor(noColor bool) {
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
+	colorEnabled = !noColor && !noColorEnv && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func colorize(color, s string) string {
+	if !colorEnabled || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// colorStatus colors an execution status: succeeded green, failed red,
+// running yellow. Both the API (FAILED) and log (Failed) spellings are handled.
+func colorStatus(status string) string {
+	switch strings.ToUpper(status) {
+	case "SUCCEEDED":
+		return colorize(ansiGreen, status)
+	case "FAILED", "TIMED_OUT", "TIMEDOUT", "ABORTED":
+		return colorize(ansiRed, status)
+	case "RUNNING", "PENDING_REDRIVE":
+		return colorize(ansiYellow, status)
+	}
+	return status
+}
+
+// colorFinding colors validation and compliance findings
+func colorFinding(s string) string {
+	return colorize(ansiYellow, s)
+}
+
+// colorViolation colors failed checks such as SLA breaches and missing permissions
+func colorViolation(s string) string {
+	return colorize(ansiRed, s)
+}
+
+// colorPermission colors a --doctor check: allowed green, missing red
+func colorPermission(status string) string {
+	switch status {
+	case permissionAllowed:
+		return colorize(ansiGreen, status)
+	case permissionMissing:
+		return colorize(ansiRed, status)
+	}
+	return status
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// countedStatuses are the count columns, in order; other statuses are only
+// in the total and execution_counts.json
+var countedStatuses = []string{"RUNNING", "SUCCEEDED", "FAILED", "TIMED_OUT", "ABORTED"}
+
+// parseCountStatuses parses a comma-separated list of execution statuses,
+// e.g. RUNNING,FAILED
+func parseCountStatuses(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	known := make(map[string]bool)
+	for _, status := range stepfunctions.ExecutionStatuses() {
+		known[status] = true
+	}
+	var statuses []string
+	for _, status := range strings.Split(list, ",") {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if status == "" {
+			continue
+		}
+		if !known[status] {
+			return nil, fmt.Errorf("unknown execution status %q (supported: %s)", status, strings.Join(stepfunctions.ExecutionStatuses(), ", "))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// runCounts is the --counts-only run: executions by status per state
+// machine, printed and saved to execution_counts.json. It returns like run.
+func runCounts(ctx context.Context, cfg runConfig) (int, error) {
+	meta := newRunMetadata(cfg)
+	errs := &runErrors{failFast: cfg.failFast, outputDir: cfg.outputDir, meta: meta}
+	if err := checkCaller(ctx, cfg, errs); err != nil {
+		return stopped(err)
+	}
+	if err := os.MkdirAll(cfg.outputDir, 0755); err != nil {
+		return stopped(errs.fatal("output", "Failed to create output directory", err))
+	}
+
+	fetcher, err := stepfunctions.NewFetcher(ctx, cfg.region, cfg.fetcherOptions()...)
+	if err != nil {
+		return stopped(errs.fatal("config", "Failed to create fetcher", err))
+	}
+	counts, err := fetcher.CountExecutions(ctx)
+	errs.addFetcher(fetcher)
+	if err != nil {
+		return stopped(errs.fatal("state machines", "Failed to count executions", err))
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Name < counts[j].Name })
+
+	columns := countedStatuses
+	if cfg.countStatuses != nil {
+		columns = cfg.countStatuses
+	}
+	countTable := newTable()
+	countTable.SetHeader(append([]string{"Name", "Type", "Total"}, columns...))
+	for _, sm := range counts {
+		row := []string{sm.Name, sm.Type, fmt.Sprintf("%d", sm.Total)}
+		for _, status := range columns {
+			row = append(row, fmt.Sprintf("%d", sm.ByStatus[status]))
+		}
+		countTable.Append(row)
+	}
+	fmt.Println("Executions by status:")
+	countTable.Render()
+	fmt.Printf("Note: Express counts cover the --express-lookback window (%v) of CloudWatch Logs, or of CloudWatch metrics when not logged.\n", cfg.expressQuery.Lookback)
+
+	data, err := stepfunctions.MarshalDocument(counts, "counts")
+	if err != nil {
+		log.Printf("Failed to marshal execution counts: %v", err)
+	} else if err := os.WriteFile(filepath.Join(cfg.outputDir, "execution_counts.json"), data, 0644); err != nil {
+		log.Printf("Failed to save execution counts: %v", err)
+		if stop := errs.record("execution_counts.json", err); stop != nil {
+			return stopped(stop)
+		}
+	}
+
+	errs.save()
+	exitCode := exitOK
+	if len(errs.items) > 0 || fetcher.BudgetExceeded() {
+		exitCode = exitPartial
+	}
+	meta.finish(fetcher, nil, exitCode)
+	meta.save(cfg.outputDir)
+	return exitCode, nil
+}
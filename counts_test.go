@@ -0,0 +1,19 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCountStatuses(t *testing.T) {
+	statuses, err := parseCountStatuses("running, FAILED")
+	if err != nil || !reflect.DeepEqual(statuses, []string{"RUNNING", "FAILED"}) {
+		t.Errorf("got %v, %v", statuses, err)
+	}
+	if statuses, err := parseCountStatuses(""); err != nil || statuses != nil {
+		t.Errorf("got %v, %v for an empty list, want every status", statuses, err)
+	}
+	if _, err := parseCountStatuses("RUNNING,DONE"); err == nil {
+		t.Error("want an error for an unknown status")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// definitionFormats are the supported --definition-format values
+var definitionFormats = map[string]bool{"json": true, "yaml": true}
+
+// definitionYAML renders a JSON definition as block-style YAML in the key
+// order of the JSON, so it reads like a hand-written SAM or Serverless
+// Framework definition and converts back to the same JSON
+func definitionYAML(definition []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(definition, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse definition: %w", err)
+	}
+	blockStyle(&root)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, fmt.Errorf("failed to render definition as YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render definition as YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlToJSON converts a YAML definition to JSON. Key order is not kept.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var definition interface{}
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	asJSON, err := json.Marshal(definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert YAML: %w", err)
+	}
+	return asJSON, nil
+}
+
+// blockStyle drops the flow style and quoting JSON parses with; the encoder
+// still quotes strings that would otherwise read as numbers or booleans
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// yamlFile returns the YAML name of an exported JSON file
+func yamlFile(name string) string {
+	return strings.TrimSuffix(name, ".json") + ".yaml"
+}
+
+// yamlStateLine returns the line of the state's key in a YAML definition,
+// nested states included, or 0 when it is not there
+func yamlStateLine(definition []byte, state string) int {
+	var root yaml.Node
+	if err := yaml.Unmarshal(definition, &root); err != nil {
+		return 0
+	}
+	var find func(node *yaml.Node) int
+	find = func(node *yaml.Node) int {
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if key.Value == "States" && value.Kind == yaml.MappingNode {
+					for j := 0; j+1 < len(value.Content); j += 2 {
+						if value.Content[j].Value == state {
+							return value.Content[j].Line
+						}
+					}
+				}
+			}
+		}
+		for _, child := range node.Content {
+			if line := find(child); line > 0 {
+				return line
+			}
+		}
+		return 0
+	}
+	return find(&root)
+}
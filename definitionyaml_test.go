@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"gopkg.in/yaml.v3"
+)
+
+const yamlFixture = `{"Comment":"Routes: orders","StartAt":"Route","States":{
+  "Route":{"Type":"Choice","Choices":[{"Variable":"$.total","NumericGreaterThan":100,"Next":"Fan"}],"Default":"Done"},
+  "Fan":{"Type":"Parallel","Branches":[{"StartAt":"Charge","States":{"Charge":{"Type":"Pass","Result":{"code":"007","ok":"true"},"End":true}}}],"Next":"Done"},
+  "Done":{"Type":"Succeed"}}}`
+
+func TestDefinitionYAML(t *testing.T) {
+	data, err := definitionYAML([]byte(yamlFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "yaml/orders.asl.yaml", data)
+
+	// Converting back gives the original definition
+	var fromYAML, fromJSON interface{}
+	if err := yaml.Unmarshal(data, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(yamlFixture), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	roundTripped, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back interface{}
+	if err := json.Unmarshal(roundTripped, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, fromJSON) {
+		t.Errorf("round trip changed the definition:\n%s", roundTripped)
+	}
+
+	for state, want := range map[string]int{"Route": 4, "Charge": 16, "Done": 23, "Missing": 0} {
+		if got := yamlStateLine(data, state); got != want {
+			t.Errorf("yamlStateLine(%q) = %d, want %d", state, got, want)
+		}
+	}
+}
+
+func TestFileExporterYAML(t *testing.T) {
+	dir := t.TempDir()
+	exporter := newFileExporter(dir)
+	exporter.definitionFormat = "yaml"
+	sm := stepfunctions.StateMachine{
+		Name:       "orders",
+		Definition: yamlFixture,
+		States:     []stepfunctions.State{{Name: "Done", Type: "Succeed", RawDefinition: map[string]interface{}{"Type": "Succeed"}}},
+	}
+	if err := exporter.WriteStateMachine(context.Background(), sm); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"orders_Done.yaml": "Type: Succeed\n"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "orders.asl.yaml")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+)
+
+// dlqFile collects, in the output directory, the records the remote sinks
+// gave up on: the newrelic and s3:// exports, the --webhook-url summary and
+// the --kinesis-stream and --firehose-stream records. retry-dlq re-sends them.
+// The file and sqlite exports write locally, so their failures are run errors
+// in errors.json rather than DLQ records.
+const dlqFile = "dlq.ndjson"
+
+// dlqRecord is one line of the DLQ file. Sink is "newrelic", "webhook",
+// "s3:<bucket>", "kinesis:<stream>" or "firehose:<stream>"; Record is what
+// was sent to it, an s3DLQRecord for S3.
+type dlqRecord struct {
+	Time   time.Time
+	Sink   string
+	Error  string
+	Record json.RawMessage
+}
+
+// appendDLQ appends records to the DLQ file at path with the error that
+// stopped them
+func appendDLQ(path, sink string, records []json.RawMessage, cause error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create DLQ directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open DLQ file: %w", err)
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	now := time.Now().UTC()
+	for _, record := range records {
+		if err := encoder.Encode(dlqRecord{Time: now, Sink: sink, Error: cause.Error(), Record: record}); err != nil {
+			return fmt.Errorf("failed to write DLQ file: %w", err)
+		}
+	}
+	return nil
+}
+
+// streamDeadLetter dead-letters the records of a Kinesis or Firehose sink.
+// The failure itself is reported by the sink as a warning.
+func streamDeadLetter(path, sink string) stepfunctions.DeadLetterFunc {
+	return func(records []json.RawMessage, cause error) {
+		if err := appendDLQ(path, sink, records, cause); err != nil {
+			log.Printf("Failed to save %d %s records to the DLQ: %v", len(records), sink, err)
+		}
+	}
+}
+
+// readDLQ reads the records of a DLQ file
+func readDLQ(path string) ([]dlqRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []dlqRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record dlqRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// runRetryDLQ re-sends the records of a DLQ file to the sinks they failed
+// on. The file is moved aside first, so records that fail again land in a
+// fresh DLQ file at the same path; records for sinks that cannot be
+// reconstructed are carried over unchanged.
+func runRetryDLQ(args []string) {
+	fs := flag.NewFlagSet("retry-dlq", flag.ExitOnError)
+	dlqPath := fs.String("dlq", filepath.Join("stepfunctions_state_definitions", dlqFile), "DLQ file to re-send")
+	region := fs.String("region", "us-west-2", "AWS region of the S3 buckets, Kinesis and Firehose streams and secrets")
+	nrRegion := fs.String("nr-region", "", "New Relic data center: us, eu or fedramp (default NEW_RELIC_REGION)")
+	nrAccountID := fs.String("nr-account-id", "", "New Relic account ID (default NEW_RELIC_ACCOUNT_ID)")
+	nrLicenseKey := fs.String("nr-license-key", "", "New Relic license key, or secretsmanager:/ssm: reference to it (default NEW_RELIC_LICENSE_KEY)")
+	webhookURL := fs.String("webhook-url", "", "Endpoint for webhook records, or secretsmanager:/ssm: reference to it")
+	webhookSecret := fs.String("webhook-secret", os.Getenv("WEBHOOK_SECRET"), "HMAC-SHA256 key for signing webhook requests, or secretsmanager:/ssm: reference to it (default $WEBHOOK_SECRET)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher retry-dlq [--dlq <file>] [sink flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	nr := newRelicFromEnv(newRelicConfig{region: *nrRegion, accountID: *nrAccountID, licenseKey: *nrLicenseKey})
+	if err := nr.validate(); err != nil {
+		log.Fatalf("Invalid --nr-region: %v", err)
+	}
+	if err := resolveSecrets(ctx, *region, &nr.licenseKey, webhookURL, webhookSecret); err != nil {
+		log.Fatalf("Failed to read the sink credentials: %v", err)
+	}
+
+	// Records left by an interrupted retry are re-sent as well
+	retrying := *dlqPath + ".retry"
+	if _, err := os.Stat(retrying); err != nil {
+		if err := os.Rename(*dlqPath, retrying); err != nil {
+			log.Fatalf("No DLQ file to re-send: %v", err)
+		}
+	} else if _, err := os.Stat(*dlqPath); err == nil {
+		log.Fatalf("Both %s and %s exist; re-send or merge %s first", *dlqPath, retrying, retrying)
+	}
+	records, err := readDLQ(retrying)
+	if err != nil {
+		log.Fatalf("Failed to read DLQ file: %v", err)
+	}
+
+	bySink := make(map[string][]json.RawMessage)
+	for _, record := range records {
+		bySink[record.Sink] = append(bySink[record.Sink], record.Record)
+	}
+	sinks := make([]string, 0, len(bySink))
+	for sink := range bySink {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+
+	retry := dlqRetry{region: *region, dlqPath: *dlqPath, nr: nr, webhookURL: *webhookURL, webhookSecret: *webhookSecret}
+	for _, sink := range sinks {
+		if err := retry.resend(ctx, sink, bySink[sink]); err != nil {
+			log.Printf("Failed to re-send %s records: %v", sink, err)
+			continue
+		}
+		fmt.Printf("Re-sent %d %s records\n", len(bySink[sink]), sink)
+	}
+	if err := os.Remove(retrying); err != nil {
+		log.Fatalf("Failed to remove %s: %v", retrying, err)
+	}
+	if remaining, err := readDLQ(*dlqPath); err == nil && len(remaining) > 0 {
+		log.Printf("%d of %d records are back in %s", len(remaining), len(records), *dlqPath)
+		os.Exit(exitPartial)
+	}
+	fmt.Printf("Re-sent all %d records\n", len(records))
+}
+
+// dlqRetry rebuilds the sinks of DLQ records. Every sink it builds
+// dead-letters to dlqPath again, so resend never loses records: on error they
+// are either back in the DLQ or were never sent.
+type dlqRetry struct {
+	region        string
+	dlqPath       string
+	nr            newRelicConfig
+	webhookURL    string
+	webhookSecret string
+}
+
+func (r dlqRetry) resend(ctx context.Context, sink string, records []json.RawMessage) error {
+	kind, stream, _ := strings.Cut(sink, ":")
+	switch kind {
+	case "newrelic":
+		exporter, err := newNewRelicExporter(r.nr, r.dlqPath)
+		if err != nil {
+			return r.keep(sink, records, err)
+		}
+		var errs []error
+		for _, record := range records {
+			errs = append(errs, exporter.sink.add(ctx, record))
+		}
+		return errors.Join(append(errs, exporter.sink.flush(ctx))...)
+	case "webhook":
+		if r.webhookURL == "" {
+			return r.keep(sink, records, errors.New("--webhook-url is required to re-send webhook records"))
+		}
+		var errs []error
+		for _, record := range records {
+			errs = append(errs, newWebhookSink(r.webhookURL, r.webhookSecret, r.dlqPath).deliver(ctx, record, []json.RawMessage{record}))
+		}
+		return errors.Join(errs...)
+	case "s3":
+		client, err := newStateObjectClient(ctx, r.region)
+		if err != nil {
+			return r.keep(sink, records, err)
+		}
+		var errs []error
+		for _, record := range records {
+			var object s3DLQRecord
+			if err := json.Unmarshal(record, &object); err != nil {
+				errs = append(errs, r.keep(sink, []json.RawMessage{record}, fmt.Errorf("failed to decode record: %w", err)))
+				continue
+			}
+			if err := putExportObject(ctx, client, stream, object.Key, object.Body); err != nil {
+				errs = append(errs, r.keep(sink, []json.RawMessage{record}, err))
+			}
+		}
+		return errors.Join(errs...)
+	case "kinesis", "firehose":
+		region := r.region
+		if parsed, err := arn.Parse(stream); err == nil {
+			region = parsed.Region
+		}
+		awsCfg, err := loadAWSConfig(ctx, region)
+		if err != nil {
+			return r.keep(sink, records, fmt.Errorf("failed to load AWS config: %w", err))
+		}
+		var streamSink stepfunctions.RecordSink
+		if kind == "kinesis" {
+			streamSink = stepfunctions.NewKinesisSink(kinesis.NewFromConfig(awsCfg), stream, streamDeadLetter(r.dlqPath, sink))
+		} else {
+			streamSink = stepfunctions.NewFirehoseSink(firehose.NewFromConfig(awsCfg), stream, streamDeadLetter(r.dlqPath, sink))
+		}
+		var decoded []stepfunctions.Record
+		for _, record := range records {
+			var item stepfunctions.Record
+			if err := json.Unmarshal(record, &item); err != nil {
+				return r.keep(sink, records, fmt.Errorf("failed to decode record: %w", err))
+			}
+			decoded = append(decoded, item)
+		}
+		return errors.Join(streamSink.Put(ctx, decoded), streamSink.Close(ctx))
+	}
+	return r.keep(sink, records, fmt.Errorf("unknown sink %q", sink))
+}
+
+// keep puts records that could not be sent back in the DLQ and returns err
+func (r dlqRetry) keep(sink string, records []json.RawMessage, err error) error {
+	if dlqErr := appendDLQ(r.dlqPath, sink, records, err); dlqErr != nil {
+		return errors.Join(err, dlqErr)
+	}
+	return err
+}
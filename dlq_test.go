@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestRetryDLQWebhook(t *testing.T) {
+	var received []string
+	status := http.StatusForbidden
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		received = append(received, string(body))
+	}))
+	defer server.Close()
+
+	dlqPath := filepath.Join(t.TempDir(), dlqFile)
+	records := []json.RawMessage{json.RawMessage(`{"exitCode":2}`)}
+	retry := dlqRetry{dlqPath: dlqPath, webhookURL: server.URL}
+	ctx := context.Background()
+
+	// Rejected again: the record is back in the DLQ
+	if err := retry.resend(ctx, "webhook", records); err == nil {
+		t.Fatal("expected an error")
+	}
+	dlq, err := readDLQ(dlqPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dlq) != 1 || dlq[0].Sink != "webhook" || string(dlq[0].Record) != `{"exitCode":2}` {
+		t.Fatalf("got DLQ %+v", dlq)
+	}
+
+	status = http.StatusOK
+	if err := retry.resend(ctx, "webhook", records); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0] != `{"exitCode":2}` {
+		t.Errorf("got webhook bodies %q", received)
+	}
+}
+
+func TestRetryDLQKeepsUnknownSinks(t *testing.T) {
+	dlqPath := filepath.Join(t.TempDir(), dlqFile)
+	retry := dlqRetry{dlqPath: dlqPath}
+	if err := retry.resend(context.Background(), "carrier-pigeon", []json.RawMessage{json.RawMessage(`{}`)}); err == nil {
+		t.Fatal("expected an error")
+	}
+	dlq, err := readDLQ(dlqPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dlq) != 1 || dlq[0].Sink != "carrier-pigeon" {
+		t.Errorf("got DLQ %+v", dlq)
+	}
+}
+
+func TestAppendDLQ(t *testing.T) {
+	dlqPath := filepath.Join(t.TempDir(), "nested", dlqFile)
+	for i := 0; i < 2; i++ {
+		if err := appendDLQ(dlqPath, "kinesis:audit", []json.RawMessage{json.RawMessage(`{"recordType":"execution"}`)}, errors.New("throttled")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dlq, err := readDLQ(dlqPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dlq) != 2 || dlq[1].Error != "throttled" || dlq[1].Time.IsZero() {
+		t.Errorf("got DLQ %+v", dlq)
+	}
+}
+
+// flakyObjects is an in-memory S3 bucket whose puts fail while down
+type flakyObjects struct {
+	memoryObjects
+	down bool
+}
+
+func (o *flakyObjects) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if o.down {
+		return nil, errors.New("connection reset")
+	}
+	return o.memoryObjects.PutObject(ctx, params, optFns...)
+}
+
+func TestS3ExportDeadLetters(t *testing.T) {
+	objects := &flakyObjects{memoryObjects: memoryObjects{}, down: true}
+	previous := newStateObjectClient
+	newStateObjectClient = func(context.Context, string) (stateObjectAPI, error) { return objects, nil }
+	t.Cleanup(func() { newStateObjectClient = previous })
+	ctx := context.Background()
+
+	dlqPath := filepath.Join(t.TempDir(), dlqFile)
+	exporter, err := newS3Exporter(ctx, "us-east-1", "s3://audit/runs", dlqPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Close(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	dlq, err := readDLQ(dlqPath)
+	if err != nil || len(dlq) != 1 || dlq[0].Sink != "s3:audit" {
+		t.Fatalf("got DLQ %+v, %v", dlq, err)
+	}
+
+	objects.down = false
+	retry := dlqRetry{region: "us-east-1", dlqPath: filepath.Join(t.TempDir(), dlqFile)}
+	if err := retry.resend(ctx, dlq[0].Sink, []json.RawMessage{dlq[0].Record}); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := objects.memoryObjects["audit/runs/state_machines.json"]; !ok || !strings.Contains(string(data), "stateMachines") {
+		t.Errorf("got objects %v, want state_machines.json re-sent", objects.memoryObjects)
+	}
+}
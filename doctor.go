@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// Outcomes of a permission check
+const (
+	permissionAllowed    = "allowed"
+	permissionMissing    = "MISSING"
+	permissionUnverified = "unverified"
+)
+
+// permissionCheck is the outcome of checking one permission
+type permissionCheck struct {
+	permission
+	Status string
+}
+
+// doctorAPI is what runDoctor calls, so tests can substitute fakes
+type doctorAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	iam.SimulatePrincipalPolicyAPIClient
+	ListStateMachines(ctx context.Context, params *sfn.ListStateMachinesInput, optFns ...func(*sfn.Options)) (*sfn.ListStateMachinesOutput, error)
+}
+
+// doctorClients sends each doctorAPI call to its service
+type doctorClients struct {
+	sts *sts.Client
+	iam *iam.Client
+	sfn *sfn.Client
+}
+
+func (c doctorClients) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return c.sts.GetCallerIdentity(ctx, params, optFns...)
+}
+
+func (c doctorClients) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return c.iam.GetRole(ctx, params, optFns...)
+}
+
+func (c doctorClients) SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	return c.iam.SimulatePrincipalPolicy(ctx, params, optFns...)
+}
+
+func (c doctorClients) ListStateMachines(ctx context.Context, params *sfn.ListStateMachinesInput, optFns ...func(*sfn.Options)) (*sfn.ListStateMachinesOutput, error) {
+	return c.sfn.ListStateMachines(ctx, params, optFns...)
+}
+
+// runDoctor is the --doctor run: it checks that the caller may perform
+// every action the configured run calls, prints the outcome and returns
+// exitFatal when any is missing, before a long run fails halfway
+func runDoctor(ctx context.Context, cfg runConfig) int {
+	awsCfg, err := loadAWSConfig(ctx, cfg.region)
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return exitFatal
+	}
+	client := doctorClients{sts.NewFromConfig(awsCfg), iam.NewFromConfig(awsCfg), sfn.NewFromConfig(awsCfg)}
+	return reportPermissions(checkPermissions(ctx, client, cfg.permissions()))
+}
+
+// checkPermissions simulates the caller's policies for perms. When the
+// caller may not simulate them, the read-only calls that can be probed are,
+// and the rest are left unverified.
+func checkPermissions(ctx context.Context, client doctorAPI, perms []permission) []permissionCheck {
+	identity, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		log.Printf("Failed to identify the caller: %v", err)
+		return withStatus(perms, permissionUnverified)
+	}
+	fmt.Printf("Checking %d permissions of %s\n", len(perms), aws.ToString(identity.Arn))
+
+	decisions, err := simulatePermissions(ctx, client, aws.ToString(identity.Arn), perms)
+	if err != nil {
+		log.Printf("Policy simulation is unavailable, probing instead: %v", err)
+		return probePermissions(ctx, client, perms)
+	}
+	checks := withStatus(perms, permissionMissing)
+	for i := range checks {
+		if decisions[checks[i].Action] == string(types.PolicyEvaluationDecisionTypeAllowed) {
+			checks[i].Status = permissionAllowed
+		}
+	}
+	return checks
+}
+
+// simulatePermissions returns the decision on each action for the IAM user
+// or role behind callerArn, on the resources the run calls it on
+func simulatePermissions(ctx context.Context, client doctorAPI, callerArn string, perms []permission) (map[string]string, error) {
+	principal, err := principalARN(ctx, client, callerArn)
+	if err != nil {
+		return nil, err
+	}
+	decisions := make(map[string]string)
+	for _, statement := range buildIAMPolicy(perms).Statement {
+		input := &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String(principal),
+			ActionNames:     statement.Action,
+		}
+		if !slices.Equal(statement.Resource, []string{"*"}) {
+			input.ResourceArns = statement.Resource
+		}
+		paginator := iam.NewSimulatePrincipalPolicyPaginator(client, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to simulate policies of %s: %w", principal, err)
+			}
+			for _, result := range page.EvaluationResults {
+				action := aws.ToString(result.EvalActionName)
+				// An action needed on several sets of resources needs all of them
+				if decisions[action] == "" || result.EvalDecision != types.PolicyEvaluationDecisionTypeAllowed {
+					decisions[action] = string(result.EvalDecision)
+				}
+			}
+		}
+	}
+	return decisions, nil
+}
+
+// principalARN returns the IAM user or role whose policies apply to
+// callerArn. An assumed-role session is traced back to its role, whose ARN
+// may include a path the session ARN drops.
+func principalARN(ctx context.Context, client doctorAPI, callerArn string) (string, error) {
+	parsed, err := arn.Parse(callerArn)
+	if err != nil {
+		return "", fmt.Errorf("invalid caller ARN: %w", err)
+	}
+	switch {
+	case parsed.Service == "iam" && strings.HasPrefix(parsed.Resource, "user/"):
+		return callerArn, nil
+	case parsed.Service == "sts" && strings.HasPrefix(parsed.Resource, "assumed-role/"):
+		roleName, _, _ := strings.Cut(strings.TrimPrefix(parsed.Resource, "assumed-role/"), "/")
+		role, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			return "", fmt.Errorf("failed to get role %s: %w", roleName, err)
+		}
+		return aws.ToString(role.Role.Arn), nil
+	}
+	return "", fmt.Errorf("cannot simulate the policies of %s", callerArn)
+}
+
+// probePermissions makes the one probe call that needs no resource and
+// leaves the other permissions unverified
+func probePermissions(ctx context.Context, client doctorAPI, perms []permission) []permissionCheck {
+	checks := withStatus(perms, permissionUnverified)
+	for i := range checks {
+		if checks[i].Action != "states:ListStateMachines" {
+			continue
+		}
+		_, err := client.ListStateMachines(ctx, &sfn.ListStateMachinesInput{MaxResults: 1})
+		var apiErr smithy.APIError
+		switch {
+		case err == nil:
+			checks[i].Status = permissionAllowed
+		case errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorCode(), "AccessDenied"):
+			checks[i].Status = permissionMissing
+		default:
+			log.Printf("Failed to probe %s: %v", checks[i].Action, err)
+		}
+	}
+	return checks
+}
+
+func withStatus(perms []permission, status string) []permissionCheck {
+	checks := make([]permissionCheck, len(perms))
+	for i, perm := range perms {
+		checks[i] = permissionCheck{permission: perm, Status: status}
+	}
+	return checks
+}
+
+// reportPermissions prints the checks and returns exitFatal when any
+// permission is missing
+func reportPermissions(checks []permissionCheck) int {
+	permissionTable := newTable()
+	permissionTable.SetColumnColor(1, colorPermission)
+	permissionTable.SetHeader([]string{"Action", "Status", "Needed For"})
+	var missing, unverified []string
+	for _, check := range checks {
+		permissionTable.Append([]string{check.Action, check.Status, check.Reason})
+		switch check.Status {
+		case permissionMissing:
+			missing = append(missing, check.Action)
+		case permissionUnverified:
+			unverified = append(unverified, check.Action)
+		}
+	}
+	permissionTable.Render()
+
+	if len(unverified) > 0 {
+		fmt.Printf("Could not verify %d permission(s); grant iam:SimulatePrincipalPolicy (and iam:GetRole for roles) to check them all\n", len(unverified))
+	}
+	if len(missing) > 0 {
+		fmt.Printf("Missing %d permission(s): %s\n", len(missing), strings.Join(missing, ", "))
+		return exitFatal
+	}
+	fmt.Println("No missing permissions found.")
+	return exitOK
+}
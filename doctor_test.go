@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// fakeDoctor is a caller with the allowed actions; simulateErr fails the
+// policy simulation
+type fakeDoctor struct {
+	caller      string
+	allowed     map[string]bool
+	simulateErr error
+	listErr     error
+	simulatedAs string
+}
+
+func (d *fakeDoctor) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(d.caller)}, nil
+}
+
+func (d *fakeDoctor) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return &iam.GetRoleOutput{Role: &types.Role{Arn: aws.String("arn:aws:iam::123456789012:role/collectors/" + aws.ToString(params.RoleName))}}, nil
+}
+
+func (d *fakeDoctor) SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	if d.simulateErr != nil {
+		return nil, d.simulateErr
+	}
+	d.simulatedAs = aws.ToString(params.PolicySourceArn)
+	out := &iam.SimulatePrincipalPolicyOutput{}
+	for _, action := range params.ActionNames {
+		decision := types.PolicyEvaluationDecisionTypeImplicitDeny
+		if d.allowed[action] {
+			decision = types.PolicyEvaluationDecisionTypeAllowed
+		}
+		out.EvaluationResults = append(out.EvaluationResults, types.EvaluationResult{EvalActionName: aws.String(action), EvalDecision: decision})
+	}
+	return out, nil
+}
+
+func (d *fakeDoctor) ListStateMachines(ctx context.Context, params *sfn.ListStateMachinesInput, optFns ...func(*sfn.Options)) (*sfn.ListStateMachinesOutput, error) {
+	return &sfn.ListStateMachinesOutput{}, d.listErr
+}
+
+func TestPermissions(t *testing.T) {
+	actions := func(cfg runConfig) []string {
+		var actions []string
+		for _, perm := range cfg.permissions() {
+			actions = append(actions, perm.Action)
+		}
+		return actions
+	}
+	base := actions(runConfig{})
+	if !slices.Contains(base, "states:ListStateMachines") || slices.Contains(base, "states:GetExecutionHistory") {
+		t.Errorf("got %v for the default run", base)
+	}
+	full := actions(runConfig{
+		includeHistory:   true,
+		stateMachineARNs: []string{"arn:aws:states:us-east-1:123456789012:stateMachine:orders"},
+		export:           "file, s3://bucket/prefix",
+		newRelic:         newRelicConfig{licenseKey: "ssm:/nr/license"},
+	})
+	for _, want := range []string{"states:GetExecutionHistory", "s3:PutObject", "ssm:GetParameter"} {
+		if !slices.Contains(full, want) {
+			t.Errorf("got %v, want %s", full, want)
+		}
+	}
+	if slices.Contains(full, "states:ListStateMachines") {
+		t.Errorf("got states:ListStateMachines with explicit state machines")
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	perms := []permission{{Action: "states:ListStateMachines"}, {Action: "states:DescribeExecution"}}
+	ctx := context.Background()
+
+	t.Run("simulated", func(t *testing.T) {
+		client := &fakeDoctor{
+			caller:  "arn:aws:sts::123456789012:assumed-role/fetcher/session",
+			allowed: map[string]bool{"states:ListStateMachines": true},
+		}
+		checks := checkPermissions(ctx, client, perms)
+		if client.simulatedAs != "arn:aws:iam::123456789012:role/collectors/fetcher" {
+			t.Errorf("simulated as %s, want the role with its path", client.simulatedAs)
+		}
+		if checks[0].Status != permissionAllowed || checks[1].Status != permissionMissing {
+			t.Errorf("got %+v", checks)
+		}
+		if code := reportPermissions(checks); code != exitFatal {
+			t.Errorf("got exit code %d with a missing permission, want %d", code, exitFatal)
+		}
+	})
+
+	t.Run("probed", func(t *testing.T) {
+		client := &fakeDoctor{
+			caller:      "arn:aws:iam::123456789012:user/alice",
+			simulateErr: &smithy.GenericAPIError{Code: "AccessDenied"},
+			listErr:     &smithy.GenericAPIError{Code: "AccessDeniedException"},
+		}
+		checks := checkPermissions(ctx, client, perms)
+		if checks[0].Status != permissionMissing || checks[1].Status != permissionUnverified {
+			t.Errorf("got %+v", checks)
+		}
+	})
+}
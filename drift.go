@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// processDrift compares the state machines created by CloudFormation with
+// their stacks, prints and saves the result to drift.json and returns the
+// findings for drifted ones
+func processDrift(ctx context.Context, fetcher *stepfunctions.Fetcher, stateMachines []stepfunctions.StateMachine, outputDir string, errs *runErrors) ([]stepfunctions.Finding, error) {
+	drifts := []*stepfunctions.StackDrift{}
+	var findings []stepfunctions.Finding
+	driftTable := newTable()
+	driftTable.SetColumnColor(3, colorViolation)
+	driftTable.SetHeader([]string{"State Machine", "Stack", "Logical ID", "Drift", "Changes"})
+	for _, sm := range stateMachines {
+		drift, err := fetcher.DetectStackDrift(ctx, sm)
+		if err != nil {
+			log.Printf("Failed to detect CloudFormation drift for %s: %v", sm.Name, err)
+			if stop := errs.record(sm.ARN, err); stop != nil {
+				return findings, stop
+			}
+			continue
+		}
+		if drift == nil {
+			continue // Not created by CloudFormation
+		}
+		drifts = append(drifts, drift)
+		findings = append(findings, stepfunctions.DriftFindings(sm, drift)...)
+
+		var changes []string
+		for _, change := range drift.DefinitionChanges {
+			changes = append(changes, fmt.Sprintf("%s %s", change.State, change.Change))
+		}
+		for _, diff := range drift.Differences {
+			if !strings.HasPrefix(diff.Path, "/Definition") {
+				changes = append(changes, fmt.Sprintf("%s %s", diff.Path, diff.Type))
+			}
+		}
+		driftTable.Append([]string{sm.Name, drift.StackName, drift.LogicalResourceID, drift.Status, strings.Join(changes, "\n")})
+	}
+	fmt.Println("CloudFormation Drift:")
+	driftTable.Render()
+	fmt.Printf("%d of %d state machines are managed by CloudFormation\n\n", len(drifts), len(stateMachines))
+
+	data, err := stepfunctions.MarshalDocument(drifts, "drifts")
+	if err != nil {
+		log.Printf("Failed to marshal drift report: %v", err)
+		return findings, nil
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "drift.json"), data, 0644); err != nil {
+		log.Printf("Failed to save drift report: %v", err)
+	}
+	return findings, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func displayRunPlan(ctx context.Context, region string, opts []stepfunctions.Option) {
+	fetcher, err := stepfunctions.NewFetcher(ctx, region, opts...)
+	if err != nil {
+		log.Fatalf("Failed to create fetcher: %v", err)
+	}
+
+	plan, err := fetcher.PlanRun(ctx)
+	if err != nil {
+		log.Fatalf("Failed to plan run: %v", err)
+	}
+
+	planTable := newTable()
+	planTable.SetHeader([]string{"Name", "Type", "States", "Executions", "API Calls", "Files"})
+	for _, sm := range plan.StateMachines {
+		executions := "unknown (logs)"
+		if sm.Executions >= 0 {
+			executions = fmt.Sprintf("%d", sm.Executions)
+		}
+		planTable.Append([]string{
+			sm.Name,
+			sm.Type,
+			fmt.Sprintf("%d", sm.States),
+			executions,
+			fmt.Sprintf("%d", sm.TotalAPICalls()),
+			fmt.Sprintf("%d", sm.Files),
+		})
+	}
+	fmt.Println("Dry run - planned work:")
+	planTable.Render()
+
+	var ops []string
+	total := 0
+	for op, calls := range plan.APICalls {
+		ops = append(ops, fmt.Sprintf("%s: %d", op, calls))
+		total += calls
+	}
+	sort.Strings(ops)
+	fmt.Printf("Estimated API calls: %d (%s)\n", total, strings.Join(ops, ", "))
+	fmt.Printf("Estimated files: %d\n", plan.Files)
+	fmt.Println("Note: Express executions are discovered from CloudWatch Logs; their calls are lower bounds.")
+}
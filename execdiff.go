@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+	"stepfunction-fetcher/stepfunctions/export"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+func runExecDiff(args []string) {
+	fs := flag.NewFlagSet("exec-diff", flag.ExitOnError)
+	region := fs.String("region", "", "AWS region (defaults to the region in the execution ARN)")
+	output := fs.String("output", "", "Also write the diff as JSON to this file")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	inputDir := fs.String("input-dir", "", "Read both executions from a previous export instead of fetching them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher exec-diff [flags] <execution-arn-a> <execution-arn-b>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	initColor(*noColor)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var a, b stepfunctions.Execution
+	if *inputDir != "" {
+		a = loadExportedExecution(*inputDir, fs.Arg(0))
+		b = loadExportedExecution(*inputDir, fs.Arg(1))
+	} else {
+		ctx := context.Background()
+		fetcher, err := stepfunctions.NewFetcher(ctx, regionFor(fs.Arg(0), *region))
+		if err != nil {
+			log.Fatalf("Failed to create fetcher: %v", err)
+		}
+		a = fetchExecutionWithHistory(ctx, fetcher, fs.Arg(0))
+		b = fetchExecutionWithHistory(ctx, fetcher, fs.Arg(1))
+	}
+	diff := stepfunctions.DiffExecutions(a, b)
+	displayExecutionDiff(diff)
+
+	if *output != "" {
+		data, err := stepfunctions.MarshalDocument(diff, "")
+		if err != nil {
+			log.Fatalf("Failed to marshal diff: %v", err)
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			log.Fatalf("Failed to save diff: %v", err)
+		}
+	}
+}
+
+func fetchExecutionWithHistory(ctx context.Context, fetcher *stepfunctions.Fetcher, executionArn string) stepfunctions.Execution {
+	exec, err := fetcher.DescribeExecution(ctx, executionArn)
+	if err != nil {
+		log.Fatalf("Failed to fetch execution: %v", err)
+	}
+	exec.History, err = fetcher.GetExecutionHistory(ctx, executionArn)
+	if err != nil {
+		log.Fatalf("Failed to fetch execution history: %v", err)
+	}
+	return exec
+}
+
+// loadExportedExecution reads an execution saved with --include-history or
+// by fetch-execution
+func loadExportedExecution(dir, executionArn string) stepfunctions.Execution {
+	exec, err := export.LoadExecution(dir, executionArn)
+	if err != nil {
+		log.Fatalf("Failed to load execution: %v", err)
+	}
+	if len(exec.History) == 0 {
+		log.Fatalf("Execution %s was exported without its history; export with --include-history", executionArn)
+	}
+	return exec
+}
+
+func displayExecutionDiff(diff stepfunctions.ExecutionDiff) {
+	fmt.Printf("A: %s (%s, %s)\n", diff.A.ExecutionArn, colorStatus(diff.A.Status), diff.A.FormatDuration())
+	fmt.Printf("B: %s (%s, %s)\n", diff.B.ExecutionArn, colorStatus(diff.B.Status), diff.B.FormatDuration())
+	if diff.DivergedAt < 0 {
+		fmt.Println("Path: identical")
+	} else {
+		fmt.Printf("Path: diverges at step %d\n", diff.DivergedAt+1)
+		fmt.Printf("  A: %s\n", strings.Join(diff.PathA[diff.DivergedAt:], " -> "))
+		fmt.Printf("  B: %s\n", strings.Join(diff.PathB[diff.DivergedAt:], " -> "))
+	}
+	fmt.Println()
+
+	diffTable := newTable()
+	diffTable.SetHeader([]string{"State", "Entries A/B", "Duration A", "Duration B", "Delta", "Input", "Output", "Errors A", "Errors B"})
+	for _, state := range diff.States {
+		diffTable.Append([]string{
+			state.StateName,
+			fmt.Sprintf("%d/%d", state.EntriesA, state.EntriesB),
+			state.DurationA.String(),
+			state.DurationB.String(),
+			state.DurationDelta().String(),
+			changedLabel(state.InputChanged),
+			changedLabel(state.OutputChanged),
+			strings.Join(state.ErrorsA, "\n"),
+			strings.Join(state.ErrorsB, "\n"),
+		})
+	}
+	diffTable.Render()
+}
+
+func changedLabel(changed bool) string {
+	if changed {
+		return "differs"
+	}
+	return ""
+}
+
+// regionFor returns the override when set, otherwise the region embedded in the ARN
+func regionFor(resourceArn, override string) string {
+	if override != "" {
+		return override
+	}
+	if parsed, err := arn.Parse(resourceArn); err == nil && parsed.Region != "" {
+		return parsed.Region
+	}
+	return "us-west-2"
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+	"stepfunction-fetcher/stepfunctions/export"
+)
+
+// iacOptions are the export subcommand flags that only some formats use
+type iacOptions struct {
+	definitionS3 string // s3://bucket/prefix the definitions are uploaded to (cloudformation)
+	since        string // NRQL time window (nrql)
+}
+
+// iacFormats are the renderers of the export subcommand: infrastructure as
+// code, and NRQL for New Relic dashboards
+var iacFormats = map[string]func([]stepfunctions.StateMachine, string, iacOptions) error{
+	"terraform":      writeTerraform,
+	"cloudformation": writeCloudFormation,
+	"cdk":            writeCDK,
+	"nrql":           writeNRQL,
+}
+
+// runExport renders a previous run's state machines as infrastructure as
+// code, to bring state machines created in the console under IaC, or as
+// NRQL queries over their exported New Relic events
+func runExport(args []string) {
+	if len(args) == 0 || iacFormats[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: stepfunction-fetcher export terraform|cloudformation|cdk|nrql [flags]")
+		fmt.Fprintln(os.Stderr, "       stepfunction-fetcher export iam-policy [run flags]")
+		os.Exit(2)
+	}
+	format := args[0]
+	fs := flag.NewFlagSet("export "+format, flag.ExitOnError)
+	inputDir := fs.String("input-dir", "stepfunctions_state_definitions", "Output directory of a previous run (reads its state_machines.json)")
+	outputDir := fs.String("output-dir", format, "Directory to write the generated files to")
+	definitionS3 := fs.String("definition-s3", "", "cloudformation: reference definitions as DefinitionS3Location under this s3://bucket/prefix instead of inlining them")
+	since := fs.String("since", defaultNRQLSince, "nrql: time window of the queries, as an NRQL SINCE clause")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: stepfunction-fetcher export %s [flags]\n", format)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	stateMachines, err := loadSnapshot(*inputDir)
+	if err != nil {
+		log.Fatalf("Failed to load state machines: %v", err)
+	}
+	if *definitionS3 != "" && !strings.HasPrefix(*definitionS3, "s3://") {
+		log.Fatalf("Invalid --definition-s3 %q: expected s3://bucket/prefix", *definitionS3)
+	}
+	if err := iacFormats[format](stateMachines, *outputDir, iacOptions{definitionS3: *definitionS3, since: *since}); err != nil {
+		log.Fatalf("Failed to export %s: %v", format, err)
+	}
+	fmt.Printf("Exported %d state machines as %s to %s\n", len(stateMachines), format, *outputDir)
+}
+
+// loadSnapshot reads the state_machines.json written by the file exporter
+func loadSnapshot(dir string) ([]stepfunctions.StateMachine, error) {
+	stateMachines, err := export.LoadStateMachines(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	return stateMachines, nil
+}
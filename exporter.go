@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+	"stepfunction-fetcher/stepfunctions/export"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Exporter writes the fetched state machines, executions and history events
+// to a destination. Write calls happen in fetch order: a state machine, then
+// each of its executions followed by that execution's history events.
+type Exporter interface {
+	WriteStateMachine(ctx context.Context, sm stepfunctions.StateMachine) error
+	WriteExecution(ctx context.Context, sm stepfunctions.StateMachine, exec stepfunctions.Execution) error
+	WriteHistoryEvent(ctx context.Context, exec stepfunctions.Execution, event stepfunctions.HistoryEvent) error
+	// Close flushes anything buffered
+	Close(ctx context.Context) error
+}
+
+// defaultExport keeps the original behavior of writing JSON files to --output-dir
+const defaultExport = "file"
+
+// newExporters creates an exporter per --hook and per comma-separated destination:
+//
+//	file                 JSON files in the output directory
+//	s3://bucket/prefix   the same files as S3 objects
+//	sqlite[:path]        a SQLite database (default <output-dir>/stepfunctions.db)
+//	newrelic             New Relic custom events (see newRelicConfig)
+func newExporters(ctx context.Context, cfg runConfig) ([]Exporter, error) {
+	var exporters []Exporter
+	for _, dest := range strings.Split(cfg.export, ",") {
+		dest = strings.TrimSpace(dest)
+		var exporter Exporter
+		var err error
+		switch {
+		case dest == "" || dest == "file":
+			exporter = newFileExporter(cfg.outputDir)
+		case strings.HasPrefix(dest, "s3://"):
+			exporter, err = newS3Exporter(ctx, cfg.region, dest, filepath.Join(cfg.outputDir, dlqFile))
+		case dest == "sqlite" || strings.HasPrefix(dest, "sqlite:"):
+			dbPath := strings.TrimPrefix(strings.TrimPrefix(dest, "sqlite"), ":")
+			if dbPath == "" {
+				dbPath = filepath.Join(cfg.outputDir, "stepfunctions.db")
+			}
+			exporter, err = newSQLiteExporter(ctx, dbPath)
+		case dest == "newrelic":
+			exporter, err = newNewRelicExporter(cfg.newRelic, filepath.Join(cfg.outputDir, dlqFile))
+		default:
+			err = fmt.Errorf("unknown export destination %q (supported: file, s3://bucket/prefix, sqlite[:path], newrelic)", dest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if object, ok := exporter.(*objectExporter); ok {
+			object.definitionFormat = cfg.definitionFormat
+			object.definitionInlineMax = cfg.definitionInlineMax
+		}
+		exporters = append(exporters, exporter)
+	}
+	for _, command := range cfg.hooks {
+		exporters = append(exporters, newHookExporter(command, cfg))
+	}
+	return exporters, nil
+}
+
+func closeExporters(ctx context.Context, exporters []Exporter) error {
+	var errs []error
+	for _, exporter := range exporters {
+		if err := exporter.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// objectExporter writes the output-directory layout (the definition, one JSON
+// document per state, execution and raw response, plus state_machines.json) through put,
+// so files and S3 objects share the same names
+type objectExporter struct {
+	put           func(ctx context.Context, name string, data []byte) error
+	exists        func(name string) bool // Reports whether an unchanged object was already written
+	stateMachines []stepfunctions.StateMachine
+	// definitionFormat is "yaml" to save definitions as .yaml, otherwise JSON
+	definitionFormat string
+	// definitionInlineMax is the largest definition, in bytes, kept in
+	// state_machines.json; larger ones are only referenced. 0 keeps every one.
+	definitionInlineMax int
+}
+
+func newFileExporter(outputDir string) *objectExporter {
+	return &objectExporter{
+		put: func(_ context.Context, name string, data []byte) error {
+			return os.WriteFile(filepath.Join(outputDir, name), data, 0644)
+		},
+		exists: func(name string) bool {
+			_, err := os.Stat(filepath.Join(outputDir, name))
+			return err == nil
+		},
+	}
+}
+
+// newS3Exporter writes the files as objects under s3://bucket/prefix. The SDK
+// retries each put; objects that still fail are appended to the DLQ file at
+// dlqPath for retry-dlq.
+func newS3Exporter(ctx context.Context, region, dest, dlqPath string) (*objectExporter, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(dest, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid export destination %q: missing bucket", dest)
+	}
+	client, err := newStateObjectClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := "s3:" + bucket
+	return &objectExporter{
+		put: func(ctx context.Context, name string, data []byte) error {
+			key := path.Join(prefix, name)
+			err := putExportObject(ctx, client, bucket, key, data)
+			if err == nil {
+				return nil
+			}
+			record, marshalErr := json.Marshal(s3DLQRecord{Key: key, Body: data})
+			if marshalErr == nil {
+				marshalErr = appendDLQ(dlqPath, sink, []json.RawMessage{record}, err)
+			}
+			if marshalErr != nil {
+				return errors.Join(err, marshalErr)
+			}
+			return fmt.Errorf("%w (saved to %s)", err, dlqPath)
+		},
+		exists: func(string) bool { return false },
+	}, nil
+}
+
+// s3DLQRecord is the DLQ record of an object the s3:// export could not put
+type s3DLQRecord struct {
+	Key  string
+	Body []byte
+}
+
+// putExportObject puts one file of the export, typed by its extension
+func putExportObject(ctx context.Context, client stateObjectAPI, bucket, key string, data []byte) error {
+	contentType := "application/json"
+	if strings.HasSuffix(key, ".yaml") {
+		contentType = "application/yaml"
+	}
+	_, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (e *objectExporter) WriteStateMachine(ctx context.Context, sm stepfunctions.StateMachine) error {
+	// The JSON definition is saved as returned, so finding line numbers match it
+	if name := e.fileName(definitionFile(sm.Name)); !sm.Cached || !e.exists(name) {
+		definition, err := e.render([]byte(sm.Definition))
+		if err != nil {
+			return fmt.Errorf("failed to convert definition for %s: %w", sm.Name, err)
+		}
+		if err := e.put(ctx, name, definition); err != nil {
+			return fmt.Errorf("failed to save definition for %s: %w", sm.Name, err)
+		}
+	}
+	for _, state := range sm.States {
+		name := e.fileName(stateDefinitionFile(sm.Name, state.Name))
+		if sm.Cached && e.exists(name) {
+			continue // Same revision as the file already written
+		}
+		rawDef, err := json.MarshalIndent(state.RawDefinition, "", "  ")
+		if err == nil {
+			rawDef, err = e.render(rawDef)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal state definition for %s/%s: %w", sm.Name, state.Name, err)
+		}
+		if err := e.put(ctx, name, rawDef); err != nil {
+			return fmt.Errorf("failed to save state definition for %s/%s: %w", sm.Name, state.Name, err)
+		}
+	}
+	if sm.RawDescribe != nil {
+		if err := e.put(ctx, fmt.Sprintf("%s_describe.json", sm.Name), sm.RawDescribe); err != nil {
+			return fmt.Errorf("failed to save raw response for %s: %w", sm.Name, err)
+		}
+	}
+	if e.definitionInlineMax > 0 && len(sm.Definition) > e.definitionInlineMax {
+		if err := e.moveDefinition(ctx, &sm); err != nil {
+			return err
+		}
+	}
+	e.stateMachines = append(e.stateMachines, sm)
+	return nil
+}
+
+// moveDefinition replaces the definition kept in state_machines.json with its
+// hash and .asl.json file, which export.LoadStateMachines reads it back from
+func (e *objectExporter) moveDefinition(ctx context.Context, sm *stepfunctions.StateMachine) error {
+	name := definitionFile(sm.Name)
+	// The .asl.yaml file is not what the hash is of, so the JSON is saved too
+	if e.definitionFormat == "yaml" && (!sm.Cached || !e.exists(name)) {
+		if err := e.put(ctx, name, []byte(sm.Definition)); err != nil {
+			return fmt.Errorf("failed to save definition for %s: %w", sm.Name, err)
+		}
+	}
+	sm.DefinitionFile = name
+	if sm.DefinitionHash == "" {
+		sm.DefinitionHash = stepfunctions.DefinitionHash(sm.Definition)
+	}
+	sm.Definition = ""
+	return nil
+}
+
+// fileName returns the name a definition file is saved under in the definition format
+func (e *objectExporter) fileName(name string) string {
+	if e.definitionFormat == "yaml" {
+		return yamlFile(name)
+	}
+	return name
+}
+
+// render converts a JSON definition to the definition format
+func (e *objectExporter) render(definition []byte) ([]byte, error) {
+	if e.definitionFormat == "yaml" {
+		return definitionYAML(definition)
+	}
+	return definition, nil
+}
+
+// WriteExecution saves the execution with its history embedded
+func (e *objectExporter) WriteExecution(ctx context.Context, sm stepfunctions.StateMachine, exec stepfunctions.Execution) error {
+	data, err := stepfunctions.MarshalDocument(exec, "")
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution %s: %w", exec.ExecutionArn, err)
+	}
+	if err := e.put(ctx, executionFile(sm.Name, exec.ExecutionArn), data); err != nil {
+		return fmt.Errorf("failed to save execution %s: %w", exec.ExecutionArn, err)
+	}
+	return nil
+}
+
+// WriteHistoryEvent is a no-op; history is part of the execution document
+func (e *objectExporter) WriteHistoryEvent(context.Context, stepfunctions.Execution, stepfunctions.HistoryEvent) error {
+	return nil
+}
+
+// Close writes state_machines.json with everything written so far
+func (e *objectExporter) Close(ctx context.Context) error {
+	data, err := stepfunctions.MarshalDocument(e.stateMachines, "stateMachines")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state machines: %w", err)
+	}
+	if err := e.put(ctx, export.StateMachinesFile, data); err != nil {
+		return fmt.Errorf("failed to save state machines: %w", err)
+	}
+	return nil
+}
+
+// The output directory layout is shared with the export package, which
+// reads it back
+
+func definitionFile(smName string) string {
+	return export.DefinitionFile(smName)
+}
+
+func stateDefinitionFile(smName, stateName string) string {
+	return export.StateFile(smName, stateName)
+}
+
+func executionFile(smName, executionArn string) string {
+	return export.ExecutionFile(smName, executionArn)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// newRelicMaxAttribute is the Event API's limit on string attribute values
+const newRelicMaxAttribute = 4096
+
+// newRelicExporter sends StepFunctionsStateMachine, StepFunctionsExecution
+// and StepFunctionsHistoryEvent custom events to the Event API, queryable
+// with NRQL (FROM StepFunctionsExecution SELECT ...). Every event carries the
+// attributes entity synthesis matches on, so it is attached to the state
+// machine entity of the AWS cloud integration in New Relic One.
+type newRelicExporter struct {
+	accountID string
+	sink      *httpSink
+}
+
+// newNewRelicExporter sends through an httpSink that dead-letters to dlqPath
+func newNewRelicExporter(nr newRelicConfig, dlqPath string) (*newRelicExporter, error) {
+	if nr.accountID == "" || nr.licenseKey == "" {
+		return nil, fmt.Errorf("the newrelic export needs NEW_RELIC_ACCOUNT_ID and NEW_RELIC_LICENSE_KEY")
+	}
+	sink := newHTTPSink("newrelic", fmt.Sprintf(nr.endpoints().eventsURL, nr.accountID), dlqPath)
+	sink.headers["Api-Key"] = nr.licenseKey
+	return &newRelicExporter{accountID: nr.accountID, sink: sink}, nil
+}
+
+func (e *newRelicExporter) WriteStateMachine(ctx context.Context, sm stepfunctions.StateMachine) error {
+	return e.add(ctx, sm.ARN, map[string]any{
+		"eventType":       "StepFunctionsStateMachine",
+		"stateMachineArn": sm.ARN,
+		"name":            sm.Name,
+		"type":            sm.Type,
+		"roleArn":         sm.RoleARN,
+		"revisionId":      sm.RevisionID,
+		"definitionHash":  sm.DefinitionHash,
+		"states":          len(sm.States),
+		"executions":      len(sm.Executions),
+		"logLevel":        sm.Logging.Level,
+		"tracing":         sm.Tracing,
+		"encryption":      sm.Encryption.Type,
+	})
+}
+
+func (e *newRelicExporter) WriteExecution(ctx context.Context, sm stepfunctions.StateMachine, exec stepfunctions.Execution) error {
+	event := map[string]any{
+		"eventType":        "StepFunctionsExecution",
+		"executionArn":     exec.ExecutionArn,
+		"stateMachineArn":  sm.ARN,
+		"stateMachineName": sm.Name,
+		"status":           exec.Status,
+		"startTime":        formatTime(exec.StartTime),
+		"endTime":          formatTime(exec.EndTime),
+		"duration":         exec.FormatDuration(),
+		"error":            exec.Error,
+		"cause":            truncateAttribute(exec.Cause),
+		"inputSize":        exec.InputSize,
+		"outputSize":       exec.OutputSize,
+		"transitions":      exec.Transitions,
+		"partial":          exec.Partial,
+		"capturedHistory":  exec.Captured.History,
+		"capturedPayloads": exec.Captured.Payloads,
+		"truncated":        exec.Captured.Truncated,
+	}
+	if exec.Duration > 0 {
+		event["durationMs"] = exec.Duration.Milliseconds() // Numeric, for percentile()
+	}
+	return e.add(ctx, sm.ARN, event)
+}
+
+func (e *newRelicExporter) WriteHistoryEvent(ctx context.Context, exec stepfunctions.Execution, event stepfunctions.HistoryEvent) error {
+	return e.add(ctx, stateMachineOf(exec.ExecutionArn), map[string]any{
+		"eventType":       "StepFunctionsHistoryEvent",
+		"timestamp":       event.Timestamp.UnixMilli(),
+		"executionArn":    exec.ExecutionArn,
+		"id":              event.ID,
+		"previousEventId": event.PreviousEventID,
+		"type":            event.Type,
+		"stateName":       event.StateName,
+		"resource":        event.Resource,
+		"error":           event.Error,
+		"cause":           truncateAttribute(event.Cause),
+	})
+}
+
+// Close sends the remaining events
+func (e *newRelicExporter) Close(ctx context.Context) error {
+	return e.sink.flush(ctx)
+}
+
+// add queues an event about the state machine stateMachineArn
+func (e *newRelicExporter) add(ctx context.Context, stateMachineArn string, event map[string]any) error {
+	e.addEntity(event, stateMachineArn)
+	return e.sink.add(ctx, event)
+}
+
+// addEntity sets the attributes entity synthesis uses to match an event to
+// the state machine entity: its ARN, region and AWS account, and the New
+// Relic account the entity belongs to
+func (e *newRelicExporter) addEntity(event map[string]any, stateMachineArn string) {
+	if id, err := strconv.ParseInt(e.accountID, 10, 64); err == nil {
+		event["accountId"] = id
+	}
+	parsed, err := arn.Parse(stateMachineArn)
+	if err != nil {
+		return
+	}
+	event["aws.arn"] = stateMachineArn
+	event["aws.region"] = parsed.Region
+	event["aws.accountId"] = parsed.AccountID
+}
+
+// stateMachineOf returns the state machine ARN of a Standard or Express
+// execution ARN, or the ARN unchanged when it is not one
+func stateMachineOf(executionArn string) string {
+	parsed, err := arn.Parse(executionArn)
+	if err != nil {
+		return executionArn
+	}
+	parts := strings.Split(parsed.Resource, ":")
+	if len(parts) < 3 || (parts[0] != "execution" && parts[0] != "express") {
+		return executionArn
+	}
+	parsed.Resource = "stateMachine:" + parts[1]
+	return parsed.String()
+}
+
+func truncateAttribute(value string) string {
+	if len(value) > newRelicMaxAttribute {
+		return value[:newRelicMaxAttribute-3] + "..."
+	}
+	return value
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestNewRelicEntityAttributes(t *testing.T) {
+	var events []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.NewDecoder(gz).Decode(&events); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := newNewRelicExporter(newRelicConfig{accountID: "1234567", licenseKey: "key"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.sink.url = server.URL
+	ctx := context.Background()
+	sm := stepfunctions.StateMachine{Name: "orders", ARN: "arn:aws:states:eu-west-1:123456789012:stateMachine:orders"}
+	exec := stepfunctions.Execution{ExecutionArn: "arn:aws:states:eu-west-1:123456789012:execution:orders:run-1"}
+	exporter.WriteStateMachine(ctx, sm)
+	exporter.WriteExecution(ctx, sm, exec)
+	exporter.WriteHistoryEvent(ctx, exec, stepfunctions.HistoryEvent{ID: 1, Type: "ExecutionStarted"})
+	if err := exporter.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for _, event := range events {
+		if event["aws.arn"] != sm.ARN || event["aws.region"] != "eu-west-1" || event["aws.accountId"] != "123456789012" || event["accountId"] != float64(1234567) {
+			t.Errorf("%s: got entity attributes %v %v %v %v", event["eventType"], event["aws.arn"], event["aws.region"], event["aws.accountId"], event["accountId"])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	_ "modernc.org/sqlite" // Pure Go driver, registered as "sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS state_machines (
+	arn           TEXT PRIMARY KEY,
+	name          TEXT NOT NULL,
+	type          TEXT,
+	role_arn      TEXT,
+	revision_id   TEXT,
+	creation_date TEXT,
+	definition    TEXT
+);
+CREATE TABLE IF NOT EXISTS executions (
+	execution_arn     TEXT PRIMARY KEY,
+	state_machine_arn TEXT NOT NULL REFERENCES state_machines(arn),
+	status            TEXT,
+	start_time        TEXT,
+	end_time          TEXT,
+	duration          TEXT,
+	error             TEXT,
+	cause             TEXT,
+	input_size        INTEGER,
+	output_size       INTEGER,
+	transitions       INTEGER
+);
+CREATE TABLE IF NOT EXISTS history_events (
+	execution_arn     TEXT NOT NULL REFERENCES executions(execution_arn),
+	id                INTEGER NOT NULL,
+	previous_event_id INTEGER,
+	type              TEXT,
+	timestamp         TEXT,
+	state_name        TEXT,
+	resource          TEXT,
+	input             TEXT,
+	output            TEXT,
+	error             TEXT,
+	cause             TEXT,
+	PRIMARY KEY (execution_arn, id)
+);`
+
+// sqliteExporter upserts everything into one database, in a single
+// transaction committed on Close, so re-running against the same file
+// refreshes rows instead of duplicating them
+type sqliteExporter struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func newSQLiteExporter(ctx context.Context, path string) (*sqliteExporter, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create SQLite schema in %s: %w", path, err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to begin SQLite transaction: %w", err)
+	}
+	return &sqliteExporter{db: db, tx: tx}, nil
+}
+
+func (e *sqliteExporter) WriteStateMachine(ctx context.Context, sm stepfunctions.StateMachine) error {
+	_, err := e.tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO state_machines (arn, name, type, role_arn, revision_id, creation_date, definition) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sm.ARN, sm.Name, sm.Type, sm.RoleARN, sm.RevisionID, formatTime(sm.CreationDate), sm.Definition)
+	if err != nil {
+		return fmt.Errorf("failed to insert state machine %s: %w", sm.ARN, err)
+	}
+	return nil
+}
+
+func (e *sqliteExporter) WriteExecution(ctx context.Context, sm stepfunctions.StateMachine, exec stepfunctions.Execution) error {
+	_, err := e.tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO executions (execution_arn, state_machine_arn, status, start_time, end_time, duration, error, cause, input_size, output_size, transitions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		exec.ExecutionArn, sm.ARN, exec.Status, formatTime(exec.StartTime), formatTime(exec.EndTime), exec.FormatDuration(), exec.Error, exec.Cause, exec.InputSize, exec.OutputSize, exec.Transitions)
+	if err != nil {
+		return fmt.Errorf("failed to insert execution %s: %w", exec.ExecutionArn, err)
+	}
+	return nil
+}
+
+func (e *sqliteExporter) WriteHistoryEvent(ctx context.Context, exec stepfunctions.Execution, event stepfunctions.HistoryEvent) error {
+	_, err := e.tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO history_events (execution_arn, id, previous_event_id, type, timestamp, state_name, resource, input, output, error, cause) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		exec.ExecutionArn, event.ID, event.PreviousEventID, event.Type, event.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z"), event.StateName, event.Resource, event.Input, event.Output, event.Error, event.Cause)
+	if err != nil {
+		return fmt.Errorf("failed to insert history event %d of %s: %w", event.ID, exec.ExecutionArn, err)
+	}
+	return nil
+}
+
+func (e *sqliteExporter) Close(context.Context) error {
+	defer e.db.Close()
+	if err := e.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit SQLite transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+	"stepfunction-fetcher/stepfunctions/export"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with testdata/<name>, or rewrites it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run go test -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s does not match the output (run go test -update to accept it)\ngot:\n%s", path, got)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]string{
+		"Charge":                 "Charge",
+		"Charge card":            "Charge card",
+		"Retry: wait/backoff":    "Retry_ wait_backoff",
+		`a\b*c?d"e<f>g|h`:        "a_b_c_d_e_f_g_h",
+		"arn:aws:states:eu-1:42": "arn_aws_states_eu-1_42",
+	}
+	for name, want := range tests {
+		if got := sanitizeFileName(name); got != want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExportFileNames(t *testing.T) {
+	if got, want := stateDefinitionFile("orders", "Charge/Refund"), "orders_Charge_Refund.json"; got != want {
+		t.Errorf("stateDefinitionFile = %q, want %q", got, want)
+	}
+	arn := "arn:aws:states:us-east-1:123456789012:execution:orders:run-1"
+	if got, want := executionFile("orders", arn), "orders_execution_arn_aws_states_us-east-1_123456789012_execution_orders_run-1.json"; got != want {
+		t.Errorf("executionFile = %q, want %q", got, want)
+	}
+}
+
+func TestFileExporterLayout(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	stateMachines := []stepfunctions.StateMachine{{
+		Name:         "orders",
+		ARN:          "arn:aws:states:us-east-1:123456789012:stateMachine:orders",
+		Type:         "STANDARD",
+		CreationDate: start,
+		States: []stepfunctions.State{
+			{Name: "Charge", Type: "Task", Next: "Ship", RawDefinition: map[string]interface{}{"Type": "Task", "Next": "Ship"}},
+			{Name: "Ship", Type: "Pass", End: true, RawDefinition: map[string]interface{}{"Type": "Pass", "End": true}},
+		},
+		Executions: []stepfunctions.Execution{
+			{
+				ExecutionArn: "arn:aws:states:us-east-1:123456789012:execution:orders:run-1",
+				Status:       "SUCCEEDED",
+				StartTime:    start,
+				EndTime:      start.Add(5 * time.Second),
+				Duration:     5 * time.Second,
+			},
+			{ExecutionArn: "N/A"}, // Placeholder for no executions; not exported
+		},
+		RawDescribe: []byte(`{"name":"orders"}`),
+	}}
+
+	errs := &runErrors{outputDir: dir}
+	processStateMachines(context.Background(), stateMachines, []Exporter{newFileExporter(dir)}, historyFilter{}, errs)
+	if len(errs.items) != 0 {
+		t.Fatalf("got errors %v", errs.items)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	assertGolden(t, "export/files.golden", []byte(strings.Join(names, "\n")+"\n"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "state_machines.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "export/state_machines.golden.json", data)
+}
+
+func TestDefinitionInlineMax(t *testing.T) {
+	small := stepfunctions.StateMachine{Name: "small", Definition: `{"StartAt":"A","States":{"A":{"Type":"Succeed"}}}`}
+	large := stepfunctions.StateMachine{Name: "large", Definition: `{"Comment":"` + strings.Repeat("x", 100) + `","StartAt":"A","States":{"A":{"Type":"Succeed"}}}`}
+
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			exporter := newFileExporter(dir)
+			exporter.definitionFormat = format
+			exporter.definitionInlineMax = 64
+			ctx := context.Background()
+			for _, sm := range []stepfunctions.StateMachine{small, large} {
+				if err := exporter.WriteStateMachine(ctx, sm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := exporter.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, export.StateMachinesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "xxxx") || !strings.Contains(string(data), `"DefinitionFile": "large.asl.json"`) {
+				t.Errorf("state_machines.json keeps the large definition inline:\n%s", data)
+			}
+			stateMachines, err := export.LoadStateMachines(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range []stepfunctions.StateMachine{small, large} {
+				if stateMachines[i].Definition != want.Definition {
+					t.Errorf("loaded definition of %s = %q, want %q", want.Name, stateMachines[i].Definition, want.Definition)
+				}
+			}
+			if stateMachines[0].DefinitionFile != "" || stateMachines[1].DefinitionHash != stepfunctions.DefinitionHash(large.Definition) {
+				t.Errorf("DefinitionFile, DefinitionHash = %q, %q", stateMachines[0].DefinitionFile, stateMachines[1].DefinitionHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// runFetchExecution fetches exactly one execution, history and payloads
+// included, for debugging an incident without crawling the account
+func runFetchExecution(args []string) {
+	fs := flag.NewFlagSet("fetch-execution", flag.ExitOnError)
+	region := fs.String("region", "", "AWS region (defaults to the region in the execution ARN)")
+	outputDir := fs.String("output-dir", "stepfunctions_state_definitions", "Directory to save the execution to")
+	expressLookback := fs.Duration("express-lookback", 24*time.Hour, "How far back to search CloudWatch Logs for an Express execution")
+	children := fs.Bool("children", false, "Also fetch the child executions it started, Map Run child workflows and states:startExecution tasks, recursively")
+	maxDepth := fs.Int("max-depth", 3, "Levels of child executions to follow with --children")
+	maxChildren := fs.Int("max-children", 50, "Child workflows to fetch per Map Run with --children; failed ones are fetched first")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher fetch-execution [flags] <execution-arn>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	initColor(*noColor)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	executionArn := fs.Arg(0)
+
+	ctx := context.Background()
+	fetcher, err := stepfunctions.NewFetcher(ctx, regionFor(executionArn, *region),
+		stepfunctions.WithExpressLogQuery(stepfunctions.ExpressLogQuery{Lookback: *expressLookback}))
+	if err != nil {
+		log.Fatalf("Failed to create fetcher: %v", err)
+	}
+
+	// The tree is saved in place of the detail, which it embeds
+	var saved interface{}
+	var detail stepfunctions.ExecutionDetail
+	var treeSummary *treeNode
+	if *children {
+		tree, err := fetcher.FetchExecutionTree(ctx, executionArn, stepfunctions.TreeOptions{MaxDepth: *maxDepth, MaxChildren: *maxChildren})
+		if err != nil {
+			log.Fatalf("Failed to fetch execution: %v", err)
+		}
+		displayExecutionDetail(tree.ExecutionDetail)
+		node := toTreeNode(tree)
+		fmt.Println("Execution tree:")
+		renderTree(os.Stdout, node)
+		fmt.Println()
+		if treeTruncated(tree) {
+			fmt.Printf("Only the first %d child workflows of each Map Run were fetched; raise --max-children for more\n", *maxChildren)
+		}
+		saved, detail, treeSummary = tree, tree.ExecutionDetail, &node
+	} else {
+		detail, err = fetcher.FetchExecution(ctx, executionArn)
+		if err != nil {
+			log.Fatalf("Failed to fetch execution: %v", err)
+		}
+		displayExecutionDetail(detail)
+		saved = detail
+	}
+
+	createOutputDirectory(*outputDir)
+	data, err := stepfunctions.MarshalDocument(saved, "")
+	if err != nil {
+		log.Fatalf("Failed to marshal execution: %v", err)
+	}
+	smName := detail.StateMachineARN[strings.LastIndex(detail.StateMachineARN, ":")+1:]
+	if err := saveExecutionDefinition(*outputDir, smName, executionArn, data); err != nil {
+		log.Fatalf("Failed to save execution: %v", err)
+	}
+	if treeSummary != nil {
+		data, err := stepfunctions.MarshalDocument(treeSummary, "")
+		if err != nil {
+			log.Fatalf("Failed to marshal execution tree: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(*outputDir, executionTreeFile(smName, executionArn)), data, 0644); err != nil {
+			log.Fatalf("Failed to save execution tree: %v", err)
+		}
+	}
+	fmt.Printf("Execution saved to %s\n", filepath.Clean(*outputDir))
+}
+
+// treeTruncated reports whether any Map Run in the tree had more children
+// than were fetched
+func treeTruncated(tree stepfunctions.ExecutionTree) bool {
+	for _, child := range tree.Children {
+		if treeTruncated(child) {
+			return true
+		}
+	}
+	return tree.ChildrenTruncated
+}
+
+func displayExecutionDetail(detail stepfunctions.ExecutionDetail) {
+	exec := detail.Execution
+	fmt.Printf("Execution:     %s\n", exec.ExecutionArn)
+	fmt.Printf("State machine: %s\n", detail.StateMachineARN)
+	fmt.Printf("Status:        %s (from %s)\n", colorStatus(exec.Status), detail.Source)
+	fmt.Printf("Started:       %s\n", formatTime(exec.StartTime))
+	fmt.Printf("Ended:         %s (%s)\n", formatTime(exec.EndTime), exec.FormatDuration())
+	if exec.Error != "" {
+		fmt.Printf("Error:         %s: %s\n", exec.Error, exec.Cause)
+	}
+	fmt.Printf("Input:         %d bytes, output: %d bytes, transitions: %d\n\n", exec.InputSize, exec.OutputSize, exec.Transitions)
+
+	historyTable := newTable()
+	historyTable.SetHeader([]string{"ID", "Timestamp", "Type", "State", "Error"})
+	for _, event := range exec.History {
+		historyTable.Append([]string{
+			fmt.Sprintf("%d", event.ID),
+			event.Timestamp.Format(time.RFC3339Nano),
+			event.Type,
+			event.StateName,
+			event.Error,
+		})
+	}
+	fmt.Println("History:")
+	historyTable.Render()
+	fmt.Println()
+}
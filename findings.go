@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// processFindings adds the remaining checks and the definition validation to
+// the audit findings, applies the --findings-config suppressions, then prints
+// and saves the rest to findings.json and, with --sarif, findings.sarif
+func processFindings(cfg runConfig, stateMachines []stepfunctions.StateMachine, findings []stepfunctions.Finding) {
+	for _, sm := range stateMachines {
+		findings = append(findings, stepfunctions.ValidateDefinition(sm)...)
+		findings = append(findings, stepfunctions.AuditStateMachine(sm)...)
+	}
+	var suppressed []stepfunctions.Finding
+	if cfg.findings != nil {
+		findings, suppressed = cfg.findings.Filter(findings)
+	}
+	stepfunctions.SortFindings(findings)
+
+	printFindings(findings, len(suppressed))
+
+	report := struct {
+		Findings   []stepfunctions.Finding
+		Suppressed []stepfunctions.Finding
+	}{findings, suppressed}
+	data, err := stepfunctions.MarshalDocument(report, "findings")
+	if err != nil {
+		log.Printf("Failed to marshal findings: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(cfg.outputDir, "findings.json"), data, 0644); err != nil {
+		log.Printf("Failed to save findings: %v", err)
+	}
+
+	if cfg.sarif {
+		// Results point at the exported <name>.asl.json next to findings.sarif
+		opts := stepfunctions.SARIFOptions{
+			ToolVersion: version,
+			ArtifactURI: func(finding stepfunctions.Finding) string { return definitionFile(finding.StateMachineName) },
+		}
+		sarifFindings := findings
+		if cfg.definitionFormat == "yaml" {
+			opts.ArtifactURI = func(finding stepfunctions.Finding) string {
+				return yamlFile(definitionFile(finding.StateMachineName))
+			}
+			definitions := make(map[string][]byte)
+			for _, sm := range stateMachines {
+				if definition, err := definitionYAML([]byte(sm.Definition)); err == nil {
+					definitions[sm.Name] = definition
+				}
+			}
+			sarifFindings = yamlFindingLines(definitions, findings)
+		}
+		if err := writeSARIFFile(filepath.Join(cfg.outputDir, "findings.sarif"), sarifFindings, opts); err != nil {
+			log.Printf("Failed to write SARIF file: %v", err)
+		}
+	}
+}
+
+func printFindings(findings []stepfunctions.Finding, suppressed int) {
+	findingsTable := newTable()
+	findingsTable.SetColumnColor(1, colorFinding)
+	findingsTable.SetHeader([]string{"Rule", "Severity", "State Machine", "State", "Message"})
+	for _, finding := range findings {
+		findingsTable.Append([]string{
+			finding.RuleID + " " + stepfunctions.Rules[finding.RuleID].Name,
+			finding.Severity,
+			finding.StateMachineName,
+			finding.State,
+			finding.Message,
+		})
+	}
+	fmt.Println("Findings:")
+	findingsTable.Render()
+	fmt.Printf("%d finding(s), %d suppressed\n\n", len(findings), suppressed)
+}
+
+func writeSARIFFile(path string, findings []stepfunctions.Finding, opts stepfunctions.SARIFOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create SARIF file: %w", err)
+	}
+	if err := stepfunctions.WriteSARIF(file, findings, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// yamlFindingLines returns a copy of the findings with line numbers in the
+// YAML definitions, by state machine name, instead of the JSON ones. Findings
+// of other state machines are unchanged.
+func yamlFindingLines(definitions map[string][]byte, findings []stepfunctions.Finding) []stepfunctions.Finding {
+	moved := make([]stepfunctions.Finding, len(findings))
+	for i, finding := range findings {
+		if definition, ok := definitions[finding.StateMachineName]; ok {
+			finding.Line = 0
+			if finding.State != "" {
+				finding.Line = yamlStateLine(definition, finding.State)
+			}
+		}
+		moved[i] = finding
+	}
+	return moved
+}
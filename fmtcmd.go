@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// runFmt rewrites definitions in canonical form, see
+// stepfunctions.CanonicalDefinition. Like gofmt it prints the result unless
+// -w or -l is given.
+func runFmt(args []string) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "Write the result back to the file instead of printing it")
+	list := fs.Bool("l", false, "List the files whose formatting differs instead of printing them")
+	inputDir := fs.String("input-dir", "stepfunctions_state_definitions", "Format every <name>.asl.json and <name>.asl.yaml of a previous run when no files are given")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher fmt [flags] [file ...] (- reads stdin)")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		for _, pattern := range []string{"*.asl.json", "*.asl.yaml"} {
+			matches, err := filepath.Glob(filepath.Join(*inputDir, pattern))
+			if err != nil {
+				log.Fatalf("Failed to list definitions: %v", err)
+			}
+			files = append(files, matches...)
+		}
+		if len(files) == 0 {
+			log.Fatalf("No definitions found in %s", *inputDir)
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			log.Printf("Failed to read %s: %v", file, err)
+			failed = true
+			continue
+		}
+		formatted, err := formatDefinition(file, data)
+		if err != nil {
+			log.Printf("Failed to format %s: %v", file, err)
+			failed = true
+			continue
+		}
+		switch {
+		case *list:
+			if !bytes.Equal(data, formatted) {
+				fmt.Println(file)
+			}
+		case *write && file != "-":
+			if bytes.Equal(data, formatted) {
+				continue
+			}
+			if err := os.WriteFile(file, formatted, 0644); err != nil {
+				log.Printf("Failed to save %s: %v", file, err)
+				failed = true
+			}
+		default:
+			os.Stdout.Write(formatted)
+		}
+	}
+	if failed {
+		os.Exit(exitFatal)
+	}
+}
+
+// formatDefinition returns the canonical form of a JSON or, by extension,
+// YAML definition in the same format
+func formatDefinition(name string, data []byte) ([]byte, error) {
+	if !isYAMLFile(name) {
+		return stepfunctions.CanonicalDefinition(data)
+	}
+	asJSON, err := yamlToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := stepfunctions.CanonicalDefinition(asJSON)
+	if err != nil {
+		return nil, err
+	}
+	return definitionYAML(canonical)
+}
+
+func isYAMLFile(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+}
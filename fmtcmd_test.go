@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestFormatDefinitionYAML(t *testing.T) {
+	got, err := formatDefinition("orders.asl.yaml", []byte("States:\n  B: {End: true, Type: Pass}\n  A:\n    Next: B\n    Type: Pass\nStartAt: A\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "StartAt: A\nStates:\n  A:\n    Type: Pass\n    Next: B\n  B:\n    Type: Pass\n    End: true\n"
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
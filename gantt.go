@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+const (
+	ganttLabelWidth = 80
+	ganttChartWidth = 1000
+	ganttRowHeight  = 24
+)
+
+var ganttColors = map[string]string{
+	"SUCCEEDED": "#4caf50",
+	"FAILED":    "#e53935",
+	"RUNNING":   "#fbc02d",
+}
+
+func processTimelines(sm stepfunctions.StateMachine, outputDir string) {
+	for _, exec := range sm.Executions {
+		spans := stepfunctions.BuildTimeline(exec.History)
+		if len(spans) == 0 {
+			continue
+		}
+
+		safeExecName := sanitizeFileName(strings.ReplaceAll(exec.ExecutionArn, ":", "_"))
+		filePath := filepath.Join(outputDir, fmt.Sprintf("%s_timeline_%s.html", sm.Name, safeExecName))
+		if err := os.WriteFile(filePath, []byte(renderGantt(exec, spans)), 0644); err != nil {
+			log.Printf("Failed to save timeline for %s: %v", exec.ExecutionArn, err)
+		}
+	}
+}
+
+// renderGantt draws one row per lane with a bar per state span, followed by a
+// table of the spans so exact timings can be copied.
+func renderGantt(exec stepfunctions.Execution, spans []stepfunctions.TimelineSpan) string {
+	start, end := spans[0].Start, spans[0].End
+	lanes := 0
+	for _, span := range spans {
+		if span.Start.Before(start) {
+			start = span.Start
+		}
+		if span.End.After(end) {
+			end = span.End
+		}
+		if span.Lane+1 > lanes {
+			lanes = span.Lane + 1
+		}
+	}
+	total := end.Sub(start).Seconds()
+	if total <= 0 {
+		total = 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head><body style=\"font-family:sans-serif\">\n", html.EscapeString(exec.ExecutionArn))
+	fmt.Fprintf(&b, "<h2>%s</h2>\n<p>Status: %s &middot; Duration: %s</p>\n", html.EscapeString(exec.ExecutionArn), html.EscapeString(exec.Status), html.EscapeString(exec.FormatDuration()))
+
+	height := lanes*ganttRowHeight + ganttRowHeight
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-size=\"11\">\n", ganttLabelWidth+ganttChartWidth, height)
+	for lane := 0; lane < lanes; lane++ {
+		fmt.Fprintf(&b, "<text x=\"0\" y=\"%d\">Lane %d</text>\n", lane*ganttRowHeight+16, lane+1)
+	}
+	for _, span := range spans {
+		x := ganttLabelWidth + int(span.Start.Sub(start).Seconds()/total*ganttChartWidth)
+		width := int(span.Duration().Seconds() / total * ganttChartWidth)
+		if width < 2 {
+			width = 2
+		}
+		y := span.Lane*ganttRowHeight + 2
+		fmt.Fprintf(&b, "<g><title>%s (%s, %v)</title>", html.EscapeString(span.StateName), span.Status, span.Duration())
+		fmt.Fprintf(&b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" stroke=\"#333\" stroke-width=\"0.5\"/>", x, y, width, ganttRowHeight-4, ganttColors[span.Status])
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">%s</text></g>\n", x+3, y+14, html.EscapeString(span.StateName))
+	}
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">0s</text><text x=\"%d\" y=\"%d\" text-anchor=\"end\">%.3fs</text>\n",
+		ganttLabelWidth, height-4, ganttLabelWidth+ganttChartWidth, height-4, total)
+	b.WriteString("</svg>\n")
+
+	b.WriteString("<table border=\"1\" cellpadding=\"4\" cellspacing=\"0\">\n<tr><th>State</th><th>Lane</th><th>Offset</th><th>Duration</th><th>Status</th></tr>\n")
+	for _, span := range spans {
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%d</td><td>%v</td><td>%v</td><td>%s</td></tr>\n",
+			html.EscapeString(span.StateName), span.Lane+1, span.Start.Sub(start), span.Duration(), span.Status)
+	}
+	b.WriteString("</table>\n</body></html>\n")
+	return b.String()
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// graphFormats render a state machine's graph
+var graphFormats = map[string]func(name string, g stepfunctions.Graph) string{
+	"dot":     renderDOT,
+	"mermaid": renderMermaid,
+	"tree":    renderStateTree,
+}
+
+// runGraph draws the state graphs of local definitions or a previous run's
+// output directory, without calling AWS
+func runGraph(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := fs.String("format", "mermaid", "Output format: mermaid, dot, tree or json")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher graph [flags] <definition file or output dir>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	render, ok := graphFormats[*format]
+	if !ok && *format != "json" {
+		log.Fatalf("Unsupported --format %q (supported: mermaid, dot, tree, json)", *format)
+	}
+
+	stateMachines, _, err := loadLocal(fs.Args())
+	if err != nil {
+		log.Fatalf("Failed to load definitions: %v", err)
+	}
+	sort.Slice(stateMachines, func(i, j int) bool { return stateMachines[i].Name < stateMachines[j].Name })
+
+	var out strings.Builder
+	graphs := make(map[string]stepfunctions.Graph)
+	for _, sm := range stateMachines {
+		g, err := stepfunctions.BuildGraph(sm.Definition)
+		if err != nil {
+			log.Fatalf("Failed to build the graph of %s: %v", sm.Name, err)
+		}
+		graphs[sm.Name] = g
+		if render != nil {
+			out.WriteString(render(sm.Name, g))
+		}
+	}
+	if *format == "json" {
+		data, err := stepfunctions.MarshalDocument(map[string]interface{}{"graphs": graphs}, "")
+		if err != nil {
+			log.Fatalf("Failed to marshal graphs: %v", err)
+		}
+		out.Write(append(data, '\n'))
+	}
+
+	if *output == "" {
+		fmt.Print(out.String())
+		return
+	}
+	if err := os.WriteFile(*output, []byte(out.String()), 0644); err != nil {
+		log.Fatalf("Failed to save graph: %v", err)
+	}
+}
+
+// renderDOT draws the graph for Graphviz, with Parallel branches and Map
+// processors as clusters
+func renderDOT(name string, g stepfunctions.Graph) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(name))
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	dotScope(&b, g, "", "  ")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotScope writes the nodes and edges of one StartAt/States scope. Nested
+// scopes get their own start point, named after the path to them.
+func dotScope(b *strings.Builder, g stepfunctions.Graph, path, indent string) {
+	start := dotQuote("start:" + path)
+	fmt.Fprintf(b, "%s%s [shape=point];\n", indent, start)
+	if g.StartAt != "" {
+		fmt.Fprintf(b, "%s%s -> %s;\n", indent, start, dotQuote(g.StartAt))
+	}
+	for _, node := range g.Nodes {
+		attrs := fmt.Sprintf("label=%s", dotQuote(node.Name+"\n"+node.Type))
+		switch node.Type {
+		case "Choice":
+			attrs += ", shape=diamond, style=solid"
+		case "Succeed", "Fail":
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(b, "%s%s [%s];\n", indent, dotQuote(node.Name), attrs)
+		for i, child := range node.Children {
+			childPath := fmt.Sprintf("%s/%s[%d]", path, node.Name, i)
+			fmt.Fprintf(b, "%ssubgraph %s {\n", indent, dotQuote("cluster:"+childPath))
+			fmt.Fprintf(b, "%s  label=%s;\n", indent, dotQuote(fmt.Sprintf("%s %d", node.Name, i+1)))
+			dotScope(b, child, childPath, indent+"  ")
+			fmt.Fprintf(b, "%s}\n", indent)
+			fmt.Fprintf(b, "%s%s -> %s [style=dashed];\n", indent, dotQuote(node.Name), dotQuote("start:"+childPath))
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.Label == "" {
+			fmt.Fprintf(b, "%s%s -> %s;\n", indent, dotQuote(edge.From), dotQuote(edge.To))
+			continue
+		}
+		fmt.Fprintf(b, "%s%s -> %s [label=%s];\n", indent, dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Label))
+	}
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// renderMermaid draws the graph as a Mermaid flowchart, which GitHub renders
+// in Markdown, with Parallel branches and Map processors as subgraphs
+func renderMermaid(name string, g stepfunctions.Graph) string {
+	m := &mermaid{ids: make(map[string]string)}
+	fmt.Fprintf(&m.b, "---\ntitle: %s\n---\nflowchart TD\n", mermaidText(name))
+	m.scope(g, "  ")
+	return m.b.String()
+}
+
+// mermaid numbers the nodes, since Mermaid IDs cannot hold every state name
+type mermaid struct {
+	b      strings.Builder
+	ids    map[string]string // State name -> node ID
+	starts int
+	scopes int
+}
+
+func (m *mermaid) id(state string) string {
+	if id, ok := m.ids[state]; ok {
+		return id
+	}
+	id := fmt.Sprintf("s%d", len(m.ids))
+	m.ids[state] = id
+	return id
+}
+
+// scope writes one StartAt/States scope and returns the ID of its start point
+func (m *mermaid) scope(g stepfunctions.Graph, indent string) string {
+	for _, node := range g.Nodes {
+		m.id(node.Name) // Number the nodes in order
+	}
+	start := fmt.Sprintf("start%d", m.starts)
+	m.starts++
+	fmt.Fprintf(&m.b, "%s%s(( ))\n", indent, start)
+	if g.StartAt != "" {
+		fmt.Fprintf(&m.b, "%s%s --> %s\n", indent, start, m.id(g.StartAt))
+	}
+	for _, node := range g.Nodes {
+		label := mermaidText(node.Name + " (" + node.Type + ")")
+		switch node.Type {
+		case "Choice":
+			fmt.Fprintf(&m.b, "%s%s{\"%s\"}\n", indent, m.id(node.Name), label)
+		case "Succeed", "Fail":
+			fmt.Fprintf(&m.b, "%s%s([\"%s\"])\n", indent, m.id(node.Name), label)
+		default:
+			fmt.Fprintf(&m.b, "%s%s[\"%s\"]\n", indent, m.id(node.Name), label)
+		}
+		for i, child := range node.Children {
+			scope := fmt.Sprintf("g%d", m.scopes)
+			m.scopes++
+			fmt.Fprintf(&m.b, "%ssubgraph %s [\"%s\"]\n", indent, scope, mermaidText(fmt.Sprintf("%s %d", node.Name, i+1)))
+			childStart := m.scope(child, indent+"  ")
+			fmt.Fprintf(&m.b, "%send\n", indent)
+			fmt.Fprintf(&m.b, "%s%s -.-> %s\n", indent, m.id(node.Name), childStart)
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&m.b, "%s%s --> %s\n", indent, m.id(edge.From), m.id(edge.To))
+			continue
+		}
+		fmt.Fprintf(&m.b, "%s%s -->|\"%s\"| %s\n", indent, m.id(edge.From), mermaidText(edge.Label), m.id(edge.To))
+	}
+	return start
+}
+
+// mermaidText escapes quotes and markup for a quoted Mermaid label
+func mermaidText(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
+
+// renderStateTree draws the flow as an indented tree for the terminal: each
+// scope's states in Next order from StartAt, with Choice rules, catchers,
+// Parallel branches and Map processors nested under their state. A state
+// already drawn is referenced with ↪ instead of being drawn again.
+func renderStateTree(name string, g stepfunctions.Graph) string {
+	var b strings.Builder
+	b.WriteString(name + "\n")
+	stateTreeScope(&b, g, "")
+	return b.String()
+}
+
+// stateTreeScope draws the states of one StartAt/States scope, then the states
+// unreachable from StartAt
+func stateTreeScope(b *strings.Builder, g stepfunctions.Graph, prefix string) {
+	nodes := make(map[string]stepfunctions.GraphNode, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes[node.Name] = node
+	}
+	t := &stateTree{b: b, nodes: nodes, edges: make(map[string][]stepfunctions.GraphEdge), drawn: make(map[string]bool)}
+	for _, edge := range g.Edges {
+		t.edges[edge.From] = append(t.edges[edge.From], edge)
+	}
+	if g.StartAt != "" {
+		t.sequence(g.StartAt, prefix)
+	}
+	for _, node := range g.Nodes {
+		if !t.drawn[node.Name] {
+			fmt.Fprintf(b, "%s(unreachable)\n", prefix)
+			t.sequence(node.Name, prefix)
+		}
+	}
+}
+
+// stateTree draws one scope; drawn holds the states already drawn
+type stateTree struct {
+	b     *strings.Builder
+	nodes map[string]stepfunctions.GraphNode
+	edges map[string][]stepfunctions.GraphEdge // By From, in definition order
+	drawn map[string]bool
+}
+
+// sequence draws state and the states following it through Next, one per
+// line at the same depth
+func (t *stateTree) sequence(state, prefix string) {
+	for state != "" {
+		if t.drawn[state] {
+			fmt.Fprintf(t.b, "%s↪ %s\n", prefix, state)
+			return
+		}
+		t.drawn[state] = true
+		node, ok := t.nodes[state]
+		if !ok {
+			fmt.Fprintf(t.b, "%s%s (missing)\n", prefix, state)
+			return
+		}
+		fmt.Fprintf(t.b, "%s%s (%s)\n", prefix, node.Name, node.Type)
+
+		// Nested scopes first, then the labeled transitions
+		type branch struct {
+			title string
+			graph *stepfunctions.Graph
+			next  string
+		}
+		var branches []branch
+		for i := range node.Children {
+			title := fmt.Sprintf("Branch %d", i+1)
+			if node.Type == "Map" {
+				title = "Items"
+			}
+			branches = append(branches, branch{title: title, graph: &node.Children[i]})
+		}
+		next := ""
+		for _, edge := range t.edges[state] {
+			if edge.Label == "" {
+				next = edge.To
+				continue
+			}
+			branches = append(branches, branch{title: edge.Label, next: edge.To})
+		}
+		for i, br := range branches {
+			connector, indent := "├── ", "│   "
+			if i == len(branches)-1 {
+				connector, indent = "└── ", "    "
+			}
+			fmt.Fprintf(t.b, "%s%s%s\n", prefix, connector, br.title)
+			if br.graph != nil {
+				stateTreeScope(t.b, *br.graph, prefix+indent)
+			} else {
+				t.sequence(br.next, prefix+indent)
+			}
+		}
+		state = next
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestRenderGraph(t *testing.T) {
+	g, err := stepfunctions.BuildGraph(`{"StartAt":"Route","States":{
+		"Route":{"Type":"Choice","Choices":[{"Variable":"$.total","NumericGreaterThan":100,"Next":"Fan"}],"Default":"Done"},
+		"Fan":{"Type":"Parallel","Branches":[{"StartAt":"Charge \"card\"","States":{"Charge \"card\"":{"Type":"Task","Resource":"arn:x","End":true}}}],"Next":"Done"},
+		"Done":{"Type":"Succeed"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for format, render := range graphFormats {
+		assertGolden(t, filepath.Join("graph", "orders."+format), []byte(render("orders", g)))
+	}
+}
+
+func TestRenderStateTree(t *testing.T) {
+	g, err := stepfunctions.BuildGraph(`{"StartAt":"Poll","States":{
+		"Poll":{"Type":"Task","Resource":"arn:x","Catch":[{"ErrorEquals":["States.ALL"],"Next":"Failed"}],"Next":"Ready?"},
+		"Ready?":{"Type":"Choice","Choices":[{"Variable":"$.ready","BooleanEquals":false,"Next":"Poll"}],"Default":"Rows"},
+		"Rows":{"Type":"Map","ItemProcessor":{"StartAt":"Row","States":{"Row":{"Type":"Pass","End":true}}},"End":true},
+		"Failed":{"Type":"Fail"},
+		"Orphan":{"Type":"Pass","End":true}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `jobs
+Poll (Task)
+└── Catch States.ALL
+    Failed (Fail)
+Ready? (Choice)
+├── Choices[0]
+│   ↪ Poll
+└── Default
+    Rows (Map)
+    └── Items
+        Row (Pass)
+(unreachable)
+Orphan (Pass)
+`
+	if got := renderStateTree("jobs", g); got != want {
+		t.Errorf("renderStateTree =\n%s\nwant\n%s", got, want)
+	}
+}
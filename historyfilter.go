@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// historyFilter selects and orders the history events that are exported.
+// Analyses still see every event.
+type historyFilter struct {
+	events  map[string]bool // Event types kept; nil keeps every event
+	reverse bool            // Newest first
+}
+
+// parseHistoryEvents parses a comma-separated list of history event types,
+// e.g. TaskFailed,ExecutionFailed
+func parseHistoryEvents(list string) (map[string]bool, error) {
+	if list == "" {
+		return nil, nil
+	}
+	known := make(map[string]bool)
+	for _, eventType := range types.HistoryEventType("").Values() {
+		known[string(eventType)] = true
+	}
+	events := make(map[string]bool)
+	var unknown []string
+	for _, eventType := range strings.Split(list, ",") {
+		eventType = strings.TrimSpace(eventType)
+		if eventType == "" {
+			continue
+		}
+		if !known[eventType] {
+			unknown = append(unknown, eventType)
+		}
+		events[eventType] = true
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown history event types %s", strings.Join(unknown, ", "))
+	}
+	return events, nil
+}
+
+// apply returns the execution with its history filtered and ordered, marked
+// truncated when events were left out. The original history is left untouched.
+func (f historyFilter) apply(exec stepfunctions.Execution) stepfunctions.Execution {
+	if f.events == nil && !f.reverse {
+		return exec
+	}
+	history := make([]stepfunctions.HistoryEvent, 0, len(exec.History))
+	for _, event := range exec.History {
+		if f.events == nil || f.events[event.Type] {
+			history = append(history, event)
+		}
+	}
+	if len(history) < len(exec.History) {
+		exec.Captured.Truncated = true
+	}
+	if f.reverse {
+		for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+			history[i], history[j] = history[j], history[i]
+		}
+	}
+	exec.History = history
+	return exec
+}
+
+// applyAll returns sm with apply run on each of its executions, so exporters
+// that write executions with the state machine see the filtered history too.
+// The original executions are left untouched.
+func (f historyFilter) applyAll(sm stepfunctions.StateMachine) stepfunctions.StateMachine {
+	if f.events == nil && !f.reverse {
+		return sm
+	}
+	executions := make([]stepfunctions.Execution, len(sm.Executions))
+	for i, exec := range sm.Executions {
+		executions[i] = f.apply(exec)
+	}
+	sm.Executions = executions
+	return sm
+}
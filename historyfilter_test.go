@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestHistoryFilter(t *testing.T) {
+	events, err := parseHistoryEvents("TaskFailed, ExecutionFailed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := stepfunctions.Execution{History: []stepfunctions.HistoryEvent{
+		{ID: 1, Type: "ExecutionStarted"},
+		{ID: 2, Type: "TaskFailed"},
+		{ID: 3, Type: "TaskSucceeded"},
+		{ID: 4, Type: "ExecutionFailed"},
+	}}
+
+	filtered := historyFilter{events: events, reverse: true}.apply(exec)
+	if len(filtered.History) != 2 || filtered.History[0].ID != 4 || filtered.History[1].ID != 2 {
+		t.Errorf("got history %+v", filtered.History)
+	}
+	if !filtered.Captured.Truncated || exec.Captured.Truncated {
+		t.Errorf("got truncated %v, original %v; want only the filtered copy truncated", filtered.Captured.Truncated, exec.Captured.Truncated)
+	}
+	if len(exec.History) != 4 || exec.History[0].ID != 1 {
+		t.Errorf("apply changed the original history: %+v", exec.History)
+	}
+	if got := (historyFilter{}).apply(exec); len(got.History) != 4 || got.Captured.Truncated {
+		t.Errorf("an empty filter dropped events: %+v", got.History)
+	}
+
+	sm := stepfunctions.StateMachine{Executions: []stepfunctions.Execution{exec}}
+	if got := (historyFilter{events: events}).applyAll(sm); len(got.Executions[0].History) != 2 || len(sm.Executions[0].History) != 4 {
+		t.Errorf("applyAll got %+v, original %+v", got.Executions[0].History, sm.Executions[0].History)
+	}
+
+	if _, err := parseHistoryEvents("TaskFailed,TaskExploded"); err == nil {
+		t.Error("expected an error for an unknown event type")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// hookList collects repeated --hook flags
+type hookList []string
+
+func (h *hookList) String() string { return strings.Join(*h, ", ") }
+
+func (h *hookList) Set(command string) error {
+	if strings.TrimSpace(command) == "" {
+		return fmt.Errorf("empty hook command")
+	}
+	*h = append(*h, command)
+	return nil
+}
+
+// hookExporter runs an external command and writes every state machine,
+// execution and history event to its stdin as NDJSON stepfunctions.Records.
+// The command inherits stdout and stderr and gets the run settings in
+// FETCHER_REGION and FETCHER_OUTPUT_DIR (and FETCHER_STATE_MACHINE_ARN when
+// it runs per state machine). A non-zero exit is reported as an error.
+type hookExporter struct {
+	command         string // Run through the shell, so quoting and pipes work
+	env             []string
+	perStateMachine bool
+
+	cmd          *exec.Cmd
+	stdin        *bufio.Writer
+	closer       func() error
+	enc          *json.Encoder
+	writeFailed  bool   // Reported once; the hook's exit status tells the rest
+	stateMachine string // ARN of the state machine being written
+	err          error  // First failure of an earlier per-state-machine run
+}
+
+func newHookExporter(command string, cfg runConfig) *hookExporter {
+	return &hookExporter{
+		command:         command,
+		env:             append(os.Environ(), "FETCHER_REGION="+cfg.region, "FETCHER_OUTPUT_DIR="+cfg.outputDir),
+		perStateMachine: cfg.hookScope == hookScopeStateMachine,
+	}
+}
+
+const (
+	hookScopeRun          = "run"
+	hookScopeStateMachine = "state-machine"
+)
+
+func (h *hookExporter) WriteStateMachine(ctx context.Context, sm stepfunctions.StateMachine) error {
+	if h.perStateMachine {
+		if err := h.finish(); err != nil {
+			// Belongs to the previous state machine; Close reports it
+			log.Printf("%v", err)
+			if h.err == nil {
+				h.err = err
+			}
+		}
+	}
+	if h.cmd == nil {
+		if err := h.start(ctx, sm.ARN); err != nil {
+			return err
+		}
+	}
+
+	h.stateMachine = sm.ARN
+	summary := sm
+	summary.Executions = nil
+	return h.write(stepfunctions.Record{RecordType: "stateMachine", StateMachineArn: sm.ARN, StateMachine: &summary})
+}
+
+func (h *hookExporter) WriteExecution(_ context.Context, sm stepfunctions.StateMachine, exec stepfunctions.Execution) error {
+	h.stateMachine = sm.ARN
+	summary := exec
+	summary.History = nil
+	return h.write(stepfunctions.Record{RecordType: "execution", StateMachineArn: sm.ARN, ExecutionArn: exec.ExecutionArn, Execution: &summary})
+}
+
+func (h *hookExporter) WriteHistoryEvent(_ context.Context, exec stepfunctions.Execution, event stepfunctions.HistoryEvent) error {
+	return h.write(stepfunctions.Record{RecordType: "historyEvent", StateMachineArn: h.stateMachine, ExecutionArn: exec.ExecutionArn, Event: &event})
+}
+
+// Close ends the input of the running hook and waits for it to exit
+func (h *hookExporter) Close(context.Context) error {
+	return errors.Join(h.err, h.finish())
+}
+
+func (h *hookExporter) start(ctx context.Context, stateMachineArn string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.command)
+	}
+	cmd.Env = h.env
+	if h.perStateMachine {
+		cmd.Env = append(cmd.Env, "FETCHER_STATE_MACHINE_ARN="+stateMachineArn)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin of hook %s: %w", h.command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start hook %s: %w", h.command, err)
+	}
+
+	h.cmd = cmd
+	h.writeFailed = false
+	h.stdin = bufio.NewWriter(stdin)
+	h.closer = stdin.Close
+	h.enc = json.NewEncoder(h.stdin)
+	return nil
+}
+
+func (h *hookExporter) write(record stepfunctions.Record) error {
+	if h.cmd == nil || h.writeFailed {
+		return nil // Already reported
+	}
+	if err := h.enc.Encode(record); err != nil {
+		h.writeFailed = true
+		return fmt.Errorf("failed to write to hook %s: %w", h.command, err)
+	}
+	return nil
+}
+
+func (h *hookExporter) finish() error {
+	if h.cmd == nil {
+		return nil
+	}
+	cmd := h.cmd
+	h.cmd = nil
+
+	flushErr := h.stdin.Flush()
+	h.closer()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("hook %s failed: %w", h.command, err)
+	}
+	if flushErr != nil && !h.writeFailed {
+		return fmt.Errorf("failed to write to hook %s: %w", h.command, flushErr)
+	}
+	return nil
+}
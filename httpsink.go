@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The batching and retry policy shared by the HTTP exporters: the newrelic
+// export, the --webhook-url summary and the --otel telemetry
+const (
+	sinkBatchItems    = 500     // Keeps New Relic Event API posts under its 1MB limit
+	sinkBatchBytes    = 1 << 20 // Uncompressed
+	sinkFlushInterval = 5 * time.Second
+	sinkMaxAttempts   = 4
+	sinkRetryBackoff  = time.Second // Doubled after each attempt
+	sinkMaxBackoff    = 30 * time.Second
+)
+
+// httpSink POSTs JSON records in batches: a JSON array is sent when it
+// reaches sinkBatchItems or sinkBatchBytes, or when its oldest record is
+// sinkFlushInterval old. Network errors, 408, 429 and 5xx responses are
+// retried with exponential backoff; when the attempts run out, or on any
+// other response, the batch's records are appended to the DLQ file rather
+// than dropped.
+type httpSink struct {
+	name    string // The destination, as in --export, for messages and DLQ records
+	url     string
+	headers map[string]string
+	gzip    bool
+	// sign, when set, adds headers computed over the uncompressed body to
+	// each attempt
+	sign    func(req *http.Request, body []byte)
+	client  *http.Client
+	dlqPath string
+
+	maxItems      int
+	maxBytes      int
+	flushInterval time.Duration
+	maxAttempts   int
+	backoff       time.Duration
+
+	records []json.RawMessage
+	size    int
+	oldest  time.Time
+}
+
+func newHTTPSink(name, url, dlqPath string) *httpSink {
+	return &httpSink{
+		name:          name,
+		url:           url,
+		headers:       make(map[string]string),
+		gzip:          true,
+		client:        newHTTPClient(30 * time.Second),
+		dlqPath:       dlqPath,
+		maxItems:      sinkBatchItems,
+		maxBytes:      sinkBatchBytes,
+		flushInterval: sinkFlushInterval,
+		maxAttempts:   sinkMaxAttempts,
+		backoff:       sinkRetryBackoff,
+	}
+}
+
+// add queues a record, flushing when the batch is full or old enough
+func (s *httpSink) add(ctx context.Context, record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", s.name, err)
+	}
+	// A failed flush dead-letters the full batch; this record still starts
+	// the next one rather than being dropped
+	var flushErr error
+	if len(s.records) > 0 && s.size+len(data) > s.maxBytes {
+		flushErr = s.flush(ctx)
+	}
+	if len(s.records) == 0 {
+		s.oldest = time.Now()
+	}
+	s.records = append(s.records, data)
+	s.size += len(data) + 1
+	if len(s.records) >= s.maxItems || time.Since(s.oldest) >= s.flushInterval {
+		return errors.Join(flushErr, s.flush(ctx))
+	}
+	return flushErr
+}
+
+// flush sends the queued records as one JSON array
+func (s *httpSink) flush(ctx context.Context) error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	records := s.records
+	s.records, s.size = nil, 0
+
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, record := range records {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(record)
+	}
+	body.WriteByte(']')
+	return s.deliver(ctx, body.Bytes(), records)
+}
+
+// deliver POSTs body, retrying transient failures, and dead-letters records,
+// the contents of body, when it cannot be delivered
+func (s *httpSink) deliver(ctx context.Context, body []byte, records []json.RawMessage) error {
+	payload := body
+	if s.gzip {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(body)
+		if err := gz.Close(); err != nil {
+			err = fmt.Errorf("failed to compress %s records: %w", s.name, err)
+			return errors.Join(err, s.deadLetter(records, err))
+		}
+		payload = compressed.Bytes()
+	}
+
+	backoff := s.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		retryable, retryAfter, sendErr := s.send(ctx, body, payload)
+		if sendErr == nil {
+			return nil
+		}
+		err = sendErr
+		if !retryable || attempt >= s.maxAttempts || ctx.Err() != nil {
+			break
+		}
+		wait := max(backoff, retryAfter)
+		fmt.Printf("Debug: %s attempt %d failed, retrying in %s: %v\n", s.name, attempt, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
+		backoff = min(2*backoff, sinkMaxBackoff)
+	}
+
+	if dlqErr := s.deadLetter(records, err); dlqErr != nil {
+		return errors.Join(fmt.Errorf("failed to send %d records to %s: %w", len(records), s.name, err), dlqErr)
+	}
+	return fmt.Errorf("failed to send %d records to %s, saved to %s: %w", len(records), s.name, s.dlqPath, err)
+}
+
+// send makes one attempt and reports whether a failure is worth retrying,
+// and after how long the server asked for
+func (s *httpSink) send(ctx context.Context, body, payload []byte) (retryable bool, retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	if s.sign != nil {
+		s.sign(req, body)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+	err = fmt.Errorf("%s returned %s", s.name, resp.Status)
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+	default:
+		if resp.StatusCode < 500 {
+			return false, 0, err
+		}
+	}
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil {
+		retryAfter = min(time.Duration(seconds)*time.Second, sinkMaxBackoff)
+	}
+	return true, retryAfter, err
+}
+
+// deadLetter appends records to the DLQ file with the error that stopped them
+func (s *httpSink) deadLetter(records []json.RawMessage, cause error) error {
+	if s.dlqPath == "" {
+		return fmt.Errorf("no DLQ file for %s, dropped %d records", s.name, len(records))
+	}
+	return appendDLQ(s.dlqPath, s.name, records, cause)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sinkServer answers each POST with the next status, then 200, and records
+// the decoded batches it accepted
+func sinkServer(t *testing.T, statuses ...int) (*httptest.Server, *[][]map[string]any) {
+	var batches [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(statuses) > 0 {
+			status := statuses[0]
+			statuses = statuses[1:]
+			w.WriteHeader(status)
+			return
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var batch []map[string]any
+		if err := json.NewDecoder(gz).Decode(&batch); err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, batch)
+	}))
+	t.Cleanup(server.Close)
+	return server, &batches
+}
+
+func testSink(t *testing.T, url string) *httpSink {
+	sink := newHTTPSink("test", url, filepath.Join(t.TempDir(), dlqFile))
+	sink.backoff = 0
+	return sink
+}
+
+func TestHTTPSinkBatches(t *testing.T) {
+	server, batches := sinkServer(t)
+	sink := testSink(t, server.URL)
+	sink.maxItems = 2
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := sink.add(ctx, map[string]any{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*batches) != 3 || len((*batches)[0]) != 2 || len((*batches)[2]) != 1 {
+		t.Errorf("got batches %v, want sizes 2, 2, 1", *batches)
+	}
+}
+
+func TestHTTPSinkRetries(t *testing.T) {
+	server, batches := sinkServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	sink := testSink(t, server.URL)
+	ctx := context.Background()
+	sink.add(ctx, map[string]any{"n": 1})
+	if err := sink.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*batches) != 1 {
+		t.Errorf("got %d batches after retries, want 1", len(*batches))
+	}
+}
+
+func TestHTTPSinkDeadLetters(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		statuses []int
+	}{
+		{"permanent", []int{http.StatusForbidden}},
+		{"attempts exhausted", []int{500, 500, 500, 500}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, batches := sinkServer(t, tc.statuses...)
+			sink := testSink(t, server.URL)
+			ctx := context.Background()
+			sink.add(ctx, map[string]any{"n": 1})
+			sink.add(ctx, map[string]any{"n": 2})
+			if err := sink.flush(ctx); err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(*batches) != 0 {
+				t.Fatalf("got %d batches, want none", len(*batches))
+			}
+
+			file, err := os.Open(sink.dlqPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			var records []dlqRecord
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var record dlqRecord
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					t.Fatal(err)
+				}
+				records = append(records, record)
+			}
+			if len(records) != 2 || records[0].Sink != "test" || records[0].Error == "" || string(records[1].Record) != `{"n":2}` {
+				t.Errorf("got DLQ records %+v", records)
+			}
+		})
+	}
+}
+
+func TestHTTPSinkKeepsRecordAfterFailedFlush(t *testing.T) {
+	server, batches := sinkServer(t, http.StatusForbidden)
+	sink := testSink(t, server.URL)
+	sink.maxBytes = 10 // Each record fills a batch
+	ctx := context.Background()
+	sink.add(ctx, map[string]any{"n": 1})
+	if err := sink.add(ctx, map[string]any{"n": 2}); err == nil {
+		t.Fatal("expected the failed flush of the first record")
+	}
+	if err := sink.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*batches) != 1 || len((*batches)[0]) != 1 || (*batches)[0][0]["n"] != float64(2) {
+		t.Errorf("got batches %v, want the second record sent after the first was dead-lettered", *batches)
+	}
+}
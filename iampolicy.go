@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// iamPolicy is an IAM policy document
+type iamPolicy struct {
+	Version   string
+	Statement []iamStatement
+}
+
+type iamStatement struct {
+	Effect   string
+	Action   []string
+	Resource []string
+}
+
+// buildIAMPolicy grants perms with one statement per set of resources, in
+// the order the run needs them
+func buildIAMPolicy(perms []permission) iamPolicy {
+	policy := iamPolicy{Version: "2012-10-17"}
+	statements := make(map[string]int)
+	for _, perm := range perms {
+		key := strings.Join(perm.Resources, ",")
+		i, ok := statements[key]
+		if !ok {
+			i = len(policy.Statement)
+			statements[key] = i
+			policy.Statement = append(policy.Statement, iamStatement{Effect: "Allow", Resource: perm.Resources})
+		}
+		statement := &policy.Statement[i]
+		if !slices.Contains(statement.Action, perm.Action) {
+			statement.Action = append(statement.Action, perm.Action)
+		}
+	}
+	for _, statement := range policy.Statement {
+		sort.Strings(statement.Action)
+	}
+	return policy
+}
+
+// writeIAMPolicy writes the least-privilege policy of the configured run, for
+// `export iam-policy`
+func writeIAMPolicy(w io.Writer, cfg runConfig) error {
+	data, err := json.MarshalIndent(buildIAMPolicy(cfg.permissions()), "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode IAM policy: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestBuildIAMPolicy(t *testing.T) {
+	cfg := runConfig{
+		region:           "us-east-1",
+		alias:            "prod",
+		stateMachineARNs: []string{"arn:aws:states:us-east-1:123456789012:stateMachine:orders"},
+		kinesisStream:    "events",
+	}
+	resources := make(map[string][]string)
+	for _, statement := range buildIAMPolicy(cfg.permissions()).Statement {
+		for _, action := range statement.Action {
+			resources[action] = statement.Resource
+		}
+	}
+
+	if _, ok := resources["states:ListStateMachines"]; ok {
+		t.Error("got states:ListStateMachines for explicit state machines")
+	}
+	for action, want := range map[string][]string{
+		"states:DescribeStateMachine": {"arn:aws:states:us-east-1:123456789012:stateMachine:orders"},
+		"states:ListExecutions":       {"arn:aws:states:us-east-1:123456789012:stateMachine:orders", "arn:aws:states:us-east-1:123456789012:stateMachine:orders:*"},
+		"states:DescribeExecution":    {"arn:aws:states:us-east-1:123456789012:execution:orders:*", "arn:aws:states:us-east-1:123456789012:express:orders:*"},
+		"kinesis:PutRecords":          {"arn:aws:kinesis:us-east-1:123456789012:stream/events"},
+	} {
+		if !slices.Equal(resources[action], want) {
+			t.Errorf("got %s on %v, want %v", action, resources[action], want)
+		}
+	}
+}
+
+func TestWriteIAMPolicy(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeIAMPolicy(&out, runConfig{region: "cn-north-1", cfnDrift: true}); err != nil {
+		t.Fatal(err)
+	}
+	var policy iamPolicy
+	if err := json.Unmarshal(out.Bytes(), &policy); err != nil {
+		t.Fatal(err)
+	}
+	last := policy.Statement[len(policy.Statement)-1]
+	if policy.Version != "2012-10-17" || !slices.Equal(last.Resource, []string{"arn:aws-cn:cloudformation:cn-north-1:*:stack/*"}) {
+		t.Errorf("got policy %+v", policy)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// callerIdentity is the account and principal the AWS credentials resolve to
+type callerIdentity struct {
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+	UserID  string `json:"userId"`
+}
+
+// getCallerIdentity asks STS who the credentials for region belong to
+func getCallerIdentity(ctx context.Context, region string) (*callerIdentity, error) {
+	awsCfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	out, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return &callerIdentity{Account: aws.ToString(out.Account), ARN: aws.ToString(out.Arn), UserID: aws.ToString(out.UserId)}, nil
+}
+
+// accountIDPattern matches a 12-digit AWS account ID
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// checkCaller prints the account and principal the credentials resolve to
+// and, with --expected-account, returns the error that stops a run that would
+// export another account. Without --expected-account a failed lookup is only
+// logged. The identity, or the lookup error, goes to run.json.
+func checkCaller(ctx context.Context, cfg runConfig, errs *runErrors) error {
+	identity, lookupErr := getCallerIdentity(ctx, cfg.region)
+	if errs.meta != nil {
+		errs.meta.setCaller(identity, lookupErr)
+	}
+	if lookupErr != nil {
+		if cfg.expectedAccount != "" {
+			return errs.fatal("caller", "Failed to verify --expected-account", lookupErr)
+		}
+		log.Printf("Could not identify the AWS caller: %v", lookupErr)
+		return nil
+	}
+	fmt.Printf("Using AWS account %s as %s\n", identity.Account, identity.ARN)
+	if err := verifyAccount(identity, cfg.expectedAccount); err != nil {
+		return errs.fatal("caller", "Wrong AWS account", err)
+	}
+	return nil
+}
+
+// verifyAccount fails when expected is set and is not the caller's account
+func verifyAccount(identity *callerIdentity, expected string) error {
+	if expected == "" || identity.Account == expected {
+		return nil
+	}
+	return fmt.Errorf("credentials resolve to account %s (%s), not the expected account %s", identity.Account, identity.ARN, expected)
+}
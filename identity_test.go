@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestVerifyAccount(t *testing.T) {
+	identity := &callerIdentity{Account: "123456789012", ARN: "arn:aws:sts::123456789012:assumed-role/fetcher/session"}
+	for _, tc := range []struct {
+		expected string
+		wantErr  bool
+	}{
+		{"", false},
+		{"123456789012", false},
+		{"210987654321", true},
+	} {
+		if err := verifyAccount(identity, tc.expected); (err != nil) != tc.wantErr {
+			t.Errorf("verifyAccount(%q) = %v, want error %v", tc.expected, err, tc.wantErr)
+		}
+	}
+}
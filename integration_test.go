@@ -0,0 +1,174 @@
+//go:build integration
+
+// The integration tests run the fetcher end to end against Step Functions
+// Local or LocalStack:
+//
+//	docker run -d -p 8083:8083 amazon/aws-stepfunctions-local
+//	go test -tags integration -run Integration -v .
+//
+// Set SFN_ENDPOINT to use another endpoint, e.g. http://localhost:4566 for LocalStack.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+const integrationDefinition = `{
+  "StartAt": "Prepare",
+  "States": {
+    "Prepare": {"Type": "Pass", "Result": {"total": 3}, "Next": "Route"},
+    "Route": {"Type": "Choice", "Choices": [{"Variable": "$.total", "NumericGreaterThan": 0, "Next": "Done"}], "Default": "Done"},
+    "Done": {"Type": "Succeed"}
+  }
+}`
+
+// integrationClient points the SDK at the local endpoint, skipping the test
+// when nothing is listening there
+func integrationClient(t *testing.T) *sfn.Client {
+	t.Helper()
+	endpoint := os.Getenv("SFN_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:8083"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatalf("invalid SFN_ENDPOINT %q: %v", endpoint, err)
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, 2*time.Second)
+	if err != nil {
+		t.Skipf("no Step Functions endpoint at %s: %v", endpoint, err)
+	}
+	conn.Close()
+
+	// NewFetcher loads the same environment, so the run under test uses it too
+	t.Setenv("AWS_ENDPOINT_URL_SFN", endpoint)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sfn.NewFromConfig(cfg)
+}
+
+// createIntegrationStateMachine creates a state machine, runs it to
+// completion and deletes it when the test ends
+func createIntegrationStateMachine(t *testing.T, client *sfn.Client) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	created, err := client.CreateStateMachine(ctx, &sfn.CreateStateMachineInput{
+		Name:       aws.String(fmt.Sprintf("fetcher-it-%d", time.Now().UnixNano())),
+		Definition: aws.String(integrationDefinition),
+		RoleArn:    aws.String("arn:aws:iam::012345678901:role/DummyRole"),
+		Type:       types.StateMachineTypeStandard,
+	})
+	if err != nil {
+		t.Fatalf("failed to create state machine: %v", err)
+	}
+	t.Cleanup(func() {
+		client.DeleteStateMachine(context.Background(), &sfn.DeleteStateMachineInput{StateMachineArn: created.StateMachineArn})
+	})
+
+	started, err := client.StartExecution(ctx, &sfn.StartExecutionInput{
+		StateMachineArn: created.StateMachineArn,
+		Input:           aws.String(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("failed to start execution: %v", err)
+	}
+	for deadline := time.Now().Add(30 * time.Second); ; time.Sleep(250 * time.Millisecond) {
+		exec, err := client.DescribeExecution(ctx, &sfn.DescribeExecutionInput{ExecutionArn: started.ExecutionArn})
+		if err != nil {
+			t.Fatalf("failed to describe execution: %v", err)
+		}
+		if exec.Status == types.ExecutionStatusSucceeded {
+			break
+		}
+		if exec.Status != types.ExecutionStatusRunning || time.Now().After(deadline) {
+			t.Fatalf("execution ended as %s, want SUCCEEDED", exec.Status)
+		}
+	}
+	return aws.ToString(created.StateMachineArn), aws.ToString(started.ExecutionArn)
+}
+
+func TestIntegrationRun(t *testing.T) {
+	client := integrationClient(t)
+	smArn, execArn := createIntegrationStateMachine(t, client)
+
+	dir := t.TempDir()
+	code, err := run(context.Background(), runConfig{
+		region:           "us-east-1",
+		outputDir:        dir,
+		includeHistory:   true,
+		stateMachineARNs: []string{smArn},
+		export:           defaultExport,
+	})
+	if code != exitOK || err != nil {
+		t.Fatalf("run exited with %d, %v, want %d", code, err, exitOK)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "state_machines.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateMachines, err := stepfunctions.ParseStateMachines(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stateMachines) != 1 || stateMachines[0].ARN != smArn {
+		t.Fatalf("state_machines.json has %d state machines, want only %s", len(stateMachines), smArn)
+	}
+	sm := stateMachines[0]
+	if len(sm.States) != 3 {
+		t.Errorf("got %d states, want 3", len(sm.States))
+	}
+	for _, state := range sm.States {
+		if _, err := os.Stat(filepath.Join(dir, stateDefinitionFile(sm.Name, state.Name))); err != nil {
+			t.Errorf("state %s was not written: %v", state.Name, err)
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, executionFile(sm.Name, execArn)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, err := stepfunctions.ParseExecution(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.Status != "SUCCEEDED" || len(exec.History) == 0 || exec.Transitions == 0 {
+		t.Errorf("got execution %s with %d history events and %d transitions, want SUCCEEDED with history",
+			exec.Status, len(exec.History), exec.Transitions)
+	}
+}
+
+func TestIntegrationMissingStateMachine(t *testing.T) {
+	integrationClient(t)
+	code, err := run(context.Background(), runConfig{
+		region:           "us-east-1",
+		outputDir:        t.TempDir(),
+		stateMachineARNs: []string{"arn:aws:states:us-east-1:012345678901:stateMachine:does-not-exist"},
+		export:           defaultExport,
+	})
+	// Without --fail-fast the missing state machine is skipped, not an error
+	if code != exitPartial || err != nil {
+		t.Fatalf("run exited with %d, %v, want %d for a state machine that does not exist", code, err, exitPartial)
+	}
+}
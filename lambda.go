@@ -0,0 +1,299 @@
+//go:build lambda
+
+// Build with -tags lambda to produce a Lambda bootstrap instead of the CLI:
+//
+//	GOOS=linux GOARCH=arm64 go build -tags lambda,lambda.norpc -o bootstrap .
+//
+// Schedule it with an EventBridge rule. Settings come from the environment
+// (see lambdaConfig) and every file of the run is uploaded to S3.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// lambdaWorkDir is Lambda's only writable path; the definition cache under it
+// survives between invocations of a warm container
+const lambdaWorkDir = "/tmp/stepfunction-fetcher"
+
+func init() {
+	lambdaMain = func() { lambda.Start(handleScheduledEvent) }
+}
+
+// lambdaResult is returned to the invoker and shows up in the Lambda console
+type lambdaResult struct {
+	Bucket   string `json:"bucket"`
+	Prefix   string `json:"prefix"`
+	Files    int    `json:"files"`
+	ExitCode int    `json:"exitCode"`
+}
+
+func handleScheduledEvent(ctx context.Context, event events.CloudWatchEvent) (lambdaResult, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return lambdaResult{}, fmt.Errorf("S3_BUCKET is not set")
+	}
+
+	runTime := event.Time
+	if runTime.IsZero() {
+		runTime = time.Now()
+	}
+	runID := runTime.UTC().Format("20060102T150405Z")
+
+	cfg, err := lambdaConfig(filepath.Join(lambdaWorkDir, runID))
+	if err != nil {
+		return lambdaResult{}, err
+	}
+	defer os.RemoveAll(cfg.outputDir)
+
+	// A run that stopped early still uploads what it wrote, errors.json
+	// included, before failing the invocation
+	exitCode, runErr := run(ctx, cfg)
+
+	prefix := path.Join(os.Getenv("S3_PREFIX"), runID)
+	files, err := uploadDir(ctx, cfg.region, cfg.outputDir, bucket, prefix)
+	if err != nil {
+		err = fmt.Errorf("failed to upload results to s3://%s/%s: %w", bucket, prefix, err)
+		return lambdaResult{}, errors.Join(runErr, err)
+	}
+	log.Printf("Uploaded %d files to s3://%s/%s (exit code %d)", files, bucket, prefix, exitCode)
+
+	result := lambdaResult{Bucket: bucket, Prefix: prefix, Files: files, ExitCode: exitCode}
+	if runErr != nil {
+		return result, fmt.Errorf("run stopped with exit code %d: %w", exitCode, runErr)
+	}
+	return result, nil
+}
+
+// lambdaConfig reads the run settings from the environment:
+//
+//	FETCHER_REGION (default AWS_REGION), EXPECTED_ACCOUNT, S3_BUCKET, S3_PREFIX,
+//	INCLUDE_HISTORY, CLOUDTRAIL_INITIATORS, CLOUDTRAIL_LOOKBACK,
+//	EXPRESS_LOOKBACK, TIMELINE, ANALYTICS, IAM_ANALYSIS, SLA_CONFIG,
+//	MAX_API_CALLS, API_TIMEOUT, QUOTA_PACING, VERBOSE, FAIL_FAST, KMS_AUDIT,
+//	KINESIS_STREAM, FIREHOSE_STREAM, NOTIFY_SNS_TOPIC, NOTIFY_SLACK_WEBHOOK,
+//	NR_LINK_TEMPLATE, WEBHOOK_URL, WEBHOOK_SECRET, REPORT, EXPORT, OTEL,
+//	SORT, COUNTS_ONLY, COUNT_STATUSES, ALIAS, FINDINGS_CONFIG, SARIF,
+//	CFN_DRIFT, DEFINITION_FORMAT, DEFINITION_INLINE_MAX, MAP_RUNS, CALLBACKS,
+//	HISTORY_EVENTS, HISTORY_REVERSE, SAMPLE, BUCKETS, NERDGRAPH_SYNC,
+//	WORKLOAD_TAG, NR_LAMBDA_TRACES
+//
+// Credentials may be secretsmanager: or ssm: references, see resolveSecret.
+// AWS_USE_FIPS_ENDPOINT, AWS_CA_BUNDLE and HTTPS_PROXY apply as in the main
+// command.
+func lambdaConfig(outputDir string) (runConfig, error) {
+	// The variables read, for run.json
+	settings := make(map[string]string)
+	getenv := func(name string) string {
+		value := os.Getenv(name)
+		if value != "" {
+			settings[name] = value
+		}
+		return value
+	}
+
+	region := getenv("FETCHER_REGION")
+	if region == "" {
+		region = getenv("AWS_REGION")
+	}
+
+	cfg := runConfig{
+		region:          region,
+		expectedAccount: getenv("EXPECTED_ACCOUNT"),
+		outputDir:       outputDir,
+		cacheDir:        filepath.Join(lambdaWorkDir, "cache"),
+		expressQuery: stepfunctions.ExpressLogQuery{
+			FilterPattern: stepfunctions.DefaultExpressFilterPattern,
+			MaxBytes:      100 * 1024 * 1024,
+			MaxTime:       5 * time.Minute,
+		},
+		cloudTrailLookback: 24 * time.Hour,
+		apiTimeout:         defaultAPITimeout,
+		quotaPacing:        true,
+		kinesisStream:      getenv("KINESIS_STREAM"),
+		firehoseStream:     getenv("FIREHOSE_STREAM"),
+		notifySNSTopic:     getenv("NOTIFY_SNS_TOPIC"),
+		notifySlackWebhook: getenv("NOTIFY_SLACK_WEBHOOK"),
+		nrLinkTemplate:     getenv("NR_LINK_TEMPLATE"),
+		webhookURL:         getenv("WEBHOOK_URL"),
+		webhookSecret:      getenv("WEBHOOK_SECRET"),
+		report:             getenv("REPORT"),
+		export:             getenv("EXPORT"),
+		alias:              getenv("ALIAS"),
+		definitionFormat:   getenv("DEFINITION_FORMAT"),
+		buckets:            getenv("BUCKETS"),
+		workloadTag:        getenv("WORKLOAD_TAG"),
+		settings:           settings,
+	}
+	// Kept next to the runs' prefixes, as /tmp does not survive a cold start
+	if bucket := getenv("S3_BUCKET"); bucket != "" {
+		cfg.notifyState = "s3://" + path.Join(bucket, getenv("S3_PREFIX"), "notified_failures.json")
+		cfg.revisionsDir = "s3://" + path.Join(bucket, getenv("S3_PREFIX"), revisionsDir)
+	}
+
+	if cfg.export == "" {
+		cfg.export = defaultExport
+	}
+	if value := getenv("SLA_CONFIG"); value != "" {
+		sla, err := stepfunctions.LoadSLAConfig(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SLA_CONFIG: %w", err)
+		}
+		cfg.sla = &sla
+	}
+	if value := getenv("FINDINGS_CONFIG"); value != "" {
+		findings, err := stepfunctions.LoadFindingsConfig(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid FINDINGS_CONFIG: %w", err)
+		}
+		cfg.findings = &findings
+	}
+	cfg.newRelic = newRelicFromEnv(newRelicConfig{})
+	if err := cfg.newRelic.validate(); err != nil {
+		return cfg, fmt.Errorf("invalid NEW_RELIC_REGION: %w", err)
+	}
+	if err := network.load(); err != nil {
+		return cfg, fmt.Errorf("invalid AWS_CA_BUNDLE: %w", err)
+	}
+	if cfg.expectedAccount != "" && !accountIDPattern.MatchString(cfg.expectedAccount) {
+		return cfg, fmt.Errorf("invalid EXPECTED_ACCOUNT %q: expected a 12-digit account ID", cfg.expectedAccount)
+	}
+	if cfg.workloadTag == "" {
+		cfg.workloadTag = defaultWorkloadTag
+	}
+	if cfg.definitionFormat == "" {
+		cfg.definitionFormat = "json"
+	}
+	if !definitionFormats[cfg.definitionFormat] {
+		return cfg, fmt.Errorf("invalid DEFINITION_FORMAT %q (supported: json, yaml)", cfg.definitionFormat)
+	}
+	if _, ok := bucketSizes[cfg.buckets]; cfg.buckets != "" && !ok {
+		return cfg, fmt.Errorf("invalid BUCKETS %q (supported: hourly, daily)", cfg.buckets)
+	}
+
+	var err error
+	bools := map[string]*bool{
+		"INCLUDE_HISTORY":       &cfg.includeHistory,
+		"CLOUDTRAIL_INITIATORS": &cfg.cloudTrailInitiators,
+		"TIMELINE":              &cfg.timelines,
+		"ANALYTICS":             &cfg.analytics,
+		"IAM_ANALYSIS":          &cfg.iamAnalysis,
+		"FAIL_FAST":             &cfg.failFast,
+		"KMS_AUDIT":             &cfg.kmsAudit,
+		"OTEL":                  &cfg.otel,
+		"COUNTS_ONLY":           &cfg.countsOnly,
+		"SARIF":                 &cfg.sarif,
+		"CFN_DRIFT":             &cfg.cfnDrift,
+		"MAP_RUNS":              &cfg.mapRuns,
+		"CALLBACKS":             &cfg.callbacks,
+		"HISTORY_REVERSE":       &cfg.historyFilter.reverse,
+		"NERDGRAPH_SYNC":        &cfg.nerdGraphSync,
+		"NR_LAMBDA_TRACES":      &cfg.nrLambdaTraces,
+		"QUOTA_PACING":          &cfg.quotaPacing,
+		"VERBOSE":               &cfg.verbose,
+	}
+	if cfg.historyFilter.events, err = parseHistoryEvents(getenv("HISTORY_EVENTS")); err != nil {
+		return cfg, fmt.Errorf("invalid HISTORY_EVENTS: %w", err)
+	}
+	if cfg.countStatuses, err = parseCountStatuses(getenv("COUNT_STATUSES")); err != nil {
+		return cfg, fmt.Errorf("invalid COUNT_STATUSES: %w", err)
+	}
+	if cfg.sample, err = stepfunctions.ParseSample(getenv("SAMPLE")); err != nil {
+		return cfg, fmt.Errorf("invalid SAMPLE: %w", err)
+	}
+	for name, target := range bools {
+		if value := getenv(name); value != "" {
+			if *target, err = strconv.ParseBool(value); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"CLOUDTRAIL_LOOKBACK": &cfg.cloudTrailLookback,
+		"EXPRESS_LOOKBACK":    &cfg.expressQuery.Lookback,
+		"API_TIMEOUT":         &cfg.apiTimeout,
+	}
+	for name, target := range durations {
+		if value := getenv(name); value != "" {
+			if *target, err = time.ParseDuration(value); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	if value := getenv("SORT"); value != "" {
+		sortBy, err := stepfunctions.ParseExecutionSort(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SORT: %w", err)
+		}
+		cfg.sort = &sortBy
+	}
+
+	if value := getenv("MAX_API_CALLS"); value != "" {
+		if cfg.maxAPICalls, err = strconv.Atoi(value); err != nil {
+			return cfg, fmt.Errorf("invalid MAX_API_CALLS: %w", err)
+		}
+	}
+	if value := getenv("DEFINITION_INLINE_MAX"); value != "" {
+		if cfg.definitionInlineMax, err = strconv.Atoi(value); err != nil || cfg.definitionInlineMax < 0 {
+			return cfg, fmt.Errorf("invalid DEFINITION_INLINE_MAX %q: expected a number of bytes", value)
+		}
+	}
+
+	return cfg, nil
+}
+
+// uploadDir copies every file under dir to s3://bucket/prefix/, keeping relative paths
+func uploadDir(ctx context.Context, region, dir, bucket, prefix string) (int, error) {
+	awsCfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg)
+
+	uploaded := 0
+	err = filepath.WalkDir(dir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   file,
+		}); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		uploaded++
+		return nil
+	})
+	return uploaded, err
+}
@@ -8,42 +8,666 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 
 	"stepfunction-fetcher/stepfunctions"
+	"stepfunction-fetcher/stepfunctions/export"
 
-	"github.com/olekukonko/tablewriter"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/jmespath/go-jmespath"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// lambdaMain is set by lambda.go in builds with the lambda tag
+var lambdaMain func()
+
 func main() {
-	region := flag.String("region", "us-west-2", "AWS region")
+	if lambdaMain != nil {
+		lambdaMain()
+		return
+	}
+
+	// export iam-policy takes the flags of the run it is for
+	var iamPolicy bool
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "analytics":
+			runAnalytics(os.Args[2:])
+			return
+		case "exec-diff":
+			runExecDiff(os.Args[2:])
+			return
+		case "export":
+			if len(os.Args) > 2 && os.Args[2] == "iam-policy" {
+				iamPolicy = true
+				os.Args = append(os.Args[:1], os.Args[3:]...)
+				break
+			}
+			runExport(os.Args[2:])
+			return
+		case "fetch-execution":
+			runFetchExecution(os.Args[2:])
+			return
+		case "fmt":
+			runFmt(os.Args[2:])
+			return
+		case "graph":
+			runGraph(os.Args[2:])
+			return
+		case "redrive":
+			runRedrive(os.Args[2:])
+			return
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "retry-dlq":
+			runRetryDLQ(os.Args[2:])
+			return
+		case "stop":
+			runStop(os.Args[2:])
+			return
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		case "version", "--version", "-version":
+			printVersion()
+			return
+		}
+	}
+
+	region := flag.String("region", "us-west-2", "AWS region, including GovCloud (us-gov-*) and China (cn-*) regions")
+	expectedAccount := flag.String("expected-account", "", "Abort unless the AWS credentials resolve to this 12-digit account ID")
+	useFIPS := flag.Bool("use-fips", network.useFIPS, "Call AWS through FIPS 140 endpoints (default $AWS_USE_FIPS_ENDPOINT)")
+	proxy := flag.String("proxy", "", "Proxy URL for AWS and New Relic, webhook and Slack requests (default $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY)")
+	caBundle := flag.String("ca-bundle", network.caBundle, "PEM file of CA certificates to trust for AWS and New Relic, webhook and Slack endpoints, e.g. of a TLS-inspecting proxy (default $AWS_CA_BUNDLE)")
 	outputDir := flag.String("output-dir", "stepfunctions_state_definitions", "Directory to save state and execution definitions")
+	cloudTrailInitiators := flag.Bool("cloudtrail-initiators", false, "Attach the CloudTrail principal that started each execution")
+	cloudTrailLookback := flag.Duration("cloudtrail-lookback", 24*time.Hour, "How far back to search CloudTrail for StartExecution events")
+	includeHistory := flag.Bool("include-history", false, "Fetch the full event history of every execution (Express histories are rebuilt from CloudWatch Logs)")
+	expressFilter := flag.String("express-filter-pattern", stepfunctions.DefaultExpressFilterPattern, "CloudWatch Logs filter pattern used to discover Express executions")
+	expressLimit := flag.Int("express-limit", 50, "Maximum log events per FilterLogEvents call for Express workflows")
+	expressLookback := flag.Duration("express-lookback", 24*time.Hour, "How far back to search CloudWatch Logs for Express executions")
+	expressMaxBytes := flag.Int64("express-max-bytes", 100*1024*1024, "Stop paging a log group after this many bytes of events (0 = unlimited)")
+	expressMaxTime := flag.Duration("express-max-time", 5*time.Minute, "Stop paging a log group after this long (0 = unlimited)")
+	historyEvents := flag.String("history-events", "", "Only export these history event types with --include-history, comma-separated (e.g. TaskFailed,ExecutionFailed)")
+	historyReverse := flag.Bool("history-reverse", false, "Export history events newest first")
+	sampleFlag := flag.String("sample", "", "Only fetch a sample of each state machine's executions, stratified by status: a percentage (10%) or latest-per-status[:N]")
+	timelines := flag.Bool("timeline", false, "Write an HTML Gantt chart per execution (requires --include-history)")
+	analytics := flag.Bool("analytics", false, "Compute the analytics report (analytics.json) over the fetched executions")
+	buckets := flag.String("buckets", "", "Aggregate execution counts and durations per state machine into hourly or daily buckets, saved to buckets.json and buckets.csv")
+	slaConfig := flag.String("sla-config", "", "JSON file of per-state-machine maxDuration/maxFailureRate thresholds; exits 3 on violations")
+	iamAnalysis := flag.Bool("iam-analysis", false, "Capture execution role policies and compare them with the actions each definition uses")
+	maxAPICalls := flag.Int("max-api-calls", 0, "Stop fetching after this many AWS API calls and write the partial results (0 = unlimited)")
+	quotaPacing := flag.Bool("quota-pacing", true, "Pace Step Functions calls under the account's API throttling quotas, read from Service Quotas (not counted by --max-api-calls)")
+	verbose := flag.Bool("verbose", false, "Print extra diagnostics, such as the rates --quota-pacing paces calls at and the executions --sample kept")
+	apiTimeout := flag.Duration("api-timeout", defaultAPITimeout, "Give up on a single AWS API call, retries included, after this long and skip what it was fetching (0 = unlimited)")
+	cacheDir := flag.String("cache-dir", stepfunctions.DefaultCacheDir(), "Directory for cached state machine definitions, keyed by revision")
+	noCache := flag.Bool("no-cache", false, "Always re-parse and re-write state definitions")
+	failFast := flag.Bool("fail-fast", false, "Stop at the first state machine, execution or analysis that fails")
+	continueOnError := flag.Bool("continue-on-error", true, "Skip what fails, record it in errors.json and carry on (the default)")
+	kinesisStream := flag.String("kinesis-stream", "", "Stream executions and history events as NDJSON to this Kinesis data stream (name or ARN)")
+	firehoseStream := flag.String("firehose-stream", "", "Stream executions and history events as NDJSON to this Firehose delivery stream")
+	nrRegion := flag.String("nr-region", "", "New Relic data center of every New Relic integration: us, eu or fedramp (default NEW_RELIC_REGION, else inferred from the license key)")
+	nrAccountID := flag.String("nr-account-id", "", "New Relic account ID (default NEW_RELIC_ACCOUNT_ID)")
+	nrLicenseKey := flag.String("nr-license-key", "", "New Relic license key, or secretsmanager:<secret-id>[#<json-key>] / ssm:<parameter> to read it from AWS (default NEW_RELIC_LICENSE_KEY)")
+	nrAPIKey := flag.String("nr-api-key", "", "New Relic user key for NerdGraph, or secretsmanager:<secret-id> / ssm:<parameter> (default NEW_RELIC_API_KEY)")
+	nerdGraphSync := flag.Bool("nerdgraph-sync", false, "Tag each state machine's New Relic entity with its definition hash, type, role and AWS tags, and keep a workload per team in sync (needs NEW_RELIC_ACCOUNT_ID and NEW_RELIC_API_KEY)")
+	workloadTag := flag.String("workload-tag", defaultWorkloadTag, "State machine tag whose value names the New Relic workload with --nerdgraph-sync")
+	nrLambdaTraces := flag.Bool("nr-lambda-traces", false, "Embed the New Relic trace ID and entity link of every Lambda invocation in the exported executions (requires --include-history; needs NEW_RELIC_ACCOUNT_ID and NEW_RELIC_API_KEY)")
+	notifySNSTopic := flag.String("notify-sns-topic", "", "Publish a digest of newly failed executions to this SNS topic ARN")
+	notifySlackWebhook := flag.String("notify-slack-webhook", "", "Post a digest of newly failed executions to this Slack incoming webhook, or secretsmanager:/ssm: reference to it")
+	revisionsLocation := flag.String("revisions-dir", "", "Directory or s3://bucket/prefix keeping every definition each state machine had, to tell when it changed (default <output-dir>/revisions)")
+	notifyState := flag.String("notify-state", "", "File or s3://bucket/key recording already-notified failures (default <output-dir>/notified_failures.json)")
+	nrLinkTemplate := flag.String("nr-link-template", "", "New Relic URL added to each notified failure; {executionArn} and {stateMachineArn} are substituted")
+	webhookURL := flag.String("webhook-url", "", "POST the run summary JSON to this HTTPS endpoint, or secretsmanager:/ssm: reference to it")
+	webhookSecret := flag.String("webhook-secret", os.Getenv("WEBHOOK_SECRET"), "HMAC-SHA256 key for signing webhook requests, or secretsmanager:/ssm: reference to it (default $WEBHOOK_SECRET)")
+	query := flag.String("query", "", "JMESPath expression applied to the fetched state machines; the result is printed and saved to query.json")
+	columns := flag.String("columns", "", "Columns of the state machine (name,arn,type,role,created,console), states (name,type,next,end,definition) and executions (arn,status,start,end,duration,captured,console) tables")
+	maxWidth := flag.Int("max-width", 0, "Truncate table cells to this many characters (0 = unlimited)")
+	wrap := flag.Bool("wrap", false, "Wrap table cells at --max-width (default 30) instead of truncating")
+	stateView := flag.String("state-view", "table", "How states are printed: table, or tree for the flow from StartAt with branches indented")
+	pageSize := flag.Int("page-size", 100, "Print at most this many executions per state machine, in --sort order (0 = all); every execution is still exported")
+	noColor := flag.Bool("no-color", false, "Disable colored output (also disabled by NO_COLOR or when stdout is not a terminal)")
+	report := flag.String("report", "", "Also write a report of the run; supported: markdown (report.md)")
+	templatePath := flag.String("template", "", "Go text/template file rendered over the fetched state machines (see template.go for the data and functions)")
+	saveRaw := flag.Bool("save-raw", false, "Also save each raw DescribeStateMachine response as <name>_describe.json")
+	kmsAudit := flag.Bool("kms-audit", false, "Check that customer managed KMS keys are enabled and rotated (calls KMS)")
+	export := flag.String("export", defaultExport, "Comma-separated destinations for state machines, executions and history: file, s3://bucket/prefix, sqlite[:path], newrelic")
+	otelEnabled := flag.Bool("otel", false, "Export the fetcher's own traces and metrics over OTLP (OTEL_EXPORTER_OTLP_*, or New Relic with NEW_RELIC_LICENSE_KEY)")
+	var hooks hookList
+	flag.Var(&hooks, "hook", "Command run after fetching that receives every state machine, execution and history event as NDJSON on stdin (repeatable)")
+	hookScope := flag.String("hook-scope", hookScopeRun, "Run each --hook once per run or once per state machine (run, state-machine)")
+	arnFile := flag.String("arn-file", "", "Fetch only the state machine ARNs listed in this file, one or more per line (- reads stdin)")
+	alias := flag.String("alias", "", "Only fetch Standard executions started through this alias (e.g. PROD) or version number")
+	sortSpec := flag.String("sort", "", "Order executions in tables and exports by start-time, duration or status, optionally with :asc or :desc")
+	countsOnly := flag.Bool("counts-only", false, "Only count executions by status per state machine (no per-execution calls) and save execution_counts.json")
+	countStatusList := flag.String("count-statuses", "", "With --counts-only, only count these statuses, comma-separated (e.g. RUNNING); each is one ListExecutions status filter")
+	cfnDrift := flag.Bool("cfn-drift", false, "Run CloudFormation drift detection on state machines created by a stack and save drift.json")
+	findingsConfig := flag.String("findings-config", "", "JSON file of findings to suppress, by rule, state machine and state")
+	sarif := flag.Bool("sarif", false, "Also write the findings, including definition validation, as SARIF (findings.sarif) against the exported <name>.asl.json files")
+	callbacks := flag.Bool("callbacks", false, "Report running executions blocked on a .waitForTaskToken callback and save callbacks.json (fetches the history of running executions)")
+	mapRuns := flag.Bool("map-runs", false, "Fetch the Distributed Map runs of Standard executions and save the items that failed, read from the runs' ResultWriter output in S3")
+	definitionInlineMax := flag.Int("definition-inline-max", 0, "Keep only the hash and <name>.asl.json path of definitions larger than this many bytes in state_machines.json (0 = keep every definition)")
+	definitionFormat := flag.String("definition-format", "json", "Format of the saved definition and state files: json or yaml (<name>.asl.yaml, <name>_<state>.yaml)")
+	dryRun := flag.Bool("dry-run", false, "List the state machines and estimate API calls and files without fetching executions")
+	doctor := flag.Bool("doctor", false, "Check that the caller has every IAM permission this run needs, print the missing ones and exit without fetching")
 	flag.Parse()
+	initColor(*noColor)
+	if *report != "" && *report != "markdown" {
+		log.Fatalf("Unsupported --report %q (supported: markdown)", *report)
+	}
+	historyEventTypes, err := parseHistoryEvents(*historyEvents)
+	if err != nil {
+		log.Fatalf("Invalid --history-events: %v", err)
+	}
+	sample, err := stepfunctions.ParseSample(*sampleFlag)
+	if err != nil {
+		log.Fatalf("Invalid --sample: %v", err)
+	}
+	if _, ok := bucketSizes[*buckets]; *buckets != "" && !ok {
+		log.Fatalf("Unsupported --buckets %q (supported: hourly, daily)", *buckets)
+	}
+	if *definitionInlineMax < 0 {
+		log.Fatalf("Invalid --definition-inline-max %d: must not be negative", *definitionInlineMax)
+	}
+	if !definitionFormats[*definitionFormat] {
+		log.Fatalf("Unsupported --definition-format %q (supported: json, yaml)", *definitionFormat)
+	}
+	countStatuses, err := parseCountStatuses(*countStatusList)
+	if err != nil {
+		log.Fatalf("Invalid --count-statuses: %v", err)
+	}
+	if *hookScope != hookScopeRun && *hookScope != hookScopeStateMachine {
+		log.Fatalf("Unsupported --hook-scope %q (supported: run, state-machine)", *hookScope)
+	}
+	newRelic := newRelicFromEnv(newRelicConfig{region: *nrRegion, accountID: *nrAccountID, licenseKey: *nrLicenseKey, apiKey: *nrAPIKey})
+	if err := newRelic.validate(); err != nil {
+		log.Fatalf("Invalid --nr-region: %v", err)
+	}
+	if *expectedAccount != "" && !accountIDPattern.MatchString(*expectedAccount) {
+		log.Fatalf("Invalid --expected-account %q: expected a 12-digit account ID", *expectedAccount)
+	}
+	if *stateView != "table" && *stateView != "tree" {
+		log.Fatalf("Unsupported --state-view %q (supported: table, tree)", *stateView)
+	}
+	layout = tableLayout{columns: parseColumns(*columns), maxWidth: *maxWidth, wrap: *wrap, pageSize: *pageSize, stateTree: *stateView == "tree"}
+	network.useFIPS, network.caBundle, network.proxy = *useFIPS, *caBundle, *proxy
+	if err := network.load(); err != nil {
+		log.Fatalf("Invalid network settings: %v", err)
+	}
+
+	cfg := runConfig{
+		region:               *region,
+		expectedAccount:      *expectedAccount,
+		outputDir:            *outputDir,
+		includeHistory:       *includeHistory,
+		cloudTrailInitiators: *cloudTrailInitiators,
+		cloudTrailLookback:   *cloudTrailLookback,
+		expressQuery: stepfunctions.ExpressLogQuery{
+			FilterPattern: *expressFilter,
+			Limit:         int32(*expressLimit),
+			Lookback:      *expressLookback,
+			MaxBytes:      *expressMaxBytes,
+			MaxTime:       *expressMaxTime,
+		},
+		timelines:   *timelines,
+		analytics:   *analytics,
+		iamAnalysis: *iamAnalysis,
+		maxAPICalls: *maxAPICalls,
+		apiTimeout:  *apiTimeout,
+		quotaPacing: *quotaPacing,
+		verbose:     *verbose,
+		cacheDir:    *cacheDir,
+		failFast:    *failFast || !*continueOnError,
+
+		kinesisStream:  *kinesisStream,
+		firehoseStream: *firehoseStream,
+
+		notifySNSTopic:     *notifySNSTopic,
+		notifySlackWebhook: *notifySlackWebhook,
+		notifyState:        *notifyState,
+		revisionsDir:       *revisionsLocation,
+		nrLinkTemplate:     *nrLinkTemplate,
+
+		webhookURL:    *webhookURL,
+		webhookSecret: *webhookSecret,
+		report:        *report,
+		saveRaw:       *saveRaw,
+		kmsAudit:      *kmsAudit,
+		export:        *export,
+		hooks:         hooks,
+		hookScope:     *hookScope,
+		otel:          *otelEnabled,
+		countsOnly:    *countsOnly,
+		countStatuses: countStatuses,
+		alias:         *alias,
+
+		cfnDrift: *cfnDrift,
+		sarif:    *sarif,
+
+		definitionFormat:    *definitionFormat,
+		definitionInlineMax: *definitionInlineMax,
+		mapRuns:             *mapRuns,
+		callbacks:           *callbacks,
+		historyFilter:       historyFilter{events: historyEventTypes, reverse: *historyReverse},
+		sample:              sample,
+		buckets:             *buckets,
+		nerdGraphSync:       *nerdGraphSync,
+		workloadTag:         *workloadTag,
+		nrLambdaTraces:      *nrLambdaTraces,
+		newRelic:            newRelic,
+		settings:            make(map[string]string),
+	}
+	flag.Visit(func(f *flag.Flag) {
+		cfg.settings[f.Name] = f.Value.String()
+	})
+	if *templatePath != "" {
+		tmpl, err := loadTemplate(*templatePath)
+		if err != nil {
+			log.Fatalf("Invalid --template: %v", err)
+		}
+		cfg.template = tmpl
+	}
+	if *slaConfig != "" {
+		sla, err := stepfunctions.LoadSLAConfig(*slaConfig)
+		if err != nil {
+			log.Fatalf("Invalid --sla-config: %v", err)
+		}
+		cfg.sla = &sla
+	}
+	if *findingsConfig != "" {
+		findingsCfg, err := stepfunctions.LoadFindingsConfig(*findingsConfig)
+		if err != nil {
+			log.Fatalf("Invalid --findings-config: %v", err)
+		}
+		cfg.findings = &findingsCfg
+	}
+	if *arnFile != "" {
+		arns, err := readARNFile(*arnFile)
+		if err != nil {
+			log.Fatalf("Invalid --arn-file: %v", err)
+		}
+		cfg.stateMachineARNs = arns
+	}
+	if *sortSpec != "" {
+		sortBy, err := stepfunctions.ParseExecutionSort(*sortSpec)
+		if err != nil {
+			log.Fatalf("Invalid --sort: %v", err)
+		}
+		cfg.sort = &sortBy
+	}
+	if *query != "" {
+		compiled, err := jmespath.Compile(*query)
+		if err != nil {
+			log.Fatalf("Invalid --query: %v", err)
+		}
+		cfg.query = compiled
+	}
+	if cfg.notifyState == "" {
+		cfg.notifyState = filepath.Join(cfg.outputDir, "notified_failures.json")
+	}
+	if cfg.revisionsDir == "" {
+		cfg.revisionsDir = filepath.Join(cfg.outputDir, revisionsDir)
+	}
+	if *noCache {
+		cfg.cacheDir = ""
+	}
 
 	ctx := context.Background()
+	if iamPolicy {
+		if err := writeIAMPolicy(os.Stdout, cfg); err != nil {
+			log.Fatalf("Failed to export IAM policy: %v", err)
+		}
+		return
+	}
+	if *doctor {
+		os.Exit(runDoctor(ctx, cfg))
+	}
+	if *dryRun {
+		displayRunPlan(ctx, cfg.region, cfg.fetcherOptions())
+		return
+	}
+	exitCode, err := run(ctx, cfg)
+	if err != nil {
+		log.Print(err)
+	}
+	os.Exit(exitCode)
+}
+
+// defaultAPITimeout is far longer than any healthy call takes, throttling
+// retries included, so it only cuts off calls that are stuck
+const defaultAPITimeout = 2 * time.Minute
+
+// runConfig holds the settings of one collection run, from flags or, when
+// running in Lambda, from the environment
+type runConfig struct {
+	region               string
+	expectedAccount      string // Account the credentials must resolve to, empty for any
+	outputDir            string
+	includeHistory       bool
+	cloudTrailInitiators bool
+	cloudTrailLookback   time.Duration
+	expressQuery         stepfunctions.ExpressLogQuery
+	timelines            bool
+	analytics            bool
+	sla                  *stepfunctions.SLAConfig // Parsed --sla-config, nil when unset
+	iamAnalysis          bool
+	maxAPICalls          int
+	apiTimeout           time.Duration
+	quotaPacing          bool
+	verbose              bool
+	cacheDir             string // Empty disables the definition cache
+	failFast             bool
+	kinesisStream        string
+	firehoseStream       string
+	notifySNSTopic       string
+	notifySlackWebhook   string
+	notifyState          string
+	revisionsDir         string // Local directory or s3://bucket/prefix
+	nrLinkTemplate       string
+	webhookURL           string
+	webhookSecret        string
+	query                *jmespath.JMESPath // Compiled --query, nil when unset
+	report               string             // Report format, empty for none
+	template             *template.Template // Parsed --template, nil when unset
+	saveRaw              bool
+	kmsAudit             bool
+	stateMachineARNs     []string // Explicit targets, nil lists every state machine
+	export               string   // Comma-separated export destinations, see newExporters
+	hooks                []string // Commands receiving the fetched data as NDJSON
+	hookScope            string
+	otel                 bool
+	sort                 *stepfunctions.ExecutionSort // nil keeps the fetch order
+	countsOnly           bool
+	countStatuses        []string // Statuses --counts-only counts, nil for all
+	alias                string
+	cfnDrift             bool
+	findings             *stepfunctions.FindingsConfig // Parsed --findings-config, nil reports every finding
+	sarif                bool
+	definitionFormat     string // json or yaml
+	definitionInlineMax  int    // Bytes, 0 keeps every definition in state_machines.json
+	mapRuns              bool
+	callbacks            bool
+	historyFilter        historyFilter
+	sample               stepfunctions.Sample
+	buckets              string // hourly or daily, empty to skip
+	nerdGraphSync        bool
+	workloadTag          string
+	nrLambdaTraces       bool
+	newRelic             newRelicConfig
+	settings             map[string]string // Flags or Lambda variables set, for run.json
+}
+
+func (cfg runConfig) fetcherOptions() []stepfunctions.Option {
+	awsOptions, _ := network.awsConfigOptions() // Checked at startup
+	return []stepfunctions.Option{
+		stepfunctions.WithConfigOptions(awsOptions...),
+		stepfunctions.WithHistory(cfg.includeHistory),
+		stepfunctions.WithExpressLogQuery(cfg.expressQuery),
+		stepfunctions.WithMaxAPICalls(cfg.maxAPICalls),
+		stepfunctions.WithAPITimeout(cfg.apiTimeout),
+		stepfunctions.WithQuotaPacing(cfg.quotaPacing),
+		stepfunctions.WithFailFast(cfg.failFast),
+		stepfunctions.WithDefinitionCache(cfg.cacheDir),
+		stepfunctions.WithRawResponses(cfg.saveRaw),
+		stepfunctions.WithStateMachineARNs(cfg.stateMachineARNs),
+		stepfunctions.WithAlias(cfg.alias),
+		stepfunctions.WithMapRuns(cfg.mapRuns),
+		stepfunctions.WithCallbacks(cfg.callbacks),
+		stepfunctions.WithSample(cfg.sample),
+		stepfunctions.WithCountStatuses(cfg.countStatuses),
+	}
+}
 
-	_, stateMachines := initializeFetcherAndStateMachines(ctx, *region)
-	createOutputDirectory(*outputDir)
+// run fetches, writes and analyzes everything cfg asks for and returns the
+// exit code. A run that stops early also returns the error that stopped it;
+// what was written until then, errors.json included, stays in place.
+func run(ctx context.Context, cfg runConfig) (int, error) {
+	if cfg.countsOnly {
+		return runCounts(ctx, cfg)
+	}
+	meta := newRunMetadata(cfg)
+	errs := &runErrors{failFast: cfg.failFast, outputDir: cfg.outputDir, meta: meta}
+	if err := checkCaller(ctx, cfg, errs); err != nil {
+		return stopped(err)
+	}
+	if err := resolveSecrets(ctx, cfg.region, &cfg.newRelic.licenseKey, &cfg.newRelic.apiKey,
+		&cfg.webhookSecret, &cfg.webhookURL, &cfg.notifySlackWebhook); err != nil {
+		return stopped(errs.fatal("secrets", "Failed to read the sink credentials", err))
+	}
+	if cfg.otel {
+		shutdown, err := setupTelemetry(ctx, cfg.newRelic)
+		if err != nil {
+			return stopped(errs.fatal("telemetry", "Failed to set up telemetry", err))
+		}
+		defer flushTelemetry(ctx, shutdown)
+	}
+	ctx, span := otel.Tracer(stepfunctions.InstrumentationName).Start(ctx, "run")
+	defer span.End()
+
+	if err := os.MkdirAll(cfg.outputDir, 0755); err != nil {
+		return stopped(errs.fatal("output", "Failed to create output directory", err))
+	}
+	exporters, err := newExporters(ctx, cfg)
+	if err != nil {
+		return stopped(errs.fatal("export", "Failed to set up export", err))
+	}
+	warnings := &runWarnings{}
+	opts := append(cfg.fetcherOptions(), stepfunctions.WithWarningSink(warnings))
+	sinks, err := newRecordSinks(ctx, cfg, errs)
+	if err != nil {
+		return stopped(err)
+	}
+	for _, sink := range sinks {
+		opts = append(opts, stepfunctions.WithRecordSink(sink))
+	}
+	fetcher, stateMachines, err := initializeFetcherAndStateMachines(ctx, cfg.region, errs, opts...)
+	if err != nil {
+		return stopped(err)
+	}
+	if cfg.verbose {
+		printAPIRates(fetcher.APIRates())
+		printSampled(fetcher.Sampled(), cfg.sample)
+	}
+	for _, sink := range sinks {
+		if err := sink.Close(ctx); err != nil {
+			log.Printf("Failed to flush record stream: %v", err)
+			if stop := errs.record("stream", err); stop != nil {
+				return stopped(stop)
+			}
+		}
+	}
+	if cfg.cloudTrailInitiators {
+		if err := fetcher.AttachInitiators(ctx, stateMachines, cfg.cloudTrailLookback); err != nil {
+			log.Printf("Failed to attribute executions via CloudTrail: %v", err)
+			if stop := errs.record("cloudtrail", err); stop != nil {
+				return stopped(stop)
+			}
+		}
+	}
+	if cfg.sort != nil {
+		for _, sm := range stateMachines {
+			cfg.sort.Apply(sm.Executions)
+		}
+	}
 	displayStateMachines(stateMachines)
-	processStateMachines(ctx, stateMachines, *outputDir) // processStates + processExecutions
-	fmt.Printf("State and execution definitions saved to %s\n", *outputDir)
+	if cfg.mapRuns {
+		displayMapRuns(stateMachines)
+	}
+	if (cfg.historyFilter.events != nil || cfg.historyFilter.reverse) && !cfg.includeHistory {
+		log.Printf("--history-events and --history-reverse apply to --include-history; no history is exported")
+	}
+	if cfg.nrLambdaTraces {
+		if !cfg.includeHistory {
+			log.Printf("--nr-lambda-traces requires --include-history; skipping Lambda correlation")
+		} else {
+			if err := processLambdaCorrelation(ctx, stateMachines, cfg.newRelic, fetcher.Now(), errs); err != nil {
+				return stopped(err)
+			}
+		}
+	}
+	processRevisions(ctx, cfg.region, stateMachines, cfg.revisionsDir, fetcher.Now())
+	if err := processStateMachines(ctx, stateMachines, exporters, cfg.historyFilter, errs); err != nil { // processStates + processExecutions
+		return stopped(err)
+	}
+	if cfg.timelines {
+		if !cfg.includeHistory {
+			log.Printf("--timeline requires --include-history; skipping timelines")
+		} else {
+			for _, sm := range stateMachines {
+				processTimelines(sm, cfg.outputDir)
+			}
+		}
+	}
+	if cfg.callbacks {
+		processCallbacks(stateMachines, cfg.outputDir, fetcher.Now())
+	}
+	findings, err := processCompliance(ctx, fetcher, stateMachines, cfg.outputDir, cfg.kmsAudit, errs)
+	if err != nil {
+		return stopped(err)
+	}
+	if cfg.analytics {
+		processAnalytics(stateMachines, cfg.outputDir)
+	}
+	if cfg.buckets != "" {
+		processTimeBuckets(stateMachines, cfg.outputDir, cfg.buckets)
+	}
+	if cfg.iamAnalysis {
+		iamFindings, err := processPermissions(ctx, fetcher, stateMachines, cfg.outputDir, errs)
+		if err != nil {
+			return stopped(err)
+		}
+		findings = append(findings, iamFindings...)
+	}
+	if cfg.cfnDrift {
+		driftFindings, err := processDrift(ctx, fetcher, stateMachines, cfg.outputDir, errs)
+		if err != nil {
+			return stopped(err)
+		}
+		findings = append(findings, driftFindings...)
+	}
+	processFindings(cfg, stateMachines, findings)
+	if cfg.nerdGraphSync {
+		if err := processNerdGraphSync(ctx, fetcher, stateMachines, cfg.newRelic, cfg.workloadTag, errs); err != nil {
+			return stopped(err)
+		}
+	}
+	if cfg.notifySNSTopic != "" || cfg.notifySlackWebhook != "" {
+		if err := processNotifications(ctx, cfg, stateMachines, fetcher.Now(), errs); err != nil {
+			return stopped(err)
+		}
+	}
+	if cfg.report == "markdown" {
+		writeMarkdownReport(stateMachines, cfg.outputDir)
+	}
+	if cfg.template != nil {
+		processTemplate(cfg.template, cfg.region, stateMachines, cfg.outputDir)
+	}
+	if cfg.query != nil {
+		processQuery(cfg.query, stateMachines, cfg.outputDir)
+	}
+	warnings.render(cfg.outputDir)
+	if cfg.export == defaultExport {
+		fmt.Printf("State and execution definitions saved to %s\n", cfg.outputDir)
+	} else {
+		fmt.Printf("State and execution definitions exported to %s (reports in %s)\n", cfg.export, cfg.outputDir)
+	}
 	fmt.Println("Done.")
 	fmt.Println("Note: For Express Workflows, ensure CloudWatch Logs are configured to fetch execution details as execution details are fetched from CloudWatch Logs..")
 	fmt.Println("Note: For Standard Workflows, execution details are fetched directly from Step Functions.")
+	errs.save()
+	if fetcher.BudgetExceeded() {
+		fmt.Printf("Warning: API call budget of %d reached; results are partial (%d state machines fetched). Raise --max-api-calls to fetch more.\n", cfg.maxAPICalls, len(stateMachines))
+	}
+
+	exitCode := exitOK
+	if len(errs.items) > 0 || fetcher.BudgetExceeded() {
+		exitCode = exitPartial
+	}
+	if cfg.sla != nil && !checkSLA(*cfg.sla, stateMachines, cfg.outputDir) {
+		exitCode = exitCheckFailed
+	}
+	meta.finish(fetcher, stateMachines, exitCode)
+	meta.save(cfg.outputDir)
+
+	if cfg.webhookURL != "" {
+		summary := buildRunSummary(cfg, fetcher, stateMachines, errs, warnings, exitCode)
+		if err := postWebhook(ctx, cfg.webhookURL, cfg.webhookSecret, summary, filepath.Join(cfg.outputDir, dlqFile)); err != nil {
+			log.Printf("Failed to post run summary to webhook: %v", err)
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("fetcher.state_machines", len(stateMachines)),
+		attribute.Int("fetcher.api_calls", fetcher.APICalls()),
+		attribute.Int("fetcher.skipped", len(errs.items)),
+		attribute.Int("fetcher.warnings", len(warnings.items)),
+		attribute.Int("fetcher.exit_code", exitCode),
+	)
+	return exitCode, nil
+}
+
+// newRecordSinks creates the Kinesis and Firehose sinks cfg asks for
+func newRecordSinks(ctx context.Context, cfg runConfig, errs *runErrors) ([]stepfunctions.RecordSink, error) {
+	if cfg.kinesisStream == "" && cfg.firehoseStream == "" {
+		return nil, nil
+	}
+	awsCfg, err := loadAWSConfig(ctx, cfg.region)
+	if err != nil {
+		return nil, errs.fatal("config", "Failed to load AWS config", err)
+	}
+
+	dlqPath := filepath.Join(cfg.outputDir, dlqFile)
+	var sinks []stepfunctions.RecordSink
+	if cfg.kinesisStream != "" {
+		sinks = append(sinks, stepfunctions.NewKinesisSink(kinesis.NewFromConfig(awsCfg), cfg.kinesisStream, streamDeadLetter(dlqPath, "kinesis:"+cfg.kinesisStream)))
+	}
+	if cfg.firehoseStream != "" {
+		sinks = append(sinks, stepfunctions.NewFirehoseSink(firehose.NewFromConfig(awsCfg), cfg.firehoseStream, streamDeadLetter(dlqPath, "firehose:"+cfg.firehoseStream)))
+	}
+	return sinks, nil
 }
 
-func initializeFetcherAndStateMachines(ctx context.Context, region string) (*stepfunctions.Fetcher, []stepfunctions.StateMachine) {
-	fetcher, err := stepfunctions.NewFetcher(ctx, region)
+func initializeFetcherAndStateMachines(ctx context.Context, region string, errs *runErrors, opts ...stepfunctions.Option) (*stepfunctions.Fetcher, []stepfunctions.StateMachine, error) {
+	fetcher, err := stepfunctions.NewFetcher(ctx, region, opts...)
 	if err != nil {
-		log.Fatalf("Failed to create fetcher: %v", err)
+		return nil, nil, errs.fatal("config", "Failed to create fetcher", err)
 	}
 
 	stateMachines, err := fetcher.ListStateMachines(ctx)
+	errs.addFetcher(fetcher)
 	if err != nil {
-		log.Fatalf("Failed to list state machines: %v", err)
+		return nil, nil, errs.fatal("state machines", "Failed to list state machines", err)
 	}
 
-	return fetcher, stateMachines
+	return fetcher, stateMachines, nil
+}
+
+// printAPIRates prints the rates Step Functions calls are paced at, if any
+func printAPIRates(rates map[string]stepfunctions.APIRate) {
+	if len(rates) == 0 {
+		return
+	}
+	var budget []string
+	for operation, rate := range rates {
+		budget = append(budget, fmt.Sprintf("%s %.4g/s (burst %.0f)", operation, rate.PerSecond, rate.Burst))
+	}
+	sort.Strings(budget)
+	fmt.Printf("Debug: Step Functions API pacing: %s\n", strings.Join(budget, ", "))
+}
+
+// printSampled prints how many executions --sample kept of each state machine
+func printSampled(sampled []stepfunctions.SampledCount, sample stepfunctions.Sample) {
+	for _, count := range sampled {
+		fmt.Printf("Debug: Sampled %d of %d executions of %s (%s)\n", count.Kept, count.Listed, count.StateMachineARN, sample)
+	}
 }
 
 func createOutputDirectory(outputDir string) {
@@ -53,15 +677,17 @@ func createOutputDirectory(outputDir string) {
 }
 
 func displayStateMachines(stateMachines []stepfunctions.StateMachine) {
-	smTable := tablewriter.NewWriter(os.Stdout)
-	smTable.SetHeader([]string{"Name", "ARN", "Type", "Role ARN", "Creation Date"})
+	smTable := newTable()
+	smTable.SetColumns("name", "arn", "type", "role", "created", "console")
+	smTable.SetHeader([]string{"Name", "ARN", "Type", "Role ARN", "Creation Date", "Console"})
 	for _, sm := range stateMachines {
 		smTable.Append([]string{
 			sm.Name,
 			sm.ARN,
 			sm.Type,
 			sm.RoleARN,
-			sm.CreationDate,
+			formatTime(sm.CreationDate),
+			sm.ConsoleURL,
 		})
 	}
 	fmt.Println("State Machines:")
@@ -69,19 +695,71 @@ func displayStateMachines(stateMachines []stepfunctions.StateMachine) {
 	fmt.Println()
 }
 
-func processStateMachines(ctx context.Context, stateMachines []stepfunctions.StateMachine, outputDir string) {
+func processStateMachines(ctx context.Context, stateMachines []stepfunctions.StateMachine, exporters []Exporter, filter historyFilter, errs *runErrors) error {
 	for _, sm := range stateMachines {
-		processStates(sm, outputDir)
-		processExecutions(sm, outputDir)
+		processStates(sm)
+		processExecutions(sm)
+		for _, exporter := range exporters {
+			if err := exportStateMachine(ctx, exporter, sm, filter, errs); err != nil {
+				// Keep what was exported before the run stopped
+				if closeErr := closeExporters(ctx, exporters); closeErr != nil {
+					log.Printf("Failed to finish export: %v", closeErr)
+				}
+				return err
+			}
+		}
 	}
 
-	if err := saveToFile(stateMachines, filepath.Join(outputDir, "state_machines.json")); err != nil {
-		log.Printf("Failed to save state machines: %v", err)
+	if err := closeExporters(ctx, exporters); err != nil {
+		log.Printf("Failed to finish export: %v", err)
+		return errs.record("export", err)
 	}
+	return nil
 }
 
-func processStates(sm stepfunctions.StateMachine, outputDir string) {
-	stateTable := tablewriter.NewWriter(os.Stdout)
+// exportStateMachine writes sm, its executions and their history events,
+// as selected by filter. A failed execution does not stop the rest of the
+// state machine; the returned error ends a --fail-fast run.
+func exportStateMachine(ctx context.Context, exporter Exporter, sm stepfunctions.StateMachine, filter historyFilter, errs *runErrors) error {
+	sm = filter.applyAll(sm)
+	if err := exporter.WriteStateMachine(ctx, sm); err != nil {
+		log.Printf("Failed to export state machine %s: %v", sm.Name, err)
+		return errs.record(sm.ARN, err)
+	}
+	for _, exec := range sm.Executions {
+		if exec.ExecutionArn == "N/A" {
+			continue
+		}
+		if err := exporter.WriteExecution(ctx, sm, exec); err != nil {
+			log.Printf("Failed to export execution %s: %v", exec.ExecutionArn, err)
+			if stop := errs.record(exec.ExecutionArn, err); stop != nil {
+				return stop
+			}
+			continue
+		}
+		for _, event := range exec.History {
+			if err := exporter.WriteHistoryEvent(ctx, exec, event); err != nil {
+				log.Printf("Failed to export history of %s: %v", exec.ExecutionArn, err)
+				if stop := errs.record(exec.ExecutionArn, err); stop != nil {
+					return stop
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func processStates(sm stepfunctions.StateMachine) {
+	if layout.stateTree {
+		if g, err := stepfunctions.BuildGraph(sm.Definition); err == nil {
+			fmt.Println(renderStateTree(fmt.Sprintf("States for %s:", sm.Name), g))
+			return
+		}
+		// An unparseable definition still has its states listed
+	}
+	stateTable := newTable()
+	stateTable.SetColumns("name", "type", "next", "end", "definition")
 	stateTable.SetHeader([]string{"State Name", "Type", "Next", "End", "Definition"})
 	for _, state := range sm.States {
 		rawDef, err := json.MarshalIndent(state.RawDefinition, "", "  ")
@@ -102,69 +780,141 @@ func processStates(sm stepfunctions.StateMachine, outputDir string) {
 			fmt.Sprintf("%v", state.End),
 			defStr,
 		})
-
-		if err := saveStateDefinition(outputDir, sm.Name, state.Name, rawDef); err != nil {
-			log.Printf("Failed to save state definition for %s/%s: %v", sm.Name, state.Name, err)
-		}
 	}
 	fmt.Printf("States for %s:\n", sm.Name)
 	stateTable.Render()
 	fmt.Println()
 }
 
-func processExecutions(sm stepfunctions.StateMachine, outputDir string) {
-	execTable := tablewriter.NewWriter(os.Stdout)
-	execTable.SetHeader([]string{"Execution ARN", "Status", "Start Time", "End Time", "Duration"})
-	for _, exec := range sm.Executions {
+func processExecutions(sm stepfunctions.StateMachine) {
+	execTable := newTable()
+	execTable.SetColumns("arn", "status", "start", "end", "duration", "captured", "console")
+	execTable.SetColumnColor(1, colorStatus)
+	execTable.SetHeader([]string{"Execution ARN", "Status", "Start Time", "End Time", "Duration", "Captured", "Console"})
+	shown := sm.Executions
+	if layout.pageSize > 0 && len(shown) > layout.pageSize {
+		shown = shown[:layout.pageSize]
+	}
+	for _, exec := range shown {
+		startTime := formatTime(exec.StartTime)
+		if exec.Partial {
+			startTime = "unknown (partial)"
+		}
 		execTable.Append([]string{
 			exec.ExecutionArn,
 			exec.Status,
-			exec.StartTime,
-			exec.EndTime,
-			exec.Duration,
+			startTime,
+			formatTime(exec.EndTime),
+			exec.FormatDuration(),
+			exec.Captured.String(),
+			exec.ConsoleURL,
 		})
-
-		if exec.ExecutionArn != "N/A" {
-			execData, err := json.MarshalIndent(exec, "", "  ")
-			if err != nil {
-				log.Printf("Failed to marshal execution %s: %v", exec.ExecutionArn, err)
-				continue
-			}
-			if err := saveExecutionDefinition(outputDir, sm.Name, exec.ExecutionArn, execData); err != nil {
-				log.Printf("Failed to save execution %s: %v", exec.ExecutionArn, err)
-			}
-		}
 	}
 	fmt.Printf("Executions for %s:\n", sm.Name)
 	execTable.Render()
+	if hidden := len(sm.Executions) - len(shown); hidden > 0 {
+		fmt.Printf("... %d more executions not shown (--page-size %d); all are exported\n", hidden, layout.pageSize)
+	}
 	fmt.Println()
 }
 
-func saveStateDefinition(outputDir, smName, stateName string, definition []byte) error {
-	safeStateName := sanitizeFileName(stateName)
-	filePath := filepath.Join(outputDir, fmt.Sprintf("%s_%s.json", smName, safeStateName))
-	return os.WriteFile(filePath, definition, 0644)
-}
+// processCompliance prints and saves the compliance audit and returns its findings
+func processCompliance(ctx context.Context, fetcher *stepfunctions.Fetcher, stateMachines []stepfunctions.StateMachine, outputDir string, kmsAudit bool, errs *runErrors) ([]stepfunctions.Finding, error) {
+	issues := []stepfunctions.ComplianceIssue{}
+	var findings []stepfunctions.Finding
+	complianceTable := newTable()
+	complianceTable.SetColumnColor(6, colorFinding)
+	complianceTable.SetHeader([]string{"State Machine", "Type", "Log Level", "Execution Data", "X-Ray", "Encryption", "Issues", "Log Groups"})
+	for _, sm := range stateMachines {
+		smIssues := stepfunctions.CheckLoggingAndTracing(sm)
+		smIssues = append(smIssues, stepfunctions.CheckEncryption(sm)...)
+		if kmsAudit {
+			keyIssues, err := fetcher.AuditKMSKey(ctx, sm)
+			if err != nil {
+				log.Printf("Failed to audit KMS key for %s: %v", sm.Name, err)
+				if stop := errs.record(sm.Encryption.KMSKeyID, err); stop != nil {
+					return findings, stop
+				}
+			}
+			smIssues = append(smIssues, keyIssues...)
+		}
+		issues = append(issues, smIssues...)
+		findings = append(findings, stepfunctions.ComplianceFindings(sm, smIssues)...)
 
-func saveExecutionDefinition(outputDir, smName, executionArn string, definition []byte) error {
-	safeExecName := sanitizeFileName(strings.ReplaceAll(executionArn, ":", "_"))
-	filePath := filepath.Join(outputDir, fmt.Sprintf("%s_execution_%s.json", smName, safeExecName))
-	return os.WriteFile(filePath, definition, 0644)
-}
+		var messages []string
+		for _, issue := range smIssues {
+			messages = append(messages, issue.Message)
+		}
+		complianceTable.Append([]string{
+			sm.Name,
+			sm.Type,
+			sm.Logging.Level,
+			fmt.Sprintf("%v", sm.Logging.IncludeExecutionData),
+			fmt.Sprintf("%v", sm.Tracing),
+			sm.Encryption.Type,
+			strings.Join(messages, "\n"),
+			strings.Join(sm.Logging.ConsoleURLs, "\n"),
+		})
+	}
+	fmt.Println("Logging, Tracing and Encryption Compliance:")
+	complianceTable.Render()
+	fmt.Printf("%d compliance issue(s) found\n\n", len(issues))
 
-func saveToFile(stateMachines []stepfunctions.StateMachine, filename string) error {
-	data, err := json.MarshalIndent(stateMachines, "", "  ")
+	data, err := stepfunctions.MarshalDocument(issues, "issues")
 	if err != nil {
-		return fmt.Errorf("failed to marshal state machines: %w", err)
+		log.Printf("Failed to marshal compliance report: %v", err)
+		return findings, nil
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "compliance.json"), data, 0644); err != nil {
+		log.Printf("Failed to save compliance report: %v", err)
 	}
-	return os.WriteFile(filename, data, 0644)
+	return findings, nil
 }
 
-func sanitizeFileName(name string) string {
-	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := name
-	for _, char := range invalidChars {
-		result = strings.ReplaceAll(result, char, "_")
+// processPermissions prints and saves the IAM analysis and returns its findings
+func processPermissions(ctx context.Context, fetcher *stepfunctions.Fetcher, stateMachines []stepfunctions.StateMachine, outputDir string, errs *runErrors) ([]stepfunctions.Finding, error) {
+	var findings []stepfunctions.Finding
+	iamTable := newTable()
+	iamTable.SetColumnColor(2, colorViolation)
+	iamTable.SetHeader([]string{"State Machine", "Role ARN", "Missing Actions", "Wildcard Grants", "Unused Actions"})
+	for _, sm := range stateMachines {
+		analysis, err := fetcher.AnalyzeRolePermissions(ctx, sm)
+		if err != nil {
+			log.Printf("Failed to analyze role permissions for %s: %v", sm.Name, err)
+			if stop := errs.record(sm.RoleARN, err); stop != nil {
+				return findings, stop
+			}
+			continue
+		}
+		findings = append(findings, stepfunctions.PermissionFindings(sm, analysis)...)
+
+		iamTable.Append([]string{
+			sm.Name,
+			sm.RoleARN,
+			strings.Join(analysis.MissingActions, "\n"),
+			strings.Join(analysis.WildcardGrants, "\n"),
+			fmt.Sprintf("%d", len(analysis.UnusedActions)),
+		})
+
+		data, err := stepfunctions.MarshalDocument(analysis, "")
+		if err != nil {
+			log.Printf("Failed to marshal role permissions for %s: %v", sm.Name, err)
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(outputDir, fmt.Sprintf("%s_iam.json", sm.Name)), data, 0644); err != nil {
+			log.Printf("Failed to save role permissions for %s: %v", sm.Name, err)
+		}
 	}
-	return result
+	fmt.Println("IAM Least-Privilege Analysis:")
+	iamTable.Render()
+	fmt.Println()
+	return findings, nil
+}
+
+func saveExecutionDefinition(outputDir, smName, executionArn string, definition []byte) error {
+	return os.WriteFile(filepath.Join(outputDir, executionFile(smName, executionArn)), definition, 0644)
+}
+
+func sanitizeFileName(name string) string {
+	return export.FileName(name)
 }
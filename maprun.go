@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// displayMapRuns prints the Distributed Map runs of the fetched executions.
+// The failed items themselves are saved with each execution.
+func displayMapRuns(stateMachines []stepfunctions.StateMachine) {
+	mapRunTable := newTable()
+	mapRunTable.SetColumnColor(2, colorStatus)
+	mapRunTable.SetHeader([]string{"Execution", "Map State", "Status", "Total", "Succeeded", "Failed", "Failed Items Saved", "Console"})
+	runs := 0
+	for _, sm := range stateMachines {
+		for _, exec := range sm.Executions {
+			for _, run := range exec.MapRuns {
+				saved := fmt.Sprintf("%d", len(run.FailedItems))
+				if run.FailedItemsTruncated {
+					saved += " (truncated)"
+				}
+				mapRunTable.Append([]string{
+					exec.ExecutionArn[strings.LastIndex(exec.ExecutionArn, ":")+1:],
+					run.MapState,
+					run.Status,
+					fmt.Sprintf("%d", run.Items.Total),
+					fmt.Sprintf("%d", run.Items.Succeeded),
+					fmt.Sprintf("%d", run.Items.Failed+run.Items.TimedOut+run.Items.Aborted),
+					saved,
+					run.ConsoleURL,
+				})
+				runs++
+			}
+		}
+	}
+	if runs == 0 {
+		return
+	}
+	fmt.Println("Map Runs:")
+	mapRunTable.Render()
+	fmt.Println()
+}
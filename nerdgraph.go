@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+const (
+	// defaultWorkloadTag is the state machine tag that names its team
+	defaultWorkloadTag = "team"
+	// workloadPrefix names the workloads the sync manages, e.g. "Step Functions: payments"
+	workloadPrefix = "Step Functions: "
+)
+
+// nerdGraphClient calls the New Relic NerdGraph (GraphQL) API with a user key
+type nerdGraphClient struct {
+	url       string
+	uiURL     string // New Relic One, for links
+	apiKey    string
+	accountID int64
+	client    *http.Client
+}
+
+func newNerdGraphClient(nr newRelicConfig) (*nerdGraphClient, error) {
+	if nr.accountID == "" || nr.apiKey == "" {
+		return nil, fmt.Errorf("NerdGraph needs NEW_RELIC_ACCOUNT_ID and NEW_RELIC_API_KEY")
+	}
+	id, err := strconv.ParseInt(nr.accountID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NEW_RELIC_ACCOUNT_ID %q: %w", nr.accountID, err)
+	}
+	endpoints := nr.endpoints()
+	return &nerdGraphClient{
+		url:       endpoints.nerdGraphURL,
+		uiURL:     endpoints.uiURL,
+		apiKey:    nr.apiKey,
+		accountID: id,
+		client:    newHTTPClient(30 * time.Second),
+	}, nil
+}
+
+// do runs a query or mutation and decodes its data into result
+func (c *nerdGraphClient) do(ctx context.Context, query string, variables map[string]any, result any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode NerdGraph request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("API-Key", c.apiKey)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call NerdGraph: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("NerdGraph returned %s", resp.Status)
+	}
+
+	var response struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode NerdGraph response: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		var messages []string
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("NerdGraph error: %s", strings.Join(messages, "; "))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Data, result)
+}
+
+const entitySearchQuery = `query($query: String!) {
+  actor { entitySearch(query: $query) { results { entities { guid name } } } }
+}`
+
+// entity is an entity search result
+type entity struct {
+	GUID string
+	Name string
+}
+
+// findEntities returns the entities matching an entity search query
+func (c *nerdGraphClient) findEntities(ctx context.Context, query string) ([]entity, error) {
+	var data struct {
+		Actor struct {
+			EntitySearch struct {
+				Results struct {
+					Entities []entity
+				}
+			}
+		}
+	}
+	if err := c.do(ctx, entitySearchQuery, map[string]any{"query": query}, &data); err != nil {
+		return nil, err
+	}
+	return data.Actor.EntitySearch.Results.Entities, nil
+}
+
+// findEntity returns the GUID of the first entity matching an entity search
+// query, empty when there is none
+func (c *nerdGraphClient) findEntity(ctx context.Context, query string) (string, error) {
+	entities, err := c.findEntities(ctx, query)
+	if err != nil || len(entities) == 0 {
+		return "", err
+	}
+	return entities[0].GUID, nil
+}
+
+const (
+	deleteTagsMutation = `mutation($guid: EntityGuid!, $tagKeys: [String!]!) {
+  taggingDeleteTagFromEntity(guid: $guid, tagKeys: $tagKeys) { errors { message } }
+}`
+	addTagsMutation = `mutation($guid: EntityGuid!, $tags: [TaggingTagInput!]!) {
+  taggingAddTagsToEntity(guid: $guid, tags: $tags) { errors { message } }
+}`
+)
+
+// setTags sets tags on an entity. taggingAddTagsToEntity adds values to a
+// key instead of replacing them, so the keys are deleted first; tags with
+// other keys are left alone.
+func (c *nerdGraphClient) setTags(ctx context.Context, guid string, tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	input := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		input = append(input, map[string]any{"key": key, "values": []string{tags[key]}})
+	}
+
+	var deleted struct {
+		TaggingDeleteTagFromEntity struct {
+			Errors []struct{ Message string }
+		}
+	}
+	if err := c.do(ctx, deleteTagsMutation, map[string]any{"guid": guid, "tagKeys": keys}, &deleted); err != nil {
+		return err
+	}
+	if errs := deleted.TaggingDeleteTagFromEntity.Errors; len(errs) > 0 {
+		return fmt.Errorf("failed to clear the tags of entity %s: %s", guid, errs[0].Message)
+	}
+	var added struct {
+		TaggingAddTagsToEntity struct {
+			Errors []struct{ Message string }
+		}
+	}
+	if err := c.do(ctx, addTagsMutation, map[string]any{"guid": guid, "tags": input}, &added); err != nil {
+		return err
+	}
+	if errs := added.TaggingAddTagsToEntity.Errors; len(errs) > 0 {
+		return fmt.Errorf("failed to tag entity %s: %s", guid, errs[0].Message)
+	}
+	return nil
+}
+
+const (
+	workloadCreateMutation = `mutation($accountId: Int!, $workload: WorkloadCreateInput!) {
+  workloadCreate(accountId: $accountId, workload: $workload) { guid }
+}`
+	workloadUpdateMutation = `mutation($guid: EntityGuid!, $workload: WorkloadUpdateInput!) {
+  workloadUpdate(guid: $guid, workload: $workload) { guid }
+}`
+)
+
+// syncWorkload creates the named workload, or replaces the entities of the
+// existing one, so it holds exactly guids
+func (c *nerdGraphClient) syncWorkload(ctx context.Context, name string, guids []string) (created bool, err error) {
+	existing, err := c.findEntity(ctx, fmt.Sprintf("type = 'WORKLOAD' AND name = '%s'", entitySearchEscape(name)))
+	if err != nil {
+		return false, err
+	}
+	workload := map[string]any{"name": name, "entityGuids": guids}
+	if existing == "" {
+		return true, c.do(ctx, workloadCreateMutation, map[string]any{"accountId": c.accountID, "workload": workload}, nil)
+	}
+	return false, c.do(ctx, workloadUpdateMutation, map[string]any{"guid": existing, "workload": workload}, nil)
+}
+
+// emptyStaleWorkloads empties the managed workloads of teams that no longer
+// have a state machine, so they stop listing deleted or retagged ones. The
+// workloads are kept, with any dashboards or alerts pointing at them.
+func (c *nerdGraphClient) emptyStaleWorkloads(ctx context.Context, teams map[string]bool) ([]string, error) {
+	query := fmt.Sprintf("type = 'WORKLOAD' AND accountId = %d AND name LIKE '%s%%'", c.accountID, entitySearchEscape(workloadPrefix))
+	workloads, err := c.findEntities(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var emptied []string
+	for _, workload := range workloads {
+		team, ok := strings.CutPrefix(workload.Name, workloadPrefix)
+		if !ok || teams[team] {
+			continue
+		}
+		update := map[string]any{"name": workload.Name, "entityGuids": []string{}}
+		if err := c.do(ctx, workloadUpdateMutation, map[string]any{"guid": workload.GUID, "workload": update}, nil); err != nil {
+			return emptied, fmt.Errorf("failed to empty workload %q: %w", workload.Name, err)
+		}
+		emptied = append(emptied, workload.Name)
+	}
+	return emptied, nil
+}
+
+// entitySearchEscape quotes a value for an entity search query string
+func entitySearchEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+// entityTags are the inventory tags pushed to a state machine entity: its
+// definition hash, type and role, and its AWS tags
+func entityTags(sm stepfunctions.StateMachine) map[string]string {
+	tags := map[string]string{
+		"stepfunctions.definitionHash": stepfunctions.DefinitionHash(sm.Definition),
+		"stepfunctions.type":           sm.Type,
+		"stepfunctions.roleArn":        sm.RoleARN,
+	}
+	for key, value := range sm.Tags {
+		tags[key] = value
+	}
+	return tags
+}
+
+// processNerdGraphSync tags the New Relic entity of every state machine with
+// its inventory and keeps one workload per value of workloadTag in sync with
+// the state machines carrying it; workloads of teams left without a state
+// machine are emptied. State machines without an entity, i.e. not
+// yet seen by the AWS cloud integration, are skipped.
+func processNerdGraphSync(ctx context.Context, fetcher *stepfunctions.Fetcher, stateMachines []stepfunctions.StateMachine, nr newRelicConfig, workloadTag string, errs *runErrors) error {
+	client, err := newNerdGraphClient(nr)
+	if err != nil {
+		log.Printf("Failed to sync NerdGraph: %v", err)
+		return errs.record("nerdgraph", err)
+	}
+	if err := fetcher.AttachTags(ctx, stateMachines); err != nil {
+		log.Printf("Failed to fetch state machine tags: %v", err)
+		return errs.record("tags", err)
+	}
+	return syncNerdGraph(ctx, client, stateMachines, workloadTag, errs)
+}
+
+func syncNerdGraph(ctx context.Context, client *nerdGraphClient, stateMachines []stepfunctions.StateMachine, workloadTag string, errs *runErrors) error {
+	workloads := make(map[string][]string) // Team -> entity GUIDs
+	teams := make(map[string]bool)         // Teams with a state machine, tagged or not
+	tagged := 0
+	for _, sm := range stateMachines {
+		if team := sm.Tags[workloadTag]; team != "" {
+			teams[team] = true
+		}
+		query := fmt.Sprintf("type = 'AWSSTATESSTATEMACHINE' AND tags.aws.arn = '%s'", entitySearchEscape(sm.ARN))
+		guid, err := client.findEntity(ctx, query)
+		if err != nil {
+			log.Printf("Failed to find the New Relic entity of %s: %v", sm.Name, err)
+			if stop := errs.record(sm.ARN, err); stop != nil {
+				return stop
+			}
+			continue
+		}
+		if guid == "" {
+			fmt.Printf("Debug: No New Relic entity for %s; is the AWS cloud integration enabled for Step Functions?\n", sm.ARN)
+			continue
+		}
+		if err := client.setTags(ctx, guid, entityTags(sm)); err != nil {
+			log.Printf("Failed to tag the New Relic entity of %s: %v", sm.Name, err)
+			if stop := errs.record(sm.ARN, err); stop != nil {
+				return stop
+			}
+			continue
+		}
+		tagged++
+		if team := sm.Tags[workloadTag]; team != "" {
+			workloads[team] = append(workloads[team], guid)
+		}
+	}
+
+	synced := make([]string, 0, len(workloads))
+	for team := range workloads {
+		synced = append(synced, team)
+	}
+	sort.Strings(synced)
+	for _, team := range synced {
+		name := workloadPrefix + team
+		created, err := client.syncWorkload(ctx, name, workloads[team])
+		if err != nil {
+			log.Printf("Failed to sync workload %q: %v", name, err)
+			if stop := errs.record("workload "+name, err); stop != nil {
+				return stop
+			}
+			continue
+		}
+		action := "Updated"
+		if created {
+			action = "Created"
+		}
+		fmt.Printf("%s workload %q with %d state machines\n", action, name, len(workloads[team]))
+	}
+	emptied, err := client.emptyStaleWorkloads(ctx, teams)
+	for _, name := range emptied {
+		fmt.Printf("Emptied workload %q; no state machine is tagged %s=%s any more\n", name, workloadTag, strings.TrimPrefix(name, workloadPrefix))
+	}
+	if err != nil {
+		log.Printf("Failed to empty stale workloads: %v", err)
+		if stop := errs.record("workloads", err); stop != nil {
+			return stop
+		}
+	}
+	fmt.Printf("Tagged %d of %d state machine entities in New Relic\n", tagged, len(stateMachines))
+	return nil
+}
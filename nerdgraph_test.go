@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// fakeNerdGraph knows two state machine entities and two existing
+// workloads, one of a team without state machines, and records the mutations it receives
+type fakeNerdGraph struct {
+	mutations []map[string]any
+}
+
+func (g *fakeNerdGraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string
+		Variables map[string]any
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	switch {
+	case strings.Contains(req.Query, "entitySearch"):
+		query := req.Variables["query"].(string)
+		if strings.Contains(query, "LIKE") {
+			fmt.Fprint(w, `{"data":{"actor":{"entitySearch":{"results":{"entities":[`+
+				`{"guid":"PAYMENTS-WORKLOAD","name":"Step Functions: payments"},`+
+				`{"guid":"LEGACY-WORKLOAD","name":"Step Functions: legacy"}]}}}}}`)
+			return
+		}
+		guid := ""
+		switch {
+		case strings.Contains(query, "stateMachine:orders'"):
+			guid = "ORDERS"
+		case strings.Contains(query, "stateMachine:refunds'"):
+			guid = "REFUNDS"
+		case strings.Contains(query, "Step Functions: payments"):
+			guid = "PAYMENTS-WORKLOAD"
+		}
+		var entities []map[string]string
+		if guid != "" {
+			entities = append(entities, map[string]string{"guid": guid})
+		}
+		fmt.Fprint(w, `{"data":{"actor":{"entitySearch":{"results":{"entities":`)
+		json.NewEncoder(w).Encode(entities)
+		fmt.Fprint(w, `}}}}}`)
+	default:
+		g.mutations = append(g.mutations, map[string]any{"query": req.Query, "variables": req.Variables})
+		fmt.Fprint(w, `{"data":{}}`)
+	}
+}
+
+func TestSyncNerdGraph(t *testing.T) {
+	graph := &fakeNerdGraph{}
+	server := httptest.NewServer(graph)
+	defer server.Close()
+	client, err := newNerdGraphClient(newRelicConfig{accountID: "1234567", apiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.url = server.URL
+
+	arn := "arn:aws:states:us-east-1:123456789012:stateMachine:"
+	stateMachines := []stepfunctions.StateMachine{
+		{Name: "orders", ARN: arn + "orders", Type: "STANDARD", Tags: map[string]string{"team": "payments"}},
+		{Name: "refunds", ARN: arn + "refunds", Type: "EXPRESS", Tags: map[string]string{"team": "support"}},
+		{Name: "unmonitored", ARN: arn + "unmonitored"}, // No entity yet
+	}
+	errs := &runErrors{outputDir: t.TempDir()}
+	syncNerdGraph(context.Background(), client, stateMachines, "team", errs)
+
+	if len(errs.items) != 0 {
+		t.Fatalf("got errors %v", errs.items)
+	}
+	var ops []string
+	for _, m := range graph.mutations {
+		query := m["query"].(string)
+		op := query[strings.Index(query, "{")+1:]
+		ops = append(ops, strings.TrimSpace(op[:strings.Index(op, "(")]))
+	}
+	want := []string{
+		"taggingDeleteTagFromEntity", "taggingAddTagsToEntity",
+		"taggingDeleteTagFromEntity", "taggingAddTagsToEntity",
+		"workloadUpdate", "workloadCreate", "workloadUpdate",
+	}
+	if strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Fatalf("got mutations %v, want %v", ops, want)
+	}
+	if keys := graph.mutations[0]["variables"].(map[string]any)["tagKeys"].([]any); len(keys) != 4 || keys[3] != "team" {
+		t.Errorf("got deleted tag keys %v, want the keys being set", keys)
+	}
+	tags := graph.mutations[1]["variables"].(map[string]any)["tags"].([]any)
+	if len(tags) != 4 || tags[0].(map[string]any)["key"] != "stepfunctions.definitionHash" || tags[3].(map[string]any)["key"] != "team" {
+		t.Errorf("got tags %v", tags)
+	}
+	if update := graph.mutations[4]["variables"].(map[string]any); update["guid"] != "PAYMENTS-WORKLOAD" {
+		t.Errorf("got workload update %v", update)
+	}
+	stale := graph.mutations[6]["variables"].(map[string]any)
+	if guids := stale["workload"].(map[string]any)["entityGuids"].([]any); stale["guid"] != "LEGACY-WORKLOAD" || len(guids) != 0 {
+		t.Errorf("got stale workload update %v, want LEGACY-WORKLOAD emptied", stale)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// networkSettings are the endpoint and TLS settings of every AWS and HTTP
+// client, for regulated environments: FIPS endpoints, and a CA bundle for
+// proxies that intercept TLS
+type networkSettings struct {
+	useFIPS  bool
+	caBundle string // PEM file trusted in addition to the system roots
+	proxy    string // Proxy URL; empty uses HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+
+	once    sync.Once
+	pem     []byte
+	roots   *x509.CertPool
+	loadErr error
+}
+
+// network starts from the SDK's AWS_USE_FIPS_ENDPOINT and AWS_CA_BUNDLE
+// variables, so subcommands and the Lambda handler follow them; the main
+// command applies --use-fips, --ca-bundle and --proxy on top
+var network = &networkSettings{
+	useFIPS:  os.Getenv("AWS_USE_FIPS_ENDPOINT") == "true",
+	caBundle: os.Getenv("AWS_CA_BUNDLE"),
+}
+
+// load checks the proxy URL and reads the CA bundle, once
+func (n *networkSettings) load() error {
+	n.once.Do(func() {
+		if n.proxy != "" {
+			if _, n.loadErr = n.proxyURL(); n.loadErr != nil {
+				return
+			}
+		}
+		if n.caBundle == "" {
+			return
+		}
+		if n.pem, n.loadErr = os.ReadFile(n.caBundle); n.loadErr != nil {
+			n.loadErr = fmt.Errorf("failed to read CA bundle: %w", n.loadErr)
+			return
+		}
+		if n.roots, n.loadErr = x509.SystemCertPool(); n.loadErr != nil {
+			n.roots = x509.NewCertPool()
+		}
+		if !n.roots.AppendCertsFromPEM(n.pem) {
+			n.loadErr = fmt.Errorf("CA bundle %s has no PEM certificates", n.caBundle)
+		}
+	})
+	return n.loadErr
+}
+
+// awsConfigOptions are the LoadDefaultConfig options for the settings
+func (n *networkSettings) awsConfigOptions() ([]func(*config.LoadOptions) error, error) {
+	var opts []func(*config.LoadOptions) error
+	if n.useFIPS {
+		opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+	if err := n.load(); err != nil {
+		return nil, err
+	}
+	if n.proxy != "" {
+		proxy := n.proxyFunc()
+		opts = append(opts, config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+			t.Proxy = proxy
+		})))
+	}
+	if n.pem != nil {
+		opts = append(opts, config.WithCustomCABundle(bytes.NewReader(n.pem)))
+	}
+	return opts, nil
+}
+
+func (n *networkSettings) proxyURL() (*url.URL, error) {
+	parsed, err := url.Parse(n.proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("proxy URL %q must use http, https or socks5", n.proxy)
+	}
+	return parsed, nil
+}
+
+// proxyFunc returns the proxy of every request: the proxy URL, otherwise the
+// environment's
+func (n *networkSettings) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if parsed, err := n.proxyURL(); n.proxy != "" && err == nil {
+		return http.ProxyURL(parsed)
+	}
+	return http.ProxyFromEnvironment
+}
+
+// tlsConfig returns the TLS settings trusting the CA bundle, nil without one
+func (n *networkSettings) tlsConfig() (*tls.Config, error) {
+	if err := n.load(); err != nil || n.roots == nil {
+		return nil, err
+	}
+	return &tls.Config{RootCAs: n.roots, MinVersion: tls.VersionTLS12}, nil
+}
+
+// loadAWSConfig loads the AWS configuration for region with the network settings
+func loadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
+	opts, err := network.awsConfigOptions()
+	if err != nil {
+		return aws.Config{}, err
+	}
+	return config.LoadDefaultConfig(ctx, append(opts, config.WithRegion(region))...)
+}
+
+// newHTTPClient returns a client for the New Relic, webhook and Slack
+// endpoints that goes through the proxy and trusts the CA bundle. A bundle
+// that cannot be loaded was already reported at startup; the system roots are
+// used instead.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = network.proxyFunc()
+	tlsConfig, err := network.tlsConfig()
+	if err != nil {
+		log.Printf("Ignoring the CA bundle: %v", err)
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := network
+	defer func() { network = saved }()
+
+	network = &networkSettings{}
+	if _, err := newHTTPClient(time.Second).Get(server.URL); err == nil {
+		t.Fatal("expected the test certificate to be untrusted without the bundle")
+	}
+
+	network = &networkSettings{caBundle: bundle}
+	resp, err := newHTTPClient(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with the CA bundle: %v", err)
+	}
+	resp.Body.Close()
+	if opts, err := network.awsConfigOptions(); err != nil || len(opts) != 1 {
+		t.Errorf("got %d AWS config options (%v), want the CA bundle", len(opts), err)
+	}
+}
+
+func TestCABundleInvalid(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, []byte("not a certificate"), 0644)
+	for _, n := range []*networkSettings{{caBundle: bundle}, {caBundle: bundle + ".missing"}} {
+		if err := n.load(); err == nil {
+			t.Errorf("%s: expected an error", n.caBundle)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	saved := network
+	defer func() { network = saved }()
+	network = &networkSettings{proxy: proxy.URL}
+	if err := network.load(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newHTTPClient(time.Second).Get("http://insights-collector.example/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://insights-collector.example/v1/events" {
+		t.Errorf("got proxied requests %v", proxied)
+	}
+
+	if err := (&networkSettings{proxy: "ftp://proxy.example"}).load(); err == nil {
+		t.Error("expected an error for an unsupported proxy scheme")
+	}
+}
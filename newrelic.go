@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// newRelicRegion holds the endpoints of one New Relic data center
+type newRelicRegion struct {
+	eventsURL    string // Event API, formatted with the account ID
+	nerdGraphURL string
+	otlpEndpoint string
+	uiURL        string // New Relic One
+}
+
+// newRelicRegions are the --nr-region values
+var newRelicRegions = map[string]newRelicRegion{
+	"us": {
+		eventsURL:    "https://insights-collector.newrelic.com/v1/accounts/%s/events",
+		nerdGraphURL: "https://api.newrelic.com/graphql",
+		otlpEndpoint: "otlp.nr-data.net",
+		uiURL:        "https://one.newrelic.com",
+	},
+	"eu": {
+		eventsURL:    "https://insights-collector.eu01.nr-data.net/v1/accounts/%s/events",
+		nerdGraphURL: "https://api.eu.newrelic.com/graphql",
+		otlpEndpoint: "otlp.eu01.nr-data.net",
+		uiURL:        "https://one.eu.newrelic.com",
+	},
+	"fedramp": {
+		eventsURL:    "https://gov-insights-collector.newrelic.com/v1/accounts/%s/events",
+		nerdGraphURL: "https://gov-api.newrelic.com/graphql",
+		otlpEndpoint: "gov-otlp.nr-data.net",
+		uiURL:        "https://one.newrelic.com",
+	},
+}
+
+// newRelicConfig is the account, data center and keys every New Relic
+// integration uses: the newrelic export, --otel, --nerdgraph-sync and
+// --nr-lambda-traces
+type newRelicConfig struct {
+	region     string // us, eu or fedramp; empty infers it from the license key
+	accountID  string
+	licenseKey string // Ingest key, for events and OTLP
+	apiKey     string // User key, for NerdGraph
+}
+
+// newRelicFromEnv reads NEW_RELIC_REGION, NEW_RELIC_ACCOUNT_ID,
+// NEW_RELIC_LICENSE_KEY and NEW_RELIC_API_KEY for the settings not given
+func newRelicFromEnv(nr newRelicConfig) newRelicConfig {
+	for _, setting := range []struct {
+		value *string
+		env   string
+	}{
+		{&nr.region, "NEW_RELIC_REGION"},
+		{&nr.accountID, "NEW_RELIC_ACCOUNT_ID"},
+		{&nr.licenseKey, "NEW_RELIC_LICENSE_KEY"},
+		{&nr.apiKey, "NEW_RELIC_API_KEY"},
+	} {
+		if *setting.value == "" {
+			*setting.value = os.Getenv(setting.env)
+		}
+	}
+	nr.region = strings.ToLower(nr.region)
+	return nr
+}
+
+// validate checks the region name
+func (nr newRelicConfig) validate() error {
+	if _, ok := newRelicRegions[nr.region]; nr.region != "" && !ok {
+		return fmt.Errorf("unsupported New Relic region %q (supported: %s)", nr.region, strings.Join(newRelicRegionNames(), ", "))
+	}
+	return nil
+}
+
+// endpoints returns the endpoints of the configured region. Without one, EU
+// license keys, which start with "eu", select the EU data center.
+func (nr newRelicConfig) endpoints() newRelicRegion {
+	if region, ok := newRelicRegions[nr.region]; ok {
+		return region
+	}
+	if strings.HasPrefix(nr.licenseKey, "eu") {
+		return newRelicRegions["eu"]
+	}
+	return newRelicRegions["us"]
+}
+
+// resolve replaces keys given as secret references, see resolveSecret
+func (nr newRelicConfig) resolve(ctx context.Context, region string) (newRelicConfig, error) {
+	err := resolveSecrets(ctx, region, &nr.licenseKey, &nr.apiKey)
+	return nr, err
+}
+
+func newRelicRegionNames() []string {
+	names := make([]string, 0, len(newRelicRegions))
+	for name := range newRelicRegions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
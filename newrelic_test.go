@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestNewRelicEndpoints(t *testing.T) {
+	tests := []struct {
+		nr   newRelicConfig
+		want string
+	}{
+		{newRelicConfig{}, "https://api.newrelic.com/graphql"},
+		{newRelicConfig{region: "eu"}, "https://api.eu.newrelic.com/graphql"},
+		{newRelicConfig{licenseKey: "eu01xx0123456789abcdef"}, "https://api.eu.newrelic.com/graphql"},
+		{newRelicConfig{region: "us", licenseKey: "eu01xx0123456789abcdef"}, "https://api.newrelic.com/graphql"},
+		{newRelicConfig{region: "fedramp"}, "https://gov-api.newrelic.com/graphql"},
+	}
+	for _, tt := range tests {
+		if got := tt.nr.endpoints().nerdGraphURL; got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.nr, got, tt.want)
+		}
+	}
+	if err := (newRelicConfig{region: "apac"}).validate(); err == nil {
+		t.Error("validate accepted region apac")
+	}
+}
+
+func TestNewRelicFromEnv(t *testing.T) {
+	t.Setenv("NEW_RELIC_REGION", "EU")
+	t.Setenv("NEW_RELIC_ACCOUNT_ID", "1")
+	t.Setenv("NEW_RELIC_LICENSE_KEY", "from-env")
+	nr := newRelicFromEnv(newRelicConfig{licenseKey: "from-flag"})
+	if nr.region != "eu" || nr.accountID != "1" || nr.licenseKey != "from-flag" {
+		t.Errorf("got %+v", nr)
+	}
+}
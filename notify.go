@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// digestFailuresShown caps the executions listed per state machine in a notification
+const digestFailuresShown = 10
+
+// notifiedRetention is how long a reported ARN stays in the notification state
+// after it was last seen failed, so the state does not grow without bound.
+const notifiedRetention = 30 * 24 * time.Hour
+
+// processNotifications posts the failed executions not reported by an earlier
+// run to SNS and/or Slack. The reported ARNs are kept in cfg.notifyState, a
+// file or s3:// object, and only updated when every notification was delivered.
+// An unreadable state skips the notifications rather than re-sending every failure.
+func processNotifications(ctx context.Context, cfg runConfig, stateMachines []stepfunctions.StateMachine, now time.Time, errs *runErrors) error {
+	notified, err := loadNotified(ctx, cfg.region, cfg.notifyState, now)
+	if err != nil {
+		log.Printf("Failed to load notification state %s: %v", cfg.notifyState, err)
+		return errs.record(cfg.notifyState, err)
+	}
+	seen := make(map[string]bool, len(notified))
+	for executionArn := range notified {
+		seen[executionArn] = true
+	}
+	digest, failed := stepfunctions.BuildFailureDigest(stateMachines, seen)
+	if digest.Total == 0 {
+		fmt.Println("No new failed executions to notify.")
+		return saveNotifiedState(ctx, cfg, mergeNotified(notified, failed, now), errs)
+	}
+
+	delivered := true
+	if cfg.notifySNSTopic != "" {
+		if err := publishDigestSNS(ctx, cfg.region, cfg.notifySNSTopic, digest, cfg.nrLinkTemplate); err != nil {
+			log.Printf("Failed to publish failure digest to SNS: %v", err)
+			if stop := errs.record(cfg.notifySNSTopic, err); stop != nil {
+				return stop
+			}
+			delivered = false
+		}
+	}
+	if cfg.notifySlackWebhook != "" {
+		if err := postDigestSlack(ctx, cfg.notifySlackWebhook, digest, cfg.nrLinkTemplate); err != nil {
+			log.Printf("Failed to post failure digest to Slack: %v", err)
+			if stop := errs.record("slack", err); stop != nil {
+				return stop
+			}
+			delivered = false
+		}
+	}
+	if delivered {
+		fmt.Printf("Notified %d new failed execution(s)\n", digest.Total)
+		return saveNotifiedState(ctx, cfg, mergeNotified(notified, failed, now), errs)
+	}
+	return nil
+}
+
+func saveNotifiedState(ctx context.Context, cfg runConfig, notified map[string]time.Time, errs *runErrors) error {
+	if err := saveNotified(ctx, cfg.region, cfg.notifyState, notified); err != nil {
+		log.Printf("Failed to save notification state %s: %v", cfg.notifyState, err)
+		return errs.record(cfg.notifyState, err)
+	}
+	return nil
+}
+
+func publishDigestSNS(ctx context.Context, region, topicArn string, digest stepfunctions.FailureDigest, nrLinkTemplate string) error {
+	awsCfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	plainLink := func(url, label string) string { return fmt.Sprintf("%s: %s", label, url) }
+	_, err = sns.NewFromConfig(awsCfg).Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Subject:  aws.String(fmt.Sprintf("Step Functions: %d new failed execution(s)", digest.Total)),
+		Message:  aws.String(formatDigest(digest, nrLinkTemplate, plainLink)),
+	})
+	return err
+}
+
+func postDigestSlack(ctx context.Context, webhookURL string, digest stepfunctions.FailureDigest, nrLinkTemplate string) error {
+	slackLink := func(url, label string) string { return fmt.Sprintf("<%s|%s>", url, label) }
+	text := fmt.Sprintf("*%d new failed Step Functions execution(s)*\n%s", digest.Total, formatDigest(digest, nrLinkTemplate, slackLink))
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := newHTTPClient(10 * time.Second).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// formatDigest renders the digest as text; link formats a URL for the target.
+// nrLinkTemplate, when set, adds a New Relic link per execution with
+// {executionArn} and {stateMachineArn} substituted.
+func formatDigest(digest stepfunctions.FailureDigest, nrLinkTemplate string, link func(url, label string) string) string {
+	var b strings.Builder
+	for _, group := range digest.Groups {
+		fmt.Fprintf(&b, "\n%s (%d new)\n", group.StateMachineName, len(group.Failures))
+		for i, failure := range group.Failures {
+			if i == digestFailuresShown {
+				fmt.Fprintf(&b, "  ... and %d more\n", len(group.Failures)-digestFailuresShown)
+				break
+			}
+
+			where := failure.Error
+			if failure.StateName != "" {
+				where = fmt.Sprintf("%s in %s", failure.Error, failure.StateName)
+			}
+			fmt.Fprintf(&b, "- %s %s at %s: %s\n", failure.Status, failure.ExecutionArn[strings.LastIndex(failure.ExecutionArn, ":")+1:], failure.EndTime.Format(time.RFC3339), where)
+			links := []string{link(failure.ConsoleURL, "console")}
+			if nrLinkTemplate != "" {
+				url := strings.NewReplacer("{executionArn}", failure.ExecutionArn, "{stateMachineArn}", group.StateMachineARN).Replace(nrLinkTemplate)
+				links = append(links, link(url, "New Relic"))
+			}
+			fmt.Fprintf(&b, "  %s\n", strings.Join(links, " | "))
+		}
+	}
+	return b.String()
+}
+
+// loadNotified reads the reported ARNs and when each was last seen failed. A
+// missing state is empty; the older plain list of ARNs is read as seen now.
+func loadNotified(ctx context.Context, region, path string, now time.Time) (map[string]time.Time, error) {
+	notified := make(map[string]time.Time)
+	data, err := readStateFile(ctx, region, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return notified, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &notified); err == nil {
+		return notified, nil
+	}
+	var arns []string
+	if err := json.Unmarshal(data, &arns); err != nil {
+		return nil, fmt.Errorf("failed to parse notification state: %w", err)
+	}
+	for _, executionArn := range arns {
+		notified[executionArn] = now
+	}
+	return notified, nil
+}
+
+// mergeNotified adds the failed ARNs, seen now, to the earlier state and drops
+// the entries not seen for notifiedRetention.
+func mergeNotified(notified map[string]time.Time, failed []string, now time.Time) map[string]time.Time {
+	merged := make(map[string]time.Time, len(notified)+len(failed))
+	for executionArn, seen := range notified {
+		if now.Sub(seen) < notifiedRetention {
+			merged[executionArn] = seen
+		}
+	}
+	for _, executionArn := range failed {
+		merged[executionArn] = now
+	}
+	return merged
+}
+
+func saveNotified(ctx context.Context, region, path string, notified map[string]time.Time) error {
+	data, err := json.MarshalIndent(notified, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeStateFile(ctx, region, path, data)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// lambdaWindowSlack widens the queried window around an execution, for
+// clock skew and telemetry reported after the invocation
+const lambdaWindowSlack = time.Minute
+
+const nrqlGraphQuery = `query($accountId: Int!, $nrql: Nrql!) {
+  actor { account(id: $accountId) { nrql(query: $nrql) { results } } }
+}`
+
+// nrql runs an NRQL query in the client's account
+func (c *nerdGraphClient) nrql(ctx context.Context, query string) ([]map[string]any, error) {
+	var data struct {
+		Actor struct {
+			Account struct {
+				NRQL struct {
+					Results []map[string]any
+				}
+			}
+		}
+	}
+	if err := c.do(ctx, nrqlGraphQuery, map[string]any{"accountId": c.accountID, "nrql": query}, &data); err != nil {
+		return nil, err
+	}
+	return data.Actor.Account.NRQL.Results, nil
+}
+
+// processLambdaCorrelation looks up the New Relic AwsLambdaInvocation events of
+// the Lambda functions each execution invoked and attaches their trace ID and
+// entity link to the execution, so they are exported with it
+func processLambdaCorrelation(ctx context.Context, stateMachines []stepfunctions.StateMachine, nr newRelicConfig, now time.Time, errs *runErrors) error {
+	client, err := newNerdGraphClient(nr)
+	if err != nil {
+		log.Printf("Failed to correlate Lambda invocations: %v", err)
+		return errs.record("nerdgraph", err)
+	}
+	return correlateLambdas(ctx, client, stateMachines, now, errs)
+}
+
+func correlateLambdas(ctx context.Context, client *nerdGraphClient, stateMachines []stepfunctions.StateMachine, now time.Time, errs *runErrors) error {
+	correlated, total := 0, 0
+	for i := range stateMachines {
+		sm := &stateMachines[i]
+		// One query per state machine: its executions' invocations are
+		// matched together, then handed back to each execution
+		var invocations []stepfunctions.LambdaInvocation
+		var executions []*stepfunctions.Execution
+		var counts []int
+		var since, until time.Time // Window covering every execution
+		for j := range sm.Executions {
+			exec := sm.Executions[j]
+			found := stepfunctions.LambdaInvocations(exec)
+			if len(found) == 0 {
+				continue
+			}
+			start, end := found[0].Start, exec.EndTime
+			if !exec.StartTime.IsZero() {
+				start = exec.StartTime
+			}
+			if end.IsZero() {
+				end = now
+			}
+			if since.IsZero() || start.Before(since) {
+				since = start
+			}
+			if end.After(until) {
+				until = end
+			}
+			invocations = append(invocations, found...)
+			executions = append(executions, &sm.Executions[j])
+			counts = append(counts, len(found))
+		}
+		if len(invocations) == 0 {
+			continue
+		}
+		results, err := client.nrql(ctx, lambdaInvocationQuery(invocations, since, until))
+		if err != nil {
+			log.Printf("Failed to query Lambda invocations of %s: %v", sm.Name, err)
+			if stop := errs.record(sm.ARN, err); stop != nil {
+				return stop
+			}
+			continue
+		}
+		correlated += matchLambdaInvocations(invocations, results, client.uiURL)
+		total += len(invocations)
+		offset := 0
+		for k, exec := range executions {
+			exec.Lambdas = invocations[offset : offset+counts[k] : offset+counts[k]]
+			offset += counts[k]
+		}
+	}
+	fmt.Printf("Debug: Correlated %d of %d Lambda invocations with New Relic telemetry\n", correlated, total)
+	return nil
+}
+
+// lambdaInvocationQuery selects the invocations of the given functions
+// between since and until. Functions are matched by prefix, since New Relic
+// reports the ARN qualified with the version or alias invoked;
+// matchLambdaInvocations checks the exact function.
+func lambdaInvocationQuery(invocations []stepfunctions.LambdaInvocation, since, until time.Time) string {
+	seen := make(map[string]bool)
+	var functions []string
+	for _, invocation := range invocations {
+		if !seen[invocation.FunctionArn] {
+			seen[invocation.FunctionArn] = true
+			functions = append(functions, "aws.lambda.arn LIKE "+nrqlString(invocation.FunctionArn+"%"))
+		}
+	}
+	return fmt.Sprintf("SELECT aws.requestId, aws.lambda.arn, traceId, entityGuid, timestamp FROM AwsLambdaInvocation WHERE %s SINCE %d UNTIL %d LIMIT MAX",
+		strings.Join(functions, " OR "), since.Add(-lambdaWindowSlack).UnixMilli(), until.Add(lambdaWindowSlack).UnixMilli())
+}
+
+// matchLambdaInvocations sets the trace and entity of each invocation from
+// the query results: by request ID when known, otherwise the first unmatched
+// invocation of the same function within the attempt. Entities are linked in
+// the New Relic One at uiURL. It returns the number matched.
+func matchLambdaInvocations(invocations []stepfunctions.LambdaInvocation, results []map[string]any, uiURL string) int {
+	used := make([]bool, len(results))
+	matched := 0
+	for i := range invocations {
+		invocation := &invocations[i]
+		for k, result := range results {
+			if used[k] || !sameFunction(result, invocation.FunctionArn) {
+				continue
+			}
+			if invocation.RequestID != "" {
+				if result["aws.requestId"] != invocation.RequestID {
+					continue
+				}
+			} else {
+				ms, _ := result["timestamp"].(float64)
+				at := time.UnixMilli(int64(ms))
+				if at.Before(invocation.Start.Add(-time.Second)) || at.After(invocation.End.Add(time.Second)) {
+					continue
+				}
+			}
+			used[k] = true
+			invocation.TraceID, _ = result["traceId"].(string)
+			invocation.EntityGUID, _ = result["entityGuid"].(string)
+			if invocation.EntityGUID != "" {
+				invocation.EntityURL = uiURL + "/redirect/entity/" + invocation.EntityGUID
+			}
+			matched++
+			break
+		}
+	}
+	return matched
+}
+
+// sameFunction compares the function ARN of a result, which may be qualified
+// with a version or alias
+func sameFunction(result map[string]any, functionArn string) bool {
+	resultArn, _ := result["aws.lambda.arn"].(string)
+	return resultArn == functionArn || strings.HasPrefix(resultArn, functionArn+":")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestMatchLambdaInvocations(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fn := "arn:aws:lambda:us-east-1:123456789012:function:charge"
+	invocations := []stepfunctions.LambdaInvocation{
+		{State: "Charge", FunctionArn: fn, RequestID: "req-2", Start: start, End: start.Add(time.Second)},
+		{State: "Retry", FunctionArn: fn, Start: start.Add(10 * time.Second), End: start.Add(12 * time.Second)},
+		{State: "Missing", FunctionArn: fn, Start: start.Add(time.Hour), End: start.Add(time.Hour)},
+	}
+	results := []map[string]any{
+		{"aws.requestId": "req-1", "aws.lambda.arn": fn, "traceId": "t1", "entityGuid": "G", "timestamp": float64(start.UnixMilli())},
+		{"aws.requestId": "req-2", "aws.lambda.arn": fn + ":live", "traceId": "t2", "entityGuid": "G", "timestamp": float64(start.UnixMilli())},
+		{"aws.requestId": "req-3", "aws.lambda.arn": fn, "traceId": "t3", "entityGuid": "G", "timestamp": float64(start.Add(11 * time.Second).UnixMilli())},
+	}
+
+	if matched := matchLambdaInvocations(invocations, results, "https://one.newrelic.com"); matched != 2 {
+		t.Errorf("matched %d, want 2", matched)
+	}
+	if invocations[0].TraceID != "t2" || invocations[0].EntityURL != "https://one.newrelic.com/redirect/entity/G" {
+		t.Errorf("got %+v, want trace t2 by request ID", invocations[0])
+	}
+	if invocations[1].TraceID != "t3" {
+		t.Errorf("got %+v, want trace t3 by time", invocations[1])
+	}
+	if invocations[2].TraceID != "" {
+		t.Errorf("got %+v, want no match", invocations[2])
+	}
+}
+
+func TestLambdaInvocationQuery(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fn := "arn:aws:lambda:us-east-1:123456789012:function:charge"
+	invocations := []stepfunctions.LambdaInvocation{{FunctionArn: fn}, {FunctionArn: fn}, {FunctionArn: fn + "-refund"}}
+	got := lambdaInvocationQuery(invocations, start, start.Add(time.Hour))
+	want := fmt.Sprintf("SELECT aws.requestId, aws.lambda.arn, traceId, entityGuid, timestamp FROM AwsLambdaInvocation "+
+		"WHERE aws.lambda.arn LIKE '%s%%' OR aws.lambda.arn LIKE '%s-refund%%' SINCE %d UNTIL %d LIMIT MAX",
+		fn, fn, start.Add(-time.Minute).UnixMilli(), start.Add(61*time.Minute).UnixMilli())
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// defaultNRQLSince is the time window of the generated queries
+const defaultNRQLSince = "1 week ago"
+
+// nrqlQuery is a generated query, for one state machine or, with an empty
+// StateMachineName, across all of them
+type nrqlQuery struct {
+	StateMachineName string `json:",omitempty"`
+	Name             string
+	Query            string
+}
+
+// failedStatusesNRQL lists the StepFunctionsExecution statuses counted as
+// failures, the same ones as stepfunctions.IsFailedStatus
+func failedStatusesNRQL() string {
+	var statuses []string
+	for _, status := range stepfunctions.FailedStatuses() {
+		statuses = append(statuses, nrqlString(status))
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// writeNRQL generates NRQL over the events of the newrelic export for the
+// state machines, in dir:
+//
+//	queries.nrql  the queries, each under a comment naming it
+//	nrql.json     the same queries, for dashboard tooling
+func writeNRQL(stateMachines []stepfunctions.StateMachine, dir string, opts iacOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create nrql directory: %w", err)
+	}
+	queries := nrqlQueries(stateMachines, opts.since)
+
+	var text strings.Builder
+	text.WriteString("-- Generated by stepfunction-fetcher export nrql, over the events of --export newrelic\n")
+	for _, q := range queries {
+		title := q.Name
+		if q.StateMachineName != "" {
+			title = q.StateMachineName + ": " + q.Name
+		}
+		fmt.Fprintf(&text, "\n-- %s\n%s\n", title, q.Query)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "queries.nrql"), []byte(text.String()), 0644); err != nil {
+		return fmt.Errorf("failed to save queries.nrql: %w", err)
+	}
+
+	data, err := stepfunctions.MarshalDocument(queries, "queries")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queries: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nrql.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to save nrql.json: %w", err)
+	}
+	return nil
+}
+
+// nrqlQueries builds the failure rate, duration percentile and per-state
+// error queries across all state machines, then for each one
+func nrqlQueries(stateMachines []stepfunctions.StateMachine, since string) []nrqlQuery {
+	if since == "" {
+		since = defaultNRQLSince
+	}
+	// Sort a copy; the caller's order is theirs
+	stateMachines = append([]stepfunctions.StateMachine(nil), stateMachines...)
+	sort.Slice(stateMachines, func(i, j int) bool { return stateMachines[i].Name < stateMachines[j].Name })
+	var names, arns []string
+	for _, sm := range stateMachines {
+		names = append(names, nrqlString(sm.Name))
+		arns = append(arns, nrqlString(sm.ARN))
+	}
+
+	all := fmt.Sprintf("stateMachineArn IN (%s)", strings.Join(arns, ", "))
+	queries := []nrqlQuery{
+		{Name: "Executions by state machine", Query: fmt.Sprintf(
+			"SELECT count(*) FROM StepFunctionsExecution WHERE stateMachineName IN (%s) FACET stateMachineName, status SINCE %s",
+			strings.Join(names, ", "), since)},
+		{Name: "Failure rate by state machine", Query: fmt.Sprintf(
+			"SELECT percentage(count(*), WHERE status IN (%s)) AS 'Failure rate' FROM StepFunctionsExecution WHERE %s FACET stateMachineName SINCE %s TIMESERIES",
+			failedStatusesNRQL(), all, since)},
+		{Name: "Duration percentiles by state machine", Query: fmt.Sprintf(
+			"SELECT percentile(durationMs, 50, 90, 99) FROM StepFunctionsExecution WHERE %s FACET stateMachineName SINCE %s",
+			all, since)},
+	}
+
+	for _, sm := range stateMachines {
+		arn := nrqlString(sm.ARN)
+		queries = append(queries,
+			nrqlQuery{StateMachineName: sm.Name, Name: "Failure rate", Query: fmt.Sprintf(
+				"SELECT percentage(count(*), WHERE status IN (%s)) AS 'Failure rate' FROM StepFunctionsExecution WHERE stateMachineArn = %s SINCE %s TIMESERIES",
+				failedStatusesNRQL(), arn, since)},
+			nrqlQuery{StateMachineName: sm.Name, Name: "Duration percentiles", Query: fmt.Sprintf(
+				"SELECT percentile(durationMs, 50, 90, 99) FROM StepFunctionsExecution WHERE stateMachineArn = %s SINCE %s TIMESERIES",
+				arn, since)},
+			nrqlQuery{StateMachineName: sm.Name, Name: "Errors by state", Query: fmt.Sprintf(
+				"SELECT count(*) FROM StepFunctionsHistoryEvent WHERE aws.arn = %s AND error IS NOT NULL AND error != '' FACET stateName, error SINCE %s",
+				arn, since)},
+		)
+	}
+	return queries
+}
+
+// nrqlString quotes a value as an NRQL string literal
+func nrqlString(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteNRQL(t *testing.T) {
+	dir := t.TempDir()
+	stateMachines := iacFixture()
+	stateMachines = append(stateMachines, stateMachines[0])
+	stateMachines[1].Name = "o'brien"
+	stateMachines[1].ARN = "arn:aws:states:us-east-1:123456789012:stateMachine:o'brien"
+	if err := writeNRQL(stateMachines, dir, iacOptions{since: "1 day ago"}); err != nil {
+		t.Fatal(err)
+	}
+	if stateMachines[1].Name != "o'brien" {
+		t.Errorf("writeNRQL reordered the caller's state machines: %s first", stateMachines[0].Name)
+	}
+	for _, name := range []string{"queries.nrql", "nrql.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, filepath.Join("nrql", name), data)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// definitionSuffixes are the extensions of local definition files, longest
+// first so they are trimmed whole from the state machine name
+var definitionSuffixes = []string{".asl.json", ".asl.yaml", ".asl.yml", ".json", ".yaml", ".yml"}
+
+// loadLocal reads state machines without calling AWS. Each path is a
+// definition file (JSON or YAML), a previous run's output directory (its
+// state_machines.json, executions included) or a directory of <name>.asl.json
+// and <name>.asl.yaml files. sources maps state machine names to the
+// definition file they were read from, when there is one.
+func loadLocal(paths []string) (stateMachines []stepfunctions.StateMachine, sources map[string]string, err error) {
+	sources = make(map[string]string)
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if !info.IsDir() {
+			sm, err := loadDefinitionFile(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			stateMachines = append(stateMachines, sm)
+			sources[sm.Name] = p
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(p, "state_machines.json")); err == nil {
+			snapshot, err := loadSnapshot(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, sm := range snapshot {
+				for _, file := range []string{definitionFile(sm.Name), yamlFile(definitionFile(sm.Name))} {
+					if _, err := os.Stat(filepath.Join(p, file)); err == nil {
+						sources[sm.Name] = filepath.Join(p, file)
+						break
+					}
+				}
+			}
+			stateMachines = append(stateMachines, snapshot...)
+			continue
+		}
+
+		var files []string
+		for _, pattern := range []string{"*.asl.json", "*.asl.yaml", "*.asl.yml"} {
+			matches, err := filepath.Glob(filepath.Join(p, pattern))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to list definitions in %s: %w", p, err)
+			}
+			files = append(files, matches...)
+		}
+		if len(files) == 0 {
+			return nil, nil, fmt.Errorf("no state_machines.json or *.asl.json/*.asl.yaml definitions in %s", p)
+		}
+		for _, file := range files {
+			sm, err := loadDefinitionFile(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			stateMachines = append(stateMachines, sm)
+			sources[sm.Name] = file
+		}
+	}
+	return stateMachines, sources, nil
+}
+
+// loadDefinitionFile reads one definition, named after the file. Definitions
+// that do not parse are kept for ValidateDefinition to report on.
+func loadDefinitionFile(file string) (stepfunctions.StateMachine, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return stepfunctions.StateMachine{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	name := filepath.Base(file)
+	for _, suffix := range definitionSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	if isYAMLFile(file) {
+		if asJSON, err := yamlToJSON(data); err == nil {
+			data = asJSON
+		}
+	}
+	sm, _ := stepfunctions.LocalStateMachine(name, string(data))
+	return sm, nil
+}
+
+// runValidate validates and audits local definitions, for CI on changes to
+// definitions. It exits 3 when a finding is at least --fail-on severe.
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	findingsConfig := fs.String("findings-config", "", "JSON file of findings to suppress, by rule, state machine and state")
+	sarif := fs.String("sarif", "", "Also write the findings as SARIF to this file, against the definition files")
+	failOn := fs.String("fail-on", stepfunctions.SeverityHigh, "Exit 3 on findings of this severity or higher: high, medium, low or none")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher validate [flags] <definition file or output dir>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	initColor(*noColor)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	switch *failOn {
+	case stepfunctions.SeverityHigh, stepfunctions.SeverityMedium, stepfunctions.SeverityLow, "none":
+	default:
+		log.Fatalf("Unsupported --fail-on %q (supported: high, medium, low, none)", *failOn)
+	}
+
+	stateMachines, sources, err := loadLocal(fs.Args())
+	if err != nil {
+		log.Fatalf("Failed to load definitions: %v", err)
+	}
+	var findings, suppressed []stepfunctions.Finding
+	for _, sm := range stateMachines {
+		findings = append(findings, stepfunctions.ValidateDefinition(sm)...)
+		findings = append(findings, stepfunctions.AuditStateMachine(sm)...)
+	}
+	if *findingsConfig != "" {
+		cfg, err := stepfunctions.LoadFindingsConfig(*findingsConfig)
+		if err != nil {
+			log.Fatalf("Failed to load findings config: %v", err)
+		}
+		findings, suppressed = cfg.Filter(findings)
+	}
+	stepfunctions.SortFindings(findings)
+	printFindings(findings, len(suppressed))
+
+	if *sarif != "" {
+		if err := writeSARIFFile(*sarif, localFindingLines(sources, findings), stepfunctions.SARIFOptions{
+			ToolVersion: version,
+			ArtifactURI: func(finding stepfunctions.Finding) string {
+				return filepath.ToSlash(sources[finding.StateMachineName])
+			},
+		}); err != nil {
+			log.Fatalf("Failed to write SARIF file: %v", err)
+		}
+	}
+
+	for _, finding := range findings {
+		if *failOn != "none" && stepfunctions.SeverityAtLeast(finding.Severity, *failOn) {
+			os.Exit(exitCheckFailed)
+		}
+	}
+}
+
+// localFindingLines moves the line numbers of findings in YAML definitions
+// to the YAML source
+func localFindingLines(sources map[string]string, findings []stepfunctions.Finding) []stepfunctions.Finding {
+	definitions := make(map[string][]byte)
+	for name, source := range sources {
+		if isYAMLFile(source) {
+			if data, err := os.ReadFile(source); err == nil {
+				definitions[name] = data
+			}
+		}
+	}
+	return yamlFindingLines(definitions, findings)
+}
+
+// runAnalytics computes the analytics report over the executions saved by a
+// previous run, without calling AWS
+func runAnalytics(args []string) {
+	fs := flag.NewFlagSet("analytics", flag.ExitOnError)
+	outputDir := fs.String("output-dir", ".", "Directory to write analytics.json to")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher analytics [flags] <output dir or definition file>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	initColor(*noColor)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	stateMachines, _, err := loadLocal(fs.Args())
+	if err != nil {
+		log.Fatalf("Failed to load state machines: %v", err)
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		log.Fatalf("Failed to create output directory: %v", err)
+	}
+	processAnalytics(stateMachines, *outputDir)
+}
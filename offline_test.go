@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestLoadLocal(t *testing.T) {
+	definitions := t.TempDir()
+	files := map[string]string{
+		"orders.asl.json":    `{"StartAt":"Charge","States":{"Charge":{"Type":"Pass","End":true}}}`,
+		"refunds.asl.yaml":   "StartAt: Refund\nStates:\n  Refund:\n    Type: Task\n    Next: Missing\n",
+		"broken.asl.json":    `{"StartAt":`,
+		"orders_Charge.json": `{"Type":"Pass","End":true}`, // A state file, not a definition
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(definitions, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot := t.TempDir()
+	data, err := json.Marshal([]stepfunctions.StateMachine{{Name: "shipping", Definition: `{}`, Executions: []stepfunctions.Execution{{Status: "SUCCEEDED"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(snapshot, "state_machines.json"), data, 0644)
+	os.WriteFile(filepath.Join(snapshot, "shipping.asl.json"), []byte(`{}`), 0644)
+
+	stateMachines, sources, err := loadLocal([]string{definitions, snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]stepfunctions.StateMachine)
+	for _, sm := range stateMachines {
+		byName[sm.Name] = sm
+	}
+	if len(byName) != 4 {
+		t.Fatalf("got state machines %v", byName)
+	}
+	if sm := byName["refunds"]; len(sm.States) != 1 || sm.States[0].Next != "Missing" {
+		t.Errorf("YAML definition parsed as %+v", sm)
+	}
+	if sm := byName["broken"]; sm.Definition != `{"StartAt":` || sm.States != nil {
+		t.Errorf("broken definition loaded as %+v", sm)
+	}
+	if len(byName["shipping"].Executions) != 1 {
+		t.Error("snapshot executions were not loaded")
+	}
+	if got, want := sources["shipping"], filepath.Join(snapshot, "shipping.asl.json"); got != want {
+		t.Errorf("shipping source = %q, want %q", got, want)
+	}
+
+	// Findings in YAML definitions point at the YAML lines
+	var findings []stepfunctions.Finding
+	findings = append(findings, stepfunctions.ValidateDefinition(byName["refunds"])...)
+	moved := localFindingLines(sources, findings)
+	if len(moved) == 0 || moved[0].State != "Refund" || moved[0].Line != 3 {
+		t.Errorf("got findings %+v", moved)
+	}
+
+	if _, _, err := loadLocal([]string{t.TempDir()}); err == nil {
+		t.Error("expected an error for a directory without definitions")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// permission is an IAM action a run calls, the resources it calls it on and
+// what the run calls it for
+type permission struct {
+	Action    string
+	Resources []string
+	Reason    string
+}
+
+// permissions lists the IAM actions the configured run calls, scoped to its
+// region and state machines
+func (cfg runConfig) permissions() []permission {
+	var perms []permission
+	add := func(reason string, resources []string, actions ...string) {
+		for _, action := range actions {
+			perms = append(perms, permission{Action: action, Resources: resources, Reason: reason})
+		}
+	}
+	scope := newPolicyScope(cfg)
+	all := []string{"*"}
+
+	if len(cfg.stateMachineARNs) == 0 {
+		add("listing state machines", all, "states:ListStateMachines")
+	}
+	add("reading definitions", scope.stateMachines(""), "states:DescribeStateMachine")
+	add("counting Express executions without logs", all, "cloudwatch:GetMetricData")
+	add("listing executions", scope.stateMachines(cfg.alias), "states:ListExecutions")
+	if !cfg.countsOnly {
+		add("listing executions", scope.executions(), "states:DescribeExecution")
+		add("discovering Express executions", []string{scope.arn("logs", "log-group:*")}, "logs:FilterLogEvents")
+	}
+	if cfg.quotaPacing {
+		add("--quota-pacing", all, "servicequotas:ListServiceQuotas", "servicequotas:ListAWSDefaultServiceQuotas")
+	}
+	if cfg.countsOnly {
+		return perms
+	}
+
+	if cfg.includeHistory || cfg.callbacks {
+		add("--include-history and --callbacks", scope.executions(), "states:GetExecutionHistory")
+	}
+	if cfg.mapRuns {
+		add("--map-runs", scope.executions(), "states:ListMapRuns")
+		add("--map-runs", scope.mapRuns(), "states:DescribeMapRun")
+		add("--map-runs", all, "s3:GetObject") // The ResultWriter buckets are in the definitions
+	}
+	if cfg.cloudTrailInitiators {
+		add("--cloudtrail-initiators", all, "cloudtrail:LookupEvents")
+	}
+	if cfg.iamAnalysis {
+		add("--iam-analysis", []string{scope.global("iam", "role/*")}, "iam:ListAttachedRolePolicies", "iam:ListRolePolicies", "iam:GetRolePolicy")
+		add("--iam-analysis", []string{scope.global("iam", "policy/*")}, "iam:GetPolicy", "iam:GetPolicyVersion")
+	}
+	if cfg.kmsAudit {
+		add("--kms-audit", []string{scope.arnIn("kms", "*", "key/*")}, "kms:DescribeKey", "kms:GetKeyRotationStatus")
+	}
+	if cfg.cfnDrift {
+		add("--cfn-drift", []string{scope.arn("cloudformation", "stack/*")}, "cloudformation:DescribeStackResources", "cloudformation:DetectStackResourceDrift")
+	}
+	if cfg.nerdGraphSync {
+		add("--nerdgraph-sync", scope.stateMachines(""), "states:ListTagsForResource")
+	}
+	if cfg.kinesisStream != "" {
+		add("--kinesis-stream", []string{scope.named("kinesis", "stream/", cfg.kinesisStream)}, "kinesis:PutRecords")
+	}
+	if cfg.firehoseStream != "" {
+		add("--firehose-stream", []string{scope.named("firehose", "deliverystream/", cfg.firehoseStream)}, "firehose:PutRecordBatch")
+	}
+	if cfg.notifySNSTopic != "" {
+		add("--notify-sns-topic", []string{cfg.notifySNSTopic}, "sns:Publish")
+	}
+	if (cfg.notifySNSTopic != "" || cfg.notifySlackWebhook != "") && strings.HasPrefix(cfg.notifyState, "s3://") {
+		add("--notify-state", []string{scope.global("s3", strings.TrimPrefix(cfg.notifyState, "s3://"))}, "s3:GetObject", "s3:PutObject")
+	}
+	if strings.HasPrefix(cfg.revisionsDir, "s3://") {
+		add("--revisions-dir", []string{scope.global("s3", strings.TrimSuffix(strings.TrimPrefix(cfg.revisionsDir, "s3://"), "/")+"/*")}, "s3:GetObject", "s3:PutObject")
+	}
+	for _, dest := range strings.Split(cfg.export, ",") {
+		if dest = strings.TrimSpace(dest); strings.HasPrefix(dest, "s3://") {
+			add("--export "+dest, []string{scope.global("s3", strings.TrimPrefix(dest, "s3://")+"*")}, "s3:PutObject")
+		}
+	}
+
+	var secrets, parameters []string
+	for _, value := range []string{cfg.newRelic.licenseKey, cfg.newRelic.apiKey, cfg.webhookURL, cfg.webhookSecret, cfg.notifySlackWebhook} {
+		switch {
+		case strings.HasPrefix(value, secretsManagerPrefix):
+			// Secret ARNs end in a random suffix the reference may leave out
+			id, _, _ := strings.Cut(strings.TrimPrefix(value, secretsManagerPrefix), "#")
+			if arn.IsARN(id) {
+				secrets = append(secrets, id+"*")
+			} else {
+				secrets = append(secrets, scope.arn("secretsmanager", "secret:"+id+"-??????"))
+			}
+		case strings.HasPrefix(value, ssmPrefix):
+			name := strings.TrimPrefix(value, ssmPrefix)
+			if arn.IsARN(name) {
+				parameters = append(parameters, name)
+			} else {
+				parameters = append(parameters, scope.arn("ssm", "parameter/"+strings.TrimPrefix(name, "/")))
+			}
+		}
+	}
+	if len(secrets) > 0 {
+		add("secretsmanager: credentials", secrets, "secretsmanager:GetSecretValue")
+	}
+	if len(parameters) > 0 {
+		add("ssm: credentials", parameters, "ssm:GetParameter")
+	}
+	return perms
+}
+
+// policyScope builds the resource ARNs of a run: its region, and the account
+// and names of its --arn-file state machines. Without them every state
+// machine in the region, of any account, is in scope.
+type policyScope struct {
+	partition string
+	region    string
+	account   string
+	names     []string // State machine names, empty for all
+	arns      []string
+}
+
+func newPolicyScope(cfg runConfig) policyScope {
+	scope := policyScope{partition: regionPartition(cfg.region), region: cfg.region, account: "*"}
+	for _, smArn := range cfg.stateMachineARNs {
+		parsed, err := arn.Parse(smArn)
+		if err != nil {
+			continue
+		}
+		scope.account = parsed.AccountID
+		scope.arns = append(scope.arns, smArn)
+		scope.names = append(scope.names, strings.TrimPrefix(parsed.Resource, "stateMachine:"))
+	}
+	return scope
+}
+
+// regionPartition returns the partition of a region's ARNs
+func regionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	}
+	return "aws"
+}
+
+func (s policyScope) arn(service, resource string) string {
+	return s.arnIn(service, s.region, resource)
+}
+
+func (s policyScope) arnIn(service, region, resource string) string {
+	return arn.ARN{Partition: s.partition, Service: service, Region: region, AccountID: s.account, Resource: resource}.String()
+}
+
+// global is the ARN of a resource without a region, or an account for S3
+func (s policyScope) global(service, resource string) string {
+	account := s.account
+	if service == "s3" {
+		account = ""
+	}
+	return arn.ARN{Partition: s.partition, Service: service, AccountID: account, Resource: resource}.String()
+}
+
+// named is the ARN of a stream given by name or ARN
+func (s policyScope) named(service, prefix, name string) string {
+	if arn.IsARN(name) {
+		return name
+	}
+	return s.arn(service, prefix+name)
+}
+
+// stateMachines are the state machine ARNs, with every alias and version
+// when the run is limited to one
+func (s policyScope) stateMachines(alias string) []string {
+	arns := s.arns
+	if len(arns) == 0 {
+		arns = []string{s.arn("states", "stateMachine:*")}
+	}
+	if alias == "" {
+		return arns
+	}
+	scoped := append([]string(nil), arns...)
+	for _, smArn := range arns {
+		if !strings.HasSuffix(smArn, "*") {
+			scoped = append(scoped, smArn+":*")
+		}
+	}
+	return scoped
+}
+
+// executions are the Standard and Express execution ARNs of the state machines
+func (s policyScope) executions() []string {
+	if len(s.names) == 0 {
+		return []string{s.arn("states", "execution:*"), s.arn("states", "express:*")}
+	}
+	var arns []string
+	for _, name := range s.names {
+		arns = append(arns, s.arn("states", "execution:"+name+":*"), s.arn("states", "express:"+name+":*"))
+	}
+	return arns
+}
+
+// mapRuns are the Map Run ARNs of the state machines
+func (s policyScope) mapRuns() []string {
+	if len(s.names) == 0 {
+		return []string{s.arn("states", "mapRun:*")}
+	}
+	var arns []string
+	for _, name := range s.names {
+		arns = append(arns, s.arn("states", "mapRun:"+name+"/*"))
+	}
+	return arns
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// processQuery applies a JMESPath expression, as with the AWS CLI's --query,
+// to the fetched state machines (shaped like the stateMachines list of
+// state_machines.json), prints the result as JSON and saves it to query.json.
+// For example, the ARNs of failed executions:
+//
+//	[].Executions[?Status=='FAILED'].ExecutionArn[]
+func processQuery(query *jmespath.JMESPath, stateMachines []stepfunctions.StateMachine, outputDir string) {
+	// Search needs plain maps and slices, so round-trip through JSON
+	data, err := json.Marshal(stateMachines)
+	if err != nil {
+		log.Printf("Failed to marshal state machines for --query: %v", err)
+		return
+	}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		log.Printf("Failed to unmarshal state machines for --query: %v", err)
+		return
+	}
+
+	result, err := query.Search(document)
+	if err != nil {
+		log.Printf("Failed to evaluate --query: %v", err)
+		return
+	}
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Printf("Failed to marshal query result: %v", err)
+		return
+	}
+
+	fmt.Println("Query result:")
+	fmt.Println(string(output))
+	if err := os.WriteFile(filepath.Join(outputDir, "query.json"), output, 0644); err != nil {
+		log.Printf("Failed to save query result: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// redriveResult is the outcome of one execution of a redrive run
+type redriveResult struct {
+	stepfunctions.RedriveCandidate
+	Outcome     string    // "redriven", "failed", "not redrivable" or "pending" in a dry run
+	RedriveDate time.Time `json:",omitempty"`
+	Error       string    `json:",omitempty"`
+}
+
+// runRedrive redrives the redrivable failed, timed out and aborted executions
+// of the given state machines within a window. Without --confirm it only
+// lists them.
+func runRedrive(args []string) {
+	fs := flag.NewFlagSet("redrive", flag.ExitOnError)
+	region := fs.String("region", "", "AWS region (defaults to the region in the state machine ARN)")
+	stateMachineArns := fs.String("state-machine-arn", "", "Comma-separated Standard state machine ARNs whose executions to redrive (required)")
+	since := fs.Duration("since", 24*time.Hour, "Only executions started within this long (redrive is possible for 14 days after an execution closes)")
+	concurrency := fs.Int("concurrency", 4, "Number of RedriveExecution calls in flight")
+	output := fs.String("output", "", "Also write the results as JSON to this file")
+	confirm := fs.Bool("confirm", false, "Redrive the listed executions; without it the command only lists them")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher redrive --state-machine-arn <arn>[,<arn>...] [--since 24h] [--confirm]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	initColor(*noColor)
+	if *stateMachineArns == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *concurrency < 1 {
+		log.Fatalf("Invalid --concurrency %d: must be at least 1", *concurrency)
+	}
+
+	ctx := context.Background()
+	arns := strings.Split(*stateMachineArns, ",")
+	fetcher, err := stepfunctions.NewFetcher(ctx, regionFor(strings.TrimSpace(arns[0]), *region))
+	if err != nil {
+		log.Fatalf("Failed to create fetcher: %v", err)
+	}
+	var results []redriveResult
+	for _, smArn := range arns {
+		candidates, err := fetcher.RedriveCandidates(ctx, strings.TrimSpace(smArn), time.Now().Add(-*since))
+		if err != nil {
+			log.Fatalf("Failed to list executions: %v", err)
+		}
+		for _, candidate := range candidates {
+			result := redriveResult{RedriveCandidate: candidate, Outcome: "pending"}
+			if !candidate.Redrivable {
+				result.Outcome = "not redrivable"
+			}
+			results = append(results, result)
+		}
+	}
+
+	if *confirm {
+		redriveAll(ctx, fetcher, results, *concurrency)
+	}
+	displayRedriveResults(results)
+
+	if *output != "" {
+		data, err := stepfunctions.MarshalDocument(results, "results")
+		if err != nil {
+			log.Fatalf("Failed to marshal results: %v", err)
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			log.Fatalf("Failed to save results: %v", err)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Outcome]++
+	}
+	if !*confirm {
+		fmt.Printf("Dry run: %d execution(s) would be redriven, %d cannot be; re-run with --confirm to redrive them\n", counts["pending"], counts["not redrivable"])
+		return
+	}
+	fmt.Printf("Redrove %d execution(s), %d failed, %d not redrivable\n", counts["redriven"], counts["failed"], counts["not redrivable"])
+	if counts["failed"] > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// redriveAll redrives the pending results with at most concurrency calls in
+// flight, recording each outcome in place
+func redriveAll(ctx context.Context, fetcher *stepfunctions.Fetcher, results []redriveResult, concurrency int) {
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Outcome != "pending" {
+			continue
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(result *redriveResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			redriveDate, err := fetcher.RedriveExecution(ctx, result.RedriveCandidate)
+			if err != nil {
+				result.Outcome, result.Error = "failed", err.Error()
+				return
+			}
+			result.Outcome, result.RedriveDate = "redriven", redriveDate
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+func displayRedriveResults(results []redriveResult) {
+	redriveTable := newTable()
+	redriveTable.SetColumnColor(1, colorStatus)
+	redriveTable.SetHeader([]string{"Execution", "Status", "Started", "Redrives", "Outcome", "Detail"})
+	for _, result := range results {
+		detail := result.Error
+		if result.Outcome == "not redrivable" {
+			detail = result.Reason
+		}
+		redriveTable.Append([]string{
+			result.ExecutionArn,
+			result.Status,
+			formatTime(result.StartTime),
+			fmt.Sprintf("%d", result.RedriveCount),
+			result.Outcome,
+			detail,
+		})
+	}
+	redriveTable.Render()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// runReplay starts a new execution with the input of an exported (usually
+// failed) execution, optionally changed by a JSON merge patch
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	region := fs.String("region", "", "AWS region (defaults to the region in the execution ARN)")
+	patch := fs.String("patch", "", "JSON merge patch (RFC 7386) applied to the input, e.g. '{\"retry\":true}'")
+	patchFile := fs.String("patch-file", "", "File holding the JSON merge patch")
+	stateMachineArn := fs.String("state-machine-arn", "", "Start the execution on this state machine, version or alias instead of the original")
+	name := fs.String("name", "", "Name of the new execution (default generated by Step Functions)")
+	dryRun := fs.Bool("dry-run", false, "Print the input that would be used without starting an execution")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher replay [flags] <execution file or execution-arn>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *patch != "" && *patchFile != "" {
+		log.Fatalf("Use either --patch or --patch-file")
+	}
+
+	ctx := context.Background()
+	detail, fetcher := replaySource(ctx, fs.Arg(0), *region)
+	input := detail.Input
+	if input == "" {
+		var err error
+		if input, err = fetcher.ExecutionInput(ctx, detail.Execution.ExecutionArn); err != nil {
+			log.Fatalf("The input of %s was not exported (use --include-history or fetch-execution) and could not be fetched: %v", detail.Execution.ExecutionArn, err)
+		}
+	}
+
+	if *patchFile != "" {
+		data, err := os.ReadFile(*patchFile)
+		if err != nil {
+			log.Fatalf("Failed to read --patch-file: %v", err)
+		}
+		*patch = string(data)
+	}
+	if *patch != "" {
+		patched, err := stepfunctions.MergePatch([]byte(input), []byte(*patch))
+		if err != nil {
+			log.Fatalf("Failed to patch the input: %v", err)
+		}
+		input = string(patched)
+	}
+
+	target := detail.StateMachineARN
+	if *stateMachineArn != "" {
+		target = *stateMachineArn
+	}
+	fmt.Printf("Replaying:     %s (%s)\n", detail.Execution.ExecutionArn, colorStatus(detail.Execution.Status))
+	fmt.Printf("State machine: %s\n", target)
+	if *dryRun {
+		fmt.Printf("Input:\n%s\n", input)
+		return
+	}
+
+	executionArn, err := fetcher.StartExecution(ctx, target, *name, input)
+	if err != nil {
+		log.Fatalf("Failed to replay execution: %v", err)
+	}
+	fmt.Printf("Started:       %s\n", executionArn)
+	if url := stepfunctions.ExecutionConsoleURL(executionArn); url != "" {
+		fmt.Printf("Console:       %s\n", url)
+	}
+}
+
+// replaySource reads the execution to replay from an exported file or, given
+// an ARN, from AWS, and returns a fetcher for its region
+func replaySource(ctx context.Context, source, region string) (stepfunctions.ExecutionDetail, *stepfunctions.Fetcher) {
+	var detail stepfunctions.ExecutionDetail
+	if !strings.HasPrefix(source, "arn:") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			log.Fatalf("Failed to read execution: %v", err)
+		}
+		if detail, err = stepfunctions.ParseExportedExecution(data); err != nil {
+			log.Fatalf("Failed to load %s: %v", source, err)
+		}
+		source = detail.Execution.ExecutionArn
+	}
+
+	fetcher, err := stepfunctions.NewFetcher(ctx, regionFor(source, region))
+	if err != nil {
+		log.Fatalf("Failed to create fetcher: %v", err)
+	}
+	if detail.Execution.ExecutionArn == "" {
+		if detail, err = fetcher.FetchExecution(ctx, source); err != nil {
+			log.Fatalf("Failed to fetch execution: %v", err)
+		}
+	}
+	return detail, fetcher
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// reportExecutionsShown caps the executions listed per state machine in the report
+const reportExecutionsShown = 25
+
+// writeMarkdownReport writes report.md: the inventory, each state machine's
+// states and executions, and the failure digest, ready to paste into an issue
+// or wiki page.
+func writeMarkdownReport(stateMachines []stepfunctions.StateMachine, outputDir string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Step Functions report\n\nGenerated %s.\n\n", time.Now().UTC().Format(time.RFC3339))
+
+	b.WriteString("## Inventory\n\n")
+	writeMarkdownTable(&b, []string{"Name", "Type", "States", "Executions", "Log Level", "X-Ray", "Created"}, func(row func(...string)) {
+		for _, sm := range stateMachines {
+			name := sm.Name
+			if url := stepfunctions.StateMachineConsoleURL(sm.ARN); url != "" {
+				name = fmt.Sprintf("[%s](%s)", name, url)
+			}
+			row(name, sm.Type, fmt.Sprintf("%d", len(sm.States)), fmt.Sprintf("%d", countExecutions(sm)),
+				sm.Logging.Level, fmt.Sprintf("%v", sm.Tracing), formatTime(sm.CreationDate))
+		}
+	})
+
+	for _, sm := range stateMachines {
+		fmt.Fprintf(&b, "## %s\n\n`%s`\n\n", sm.Name, sm.ARN)
+
+		b.WriteString("### States\n\n")
+		states := append([]stepfunctions.State(nil), sm.States...)
+		sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+		writeMarkdownTable(&b, []string{"State", "Type", "Next", "End"}, func(row func(...string)) {
+			for _, state := range states {
+				row(state.Name, state.Type, state.Next, fmt.Sprintf("%v", state.End))
+			}
+		})
+
+		b.WriteString("### Executions\n\n")
+		byStatus := make(map[string]int)
+		for _, exec := range sm.Executions {
+			if exec.ExecutionArn != "N/A" {
+				byStatus[exec.Status]++
+			}
+		}
+		var statuses []string
+		for status, count := range byStatus {
+			statuses = append(statuses, fmt.Sprintf("%s: %d", status, count))
+		}
+		sort.Strings(statuses)
+		if len(statuses) == 0 {
+			b.WriteString("No executions found.\n\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n\n", strings.Join(statuses, ", "))
+
+		writeMarkdownTable(&b, []string{"Execution", "Status", "Start", "Duration", "Error"}, func(row func(...string)) {
+			for i, exec := range sm.Executions {
+				if i == reportExecutionsShown {
+					row(fmt.Sprintf("... and %d more", len(sm.Executions)-reportExecutionsShown), "", "", "", "")
+					break
+				}
+				name := exec.ExecutionArn[strings.LastIndex(exec.ExecutionArn, ":")+1:]
+				if url := stepfunctions.ExecutionConsoleURL(exec.ExecutionArn); url != "" {
+					name = fmt.Sprintf("[%s](%s)", name, url)
+				}
+				row(name, exec.Status, formatTime(exec.StartTime), exec.FormatDuration(), exec.Error)
+			}
+		})
+	}
+
+	b.WriteString("## Failure digest\n\n")
+	failures := stepfunctions.AggregateFailures(stateMachines)
+	if len(failures) == 0 {
+		b.WriteString("No failed executions.\n")
+	} else {
+		writeMarkdownTable(&b, []string{"State Machine", "State", "Error", "Count", "Example Cause"}, func(row func(...string)) {
+			for _, sig := range failures {
+				cause := ""
+				if len(sig.ExampleCauses) > 0 {
+					cause = sig.ExampleCauses[0]
+				}
+				row(sig.StateMachineName, sig.StateName, sig.Error, fmt.Sprintf("%d", sig.Count), cause)
+			}
+		})
+	}
+
+	path := filepath.Join(outputDir, "report.md")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		log.Printf("Failed to save Markdown report: %v", err)
+		return
+	}
+	fmt.Printf("Markdown report saved to %s\n", path)
+}
+
+func countExecutions(sm stepfunctions.StateMachine) int {
+	count := 0
+	for _, exec := range sm.Executions {
+		if exec.ExecutionArn != "N/A" {
+			count++
+		}
+	}
+	return count
+}
+
+// writeMarkdownTable writes a GitHub-flavored Markdown table; rows adds the
+// rows through the callback it is given
+func writeMarkdownTable(b *strings.Builder, headers []string, rows func(row func(...string))) {
+	fmt.Fprintf(b, "| %s |\n|%s\n", strings.Join(headers, " | "), strings.Repeat(" --- |", len(headers)))
+	rows(func(cells ...string) {
+		for i, cell := range cells {
+			cells[i] = markdownCell(cell)
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+	})
+	b.WriteString("\n")
+}
+
+// markdownCell escapes pipes and flattens newlines so a value stays in its cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+	"stepfunction-fetcher/stepfunctions/export"
+)
+
+// revisionsDir keeps, per state machine, every definition the runs saw, to
+// tell when a workflow changed. It is under the output directory unless
+// --revisions-dir moves it, e.g. to S3 for Lambda runs.
+const revisionsDir = "revisions"
+
+// definitionRevision is one definition of a state machine, saved as File in
+// its revisions directory
+type definitionRevision struct {
+	Hash       string    `json:"hash"`
+	RevisionID string    `json:"revisionId,omitempty"`
+	File       string    `json:"file"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+// processRevisions records each definition in <location>/<name>/, sets when
+// it was first seen, and prints the state machines changed since the last run
+func processRevisions(ctx context.Context, region string, stateMachines []stepfunctions.StateMachine, location string, now time.Time) {
+	for i := range stateMachines {
+		sm := &stateMachines[i]
+		if sm.Definition == "" {
+			continue
+		}
+		previous, err := recordRevision(ctx, region, joinStateLocation(location, export.FileName(sm.Name)), sm, now)
+		if err != nil {
+			log.Printf("Failed to record the definition revision of %s: %v", sm.Name, err)
+			continue
+		}
+		if previous != nil {
+			fmt.Printf("Definition of %s changed since %s (was %.12s, now %.12s)\n", sm.Name, formatTime(previous.LastSeen), previous.Hash, sm.DefinitionHash)
+		}
+	}
+}
+
+// recordRevision adds the definition to the revisions in dir, a local
+// directory or s3:// prefix, unless it is the latest, and returns the revision
+// it replaced, nil when unchanged or new
+func recordRevision(ctx context.Context, region, dir string, sm *stepfunctions.StateMachine, now time.Time) (*definitionRevision, error) {
+	if sm.DefinitionHash == "" {
+		sm.DefinitionHash = stepfunctions.DefinitionHash(sm.Definition)
+	}
+	revisions, err := loadRevisions(ctx, region, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *definitionRevision
+	if n := len(revisions); n > 0 && revisions[n-1].Hash == sm.DefinitionHash {
+		revisions[n-1].LastSeen = now
+	} else {
+		if n > 0 {
+			replaced := revisions[n-1]
+			previous = &replaced
+		}
+		revision := definitionRevision{
+			Hash:       sm.DefinitionHash,
+			RevisionID: sm.RevisionID,
+			File:       fmt.Sprintf("%s_%.12s.asl.json", now.UTC().Format("20060102T150405Z"), sm.DefinitionHash),
+			FirstSeen:  now,
+			LastSeen:   now,
+		}
+		if err := writeStateFile(ctx, region, joinStateLocation(dir, revision.File), []byte(sm.Definition)); err != nil {
+			return nil, fmt.Errorf("failed to save revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	updated := revisions[len(revisions)-1].FirstSeen
+	sm.DefinitionUpdated = &updated
+
+	data, err := stepfunctions.MarshalDocument(revisions, "revisions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal revisions: %w", err)
+	}
+	if err := writeStateFile(ctx, region, joinStateLocation(dir, "revisions.json"), data); err != nil {
+		return nil, fmt.Errorf("failed to save revisions.json: %w", err)
+	}
+	return previous, nil
+}
+
+// loadRevisions reads revisions.json in dir, oldest first; none when missing
+func loadRevisions(ctx context.Context, region, dir string) ([]definitionRevision, error) {
+	data, err := readStateFile(ctx, region, joinStateLocation(dir, "revisions.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revisions.json: %w", err)
+	}
+	if _, err := stepfunctions.DocumentVersion(data); err != nil {
+		return nil, err
+	}
+	var document struct {
+		Revisions []definitionRevision `json:"revisions"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse revisions.json: %w", err)
+	}
+	return document.Revisions, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestRecordRevision(t *testing.T) {
+	useMemoryObjects(t, memoryObjects{})
+	for _, dir := range []string{t.TempDir(), "s3://bucket/runs/revisions/orders"} {
+		t.Run(dir, func(t *testing.T) { testRecordRevision(t, dir) })
+	}
+}
+
+func testRecordRevision(t *testing.T, dir string) {
+	ctx := context.Background()
+	first := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	v1 := `{"StartAt":"A","States":{"A":{"Type":"Succeed"}}}`
+	v2 := `{"StartAt":"A","States":{"A":{"Type":"Pass","End":true}}}`
+
+	record := func(definition string, now time.Time) (stepfunctions.StateMachine, *definitionRevision) {
+		t.Helper()
+		sm := stepfunctions.StateMachine{Name: "orders", Definition: definition}
+		previous, err := recordRevision(ctx, "us-east-1", dir, &sm, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sm, previous
+	}
+
+	updated := func(sm stepfunctions.StateMachine) time.Time {
+		t.Helper()
+		if sm.DefinitionUpdated == nil {
+			t.Fatal("DefinitionUpdated not set")
+		}
+		return *sm.DefinitionUpdated
+	}
+
+	sm, previous := record(v1, first)
+	if previous != nil || !updated(sm).Equal(first) || sm.DefinitionHash != stepfunctions.DefinitionHash(v1) {
+		t.Fatalf("first run: previous %+v, updated %v, hash %s", previous, updated(sm), sm.DefinitionHash)
+	}
+	// Reformatting does not change the normalized definition
+	sm, previous = record("{\n  \"States\": {\"A\": {\"Type\": \"Succeed\"}},\n  \"StartAt\": \"A\"\n}", first.Add(time.Hour))
+	if previous != nil || !updated(sm).Equal(first) {
+		t.Fatalf("unchanged run: previous %+v, updated %v", previous, updated(sm))
+	}
+	sm, previous = record(v2, first.Add(2*time.Hour))
+	if previous == nil || previous.Hash != stepfunctions.DefinitionHash(v1) || !previous.LastSeen.Equal(first.Add(time.Hour)) {
+		t.Fatalf("changed run: previous %+v", previous)
+	}
+	if !updated(sm).Equal(first.Add(2 * time.Hour)) {
+		t.Errorf("DefinitionUpdated = %v, want the changed run", updated(sm))
+	}
+
+	revisions, err := loadRevisions(ctx, "us-east-1", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(revisions))
+	}
+	for i, want := range []string{v1, v2} {
+		data, err := readStateFile(ctx, "us-east-1", joinStateLocation(dir, revisions[i].File))
+		if err != nil || string(data) != want {
+			t.Errorf("revision %d file = %q, %v; want %q", i, data, err, want)
+		}
+	}
+}
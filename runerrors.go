@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// Exit codes, so wrappers can branch on the outcome of a run
+const (
+	exitOK          = 0 // Everything fetched and all checks passed
+	exitFatal       = 1 // Configuration or credential problem; nothing useful fetched
+	exitPartial     = 2 // Some state machines, executions or analyses were skipped
+	exitCheckFailed = 3 // SLA or validation failures (takes precedence over exitPartial)
+)
+
+// runErrors collects what a run skipped because of an error. With failFast
+// the first error ends the run; errors.json is written either way, and
+// run.json too when the run stops early.
+type runErrors struct {
+	failFast  bool
+	outputDir string
+	items     []stepfunctions.SkippedItem
+	meta      *runMetadata           // Saved with the exit code a run stops with; nil skips run.json
+	fetcher   *stepfunctions.Fetcher // For the API calls in run.json, once created
+}
+
+// runStopped is the error that ends a run early, with its exit code. run
+// returns it instead of exiting, so the Lambda handler can still upload
+// what was written.
+type runStopped struct {
+	code int
+	err  error
+}
+
+func (e *runStopped) Error() string { return e.err.Error() }
+func (e *runStopped) Unwrap() error { return e.err }
+
+// stopped returns the exit code and error run ends with for err
+func stopped(err error) (int, error) {
+	var stop *runStopped
+	if errors.As(err, &stop) {
+		return stop.code, err
+	}
+	return exitFatal, err
+}
+
+// record adds a skipped item; callers log the failure themselves. With
+// failFast it returns the error that ends the run, which callers pass up.
+func (r *runErrors) record(resource string, err error) error {
+	r.add(resource, err)
+	if r.failFast {
+		return r.stopFailFast(err)
+	}
+	return nil
+}
+
+// stopFailFast ends a --fail-fast run after its first error: exitFatal when
+// access was denied, otherwise the partial-result exitPartial
+func (r *runErrors) stopFailFast(err error) error {
+	r.save()
+	code := exitPartial
+	if errors.Is(err, stepfunctions.ErrAccessDenied) {
+		code = exitFatal
+	}
+	r.saveMeta(code)
+	return &runStopped{code: code, err: fmt.Errorf("stopped after the first error (--fail-fast): %w", err)}
+}
+
+// fatal records an error that ends the run in either mode, with exitFatal.
+// An error the fetcher failed fast on is already recorded through
+// addFetcher and ends the run like record does.
+func (r *runErrors) fatal(resource, message string, err error) error {
+	if errors.Is(err, stepfunctions.ErrFailedFast) {
+		return r.stopFailFast(err)
+	}
+	r.add(resource, err)
+	r.save()
+	r.saveMeta(exitFatal)
+	return &runStopped{code: exitFatal, err: fmt.Errorf("%s (%s): %w", message, stepfunctions.ErrorKind(err), err)}
+}
+
+func (r *runErrors) add(resource string, err error) {
+	r.items = append(r.items, stepfunctions.SkippedItem{
+		Resource: resource,
+		Kind:     stepfunctions.ErrorKind(err),
+		Error:    err.Error(),
+	})
+}
+
+// addFetcher merges in what the fetcher skipped
+func (r *runErrors) addFetcher(fetcher *stepfunctions.Fetcher) {
+	r.fetcher = fetcher
+	r.items = append(r.items, fetcher.Skipped()...)
+}
+
+// saveMeta writes run.json for a run stopping early with code
+func (r *runErrors) saveMeta(code int) {
+	if r.meta == nil {
+		return
+	}
+	r.meta.finish(r.fetcher, nil, code)
+	r.meta.save(r.outputDir)
+}
+
+// save writes errors.json, an empty list when nothing was skipped
+func (r *runErrors) save() {
+	items := r.items
+	if items == nil {
+		items = []stepfunctions.SkippedItem{}
+	}
+	data, err := stepfunctions.MarshalDocument(items, "errors")
+	if err != nil {
+		log.Printf("Failed to marshal errors: %v", err)
+		return
+	}
+	if err := os.MkdirAll(r.outputDir, 0755); err != nil {
+		log.Printf("Failed to create output directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(r.outputDir, "errors.json"), data, 0644); err != nil {
+		log.Printf("Failed to save errors: %v", err)
+		return
+	}
+	if len(r.items) > 0 {
+		fmt.Printf("%d item(s) skipped because of errors; see %s\n", len(r.items), filepath.Join(r.outputDir, "errors.json"))
+	}
+}
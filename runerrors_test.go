@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestRunErrorsFailFast(t *testing.T) {
+	errs := &runErrors{failFast: true, outputDir: t.TempDir()}
+	stop := errs.record("arn:aws:states:us-east-1:123456789012:stateMachine:a", errors.New("boom"))
+	if code, err := stopped(stop); code != exitPartial || err == nil {
+		t.Errorf("stopped(record) = %d, %v, want exitPartial", code, err)
+	}
+
+	denied := fmt.Errorf("describe: %w", stepfunctions.ErrAccessDenied)
+	if code, _ := stopped(errs.record("b", denied)); code != exitFatal {
+		t.Errorf("access denied stopped with %d, want exitFatal", code)
+	}
+
+	errs = &runErrors{outputDir: t.TempDir()}
+	if stop := errs.record("c", errors.New("boom")); stop != nil {
+		t.Errorf("record without fail-fast = %v, want nil", stop)
+	}
+}
+
+func TestRunErrorsFatalFailedFast(t *testing.T) {
+	// The fetcher already recorded the item it failed fast on
+	errs := &runErrors{failFast: true, outputDir: t.TempDir()}
+	err := fmt.Errorf("list: %w", errors.Join(stepfunctions.ErrFailedFast, errors.New("boom")))
+	code, stop := stopped(errs.fatal("state machines", "Failed to list state machines", err))
+	if code != exitPartial || stop == nil {
+		t.Errorf("stopped(fatal) = %d, %v, want exitPartial", code, stop)
+	}
+	if len(errs.items) != 0 {
+		t.Errorf("got %d items, want the fetcher's item not recorded again", len(errs.items))
+	}
+
+	code, _ = stopped(errs.fatal("config", "Failed to create fetcher", errors.New("no region")))
+	if code != exitFatal || len(errs.items) != 1 {
+		t.Errorf("fatal = %d with %d items, want exitFatal with 1", code, len(errs.items))
+	}
+}
+
+func TestRunErrorsStopSavesRunMetadata(t *testing.T) {
+	dir := t.TempDir()
+	meta := &runMetadata{Version: "v1.2.0", region: "us-east-1"}
+	errs := &runErrors{outputDir: dir, meta: meta}
+	errs.fatal("caller", "Wrong AWS account", errors.New("credentials resolve to account 111111111111"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "run.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved runMetadata
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.ExitCode != exitFatal || saved.FinishedAt.IsZero() || len(saved.Regions) != 1 || saved.Regions[0] != "us-east-1" {
+		t.Errorf("got run.json %s", data)
+	}
+}
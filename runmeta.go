@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// runMetadata is run.json: what produced an output directory, so an export
+// can be audited and reproduced
+type runMetadata struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+	// Settings are the flags set on the command line, or the variables a
+	// Lambda run read, with credentials redacted
+	Settings            map[string]string `json:"settings"`
+	Caller              *callerIdentity   `json:"caller,omitempty"`
+	CallerError         string            `json:"callerError,omitempty"`
+	Regions             []string          `json:"regions"`
+	StartedAt           time.Time         `json:"startedAt"`
+	FinishedAt          time.Time         `json:"finishedAt"`
+	ExitCode            int               `json:"exitCode"`
+	APICalls            int               `json:"apiCalls"`
+	APICallsByOperation map[string]int    `json:"apiCallsByOperation"`
+
+	region string // The run's region, always among Regions
+}
+
+// sensitiveSettings are the flags and Lambda variables holding credentials
+var sensitiveSettings = map[string]bool{
+	"nr-license-key":       true,
+	"nr-api-key":           true,
+	"webhook-url":          true,
+	"webhook-secret":       true,
+	"notify-slack-webhook": true,
+	"WEBHOOK_URL":          true,
+	"WEBHOOK_SECRET":       true,
+	"NOTIFY_SLACK_WEBHOOK": true,
+}
+
+// newRunMetadata starts the metadata of a run; setCaller adds who ran it
+func newRunMetadata(cfg runConfig) *runMetadata {
+	v, rev, _ := buildInfo()
+	meta := &runMetadata{
+		Version:   v,
+		Commit:    rev,
+		GoVersion: runtime.Version(),
+		Settings:  make(map[string]string, len(cfg.settings)),
+		StartedAt: time.Now().UTC(),
+		region:    cfg.region,
+	}
+	for name, value := range cfg.settings {
+		meta.Settings[name] = redactSetting(name, value)
+	}
+	return meta
+}
+
+// setCaller records the caller identity, or the error looking it up
+func (m *runMetadata) setCaller(identity *callerIdentity, identityErr error) {
+	m.Caller = identity
+	if identityErr != nil {
+		m.CallerError = identityErr.Error()
+	}
+}
+
+// redactSetting hides credentials; secretsmanager: and ssm: references are
+// kept, as they only name where the credential is
+func redactSetting(name, value string) string {
+	switch {
+	case value == "" || strings.HasPrefix(value, secretsManagerPrefix) || strings.HasPrefix(value, ssmPrefix):
+		return value
+	case sensitiveSettings[name]:
+		return "REDACTED"
+	case name == "proxy":
+		if parsed, err := url.Parse(value); err == nil {
+			return parsed.Redacted()
+		}
+	}
+	return value
+}
+
+// finish records the outcome: the regions the state machines are in, and the
+// API calls the fetcher made. fetcher is nil when the run stopped before
+// creating it.
+func (m *runMetadata) finish(fetcher *stepfunctions.Fetcher, stateMachines []stepfunctions.StateMachine, exitCode int) {
+	m.FinishedAt = time.Now().UTC()
+	m.ExitCode = exitCode
+	m.APICallsByOperation = map[string]int{}
+	if fetcher != nil {
+		m.APICalls = fetcher.APICalls()
+		m.APICallsByOperation = fetcher.APICallsByOperation()
+	}
+
+	regions := map[string]bool{m.region: true}
+	for _, sm := range stateMachines {
+		if parsed, err := arn.Parse(sm.ARN); err == nil {
+			regions[parsed.Region] = true
+		}
+	}
+	m.Regions = make([]string, 0, len(regions))
+	for region := range regions {
+		m.Regions = append(m.Regions, region)
+	}
+	sort.Strings(m.Regions)
+}
+
+// save writes run.json to the output directory
+func (m *runMetadata) save(outputDir string) {
+	data, err := stepfunctions.MarshalDocument(m, "")
+	if err != nil {
+		log.Printf("Failed to marshal run metadata: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "run.json"), data, 0644); err != nil {
+		log.Printf("Failed to save run metadata: %v", err)
+		return
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Credentials such as the New Relic keys and webhook secrets can be given as
+// references, read when the run starts so the keys never land in CI
+// configuration or shell history:
+//
+//	secretsmanager:<name or ARN>        the string value of a Secrets Manager secret
+//	secretsmanager:<name or ARN>#<key>  one key of a secret holding a JSON object
+//	ssm:<name or ARN>                   a Parameter Store parameter, decrypted
+const (
+	secretsManagerPrefix = "secretsmanager:"
+	ssmPrefix            = "ssm:"
+)
+
+// secretsManagerAPI is the part of the Secrets Manager client that secret
+// references use
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// parameterAPI is the part of the SSM client that parameter references use
+type parameterAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// newSecretsManagerClient and newParameterClient create the clients for
+// secret references; tests replace them
+var (
+	newSecretsManagerClient = func(ctx context.Context, region string) (secretsManagerAPI, error) {
+		cfg, err := loadAWSConfig(ctx, region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		return secretsmanager.NewFromConfig(cfg), nil
+	}
+	newParameterClient = func(ctx context.Context, region string) (parameterAPI, error) {
+		cfg, err := loadAWSConfig(ctx, region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		return ssm.NewFromConfig(cfg), nil
+	}
+)
+
+// resolveSecret returns value, or the secret it references. Names are looked
+// up in region; ARNs carry their own.
+func resolveSecret(ctx context.Context, region, value string) (string, error) {
+	var id string
+	switch {
+	case strings.HasPrefix(value, secretsManagerPrefix):
+		id = strings.TrimPrefix(value, secretsManagerPrefix)
+	case strings.HasPrefix(value, ssmPrefix):
+		id = strings.TrimPrefix(value, ssmPrefix)
+	default:
+		return value, nil
+	}
+	if strings.HasPrefix(value, ssmPrefix) {
+		return readParameter(ctx, regionOf(id, region), id)
+	}
+	id, key, hasKey := strings.Cut(id, "#")
+	secret, err := readSecret(ctx, regionOf(id, region), id)
+	if err != nil || !hasKey {
+		return secret, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, so it has no key %q", id, key)
+	}
+	field, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no string key %q", id, key)
+	}
+	return strings.TrimSpace(field), nil
+}
+
+// regionOf returns the region of an ARN, or region for a plain name
+func regionOf(id, region string) string {
+	if parsed, err := arn.Parse(id); err == nil {
+		return parsed.Region
+	}
+	return region
+}
+
+func readSecret(ctx context.Context, region, id string) (string, error) {
+	client, err := newSecretsManagerClient(ctx, region)
+	if err != nil {
+		return "", err
+	}
+	result, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", id, err)
+	}
+	if result.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", id)
+	}
+	return strings.TrimSpace(*result.SecretString), nil
+}
+
+func readParameter(ctx context.Context, region, id string) (string, error) {
+	client, err := newParameterClient(ctx, region)
+	if err != nil {
+		return "", err
+	}
+	result, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(id), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return "", fmt.Errorf("failed to read parameter %s: %w", id, err)
+	}
+	if result.Parameter == nil || result.Parameter.Value == nil {
+		return "", fmt.Errorf("parameter %s has no value", id)
+	}
+	return strings.TrimSpace(*result.Parameter.Value), nil
+}
+
+// resolveSecrets resolves every value in place
+func resolveSecrets(ctx context.Context, region string, values ...*string) error {
+	for _, value := range values {
+		resolved, err := resolveSecret(ctx, region, *value)
+		if err != nil {
+			return err
+		}
+		*value = resolved
+	}
+	return nil
+}
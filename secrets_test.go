@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+func TestResolveSecretsPassesPlainValues(t *testing.T) {
+	key, url, empty := "NRAK-123", "https://hooks.slack.com/services/T/B/x", ""
+	if err := resolveSecrets(context.Background(), "us-east-1", &key, &url, &empty); err != nil {
+		t.Fatal(err)
+	}
+	if key != "NRAK-123" || url != "https://hooks.slack.com/services/T/B/x" || empty != "" {
+		t.Errorf("values changed: %q %q %q", key, url, empty)
+	}
+}
+
+// fakeSecrets serves Secrets Manager secrets and SSM parameters by ID and
+// records the region each client was created for
+type fakeSecrets struct {
+	secrets    map[string]string
+	parameters map[string]string
+	regions    []string
+}
+
+func (f *fakeSecrets) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	value, ok := f.secrets[aws.ToString(params.SecretId)]
+	if !ok {
+		return nil, &smtypes.ResourceNotFoundException{Message: aws.String("secret not found")}
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(value)}, nil
+}
+
+func (f *fakeSecrets) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	if !aws.ToBool(params.WithDecryption) {
+		return nil, errors.New("parameter read without decryption")
+	}
+	value, ok := f.parameters[aws.ToString(params.Name)]
+	if !ok {
+		return nil, &ssmtypes.ParameterNotFound{Message: aws.String("parameter not found")}
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Value: aws.String(value)}}, nil
+}
+
+// useFakeSecrets makes secret references read from fake for the test
+func useFakeSecrets(t *testing.T, fake *fakeSecrets) {
+	previousSecrets, previousParameters := newSecretsManagerClient, newParameterClient
+	newSecretsManagerClient = func(ctx context.Context, region string) (secretsManagerAPI, error) {
+		fake.regions = append(fake.regions, region)
+		return fake, nil
+	}
+	newParameterClient = func(ctx context.Context, region string) (parameterAPI, error) {
+		fake.regions = append(fake.regions, region)
+		return fake, nil
+	}
+	t.Cleanup(func() { newSecretsManagerClient, newParameterClient = previousSecrets, previousParameters })
+}
+
+func TestResolveSecret(t *testing.T) {
+	secretArn := "arn:aws:secretsmanager:eu-west-1:123456789012:secret:nr-keys-AbCdEf"
+	useFakeSecrets(t, &fakeSecrets{
+		secrets: map[string]string{
+			"nr-license": " LICENSE\n",
+			secretArn:    `{"licenseKey": "FROM-JSON", "port": 443}`,
+		},
+		parameters: map[string]string{"/nr/api-key": "NRAK-456"},
+	})
+	ctx := context.Background()
+
+	for value, want := range map[string]string{
+		"secretsmanager:nr-license":                   "LICENSE",
+		"secretsmanager:" + secretArn + "#licenseKey": "FROM-JSON",
+		"ssm:/nr/api-key":                             "NRAK-456",
+	} {
+		if got, err := resolveSecret(ctx, "us-east-1", value); err != nil || got != want {
+			t.Errorf("resolveSecret(%s) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{
+		"secretsmanager:missing",
+		"secretsmanager:nr-license#licenseKey",  // Not JSON
+		"secretsmanager:" + secretArn + "#port", // Not a string
+		"secretsmanager:" + secretArn + "#absent",
+		"ssm:/nr/missing",
+	} {
+		if got, err := resolveSecret(ctx, "us-east-1", value); err == nil {
+			t.Errorf("resolveSecret(%s) = %q, want an error", value, got)
+		}
+	}
+}
+
+func TestResolveSecretRegion(t *testing.T) {
+	fake := &fakeSecrets{parameters: map[string]string{
+		"key": "a",
+		"arn:aws:ssm:ap-south-1:123456789012:parameter/key": "b",
+	}}
+	useFakeSecrets(t, fake)
+	ctx := context.Background()
+	resolveSecret(ctx, "us-east-1", "ssm:key")
+	resolveSecret(ctx, "us-east-1", "ssm:arn:aws:ssm:ap-south-1:123456789012:parameter/key")
+	if strings.Join(fake.regions, ",") != "us-east-1,ap-south-1" {
+		t.Errorf("got client regions %v, want the run's region for names and the ARN's region for ARNs", fake.regions)
+	}
+}
+
+func TestResolveSecretsStopsAtFirstError(t *testing.T) {
+	useFakeSecrets(t, &fakeSecrets{parameters: map[string]string{"/nr/api-key": "NRAK-456"}})
+	key, missing := "ssm:/nr/api-key", "ssm:/nr/missing"
+	if err := resolveSecrets(context.Background(), "us-east-1", &key, &missing); err == nil || !strings.Contains(err.Error(), "/nr/missing") {
+		t.Errorf("got %v, want the missing parameter named", err)
+	}
+	if key != "NRAK-456" || missing != "ssm:/nr/missing" {
+		t.Errorf("got %q %q, want the first resolved and the failed one left as given", key, missing)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// checkSLA evaluates the SLA config against the fetched executions, prints and
+// saves the violations, and reports whether every threshold was met
+func checkSLA(cfg stepfunctions.SLAConfig, stateMachines []stepfunctions.StateMachine, outputDir string) bool {
+	violations := stepfunctions.EvaluateSLA(cfg, stateMachines)
+	if len(violations) == 0 {
+		fmt.Println("SLA check passed.")
+		return true
+	}
+
+	slaTable := newTable()
+	slaTable.SetColumnColor(3, colorViolation)
+	slaTable.SetHeader([]string{"State Machine", "Check", "Threshold", "Actual"})
+	for _, v := range violations {
+		slaTable.Append([]string{v.StateMachineName, v.Check, v.Threshold, v.Actual})
+	}
+	fmt.Println("SLA Violations:")
+	slaTable.Render()
+
+	data, err := stepfunctions.MarshalDocument(violations, "violations")
+	if err != nil {
+		log.Printf("Failed to marshal SLA violations: %v", err)
+		return false
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "sla_violations.json"), data, 0644); err != nil {
+		log.Printf("Failed to save SLA violations: %v", err)
+	}
+	return false
+}
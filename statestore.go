@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// stateObjectAPI is the part of the S3 client that state files and the s3://
+// export use
+type stateObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// newStateObjectClient creates the client for s3:// state files and the
+// s3:// export; tests replace it
+var newStateObjectClient = func(ctx context.Context, region string) (stateObjectAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return s3.NewFromConfig(awsCfg), nil
+}
+
+// readStateFile reads a file kept between runs, such as the notification
+// state or definition revisions, from a local path or an s3://bucket/key location. A missing file
+// returns an error matching fs.ErrNotExist.
+func readStateFile(ctx context.Context, region, location string) ([]byte, error) {
+	bucket, key, ok := parseS3Location(location)
+	if !ok {
+		return os.ReadFile(location)
+	}
+	client, err := newStateObjectClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%s: %w", location, fs.ErrNotExist)
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+// writeStateFile writes a file for readStateFile to read in the next run,
+// creating the directories of a local path
+func writeStateFile(ctx context.Context, region, location string, data []byte) error {
+	bucket, key, ok := parseS3Location(location)
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(location, data, 0644)
+	}
+	client, err := newStateObjectClient(ctx, region)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: bytes.NewReader(data)})
+	return err
+}
+
+// joinStateLocation joins names onto a local directory or s3://bucket/prefix
+func joinStateLocation(location string, names ...string) string {
+	if rest, ok := strings.CutPrefix(location, "s3://"); ok {
+		return "s3://" + path.Join(append([]string{rest}, names...)...)
+	}
+	return filepath.Join(append([]string{location}, names...)...)
+}
+
+// parseS3Location splits s3://bucket/key; ok is false for local paths
+func parseS3Location(location string) (bucket, key string, ok bool) {
+	if !strings.HasPrefix(location, "s3://") {
+		return "", "", false
+	}
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	return bucket, key, true
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// memoryObjects is an in-memory S3 bucket
+type memoryObjects map[string][]byte
+
+func (m memoryObjects) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := m[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (m memoryObjects) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	m[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = data
+	return &s3.PutObjectOutput{}, err
+}
+
+// useMemoryObjects makes s3:// state files use objects for the test
+func useMemoryObjects(t *testing.T, objects memoryObjects) {
+	previous := newStateObjectClient
+	newStateObjectClient = func(context.Context, string) (stateObjectAPI, error) { return objects, nil }
+	t.Cleanup(func() { newStateObjectClient = previous })
+}
+
+func TestStateFile(t *testing.T) {
+	objects := memoryObjects{}
+	useMemoryObjects(t, objects)
+	ctx := context.Background()
+
+	for _, location := range []string{filepath.Join(t.TempDir(), "state.json"), "s3://bucket/runs/state.json"} {
+		if _, err := readStateFile(ctx, "us-east-1", location); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("readStateFile(%s) before writing = %v, want fs.ErrNotExist", location, err)
+		}
+		if err := writeStateFile(ctx, "us-east-1", location, []byte(`["a"]`)); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := readStateFile(ctx, "us-east-1", location); err != nil || string(data) != `["a"]` {
+			t.Errorf("readStateFile(%s) = %s, %v", location, data, err)
+		}
+	}
+	if _, ok := objects["bucket/runs/state.json"]; !ok {
+		t.Errorf("got objects %v, want bucket/runs/state.json", objects)
+	}
+}
+
+func TestNotifiedStateInS3(t *testing.T) {
+	objects := memoryObjects{}
+	useMemoryObjects(t, objects)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := saveNotified(ctx, "us-east-1", "s3://bucket/notified_failures.json", map[string]time.Time{"arn:exec:1": now}); err != nil {
+		t.Fatal(err)
+	}
+	notified, err := loadNotified(ctx, "us-east-1", "s3://bucket/notified_failures.json", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notified["arn:exec:1"].Equal(now) {
+		t.Errorf("got %v, want the saved ARN", notified)
+	}
+
+	objects["bucket/legacy.json"] = []byte(`["arn:exec:2"]`)
+	if notified, err := loadNotified(ctx, "us-east-1", "s3://bucket/legacy.json", now); err != nil || !notified["arn:exec:2"].Equal(now) {
+		t.Errorf("got %v, %v, want the listed ARN seen now", notified, err)
+	}
+	if notified, err := loadNotified(ctx, "us-east-1", "s3://bucket/missing.json", now); err != nil || len(notified) != 0 {
+		t.Errorf("got %v, %v, want an empty state for a missing file", notified, err)
+	}
+	objects["bucket/corrupt.json"] = []byte(`{`)
+	if _, err := loadNotified(ctx, "us-east-1", "s3://bucket/corrupt.json", now); err == nil {
+		t.Error("got no error, want an error for an unreadable state")
+	}
+}
+
+func TestMergeNotified(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	notified := map[string]time.Time{
+		"arn:exec:recent": now.Add(-time.Hour),
+		"arn:exec:old":    now.Add(-notifiedRetention),
+		"arn:exec:again":  now.Add(-notifiedRetention),
+	}
+	merged := mergeNotified(notified, []string{"arn:exec:new", "arn:exec:again"}, now)
+	want := map[string]time.Time{
+		"arn:exec:recent": now.Add(-time.Hour),
+		"arn:exec:new":    now,
+		"arn:exec:again":  now,
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("got %v, want %v", merged, want)
+	}
+	for executionArn, seen := range want {
+		if !merged[executionArn].Equal(seen) {
+			t.Errorf("%s: got %v, want %v", executionArn, merged[executionArn], seen)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// List prices (us-east-1) used for the Standard vs Express estimate
+const (
+	standardPricePerTransition = 0.000025   // $0.025 per 1,000 state transitions
+	expressPricePerRequest     = 0.000001   // $1.00 per 1M requests
+	expressPricePerGBSecond    = 0.00001667 // First 1,000 hours GB-second tier
+	expressMaxDuration         = 5 * time.Minute
+	expressMemoryChunkBytes    = 64 * 1024 * 1024
+	expressBillingIncrement    = 100 * time.Millisecond
+)
+
+// WorkflowTypeAdvice estimates what the observed executions would have cost on
+// each workflow type and recommends a switch when the other type is cheaper.
+type WorkflowTypeAdvice struct {
+	StateMachineName        string
+	StateMachineARN         string
+	CurrentType             string
+	RecommendedType         string
+	Executions              int
+	AvgDurationSeconds      float64
+	MaxDurationSeconds      float64
+	AvgPayloadBytes         int
+	TransitionsPerExecution float64
+	StandardCost            float64  // Estimated USD for the observed executions
+	ExpressCost             float64  // Estimated USD for the observed executions
+	CostDelta               float64  // Savings from switching to RecommendedType
+	Blockers                []string // Reasons the workflow cannot run as Express
+}
+
+// AdviseWorkflowType compares the estimated Standard and Express cost of the
+// completed executions of a state machine. It returns false when there is
+// nothing to base an estimate on.
+func AdviseWorkflowType(sm StateMachine) (WorkflowTypeAdvice, bool) {
+	advice := WorkflowTypeAdvice{
+		StateMachineName: sm.Name,
+		StateMachineARN:  sm.ARN,
+		CurrentType:      sm.Type,
+		RecommendedType:  sm.Type,
+		Blockers:         expressBlockers(sm),
+	}
+
+	var totalDuration, maxDuration time.Duration
+	var totalPayload int
+	var totalTransitions float64
+	for _, exec := range sm.Executions {
+		if !exec.DurationKnown() {
+			continue // Running or placeholder executions
+		}
+		duration := exec.Duration
+		advice.Executions++
+		totalDuration += duration
+		totalPayload += exec.InputSize + exec.OutputSize
+		if duration > maxDuration {
+			maxDuration = duration
+		}
+
+		transitions := estimatedTransitions(sm, exec)
+		totalTransitions += transitions
+		advice.StandardCost += transitions * standardPricePerTransition
+		advice.ExpressCost += expressExecutionCost(duration, exec.InputSize+exec.OutputSize)
+	}
+	if advice.Executions == 0 {
+		return advice, false
+	}
+
+	advice.AvgDurationSeconds = totalDuration.Seconds() / float64(advice.Executions)
+	advice.MaxDurationSeconds = maxDuration.Seconds()
+	advice.AvgPayloadBytes = totalPayload / advice.Executions
+	advice.TransitionsPerExecution = totalTransitions / float64(advice.Executions)
+	if maxDuration > expressMaxDuration {
+		advice.Blockers = append(advice.Blockers, fmt.Sprintf("longest execution (%v) exceeds the Express limit of %v", maxDuration, expressMaxDuration))
+	}
+
+	switch {
+	case sm.Type == "STANDARD" && len(advice.Blockers) == 0 && advice.ExpressCost < advice.StandardCost:
+		advice.RecommendedType = "EXPRESS"
+		advice.CostDelta = advice.StandardCost - advice.ExpressCost
+	case sm.Type == "EXPRESS" && advice.StandardCost < advice.ExpressCost:
+		advice.RecommendedType = "STANDARD"
+		advice.CostDelta = advice.ExpressCost - advice.StandardCost
+	}
+
+	return advice, true
+}
+
+// estimatedTransitions returns the transitions counted from the execution's
+// history, falling back to the number of top-level states in the definition.
+func estimatedTransitions(sm StateMachine, exec Execution) float64 {
+	if exec.Transitions > 0 {
+		return float64(exec.Transitions)
+	}
+	return float64(len(sm.States))
+}
+
+// expressExecutionCost prices one Express execution: a request charge plus
+// duration billed in 100ms increments at memory sized in 64MB payload chunks.
+func expressExecutionCost(duration time.Duration, payloadBytes int) float64 {
+	increments := math.Ceil(float64(duration) / float64(expressBillingIncrement))
+	billedSeconds := math.Max(increments, 1) * expressBillingIncrement.Seconds()
+	chunks := math.Max(math.Ceil(float64(payloadBytes)/expressMemoryChunkBytes), 1)
+	memoryGB := chunks * expressMemoryChunkBytes / (1024 * 1024 * 1024)
+	return expressPricePerRequest + billedSeconds*memoryGB*expressPricePerGBSecond
+}
+
+// expressBlockers lists definition features that only Standard workflows support
+func expressBlockers(sm StateMachine) []string {
+	var blockers []string
+	seen := make(map[string]bool)
+	walkStates(sm.Definition, func(name string, rawDef map[string]interface{}) {
+		resource, _ := rawDef["Resource"].(string)
+		for _, pattern := range []string{".sync", ".waitForTaskToken"} {
+			if strings.Contains(resource, pattern) && !seen[pattern] {
+				seen[pattern] = true
+				blockers = append(blockers, fmt.Sprintf("state %q uses the %s integration pattern", name, pattern))
+			}
+		}
+		if processor, ok := rawDef["ItemProcessor"].(map[string]interface{}); ok && !seen["DISTRIBUTED"] {
+			config, _ := processor["ProcessorConfig"].(map[string]interface{})
+			if mode, _ := config["Mode"].(string); mode == "DISTRIBUTED" {
+				seen["DISTRIBUTED"] = true
+				blockers = append(blockers, fmt.Sprintf("state %q is a Distributed Map", name))
+			}
+		}
+	})
+	return blockers
+}
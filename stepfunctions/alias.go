@@ -0,0 +1,33 @@
+package stepfunctions
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// WithAlias limits Standard executions to those started through an alias
+// (e.g. "PROD") or a version (e.g. "3") of each state machine. State machines
+// without that alias or version have no executions in scope.
+func WithAlias(alias string) Option {
+	return func(f *Fetcher) {
+		f.alias = alias
+	}
+}
+
+// executionScope is the ARN ListExecutions is called with: the alias or
+// version ARN when WithAlias is set, otherwise the state machine itself
+func (f *Fetcher) executionScope(stateMachineArn string) string {
+	if f.alias == "" {
+		return stateMachineArn
+	}
+	return stateMachineArn + ":" + f.alias
+}
+
+// isMissingScope reports whether ListExecutions failed because the alias or
+// version does not exist
+func isMissingScope(err error) bool {
+	var notFound *types.ResourceNotFound
+	var noStateMachine *types.StateMachineDoesNotExist
+	return errors.As(err, &notFound) || errors.As(err, &noStateMachine)
+}
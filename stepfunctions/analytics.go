@@ -0,0 +1,50 @@
+package stepfunctions
+
+import "time"
+
+// AnalyticsReport aggregates the analyses computed over the fetched state machines
+type AnalyticsReport struct {
+	GeneratedAt        string
+	WorkflowTypeAdvice []WorkflowTypeAdvice
+	TransitionStats    []TransitionStats
+	TopErrors          []FailureSignature
+	RetryStats         []RetryStats
+	ChoiceStats        []ChoiceStats
+	IdleTime           []IdleTimeStats
+	Heartbeats         []HeartbeatStats
+	ResourceLatency    []ResourceLatency
+	PayloadSizes       []PayloadStats
+	S3Offload          []OffloadCandidate
+}
+
+// BuildAnalyticsReport runs every analysis over the fetched state machines
+func BuildAnalyticsReport(stateMachines []StateMachine) AnalyticsReport {
+	report := AnalyticsReport{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}
+
+	for _, sm := range stateMachines {
+		if advice, ok := AdviseWorkflowType(sm); ok {
+			report.WorkflowTypeAdvice = append(report.WorkflowTypeAdvice, advice)
+		}
+		if stats, ok := ComputeTransitionStats(sm); ok {
+			report.TransitionStats = append(report.TransitionStats, stats)
+		}
+		report.RetryStats = append(report.RetryStats, ComputeRetryStats(sm)...)
+		report.ChoiceStats = append(report.ChoiceStats, ComputeChoiceStats(sm)...)
+		report.Heartbeats = append(report.Heartbeats, ComputeHeartbeatStats(sm)...)
+		payloads := ComputePayloadStats(sm)
+		report.PayloadSizes = append(report.PayloadSizes, payloads...)
+		if candidate, ok := AdviseOffload(sm, payloads); ok {
+			report.S3Offload = append(report.S3Offload, candidate)
+		}
+		if stats, ok := ComputeIdleTime(sm); ok {
+			report.IdleTime = append(report.IdleTime, stats)
+		}
+	}
+
+	report.TopErrors = AggregateFailures(stateMachines)
+	report.ResourceLatency = ComputeResourceLatency(stateMachines)
+
+	return report
+}
@@ -0,0 +1,41 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// walkStates calls fn for every state in the definition, including states
+// nested inside Parallel branches and Map iterators.
+func walkStates(definition string, fn func(name string, rawDef map[string]interface{})) error {
+	var root map[string]interface{}
+	if err := json.Unmarshal([]byte(definition), &root); err != nil {
+		return &classifiedError{kind: ErrDefinitionParse, err: fmt.Errorf("failed to unmarshal ASL definition: %w", err)}
+	}
+	walkStateMap(root, fn)
+	return nil
+}
+
+func walkStateMap(machine map[string]interface{}, fn func(name string, rawDef map[string]interface{})) {
+	states, _ := machine["States"].(map[string]interface{})
+	for name, raw := range states {
+		rawDef, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fn(name, rawDef)
+
+		if branches, ok := rawDef["Branches"].([]interface{}); ok {
+			for _, branch := range branches {
+				if sub, ok := branch.(map[string]interface{}); ok {
+					walkStateMap(sub, fn)
+				}
+			}
+		}
+		for _, key := range []string{"ItemProcessor", "Iterator"} {
+			if sub, ok := rawDef[key].(map[string]interface{}); ok {
+				walkStateMap(sub, fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// benchSFN generates an account of stateMachines Standard state machines with
+// executionsPer executions each. Responses are built from the ARNs on demand,
+// so memory measured by the benchmarks is the fetcher's own.
+type benchSFN struct {
+	sfnAPI        // Only the calls of a fetch are implemented
+	stateMachines int
+	executionsPer int
+	definition    string
+	history       []types.HistoryEvent
+}
+
+func benchStateMachineArn(i int) string {
+	return fmt.Sprintf("arn:aws:states:us-east-1:%s:stateMachine:sm-%d", testAccount, i)
+}
+
+// pageRange returns the items of the page at token and the next token
+func pageRange(token *string, max int32, total int) (int, int, *string) {
+	start := 0
+	if token != nil {
+		start, _ = strconv.Atoi(*token)
+	}
+	end := start + int(max)
+	if max <= 0 || end > total {
+		end = total
+	}
+	if end < total {
+		return start, end, aws.String(strconv.Itoa(end))
+	}
+	return start, end, nil
+}
+
+func (c *benchSFN) ListStateMachines(_ context.Context, in *sfn.ListStateMachinesInput, _ ...func(*sfn.Options)) (*sfn.ListStateMachinesOutput, error) {
+	start, end, next := pageRange(in.NextToken, 1000, c.stateMachines)
+	out := &sfn.ListStateMachinesOutput{NextToken: next}
+	for i := start; i < end; i++ {
+		out.StateMachines = append(out.StateMachines, types.StateMachineListItem{
+			StateMachineArn: aws.String(benchStateMachineArn(i)),
+			Name:            aws.String(fmt.Sprintf("sm-%d", i)),
+			Type:            types.StateMachineTypeStandard,
+		})
+	}
+	return out, nil
+}
+
+func (c *benchSFN) DescribeStateMachine(_ context.Context, in *sfn.DescribeStateMachineInput, _ ...func(*sfn.Options)) (*sfn.DescribeStateMachineOutput, error) {
+	arn := aws.ToString(in.StateMachineArn)
+	return &sfn.DescribeStateMachineOutput{
+		StateMachineArn: in.StateMachineArn,
+		Name:            aws.String(arn[strings.LastIndex(arn, ":")+1:]),
+		Type:            types.StateMachineTypeStandard,
+		Definition:      aws.String(c.definition),
+		RoleArn:         aws.String("arn:aws:iam::" + testAccount + ":role/bench"),
+		CreationDate:    aws.Time(testStart),
+	}, nil
+}
+
+func (c *benchSFN) ListExecutions(_ context.Context, in *sfn.ListExecutionsInput, _ ...func(*sfn.Options)) (*sfn.ListExecutionsOutput, error) {
+	start, end, next := pageRange(in.NextToken, in.MaxResults, c.executionsPer)
+	prefix := strings.Replace(aws.ToString(in.StateMachineArn), ":stateMachine:", ":execution:", 1) + ":"
+	out := &sfn.ListExecutionsOutput{NextToken: next}
+	for i := start; i < end; i++ {
+		out.Executions = append(out.Executions, types.ExecutionListItem{
+			ExecutionArn: aws.String(prefix + strconv.Itoa(i)),
+			Status:       types.ExecutionStatusSucceeded,
+		})
+	}
+	return out, nil
+}
+
+func (c *benchSFN) DescribeExecution(_ context.Context, in *sfn.DescribeExecutionInput, _ ...func(*sfn.Options)) (*sfn.DescribeExecutionOutput, error) {
+	return &sfn.DescribeExecutionOutput{
+		ExecutionArn: in.ExecutionArn,
+		Status:       types.ExecutionStatusSucceeded,
+		StartDate:    aws.Time(testStart),
+		StopDate:     aws.Time(testStart.Add(90 * time.Second)),
+		Input:        aws.String(`{"items":[1,2]}`),
+		Output:       aws.String(`{"ok":true}`),
+	}, nil
+}
+
+func (c *benchSFN) GetExecutionHistory(context.Context, *sfn.GetExecutionHistoryInput, ...func(*sfn.Options)) (*sfn.GetExecutionHistoryOutput, error) {
+	return &sfn.GetExecutionHistoryOutput{Events: c.history}, nil
+}
+
+// silenceStdout discards the fetcher's Debug output for the rest of the benchmark
+func silenceStdout(b *testing.B) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = devNull
+	b.Cleanup(func() {
+		os.Stdout = stdout
+		devNull.Close()
+	})
+}
+
+func BenchmarkListStateMachines(b *testing.B) {
+	fixture := standardFixture(b)
+	sizes := []struct{ stateMachines, executionsPer int }{
+		{10, 100},
+		{100, 100},
+		{1000, 100}, // 100k executions
+	}
+	for _, size := range sizes {
+		for _, history := range []bool{false, true} {
+			name := fmt.Sprintf("sm=%d/executions=%d/history=%t", size.stateMachines, size.stateMachines*size.executionsPer, history)
+			b.Run(name, func(b *testing.B) {
+				silenceStdout(b)
+				client := &benchSFN{
+					stateMachines: size.stateMachines,
+					executionsPer: size.executionsPer,
+					definition:    readDefinition(b, "order.asl.json"),
+					history:       fixture.histories[ordersExecution+"succeeded"],
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					f := newTestFetcher(client, &fakeLogs{}, WithHistory(history), WithWarningSink(&collectWarnings{}))
+					stateMachines, err := f.ListStateMachines(context.Background())
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(stateMachines) != size.stateMachines {
+						b.Fatalf("got %d state machines, want %d", len(stateMachines), size.stateMachines)
+					}
+				}
+				executions := float64(b.N * size.stateMachines * size.executionsPer)
+				b.ReportMetric(executions/b.Elapsed().Seconds(), "executions/s")
+			})
+		}
+	}
+}
+
+func BenchmarkParseDefinition(b *testing.B) {
+	// A definition with 500 Task states, about the size of large generated workflows
+	var sb strings.Builder
+	sb.WriteString(`{"StartAt":"S0","States":{`)
+	for i := 0; i < 500; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `"S%d":{"Type":"Task","Resource":"arn:aws:states:::lambda:invoke","Parameters":{"FunctionName":"f%d","Payload.$":"$"},"Retry":[{"ErrorEquals":["States.ALL"],"MaxAttempts":3}],"Next":"S%d"}`, i, i, i+1)
+	}
+	sb.WriteString(`}}`)
+	definition := sb.String()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(definition)))
+	for i := 0; i < b.N; i++ {
+		if _, err := parseDefinition(definition); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMergeExecutionEvent(b *testing.B) {
+	f := newTestFetcher(&fakeSFN{}, &fakeLogs{}, WithWarningSink(&collectWarnings{}))
+	group := logGroup{Name: "/aws/vendedlogs/states/checkout"}
+	messages := make([]string, 2000)
+	for i := 0; i < len(messages); i += 2 {
+		arn := checkoutExecution + strconv.Itoa(i)
+		messages[i] = `{"type":"ExecutionStarted","execution_arn":"` + arn + `","event_timestamp":"1709287200000"}`
+		messages[i+1] = `{"type":"ExecutionSucceeded","execution_arn":"` + arn + `","event_timestamp":"1709287212000"}`
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		executionMap := make(map[string]*Execution)
+		for _, message := range messages {
+			f.mergeExecutionEvent(group, message, executionMap)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimeBucket aggregates the executions of one state machine that started in
+// one interval
+type TimeBucket struct {
+	StateMachineName string
+	Start            time.Time // UTC
+	Executions       int
+	Succeeded        int
+	Failed           int // Failed or timed out
+	Aborted          int
+	Running          int
+	FailureRate      float64 // (Failed + Aborted) / finished executions
+	AvgSeconds       float64 // Over finished executions with a known duration
+	P90Seconds       float64
+	MaxSeconds       float64
+}
+
+// ComputeTimeBuckets groups the executions of a state machine by start time
+// into buckets of size, aligned to UTC, oldest first. Buckets without
+// executions between the first and the last are included with zero counts
+// so the series has no gaps. Executions without a start time are left out.
+func ComputeTimeBuckets(sm StateMachine, size time.Duration) []TimeBucket {
+	if size <= 0 {
+		return nil
+	}
+	buckets := make(map[time.Time]*TimeBucket)
+	durations := make(map[time.Time][]float64)
+	var first, last time.Time
+	for _, exec := range sm.Executions {
+		if exec.StartTime.IsZero() {
+			continue
+		}
+		start := exec.StartTime.UTC().Truncate(size)
+		b := buckets[start]
+		if b == nil {
+			b = &TimeBucket{StateMachineName: sm.Name, Start: start}
+			buckets[start] = b
+		}
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+
+		b.Executions++
+		switch {
+		case strings.EqualFold(exec.Status, "RUNNING"):
+			b.Running++
+			continue
+		case strings.EqualFold(exec.Status, "SUCCEEDED"):
+			b.Succeeded++
+		case IsFailedStatus(exec.Status):
+			b.Failed++
+		case strings.EqualFold(exec.Status, "ABORTED"):
+			b.Aborted++
+		}
+		if exec.Duration > 0 {
+			durations[start] = append(durations[start], exec.Duration.Seconds())
+		}
+	}
+
+	var result []TimeBucket
+	if first.IsZero() {
+		return result
+	}
+	for start := first; !start.After(last); start = start.Add(size) {
+		b := buckets[start]
+		if b == nil {
+			result = append(result, TimeBucket{StateMachineName: sm.Name, Start: start})
+			continue
+		}
+		if finished := b.Executions - b.Running; finished > 0 {
+			b.FailureRate = float64(b.Failed+b.Aborted) / float64(finished)
+		}
+		if d := durations[start]; len(d) > 0 {
+			sort.Float64s(d)
+			for _, seconds := range d {
+				b.AvgSeconds += seconds
+			}
+			b.AvgSeconds /= float64(len(d))
+			b.P90Seconds = percentile(d, 90)
+			b.MaxSeconds = d[len(d)-1]
+		}
+		result = append(result, *b)
+	}
+	return result
+}
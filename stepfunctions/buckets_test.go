@@ -0,0 +1,40 @@
+package stepfunctions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeTimeBuckets(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	exec := func(offset time.Duration, status string, duration time.Duration) Execution {
+		return Execution{StartTime: start.Add(offset), Status: status, Duration: duration}
+	}
+	sm := StateMachine{Name: "orders", Executions: []Execution{
+		exec(5*time.Minute, "SUCCEEDED", 10*time.Second),
+		exec(50*time.Minute, "FAILED", 30*time.Second),
+		exec(3*time.Hour+time.Minute, "SUCCEEDED", 20*time.Second),
+		exec(3*time.Hour+2*time.Minute, "RUNNING", 0),
+		{Status: "Succeeded", Partial: true}, // Start not logged
+	}}
+
+	buckets := ComputeTimeBuckets(sm, time.Hour)
+	if len(buckets) != 4 {
+		t.Fatalf("got %d buckets, want 4 (10:00 to 13:00): %+v", len(buckets), buckets)
+	}
+	first := buckets[0]
+	if !first.Start.Equal(start) || first.Executions != 2 || first.Failed != 1 || first.FailureRate != 0.5 || first.AvgSeconds != 20 || first.MaxSeconds != 30 {
+		t.Errorf("got first bucket %+v", first)
+	}
+	if gap := buckets[1]; gap.Executions != 0 || !gap.Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("got gap bucket %+v", gap)
+	}
+	if last := buckets[3]; last.Executions != 2 || last.Running != 1 || last.FailureRate != 0 || last.AvgSeconds != 20 {
+		t.Errorf("got last bucket %+v", last)
+	}
+
+	daily := ComputeTimeBuckets(sm, 24*time.Hour)
+	if len(daily) != 1 || daily[0].Executions != 4 || !daily[0].Start.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got daily buckets %+v", daily)
+	}
+}
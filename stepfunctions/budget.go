@@ -0,0 +1,70 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/smithy-go/middleware"
+)
+
+// apiBudget counts AWS operations across every client the fetcher creates
+type apiBudget struct {
+	max      int64 // 0 = unlimited
+	calls    atomic.Int64
+	exceeded atomic.Bool
+
+	mu          sync.Mutex
+	byOperation map[string]int // Keyed service.operation, e.g. SFN.ListExecutions
+}
+
+// WithMaxAPICalls caps the number of AWS API operations the fetcher makes.
+// Once the cap is reached the fetch stops and returns what it has so far.
+func WithMaxAPICalls(max int) Option {
+	return func(f *Fetcher) {
+		f.budget.max = int64(max)
+	}
+}
+
+// middleware counts each operation once (retries are not counted) and fails
+// it without sending once the budget is spent
+func (b *apiBudget) middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("APICallBudget",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			if b.max > 0 && b.calls.Load() >= b.max {
+				b.exceeded.Store(true)
+				return middleware.InitializeOutput{}, middleware.Metadata{}, fmt.Errorf("%w (%d calls)", ErrAPIBudgetExceeded, b.max)
+			}
+			b.calls.Add(1)
+			b.mu.Lock()
+			if b.byOperation == nil {
+				b.byOperation = make(map[string]int)
+			}
+			b.byOperation[middleware.GetServiceID(ctx)+"."+middleware.GetOperationName(ctx)]++
+			b.mu.Unlock()
+			return next.HandleInitialize(ctx, in)
+		}), middleware.Before)
+}
+
+// APICalls returns the number of AWS operations made so far
+func (f *Fetcher) APICalls() int {
+	return int(f.budget.calls.Load())
+}
+
+// APICallsByOperation returns the number of AWS operations made so far by
+// service and operation, e.g. "SFN.ListExecutions"
+func (f *Fetcher) APICallsByOperation() map[string]int {
+	f.budget.mu.Lock()
+	defer f.budget.mu.Unlock()
+	calls := make(map[string]int, len(f.budget.byOperation))
+	for operation, n := range f.budget.byOperation {
+		calls[operation] = n
+	}
+	return calls
+}
+
+// BudgetExceeded reports whether the fetch was cut short by WithMaxAPICalls
+func (f *Fetcher) BudgetExceeded() bool {
+	return f.budget.exceeded.Load()
+}
@@ -0,0 +1,72 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cachedDefinition is the on-disk cache entry for one state machine revision
+type cachedDefinition struct {
+	ARN        string
+	RevisionID string
+	States     []State
+}
+
+// WithDefinitionCache keeps parsed definitions in dir, keyed by state machine
+// ARN and revision ID. An empty dir disables the cache.
+func WithDefinitionCache(dir string) Option {
+	return func(f *Fetcher) {
+		f.cacheDir = dir
+	}
+}
+
+// DefaultCacheDir returns the per-user cache directory for definitions
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "stepfunction-fetcher", "definitions")
+}
+
+func (f *Fetcher) cachePath(stateMachineArn string) string {
+	return filepath.Join(f.cacheDir, strings.NewReplacer(":", "_", "/", "_").Replace(stateMachineArn)+".json")
+}
+
+// cachedStates returns the parsed states cached for this revision, if any
+func (f *Fetcher) cachedStates(stateMachineArn, revisionID string) ([]State, bool) {
+	if f.cacheDir == "" || revisionID == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(f.cachePath(stateMachineArn))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedDefinition
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if entry.ARN != stateMachineArn || entry.RevisionID != revisionID {
+		return nil, false
+	}
+	return entry.States, true
+}
+
+func (f *Fetcher) cacheStates(stateMachineArn, revisionID string, states []State) error {
+	if f.cacheDir == "" || revisionID == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(f.cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.Marshal(cachedDefinition{ARN: stateMachineArn, RevisionID: revisionID, States: states})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	return os.WriteFile(f.cachePath(stateMachineArn), data, 0644)
+}
@@ -0,0 +1,104 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// CallbackWait is a running execution blocked on a .waitForTaskToken task
+// whose token has not been returned yet
+type CallbackWait struct {
+	StateMachineName string
+	ExecutionArn     string
+	State            string
+	Resource         string // e.g. sqs:sendMessage.waitForTaskToken
+	Since            time.Time
+	Waiting          time.Duration
+	HeartbeatSeconds int64 `json:",omitempty"`
+	TimeoutSeconds   int64 `json:",omitempty"`
+}
+
+// WithCallbacks makes the fetcher retrieve the history of running Standard
+// executions even without WithHistory, so PendingCallbacks can find the
+// tasks they are waiting on.
+func WithCallbacks(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.callbacks = enabled
+	}
+}
+
+// callbackClosed are the events that end a scheduled task. TaskStateExited
+// and TaskStateAborted are left out: they close the state, which a completed
+// task has already been matched for.
+var callbackClosed = map[string]bool{
+	"TaskSucceeded":    true,
+	"TaskFailed":       true,
+	"TaskTimedOut":     true,
+	"TaskStartFailed":  true,
+	"TaskSubmitFailed": true,
+}
+
+// callbackProgress are the events between a task's scheduling and its end
+var callbackProgress = map[string]bool{
+	"TaskStarted":   true,
+	"TaskSubmitted": true,
+}
+
+// PendingCallbacks lists the .waitForTaskToken tasks that the running
+// executions of a state machine are still waiting on, longest waiting first.
+// Executions without a history are skipped.
+func PendingCallbacks(sm StateMachine, now time.Time) []CallbackWait {
+	var waits []CallbackWait
+	for _, exec := range sm.Executions {
+		if exec.Status != "RUNNING" {
+			continue
+		}
+		// Each event of a task points at the one before it through
+		// PreviousEventID, so an end event is paired with its own
+		// TaskScheduled even when Map items finish out of order
+		open := make(map[int64]bool)       // IDs of TaskScheduled events still waiting
+		scheduled := make(map[int64]int64) // Event ID -> ID of its task's TaskScheduled
+		for _, event := range exec.History {
+			switch {
+			case event.Type == "TaskScheduled" && strings.HasSuffix(event.Resource, ".waitForTaskToken"):
+				open[event.ID] = true
+				scheduled[event.ID] = event.ID
+			case callbackProgress[event.Type]:
+				if id, ok := scheduled[event.PreviousEventID]; ok {
+					scheduled[event.ID] = id
+				}
+			case callbackClosed[event.Type]:
+				if id, ok := scheduled[event.PreviousEventID]; ok {
+					delete(open, id)
+				}
+			}
+		}
+		for _, event := range exec.History {
+			if event.Type != "TaskScheduled" || !open[event.ID] {
+				continue
+			}
+			resource := event.Resource
+			if event.ResourceType != "" {
+				resource = event.ResourceType + ":" + resource
+			}
+			waits = append(waits, CallbackWait{
+				StateMachineName: sm.Name,
+				ExecutionArn:     exec.ExecutionArn,
+				State:            event.StateName,
+				Resource:         resource,
+				Since:            event.Timestamp,
+				Waiting:          now.Sub(event.Timestamp),
+				HeartbeatSeconds: event.HeartbeatSeconds,
+				TimeoutSeconds:   event.TimeoutSeconds,
+			})
+		}
+	}
+	sort.SliceStable(waits, func(i, j int) bool {
+		if !waits[i].Since.Equal(waits[j].Since) {
+			return waits[i].Since.Before(waits[j].Since)
+		}
+		return waits[i].ExecutionArn < waits[j].ExecutionArn
+	})
+	return waits
+}
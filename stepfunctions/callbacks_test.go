@@ -0,0 +1,64 @@
+package stepfunctions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingCallbacks(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	sm := StateMachine{Name: "orders", Executions: []Execution{
+		{ExecutionArn: "arn:exec:waiting", Status: "RUNNING", History: []HistoryEvent{
+			{ID: 1, Type: "ExecutionStarted", Timestamp: at(0)},
+			{ID: 2, Type: "TaskStateEntered", StateName: "Approve", Timestamp: at(1)},
+			// The first attempt failed and was retried
+			{ID: 3, Type: "TaskScheduled", StateName: "Approve", Resource: "sendMessage.waitForTaskToken", ResourceType: "sqs", Timestamp: at(1)},
+			{ID: 4, Type: "TaskFailed", StateName: "Approve", PreviousEventID: 3, Timestamp: at(2)},
+			{ID: 5, Type: "TaskScheduled", StateName: "Approve", Resource: "sendMessage.waitForTaskToken", ResourceType: "sqs", TimeoutSeconds: 3600, Timestamp: at(3)},
+			{ID: 6, Type: "TaskStarted", StateName: "Approve", PreviousEventID: 5, Timestamp: at(3)},
+			// Plain tasks are not callbacks
+			{ID: 7, Type: "TaskScheduled", StateName: "Audit", Resource: "invoke", ResourceType: "lambda", Timestamp: at(3)},
+		}},
+		{ExecutionArn: "arn:exec:answered", Status: "RUNNING", History: []HistoryEvent{
+			{ID: 1, Type: "TaskScheduled", StateName: "Approve", Resource: "sendMessage.waitForTaskToken", ResourceType: "sqs", Timestamp: at(0)},
+			{ID: 2, Type: "TaskStarted", StateName: "Approve", PreviousEventID: 1, Timestamp: at(0)},
+			{ID: 3, Type: "TaskSucceeded", StateName: "Approve", PreviousEventID: 2, Timestamp: at(5)},
+		}},
+		{ExecutionArn: "arn:exec:failed", Status: "FAILED", History: []HistoryEvent{
+			{ID: 1, Type: "TaskScheduled", StateName: "Approve", Resource: "sendMessage.waitForTaskToken", ResourceType: "sqs", Timestamp: at(0)},
+		}},
+	}}
+
+	waits := PendingCallbacks(sm, at(63))
+	if len(waits) != 1 {
+		t.Fatalf("got %d waits, want 1: %+v", len(waits), waits)
+	}
+	w := waits[0]
+	if w.ExecutionArn != "arn:exec:waiting" || w.State != "Approve" || w.Resource != "sqs:sendMessage.waitForTaskToken" ||
+		!w.Since.Equal(at(3)) || w.Waiting != time.Hour || w.TimeoutSeconds != 3600 {
+		t.Errorf("got %+v", w)
+	}
+}
+
+func TestPendingCallbacksMapItemsOutOfOrder(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	// Two Map items wait on the same state; the second one is answered first
+	// and the first item's state exit must not close the second's task
+	sm := StateMachine{Name: "orders", Executions: []Execution{
+		{ExecutionArn: "arn:exec:map", Status: "RUNNING", History: []HistoryEvent{
+			{ID: 1, Type: "TaskScheduled", StateName: "Approve", Resource: "sendMessage.waitForTaskToken", ResourceType: "sqs", Timestamp: at(0)},
+			{ID: 2, Type: "TaskScheduled", StateName: "Approve", Resource: "sendMessage.waitForTaskToken", ResourceType: "sqs", Timestamp: at(1)},
+			{ID: 3, Type: "TaskStarted", StateName: "Approve", PreviousEventID: 1, Timestamp: at(1)},
+			{ID: 4, Type: "TaskStarted", StateName: "Approve", PreviousEventID: 2, Timestamp: at(2)},
+			{ID: 5, Type: "TaskSucceeded", StateName: "Approve", PreviousEventID: 4, Timestamp: at(5)},
+			{ID: 6, Type: "TaskStateExited", StateName: "Approve", PreviousEventID: 5, Timestamp: at(5)},
+		}},
+	}}
+
+	waits := PendingCallbacks(sm, at(10))
+	if len(waits) != 1 || !waits[0].Since.Equal(at(0)) {
+		t.Fatalf("got %+v, want only the first item waiting since %v", waits, at(0))
+	}
+}
@@ -0,0 +1,177 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Key order of canonical definitions. Listed keys come first (or, for the
+// trailing lists, last) in this order; every other key is sorted.
+var (
+	machineKeysFirst = []string{"Comment", "QueryLanguage", "StartAt", "TimeoutSeconds", "Version", "ProcessorConfig"}
+	machineKeysLast  = []string{"States"}
+	stateKeysFirst   = []string{"Type", "Comment", "QueryLanguage"}
+	stateKeysLast    = []string{"Next", "End"}
+)
+
+// canonicalContext says which part of a definition an object is, since
+// payload objects such as Parameters are sorted without exceptions
+type canonicalContext int
+
+const (
+	inPayload canonicalContext = iota
+	inMachine
+	inStates
+	inState
+	inBranches
+)
+
+// CanonicalDefinition pretty-prints a definition with two-space indentation,
+// states sorted by name and keys in a fixed order (Type first and Next/End
+// last in states, everything else sorted), so two revisions of a definition
+// diff only where they differ. Numbers are kept as written and characters
+// such as < and & are not escaped.
+func CanonicalDefinition(definition []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(definition))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to parse definition: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("failed to parse definition: unexpected data after the top-level object")
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, root, inMachine, ""); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// DefinitionHash is the SHA-256 of a definition's canonical form, so
+// formatting changes and key reordering do not change it. Definitions that
+// are not valid JSON are hashed as is.
+func DefinitionHash(definition string) string {
+	data, err := CanonicalDefinition([]byte(definition))
+	if err != nil {
+		data = []byte(definition)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeCanonical(buf *bytes.Buffer, value interface{}, ctx canonicalContext, indent string) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, key := range canonicalKeys(v, ctx) {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(indent + "  ")
+			if err := writeScalar(buf, key); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := writeCanonical(buf, v[key], childContext(ctx, key), indent+"  "); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		// Parallel branches are state machines of their own
+		elementCtx := inPayload
+		if ctx == inBranches {
+			elementCtx = inMachine
+		}
+		buf.WriteString("[\n")
+		for i, element := range v {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(indent + "  ")
+			if err := writeCanonical(buf, element, elementCtx, indent+"  "); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + indent + "]")
+	default:
+		return writeScalar(buf, v)
+	}
+	return nil
+}
+
+// childContext returns the context of the value of key in an object of ctx
+func childContext(ctx canonicalContext, key string) canonicalContext {
+	switch {
+	case ctx == inMachine && key == "States":
+		return inStates
+	case ctx == inStates:
+		return inState
+	case ctx == inState && (key == "ItemProcessor" || key == "Iterator"):
+		return inMachine
+	case ctx == inState && key == "Branches":
+		return inBranches
+	}
+	return inPayload
+}
+
+func canonicalKeys(object map[string]interface{}, ctx canonicalContext) []string {
+	var first, last []string
+	switch ctx {
+	case inMachine:
+		first, last = machineKeysFirst, machineKeysLast
+	case inState:
+		first, last = stateKeysFirst, stateKeysLast
+	}
+	rank := func(key string) int {
+		for i, k := range first {
+			if k == key {
+				return i - len(first)
+			}
+		}
+		for i, k := range last {
+			if k == key {
+				return i + 1
+			}
+		}
+		return 0
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := rank(keys[i]), rank(keys[j]); ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// writeScalar writes a string, number, boolean or null without HTML escaping
+func writeScalar(buf *bytes.Buffer, value interface{}) error {
+	var scalar bytes.Buffer
+	enc := json.NewEncoder(&scalar)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode %v: %w", value, err)
+	}
+	buf.WriteString(strings.TrimSuffix(scalar.String(), "\n"))
+	return nil
+}
@@ -0,0 +1,98 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalDefinition(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "definitions", "*.asl.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".asl.json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			canonical, err := CanonicalDefinition(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, filepath.Join("definitions", name+".canonical.json"), canonical)
+
+			// Formatting twice changes nothing, and the definition means the same
+			again, err := CanonicalDefinition(canonical)
+			if err != nil || string(again) != string(canonical) {
+				t.Errorf("second pass changed the definition (err %v):\n%s", err, again)
+			}
+			var before, after interface{}
+			json.Unmarshal(data, &before)
+			json.Unmarshal(canonical, &after)
+			if !reflect.DeepEqual(before, after) {
+				t.Error("canonical form changed the definition")
+			}
+		})
+	}
+}
+
+func TestCanonicalDefinitionKeyOrder(t *testing.T) {
+	got, err := CanonicalDefinition([]byte(`{"States":{"B":{"End":true,"Type":"Pass","Parameters":{"z":1,"Type":"<x&y>","a":1.50}},
+		"A":{"Next":"B","Branches":[{"States":{"C":{"Type":"Succeed"}},"StartAt":"C"}],"Type":"Parallel"}},"StartAt":"A"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "StartAt": "A",
+  "States": {
+    "A": {
+      "Type": "Parallel",
+      "Branches": [
+        {
+          "StartAt": "C",
+          "States": {
+            "C": {
+              "Type": "Succeed"
+            }
+          }
+        }
+      ],
+      "Next": "B"
+    },
+    "B": {
+      "Type": "Pass",
+      "Parameters": {
+        "Type": "<x&y>",
+        "a": 1.50,
+        "z": 1
+      },
+      "End": true
+    }
+  }
+}
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := CanonicalDefinition([]byte(`{"StartAt":"A"} {}`)); err == nil {
+		t.Error("expected an error for trailing data")
+	}
+}
+
+func TestDefinitionHash(t *testing.T) {
+	a := DefinitionHash(`{"StartAt":"A","States":{"A":{"Type":"Pass","End":true}}}`)
+	b := DefinitionHash("{\n  \"States\": {\"A\": {\"End\": true, \"Type\": \"Pass\"}},\n  \"StartAt\": \"A\"\n}")
+	if a != b || len(a) != 64 {
+		t.Errorf("reformatted definition hashed to %s, want %s", b, a)
+	}
+	if c := DefinitionHash(`{"StartAt":"A","States":{"A":{"Type":"Succeed"}}}`); c == a {
+		t.Error("changed definition has the same hash")
+	}
+}
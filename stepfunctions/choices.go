@@ -0,0 +1,94 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChoiceStats counts how often each branch of a Choice state was taken
+type ChoiceStats struct {
+	StateMachineName string
+	ChoiceState      string
+	Evaluations      int
+	Branches         []ChoiceBranch
+}
+
+// ChoiceBranch is a Choice transition target and the rules that lead to it
+type ChoiceBranch struct {
+	Next        string
+	Rules       []string // e.g., "Choices[0]", "Default"
+	Taken       int
+	Unexercised bool
+}
+
+// ComputeChoiceStats matches every ChoiceStateExited event in the fetched
+// histories with the state entered next. Returns nil when no history was fetched.
+func ComputeChoiceStats(sm StateMachine) []ChoiceStats {
+	taken := make(map[string]map[string]int) // choice state -> next state -> count
+	evaluations := make(map[string]int)
+	withHistory := false
+	for _, exec := range sm.Executions {
+		if len(exec.History) == 0 {
+			continue
+		}
+		withHistory = true
+
+		exits := make(map[int64]string) // ChoiceStateExited ID -> choice state
+		for _, event := range exec.History {
+			if event.Type == "ChoiceStateExited" {
+				exits[event.ID] = event.StateName
+				continue
+			}
+			if choice, ok := exits[event.PreviousEventID]; ok && isStateEntered(event) {
+				if taken[choice] == nil {
+					taken[choice] = make(map[string]int)
+				}
+				taken[choice][event.StateName]++
+				evaluations[choice]++
+			}
+		}
+	}
+	if !withHistory {
+		return nil
+	}
+
+	var result []ChoiceStats
+	walkStates(sm.Definition, func(name string, rawDef map[string]interface{}) {
+		if stateType, _ := rawDef["Type"].(string); stateType != "Choice" {
+			return
+		}
+
+		stats := ChoiceStats{StateMachineName: sm.Name, ChoiceState: name, Evaluations: evaluations[name]}
+		byNext := make(map[string]*ChoiceBranch)
+		var order []string
+		addRule := func(next, rule string) {
+			if byNext[next] == nil {
+				byNext[next] = &ChoiceBranch{Next: next}
+				order = append(order, next)
+			}
+			byNext[next].Rules = append(byNext[next].Rules, rule)
+		}
+
+		choices, _ := rawDef["Choices"].([]interface{})
+		for i, c := range choices {
+			rule, _ := c.(map[string]interface{})
+			if next, ok := rule["Next"].(string); ok {
+				addRule(next, fmt.Sprintf("Choices[%d]", i))
+			}
+		}
+		if next, ok := rawDef["Default"].(string); ok {
+			addRule(next, "Default")
+		}
+
+		for _, next := range order {
+			branch := byNext[next]
+			branch.Taken = taken[name][next]
+			branch.Unexercised = branch.Taken == 0
+			stats.Branches = append(stats.Branches, *branch)
+		}
+		result = append(result, stats)
+	})
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ChoiceState < result[j].ChoiceState })
+	return result
+}
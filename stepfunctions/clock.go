@@ -0,0 +1,25 @@
+package stepfunctions
+
+import "time"
+
+// Clock tells the fetcher the current time, for lookback windows and budgets
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// WithClock replaces the wall clock, e.g. to make lookback windows reproducible
+func WithClock(clock Clock) Option {
+	return func(f *Fetcher) {
+		f.clock = clock
+	}
+}
+
+// Now returns the current time from the fetcher's clock, so callers measure
+// against the same time as the fetched lookback windows
+func (f *Fetcher) Now() time.Time {
+	return f.clock.Now()
+}
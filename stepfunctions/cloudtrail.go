@@ -0,0 +1,117 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cttypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+// startEventNames are the CloudTrail event names that create a new execution
+var startEventNames = []string{"StartExecution", "StartSyncExecution"}
+
+// AttachInitiators looks up StartExecution events in CloudTrail for the given
+// lookback window and attaches the calling principal to every matching execution.
+func (f *Fetcher) AttachInitiators(ctx context.Context, stateMachines []StateMachine, lookback time.Duration) error {
+	initiators, err := f.lookupInitiators(ctx, f.clock.Now().Add(-lookback))
+	if err != nil {
+		return err
+	}
+
+	attached := 0
+	for i := range stateMachines {
+		for j := range stateMachines[i].Executions {
+			exec := &stateMachines[i].Executions[j]
+			if initiator, ok := initiators[exec.ExecutionArn]; ok {
+				exec.Initiator = initiator
+				attached++
+			}
+		}
+	}
+	fmt.Printf("Debug: Attributed %d executions to a CloudTrail principal\n", attached)
+
+	return nil
+}
+
+func (f *Fetcher) lookupInitiators(ctx context.Context, since time.Time) (map[string]*Initiator, error) {
+	initiators := make(map[string]*Initiator)
+	for _, eventName := range startEventNames {
+		input := &cloudtrail.LookupEventsInput{
+			LookupAttributes: []cttypes.LookupAttribute{{
+				AttributeKey:   cttypes.LookupAttributeKeyEventName,
+				AttributeValue: aws.String(eventName),
+			}},
+			StartTime: aws.Time(since),
+			EndTime:   aws.Time(f.clock.Now()),
+		}
+
+		paginator := cloudtrail.NewLookupEventsPaginator(f.trailClient, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up %s events in CloudTrail: %w", eventName, err)
+			}
+
+			for _, event := range page.Events {
+				if event.CloudTrailEvent == nil {
+					continue
+				}
+				executionArn, initiator, err := parseStartEvent(*event.CloudTrailEvent)
+				if err != nil {
+					f.warn(aws.ToString(event.EventId), "ParseCloudTrailEvent", err)
+					continue
+				}
+				if executionArn != "" {
+					initiators[executionArn] = initiator
+				}
+			}
+		}
+	}
+
+	return initiators, nil
+}
+
+// parseStartEvent extracts the execution ARN and calling principal from a raw
+// CloudTrail StartExecution record. Failed calls carry no execution ARN.
+func parseStartEvent(record string) (string, *Initiator, error) {
+	var event struct {
+		EventTime    string `json:"eventTime"`
+		SourceIP     string `json:"sourceIPAddress"`
+		UserAgent    string `json:"userAgent"`
+		UserIdentity struct {
+			Type           string `json:"type"`
+			ARN            string `json:"arn"`
+			InvokedBy      string `json:"invokedBy"`
+			SessionContext struct {
+				SessionIssuer struct {
+					ARN string `json:"arn"`
+				} `json:"sessionIssuer"`
+			} `json:"sessionContext"`
+		} `json:"userIdentity"`
+		ResponseElements struct {
+			ExecutionArn string `json:"executionArn"`
+		} `json:"responseElements"`
+	}
+
+	if err := json.Unmarshal([]byte(record), &event); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal CloudTrail record: %w", err)
+	}
+
+	principal := event.UserIdentity.ARN
+	if principal == "" {
+		principal = event.UserIdentity.InvokedBy
+	}
+
+	return event.ResponseElements.ExecutionArn, &Initiator{
+		PrincipalARN:  principal,
+		PrincipalType: event.UserIdentity.Type,
+		RoleARN:       event.UserIdentity.SessionContext.SessionIssuer.ARN,
+		SourceIP:      event.SourceIP,
+		UserAgent:     event.UserAgent,
+		EventTime:     event.EventTime,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package stepfunctions
+
+import "fmt"
+
+// CheckLoggingAndTracing audits the observability settings of a state machine:
+// Express workflows must log to CloudWatch Logs (their only execution record)
+// and Standard workflows must have X-Ray tracing enabled.
+func CheckLoggingAndTracing(sm StateMachine) []ComplianceIssue {
+	var issues []ComplianceIssue
+	issue := func(check, format string, args ...interface{}) {
+		issues = append(issues, ComplianceIssue{
+			StateMachineName: sm.Name,
+			StateMachineARN:  sm.ARN,
+			Check:            check,
+			Message:          fmt.Sprintf(format, args...),
+		})
+	}
+
+	switch sm.Type {
+	case "EXPRESS":
+		if sm.Logging.Level == "" || sm.Logging.Level == "OFF" || len(sm.Logging.Destinations) == 0 {
+			issue("logging", "Express workflow has no CloudWatch Logs destination; execution history is not recorded")
+		} else if sm.Logging.Level != "ALL" {
+			issue("logging", "Express workflow logs at level %s; only executions with errors are recorded", sm.Logging.Level)
+		}
+	case "STANDARD":
+		if !sm.Tracing {
+			issue("tracing", "Standard workflow does not have X-Ray tracing enabled")
+		}
+	}
+
+	return issues
+}
+
+// CheckEncryption flags state machines whose data is encrypted with an
+// AWS-owned key rather than a customer managed KMS key.
+func CheckEncryption(sm StateMachine) []ComplianceIssue {
+	if sm.Encryption.Type == "CUSTOMER_MANAGED_KMS_KEY" {
+		return nil
+	}
+	return []ComplianceIssue{{
+		StateMachineName: sm.Name,
+		StateMachineARN:  sm.ARN,
+		Check:            "encryption",
+		Message:          "State machine is encrypted with an AWS-owned key instead of a customer managed KMS key",
+	}}
+}
@@ -0,0 +1,173 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// ExecutionCounts is the number of executions of one state machine by status
+type ExecutionCounts struct {
+	Name     string
+	ARN      string
+	Type     string
+	ByStatus map[string]int // RUNNING, SUCCEEDED, FAILED, TIMED_OUT, ABORTED, ...
+	Total    int
+	Source   string // "ListExecutions", or "logs" or MetricsSource for Express executions
+}
+
+// WithCountStatuses limits CountExecutions to the given execution statuses.
+// Standard executions are listed once per status with a ListExecutions status
+// filter, so counting only RUNNING executions does not page through the
+// finished ones. Empty counts every status.
+func WithCountStatuses(statuses []string) Option {
+	return func(f *Fetcher) {
+		f.countStatuses = statuses
+	}
+}
+
+// ExecutionStatuses are the statuses ListExecutions filters on
+func ExecutionStatuses() []string {
+	var statuses []string
+	for _, status := range types.ExecutionStatus("").Values() {
+		statuses = append(statuses, string(status))
+	}
+	return statuses
+}
+
+// CountExecutions counts the executions of every state machine by status
+// without describing them. Standard executions are counted from ListExecutions
+// pages of the largest size, one status filter at a time; Express executions
+// from the CloudWatch Logs start and end events within the lookback, or from
+// CloudWatch metrics when they aren't logged.
+func (f *Fetcher) CountExecutions(ctx context.Context) ([]ExecutionCounts, error) {
+	arns, err := f.stateMachineARNs(ctx)
+	if errors.Is(err, ErrAPIBudgetExceeded) {
+		f.skipOverBudget(append([]string{"ListStateMachines"}, arns...), err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []ExecutionCounts
+	for i, smArn := range arns {
+		smCounts, err := f.countStateMachine(ctx, smArn)
+		if errors.Is(err, ErrAPIBudgetExceeded) {
+			f.skipOverBudget(arns[i:], err)
+			return counts, nil
+		}
+		if err != nil {
+			f.warn(smArn, "CountExecutions", err)
+			if err := f.skip(smArn, err); err != nil {
+				return counts, err
+			}
+			continue
+		}
+		counts = append(counts, smCounts)
+	}
+	return counts, nil
+}
+
+func (f *Fetcher) countStateMachine(ctx context.Context, arn string) (ExecutionCounts, error) {
+	result, err := f.sfnClient.DescribeStateMachine(ctx, &sfn.DescribeStateMachineInput{StateMachineArn: aws.String(arn)})
+	if err != nil {
+		return ExecutionCounts{}, fmt.Errorf("failed to describe state machine %s: %w", arn, err)
+	}
+
+	counts := ExecutionCounts{
+		Name: aws.ToString(result.Name),
+		ARN:  arn,
+		Type: string(result.Type),
+	}
+	switch counts.Type {
+	case "STANDARD":
+		counts.Source = "ListExecutions"
+		counts.ByStatus, err = f.countExecutionsByStatus(ctx, arn)
+		if err != nil {
+			return counts, err
+		}
+	case "EXPRESS":
+		counts.Source = "logs"
+		executions, _, err := f.discoverExpressExecutions(ctx, result)
+		if metrics := f.expressMetricsFallback(ctx, arn, err); metrics != nil {
+			counts.Source = metrics.Source
+			counts.ByStatus = f.countedOnly(metrics.ByStatus)
+			counts.Total = metrics.Started
+			if len(f.countStatuses) > 0 {
+				counts.Total = sumCounts(counts.ByStatus)
+			}
+			return counts, nil
+		}
+		if err != nil {
+			return counts, err
+		}
+		counts.ByStatus = make(map[string]int)
+		for _, exec := range executions {
+			counts.ByStatus[normalizeStatus(exec.Status)]++
+		}
+		counts.ByStatus = f.countedOnly(counts.ByStatus)
+	default:
+		return counts, fmt.Errorf("unknown state machine type %s", counts.Type)
+	}
+	counts.Total = sumCounts(counts.ByStatus)
+	return counts, nil
+}
+
+// countExecutionsByStatus counts the executions of a Standard state machine
+// with one paged ListExecutions status filter per counted status
+func (f *Fetcher) countExecutionsByStatus(ctx context.Context, stateMachineArn string) (map[string]int, error) {
+	statuses := f.countStatuses
+	if len(statuses) == 0 {
+		statuses = ExecutionStatuses()
+	}
+	byStatus := make(map[string]int)
+	for _, status := range statuses {
+		paginator := sfn.NewListExecutionsPaginator(f.sfnClient, &sfn.ListExecutionsInput{
+			StateMachineArn: aws.String(f.executionScope(stateMachineArn)),
+			StatusFilter:    types.ExecutionStatus(status),
+			MaxResults:      1000,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if f.alias != "" && isMissingScope(err) {
+				return byStatus, nil // No executions in scope
+			}
+			if err != nil {
+				return byStatus, fmt.Errorf("failed to count %s executions for %s: %w", status, stateMachineArn, err)
+			}
+			if len(page.Executions) > 0 {
+				byStatus[status] += len(page.Executions)
+			}
+		}
+	}
+	return byStatus, nil
+}
+
+// countedOnly drops the statuses WithCountStatuses leaves out
+func (f *Fetcher) countedOnly(byStatus map[string]int) map[string]int {
+	if len(f.countStatuses) == 0 {
+		return byStatus
+	}
+	counted := make(map[string]int)
+	for _, status := range f.countStatuses {
+		if count, ok := byStatus[status]; ok {
+			counted[status] = count
+		}
+	}
+	return counted
+}
+
+// normalizeStatus maps the statuses of logged Express executions ("Succeeded",
+// "TimedOut") to the ones ListExecutions returns ("SUCCEEDED", "TIMED_OUT")
+func normalizeStatus(status string) string {
+	if status == "TimedOut" {
+		return "TIMED_OUT"
+	}
+	return strings.ToUpper(status)
+}
@@ -0,0 +1,64 @@
+package stepfunctions
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+func TestCountExecutions(t *testing.T) {
+	client := standardFixture(t)
+	client.stateMachines = append(client.stateMachines, &sfn.DescribeStateMachineOutput{
+		StateMachineArn: aws.String(checkoutArn),
+		Name:            aws.String("checkout"),
+		Type:            types.StateMachineTypeExpress,
+		Definition:      aws.String(`{"StartAt":"Price","States":{"Price":{"Type":"Pass","End":true}}}`),
+		LoggingConfiguration: &types.LoggingConfiguration{
+			Level:        types.LogLevelAll,
+			Destinations: []types.LogDestination{{CloudWatchLogsLogGroup: &types.CloudWatchLogsLogGroup{LogGroupArn: aws.String(checkoutLogGroup)}}},
+		},
+	})
+	f := newTestFetcher(client, &fakeLogs{messages: expressLogMessages()}, WithHistory(true),
+		WithClock(fixedClock(testStart.Add(time.Hour))), WithWarningSink(&collectWarnings{}))
+
+	counts, err := f.CountExecutions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ExecutionCounts{
+		{Name: "orders", ARN: ordersArn, Type: "STANDARD", Source: "ListExecutions", Total: 3,
+			ByStatus: map[string]int{"SUCCEEDED": 1, "FAILED": 1, "RUNNING": 1}},
+		{Name: "checkout", ARN: checkoutArn, Type: "EXPRESS", Source: "logs", Total: 2,
+			ByStatus: map[string]int{"SUCCEEDED": 1, "FAILED": 1}},
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("got %+v\nwant %+v", counts, want)
+	}
+	for _, op := range []string{"DescribeExecution", "GetExecutionHistory"} {
+		if client.calls[op] != 0 {
+			t.Errorf("%s called %d times, want none", op, client.calls[op])
+		}
+	}
+}
+
+func TestCountExecutionsRunningOnly(t *testing.T) {
+	client := standardFixture(t)
+	f := newTestFetcher(client, &fakeLogs{}, WithCountStatuses([]string{"RUNNING"}), WithWarningSink(&collectWarnings{}))
+
+	counts, err := f.CountExecutions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts[0].Total != 1 || !reflect.DeepEqual(counts[0].ByStatus, map[string]int{"RUNNING": 1}) {
+		t.Errorf("got %+v, want 1 running execution", counts)
+	}
+	// One page for the running execution; the finished ones are never listed
+	if client.calls["ListExecutions"] != 1 {
+		t.Errorf("ListExecutions called %d times, want 1", client.calls["ListExecutions"])
+	}
+}
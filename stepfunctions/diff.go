@@ -0,0 +1,127 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExecutionDiff compares two executions of the same state machine state by state
+type ExecutionDiff struct {
+	A, B       Execution
+	PathA      []string // States in order of entry
+	PathB      []string
+	DivergedAt int // Index of the first differing step, -1 when the paths match
+	States     []StateDiff
+}
+
+// StateDiff compares one state across the two executions
+type StateDiff struct {
+	StateName     string
+	EntriesA      int
+	EntriesB      int
+	DurationA     time.Duration
+	DurationB     time.Duration
+	InputChanged  bool
+	OutputChanged bool
+	ErrorsA       []string
+	ErrorsB       []string
+}
+
+// DurationDelta returns how much longer the state took in B than in A
+func (d StateDiff) DurationDelta() time.Duration {
+	return d.DurationB - d.DurationA
+}
+
+type stateSummary struct {
+	entries  int
+	duration time.Duration
+	input    string
+	output   string
+	errors   []string
+}
+
+// DiffExecutions aligns the histories of two executions by state name. Inputs
+// compare the first entry into a state and outputs the last exit from it.
+func DiffExecutions(a, b Execution) ExecutionDiff {
+	diff := ExecutionDiff{
+		A:          a,
+		B:          b,
+		PathA:      statePath(a.History),
+		PathB:      statePath(b.History),
+		DivergedAt: -1,
+	}
+	diff.A.History, diff.B.History = nil, nil
+
+	for i := 0; i < len(diff.PathA) || i < len(diff.PathB); i++ {
+		if i >= len(diff.PathA) || i >= len(diff.PathB) || diff.PathA[i] != diff.PathB[i] {
+			diff.DivergedAt = i
+			break
+		}
+	}
+
+	summaryA, summaryB := summarizeStates(a.History), summarizeStates(b.History)
+	seen := make(map[string]bool)
+	for _, name := range append(diff.PathA, diff.PathB...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		sa, sb := summaryA[name], summaryB[name]
+		diff.States = append(diff.States, StateDiff{
+			StateName:     name,
+			EntriesA:      sa.entries,
+			EntriesB:      sb.entries,
+			DurationA:     sa.duration,
+			DurationB:     sb.duration,
+			InputChanged:  sa.entries > 0 && sb.entries > 0 && sa.input != sb.input,
+			OutputChanged: sa.entries > 0 && sb.entries > 0 && sa.output != sb.output,
+			ErrorsA:       sa.errors,
+			ErrorsB:       sb.errors,
+		})
+	}
+
+	return diff
+}
+
+func statePath(history []HistoryEvent) []string {
+	var path []string
+	for _, event := range history {
+		if isStateEntered(event) {
+			path = append(path, event.StateName)
+		}
+	}
+	return path
+}
+
+func summarizeStates(history []HistoryEvent) map[string]*stateSummary {
+	summaries := make(map[string]*stateSummary)
+	get := func(name string) *stateSummary {
+		if summaries[name] == nil {
+			summaries[name] = &stateSummary{}
+		}
+		return summaries[name]
+	}
+
+	for _, span := range BuildTimeline(history) {
+		get(span.StateName).duration += span.Duration()
+	}
+	for _, event := range history {
+		if event.StateName == "" {
+			continue
+		}
+		summary := get(event.StateName)
+		switch {
+		case isStateEntered(event):
+			if summary.entries == 0 {
+				summary.input = event.Input
+			}
+			summary.entries++
+		case isStateExited(event):
+			summary.output = event.Output
+		case event.Error != "":
+			summary.errors = append(summary.errors, fmt.Sprintf("%s: %s", event.Type, event.Error))
+		}
+	}
+	return summaries
+}
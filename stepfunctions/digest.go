@@ -0,0 +1,64 @@
+package stepfunctions
+
+import "time"
+
+// FailureDigest lists the failed executions not reported by an earlier run
+type FailureDigest struct {
+	Groups []DigestGroup
+	Total  int
+}
+
+// DigestGroup holds the new failures of one state machine
+type DigestGroup struct {
+	StateMachineName string
+	StateMachineARN  string
+	Failures         []DigestFailure
+}
+
+// DigestFailure is one failed execution in a digest
+type DigestFailure struct {
+	ExecutionArn string
+	Status       string
+	StateName    string // Empty when the history was not fetched
+	Error        string
+	Cause        string
+	EndTime      time.Time
+	ConsoleURL   string
+}
+
+// BuildFailureDigest collects the failed executions whose ARN is not in
+// notified, grouped by state machine. It also returns the ARNs of every
+// failed execution in this run, which is what the next run should treat as
+// already notified.
+func BuildFailureDigest(stateMachines []StateMachine, notified map[string]bool) (FailureDigest, []string) {
+	var digest FailureDigest
+	var failed []string
+	for _, sm := range stateMachines {
+		group := DigestGroup{StateMachineName: sm.Name, StateMachineARN: sm.ARN}
+		for _, exec := range sm.Executions {
+			if !IsFailedStatus(exec.Status) {
+				continue
+			}
+			failed = append(failed, exec.ExecutionArn)
+			if notified[exec.ExecutionArn] {
+				continue
+			}
+
+			state, errorCode, cause := failurePoint(exec)
+			group.Failures = append(group.Failures, DigestFailure{
+				ExecutionArn: exec.ExecutionArn,
+				Status:       exec.Status,
+				StateName:    state,
+				Error:        errorCode,
+				Cause:        cause,
+				EndTime:      exec.EndTime,
+				ConsoleURL:   ExecutionConsoleURL(exec.ExecutionArn),
+			})
+		}
+		if len(group.Failures) > 0 {
+			digest.Groups = append(digest.Groups, group)
+			digest.Total += len(group.Failures)
+		}
+	}
+	return digest, failed
+}
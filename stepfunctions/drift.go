@@ -0,0 +1,165 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/smithy-go"
+)
+
+// cfnAPI is the part of the CloudFormation client the fetcher uses
+type cfnAPI interface {
+	DescribeStackResources(ctx context.Context, params *cloudformation.DescribeStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourcesOutput, error)
+	DetectStackResourceDrift(ctx context.Context, params *cloudformation.DetectStackResourceDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackResourceDriftOutput, error)
+}
+
+// StackDrift compares a state machine with the CloudFormation stack that owns it
+type StackDrift struct {
+	StateMachineName  string
+	StateMachineARN   string
+	StackName         string
+	LogicalResourceID string
+	Status            string // IN_SYNC, MODIFIED, DELETED or NOT_CHECKED
+	Differences       []DriftDifference
+	DefinitionChanges []DefinitionChange // States that differ from the template's definition
+}
+
+// DriftDifference is a property that differs from the stack template. The
+// values of definition properties are left out; see DefinitionChanges.
+type DriftDifference struct {
+	Path     string
+	Type     string // ADD, REMOVE or NOT_EQUAL
+	Expected string `json:",omitempty"`
+	Actual   string `json:",omitempty"`
+}
+
+// DefinitionChange is a state added, removed or modified outside the stack
+type DefinitionChange struct {
+	State  string
+	Change string // "added", "removed" or "modified"
+}
+
+// DetectStackDrift finds the CloudFormation stack that created the state
+// machine and runs CloudFormation drift detection on it. It returns nil when
+// no stack owns the state machine.
+func (f *Fetcher) DetectStackDrift(ctx context.Context, sm StateMachine) (*StackDrift, error) {
+	resources, err := f.cfnClient.DescribeStackResources(ctx, &cloudformation.DescribeStackResourcesInput{
+		PhysicalResourceId: aws.String(sm.ARN),
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+		return nil, nil // Not created by CloudFormation
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the stack of %s: %w", sm.ARN, err)
+	}
+
+	drift := &StackDrift{StateMachineName: sm.Name, StateMachineARN: sm.ARN}
+	for _, resource := range resources.StackResources {
+		if aws.ToString(resource.PhysicalResourceId) == sm.ARN {
+			drift.StackName = aws.ToString(resource.StackName)
+			drift.LogicalResourceID = aws.ToString(resource.LogicalResourceId)
+		}
+	}
+	if drift.StackName == "" {
+		return nil, nil
+	}
+
+	result, err := f.cfnClient.DetectStackResourceDrift(ctx, &cloudformation.DetectStackResourceDriftInput{
+		StackName:         aws.String(drift.StackName),
+		LogicalResourceId: aws.String(drift.LogicalResourceID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect drift of %s in stack %s: %w", drift.LogicalResourceID, drift.StackName, err)
+	}
+	resourceDrift := result.StackResourceDrift
+	drift.Status = string(resourceDrift.StackResourceDriftStatus)
+	for _, diff := range resourceDrift.PropertyDifferences {
+		difference := DriftDifference{
+			Path:     aws.ToString(diff.PropertyPath),
+			Type:     string(diff.DifferenceType),
+			Expected: aws.ToString(diff.ExpectedValue),
+			Actual:   aws.ToString(diff.ActualValue),
+		}
+		if isDefinitionPath(difference.Path) {
+			difference.Expected, difference.Actual = "", ""
+		}
+		drift.Differences = append(drift.Differences, difference)
+	}
+	if drift.Status == "MODIFIED" {
+		expected := propertyDefinition(aws.ToString(resourceDrift.ExpectedProperties))
+		actual := propertyDefinition(aws.ToString(resourceDrift.ActualProperties))
+		if expected != "" && actual != "" {
+			drift.DefinitionChanges = diffDefinitions(expected, actual)
+		}
+	}
+	return drift, nil
+}
+
+func isDefinitionPath(path string) bool {
+	return strings.HasPrefix(path, "/DefinitionString") || strings.HasPrefix(path, "/Definition/") || path == "/Definition"
+}
+
+// propertyDefinition returns the definition in a drift properties document,
+// from DefinitionString or the Definition object
+func propertyDefinition(properties string) string {
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(properties), &props); err != nil {
+		return ""
+	}
+	if definition, ok := props["DefinitionString"].(string); ok {
+		return definition
+	}
+	if definition, ok := props["Definition"].(map[string]interface{}); ok {
+		data, err := json.Marshal(definition)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// diffDefinitions lists the states, nested ones included, that were added,
+// removed or modified between two definitions
+func diffDefinitions(expected, actual string) []DefinitionChange {
+	collect := func(definition string) map[string]map[string]interface{} {
+		states := make(map[string]map[string]interface{})
+		walkStates(definition, func(name string, rawDef map[string]interface{}) {
+			// Nested states are compared on their own
+			own := make(map[string]interface{}, len(rawDef))
+			for key, value := range rawDef {
+				if key != "Branches" && key != "ItemProcessor" && key != "Iterator" {
+					own[key] = value
+				}
+			}
+			states[name] = own
+		})
+		return states
+	}
+	before, after := collect(expected), collect(actual)
+
+	var changes []DefinitionChange
+	for name, rawDef := range after {
+		old, ok := before[name]
+		switch {
+		case !ok:
+			changes = append(changes, DefinitionChange{State: name, Change: "added"})
+		case !reflect.DeepEqual(old, rawDef):
+			changes = append(changes, DefinitionChange{State: name, Change: "modified"})
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, DefinitionChange{State: name, Change: "removed"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].State < changes[j].State })
+	return changes
+}
@@ -0,0 +1,94 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeCFN owns the state machines in stacks, by ARN, and reports the
+// properties in drifted as their live values
+type fakeCFN struct {
+	stacks   map[string]string // State machine ARN -> stack name
+	expected map[string]interface{}
+	actual   map[string]interface{}
+}
+
+func (c *fakeCFN) DescribeStackResources(_ context.Context, in *cloudformation.DescribeStackResourcesInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourcesOutput, error) {
+	stack, ok := c.stacks[aws.ToString(in.PhysicalResourceId)]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "ValidationError", Message: "Stack for " + aws.ToString(in.PhysicalResourceId) + " does not exist"}
+	}
+	return &cloudformation.DescribeStackResourcesOutput{StackResources: []cfntypes.StackResource{
+		{StackName: aws.String(stack), LogicalResourceId: aws.String("Role"), PhysicalResourceId: aws.String("checkout-role")},
+		{StackName: aws.String(stack), LogicalResourceId: aws.String("Checkout"), PhysicalResourceId: in.PhysicalResourceId},
+	}}, nil
+}
+
+func (c *fakeCFN) DetectStackResourceDrift(_ context.Context, in *cloudformation.DetectStackResourceDriftInput, _ ...func(*cloudformation.Options)) (*cloudformation.DetectStackResourceDriftOutput, error) {
+	expected, _ := json.Marshal(c.expected)
+	actual, _ := json.Marshal(c.actual)
+	return &cloudformation.DetectStackResourceDriftOutput{StackResourceDrift: &cfntypes.StackResourceDrift{
+		LogicalResourceId:        in.LogicalResourceId,
+		StackResourceDriftStatus: cfntypes.StackResourceDriftStatusModified,
+		ExpectedProperties:       aws.String(string(expected)),
+		ActualProperties:         aws.String(string(actual)),
+		PropertyDifferences: []cfntypes.PropertyDifference{
+			{PropertyPath: aws.String("/DefinitionString"), DifferenceType: cfntypes.DifferenceTypeNotEqual, ExpectedValue: aws.String("{...}"), ActualValue: aws.String("{...}")},
+			{PropertyPath: aws.String("/TracingConfiguration/Enabled"), DifferenceType: cfntypes.DifferenceTypeNotEqual, ExpectedValue: aws.String("true"), ActualValue: aws.String("false")},
+		},
+	}}, nil
+}
+
+func TestDetectStackDrift(t *testing.T) {
+	cfn := &fakeCFN{
+		stacks: map[string]string{checkoutArn: "shop"},
+		expected: map[string]interface{}{"DefinitionString": `{"StartAt":"Price","States":{
+			"Price":{"Type":"Pass","Next":"Fan"},
+			"Fan":{"Type":"Parallel","End":true,"Branches":[{"StartAt":"Charge","States":{"Charge":{"Type":"Pass","End":true}}}]},
+			"Audit":{"Type":"Pass","End":true}}}`},
+		actual: map[string]interface{}{"DefinitionString": `{"StartAt":"Price","States":{
+			"Price":{"Type":"Pass","Next":"Fan"},
+			"Fan":{"Type":"Parallel","End":true,"Branches":[{"StartAt":"Charge","States":{"Charge":{"Type":"Pass","Result":"hotfix","End":true}}}]},
+			"Notify":{"Type":"Pass","End":true}}}`},
+	}
+	f := newTestFetcher(&fakeSFN{}, &fakeLogs{})
+	f.cfnClient = cfn
+
+	drift, err := f.DetectStackDrift(context.Background(), StateMachine{Name: "checkout", ARN: checkoutArn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift.StackName != "shop" || drift.LogicalResourceID != "Checkout" || drift.Status != "MODIFIED" {
+		t.Fatalf("got drift %+v", drift)
+	}
+	want := []DefinitionChange{{"Audit", "removed"}, {"Charge", "modified"}, {"Notify", "added"}}
+	if len(drift.DefinitionChanges) != len(want) {
+		t.Fatalf("got changes %v, want %v", drift.DefinitionChanges, want)
+	}
+	for i := range want {
+		if drift.DefinitionChanges[i] != want[i] {
+			t.Errorf("change %d = %v, want %v", i, drift.DefinitionChanges[i], want[i])
+		}
+	}
+	if d := drift.Differences[0]; d.Path != "/DefinitionString" || d.Expected != "" || d.Actual != "" {
+		t.Errorf("definition difference %+v should leave out the values", d)
+	}
+	if d := drift.Differences[1]; d.Expected != "true" || d.Actual != "false" {
+		t.Errorf("got tracing difference %+v", d)
+	}
+	if findings := DriftFindings(StateMachine{Name: "checkout"}, drift); len(findings) != 3 || findings[1].State != "Charge" {
+		t.Errorf("got findings %v", findings)
+	}
+
+	// State machines that no stack owns have no drift to report
+	drift, err = f.DetectStackDrift(context.Background(), StateMachine{Name: "orders", ARN: ordersArn})
+	if err != nil || drift != nil {
+		t.Errorf("got drift %+v, err %v for an unmanaged state machine", drift, err)
+	}
+}
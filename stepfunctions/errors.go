@@ -0,0 +1,102 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Sentinel errors for classifying failures with errors.Is. AWS API errors keep
+// their original error in the chain as well.
+var (
+	ErrThrottled            = errors.New("throttled")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrLoggingNotConfigured = errors.New("logging not configured")
+	ErrDefinitionParse      = errors.New("definition parse error")
+
+	// ErrAPIBudgetExceeded is returned for every AWS call made after the
+	// WithMaxAPICalls budget has been spent
+	ErrAPIBudgetExceeded = errors.New("API call budget exceeded")
+
+	// ErrAPITimeout is returned for AWS calls that ran past WithAPITimeout
+	ErrAPITimeout = errors.New("API call timed out")
+)
+
+// throttlingCodes and accessDeniedCodes are the AWS error codes for each class
+var (
+	throttlingCodes = map[string]bool{
+		"ThrottlingException":                    true,
+		"Throttling":                             true,
+		"TooManyRequestsException":               true,
+		"RequestLimitExceeded":                   true,
+		"ProvisionedThroughputExceededException": true,
+		"LimitExceededException":                 true,
+	}
+	accessDeniedCodes = map[string]bool{
+		"AccessDeniedException":       true,
+		"AccessDenied":                true,
+		"UnauthorizedOperation":       true,
+		"UnrecognizedClientException": true,
+		"ExpiredTokenException":       true,
+		"InvalidClientTokenId":        true,
+	}
+)
+
+// classifiedError tags an error with one of the sentinels without changing its message
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// classify tags AWS API errors with ErrThrottled or ErrAccessDenied
+func classify(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch code := apiErr.ErrorCode(); {
+	case throttlingCodes[code]:
+		return &classifiedError{kind: ErrThrottled, err: err}
+	case accessDeniedCodes[code]:
+		return &classifiedError{kind: ErrAccessDenied, err: err}
+	}
+	return err
+}
+
+// classifyMiddleware runs classify on the final error of every operation, after retries
+func classifyMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ClassifyErrors",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			if err != nil {
+				err = classify(err)
+			}
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// ErrorKind names the class of an error for summaries: throttled,
+// access_denied, logging_not_configured, definition_parse, budget_exceeded,
+// timeout or other
+func ErrorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrThrottled):
+		return "throttled"
+	case errors.Is(err, ErrAccessDenied):
+		return "access_denied"
+	case errors.Is(err, ErrLoggingNotConfigured):
+		return "logging_not_configured"
+	case errors.Is(err, ErrDefinitionParse):
+		return "definition_parse"
+	case errors.Is(err, ErrAPIBudgetExceeded):
+		return "budget_exceeded"
+	case errors.Is(err, ErrAPITimeout):
+		return "timeout"
+	}
+	return "other"
+}
@@ -0,0 +1,51 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StatusChangeDetailType is the EventBridge detail-type Step Functions emits
+// when a Standard execution changes status
+const StatusChangeDetailType = "Step Functions Execution Status Change"
+
+// StatusChangeEvent is the detail of an execution status change event
+type StatusChangeEvent struct {
+	ExecutionArn    string `json:"executionArn"`
+	StateMachineArn string `json:"stateMachineArn"`
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	StartDate       int64  `json:"startDate"` // Epoch milliseconds
+	StopDate        int64  `json:"stopDate"`
+}
+
+// Terminal reports whether the execution has finished
+func (e StatusChangeEvent) Terminal() bool {
+	return e.Status != "RUNNING" && e.Status != "PENDING_REDRIVE"
+}
+
+// StateMachineName returns the name segment of the state machine ARN
+func (e StatusChangeEvent) StateMachineName() string {
+	return e.StateMachineArn[strings.LastIndex(e.StateMachineArn, ":")+1:]
+}
+
+// ParseStatusChangeEvent decodes an EventBridge envelope, as delivered to an
+// SQS queue targeted by the rule, and returns its status change detail
+func ParseStatusChangeEvent(body []byte) (StatusChangeEvent, error) {
+	var envelope struct {
+		DetailType string            `json:"detail-type"`
+		Source     string            `json:"source"`
+		Detail     StatusChangeEvent `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return StatusChangeEvent{}, fmt.Errorf("failed to unmarshal EventBridge event: %w", err)
+	}
+	if envelope.Source != "aws.states" || envelope.DetailType != StatusChangeDetailType {
+		return StatusChangeEvent{}, fmt.Errorf("unexpected event %q from %q", envelope.DetailType, envelope.Source)
+	}
+	if envelope.Detail.ExecutionArn == "" {
+		return StatusChangeEvent{}, fmt.Errorf("event has no executionArn")
+	}
+	return envelope.Detail, nil
+}
@@ -0,0 +1,141 @@
+// Package export reads the output directory of a stepfunction-fetcher run
+// back into the stepfunctions types, so other Go tools can reuse an export
+// without parsing its files themselves. Documents of every schema version
+// are read, see stepfunctions.SchemaVersion.
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// StateMachinesFile lists every exported state machine, executions included
+const StateMachinesFile = "state_machines.json"
+
+// DefinitionFile is the name of a state machine's saved definition
+func DefinitionFile(smName string) string {
+	return smName + ".asl.json"
+}
+
+// StateFile is the name of a state's saved definition
+func StateFile(smName, stateName string) string {
+	return fmt.Sprintf("%s_%s.json", smName, FileName(stateName))
+}
+
+// ExecutionFile is the name of a saved execution
+func ExecutionFile(smName, executionArn string) string {
+	return fmt.Sprintf("%s_execution_%s.json", smName, FileName(strings.ReplaceAll(executionArn, ":", "_")))
+}
+
+// FileName replaces the characters that are not allowed in file names
+func FileName(name string) string {
+	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
+	result := name
+	for _, char := range invalidChars {
+		result = strings.ReplaceAll(result, char, "_")
+	}
+	return result
+}
+
+// LoadStateMachines reads every state machine of an export
+func LoadStateMachines(dir string) ([]stepfunctions.StateMachine, error) {
+	data, err := os.ReadFile(filepath.Join(dir, StateMachinesFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", StateMachinesFile, err)
+	}
+	stateMachines, err := stepfunctions.ParseStateMachines(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, sm := range stateMachines {
+		if sm.Definition != "" || sm.DefinitionFile == "" {
+			continue
+		}
+		// A definition too large to keep inline is read back from its file
+		definition, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(sm.DefinitionFile)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read definition of %s: %w", sm.Name, err)
+		}
+		if hash := stepfunctions.DefinitionHash(string(definition)); sm.DefinitionHash != "" && hash != sm.DefinitionHash {
+			return nil, fmt.Errorf("definition of %s in %s changed since the export", sm.Name, sm.DefinitionFile)
+		}
+		stateMachines[i].Definition = string(definition)
+	}
+	return stateMachines, nil
+}
+
+// LoadStateMachine reads one state machine of an export, by name or ARN
+func LoadStateMachine(dir, name string) (stepfunctions.StateMachine, error) {
+	stateMachines, err := LoadStateMachines(dir)
+	if err != nil {
+		return stepfunctions.StateMachine{}, err
+	}
+	for _, sm := range stateMachines {
+		if sm.Name == name || sm.ARN == name {
+			return sm, nil
+		}
+	}
+	return stepfunctions.StateMachine{}, fmt.Errorf("state machine %s is not in %s", name, dir)
+}
+
+// LoadExecutions reads the execution files of a state machine, newest first.
+// Files saved by fetch-execution are read too.
+func LoadExecutions(dir, smName string) ([]stepfunctions.Execution, error) {
+	files, err := filepath.Glob(filepath.Join(dir, smName+"_execution_*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions of %s: %w", smName, err)
+	}
+	var executions []stepfunctions.Execution
+	for _, file := range files {
+		if strings.HasSuffix(file, ".tree.json") {
+			continue
+		}
+		detail, err := loadExecutionFile(file)
+		if err != nil {
+			return nil, err
+		}
+		// Another state machine whose name starts with smName_execution_
+		if !strings.HasSuffix(detail.StateMachineARN, ":"+smName) {
+			continue
+		}
+		executions = append(executions, detail.Execution)
+	}
+	sort.SliceStable(executions, func(i, j int) bool { return executions[i].StartTime.After(executions[j].StartTime) })
+	return executions, nil
+}
+
+// LoadExecution reads one saved execution by ARN
+func LoadExecution(dir, executionArn string) (stepfunctions.Execution, error) {
+	suffix := "_execution_" + FileName(strings.ReplaceAll(executionArn, ":", "_")) + ".json"
+	files, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+	if err != nil {
+		return stepfunctions.Execution{}, fmt.Errorf("failed to find execution %s: %w", executionArn, err)
+	}
+	for _, file := range files {
+		detail, err := loadExecutionFile(file)
+		if err != nil {
+			return stepfunctions.Execution{}, err
+		}
+		if detail.Execution.ExecutionArn == executionArn {
+			return detail.Execution, nil
+		}
+	}
+	return stepfunctions.Execution{}, fmt.Errorf("execution %s is not in %s", executionArn, dir)
+}
+
+func loadExecutionFile(file string) (stepfunctions.ExecutionDetail, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return stepfunctions.ExecutionDetail{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	detail, err := stepfunctions.ParseExportedExecution(data)
+	if err != nil {
+		return stepfunctions.ExecutionDetail{}, fmt.Errorf("failed to read %s: %w", filepath.Base(file), err)
+	}
+	return detail, nil
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+const smArn = "arn:aws:states:us-east-1:123456789012:stateMachine:orders"
+
+func writeDocument(t *testing.T, dir, name string, v interface{}, listKey string) {
+	t.Helper()
+	data, err := stepfunctions.MarshalDocument(v, listKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func execution(name string, start time.Time) stepfunctions.Execution {
+	return stepfunctions.Execution{
+		ExecutionArn: "arn:aws:states:us-east-1:123456789012:execution:orders:" + name,
+		Status:       "SUCCEEDED",
+		StartTime:    start,
+		History:      []stepfunctions.HistoryEvent{{ID: 1, Type: "ExecutionStarted", Input: `{}`}},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	older, newer := execution("run-1", start), execution("run-2", start.Add(time.Hour))
+
+	writeDocument(t, dir, StateMachinesFile, []stepfunctions.StateMachine{
+		{Name: "orders", ARN: smArn, Executions: []stepfunctions.Execution{older, newer}},
+	}, "stateMachines")
+	// Saved by the exporter
+	writeDocument(t, dir, ExecutionFile("orders", older.ExecutionArn), older, "")
+	// Saved by fetch-execution, along with its tree
+	writeDocument(t, dir, ExecutionFile("orders", newer.ExecutionArn), stepfunctions.ExecutionDetail{StateMachineARN: smArn, Execution: newer}, "")
+	writeDocument(t, dir, "orders_execution_run-2.tree.json", map[string]string{"ExecutionArn": newer.ExecutionArn}, "")
+	// A state machine whose name starts with orders_execution_
+	other := stepfunctions.Execution{ExecutionArn: "arn:aws:states:us-east-1:123456789012:execution:orders_execution_x:run-3"}
+	writeDocument(t, dir, ExecutionFile("orders_execution_x", other.ExecutionArn), other, "")
+
+	sm, err := LoadStateMachine(dir, smArn)
+	if err != nil || sm.Name != "orders" || len(sm.Executions) != 2 {
+		t.Fatalf("LoadStateMachine = %+v, %v", sm, err)
+	}
+	if _, err := LoadStateMachine(dir, "payments"); err == nil {
+		t.Error("LoadStateMachine(payments) succeeded, want an error")
+	}
+
+	executions, err := LoadExecutions(dir, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(executions) != 2 || executions[0].ExecutionArn != newer.ExecutionArn || executions[1].ExecutionArn != older.ExecutionArn {
+		t.Fatalf("LoadExecutions = %+v, want run-2 then run-1", executions)
+	}
+
+	exec, err := LoadExecution(dir, older.ExecutionArn)
+	if err != nil || len(exec.History) != 1 || !exec.StartTime.Equal(start) {
+		t.Errorf("LoadExecution = %+v, %v", exec, err)
+	}
+	if _, err := LoadExecution(dir, "arn:aws:states:us-east-1:123456789012:execution:orders:missing"); err == nil {
+		t.Error("LoadExecution(missing) succeeded, want an error")
+	}
+}
+
+func TestLoadStateMachinesVersion1(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, StateMachinesFile), []byte(`[{"Name":"orders","ARN":"`+smArn+`"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stateMachines, err := LoadStateMachines(dir)
+	if err != nil || len(stateMachines) != 1 || stateMachines[0].Name != "orders" {
+		t.Errorf("LoadStateMachines = %+v, %v", stateMachines, err)
+	}
+}
+
+func TestLoadStateMachinesChangedDefinition(t *testing.T) {
+	dir := t.TempDir()
+	definition := `{"StartAt":"A","States":{"A":{"Type":"Succeed"}}}`
+	writeDocument(t, dir, StateMachinesFile, []stepfunctions.StateMachine{
+		{Name: "orders", ARN: smArn, DefinitionFile: DefinitionFile("orders"), DefinitionHash: stepfunctions.DefinitionHash(definition)},
+	}, "stateMachines")
+	if err := os.WriteFile(filepath.Join(dir, DefinitionFile("orders")), []byte(`{"StartAt":"B","States":{"B":{"Type":"Fail"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStateMachines(dir); err == nil {
+		t.Error("LoadStateMachines succeeded with a changed definition file, want an error")
+	}
+}
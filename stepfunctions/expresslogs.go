@@ -0,0 +1,157 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// expressLogEvent is one Step Functions execution event as written to
+// CloudWatch Logs. Both the documented snake_case fields and the camelCase
+// fields used by older log forwarders are accepted.
+type expressLogEvent struct {
+	ID                string                 `json:"id"`
+	PreviousEventID   string                 `json:"previous_event_id"`
+	Type              string                 `json:"type"`
+	EventType         string                 `json:"eventType"`
+	ExecutionArn      string                 `json:"execution_arn"`
+	ExecutionArnCamel string                 `json:"executionArn"`
+	EventTimestamp    string                 `json:"event_timestamp"`
+	Timestamp         int64                  `json:"timestamp"`
+	Details           map[string]interface{} `json:"details"`
+}
+
+// parseLogEvent decodes a log message and normalizes the field variants
+func parseLogEvent(message string) (expressLogEvent, error) {
+	var event expressLogEvent
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		return event, err
+	}
+
+	if event.Type == "" {
+		event.Type = event.EventType
+	}
+	if event.ExecutionArn == "" {
+		event.ExecutionArn = event.ExecutionArnCamel
+	}
+	if event.Type == "" {
+		return event, fmt.Errorf("log event has no type")
+	}
+	if event.ExecutionArn == "" {
+		return event, fmt.Errorf("%s log event has no execution ARN", event.Type)
+	}
+	if event.Timestamp == 0 && event.EventTimestamp != "" {
+		ms, err := strconv.ParseInt(event.EventTimestamp, 10, 64)
+		if err != nil {
+			return event, fmt.Errorf("invalid event_timestamp %q: %w", event.EventTimestamp, err)
+		}
+		event.Timestamp = ms
+	}
+	return event, nil
+}
+
+// toHistoryEvent converts a logged event into the same shape GetExecutionHistory returns
+func (e expressLogEvent) toHistoryEvent() HistoryEvent {
+	id, _ := strconv.ParseInt(e.ID, 10, 64)
+	prev, _ := strconv.ParseInt(e.PreviousEventID, 10, 64)
+	event := HistoryEvent{
+		ID:              id,
+		PreviousEventID: prev,
+		Type:            e.Type,
+		Timestamp:       time.UnixMilli(e.Timestamp),
+	}
+
+	str := func(key string) string {
+		switch v := e.Details[key].(type) {
+		case string:
+			return v
+		case nil:
+			return ""
+		default:
+			data, _ := json.Marshal(v)
+			return string(data)
+		}
+	}
+	num := func(key string) int64 {
+		switch v := e.Details[key].(type) {
+		case float64:
+			return int64(v)
+		case string:
+			n, _ := strconv.ParseInt(v, 10, 64)
+			return n
+		}
+		return 0
+	}
+
+	event.StateName = str("name")
+	event.Resource = str("resource")
+	event.ResourceType = str("resourceType")
+	event.Parameters = str("parameters")
+	event.Input = str("input")
+	event.Output = str("output")
+	event.Error = str("error")
+	event.Cause = str("cause")
+	event.HeartbeatSeconds = num("heartbeatInSeconds")
+	event.TimeoutSeconds = num("timeoutInSeconds")
+	event.MapRunArn = str("mapRunArn")
+	return event
+}
+
+// expressCapture describes an execution history rebuilt from logs: payloads
+// are logged with IncludeExecutionData, and below level ALL only error events
+// are
+func expressCapture(logging *types.LoggingConfiguration, exec Execution) Capture {
+	capture := Capture{History: true, Truncated: exec.Partial}
+	if logging != nil {
+		capture.Payloads = logging.IncludeExecutionData
+		capture.Truncated = capture.Truncated || logging.Level != types.LogLevelAll
+	}
+	return capture
+}
+
+// getExpressExecutionHistory collects every logged event of one Express
+// execution and reconstructs its history, oldest first.
+func (f *Fetcher) getExpressExecutionHistory(ctx context.Context, group logGroup, exec Execution) ([]HistoryEvent, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		FilterPattern: aws.String(fmt.Sprintf(`{ $.execution_arn = "%s" || $.executionArn = "%s" }`, exec.ExecutionArn, exec.ExecutionArn)),
+	}
+	group.setOn(input)
+	if start := exec.StartTime; !start.IsZero() {
+		input.StartTime = aws.Int64(start.Add(-time.Minute).UnixMilli())
+	}
+	if end := exec.EndTime; !end.IsZero() {
+		input.EndTime = aws.Int64(end.Add(time.Minute).UnixMilli())
+		if exec.Partial {
+			// Express executions run at most five minutes
+			input.StartTime = aws.Int64(end.Add(-expressMaxDuration - time.Minute).UnixMilli())
+		}
+	}
+
+	var history []HistoryEvent
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(f.logsClientFor(group.Region), input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query log events for %s: %w", exec.ExecutionArn, err)
+		}
+
+		for _, logEvent := range page.Events {
+			event, err := parseLogEvent(aws.ToString(logEvent.Message))
+			if err != nil {
+				f.warn(exec.ExecutionArn, "ParseLogEvent", err)
+				continue
+			}
+			history = append(history, event.toHistoryEvent())
+		}
+	}
+
+	sortHistory(history)
+	resolveStateNames(history)
+	return history, nil
+}
@@ -0,0 +1,220 @@
+package stepfunctions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+const (
+	checkoutArn       = "arn:aws:states:us-east-1:" + testAccount + ":stateMachine:checkout"
+	checkoutExecution = "arn:aws:states:us-east-1:" + testAccount + ":express:checkout:"
+	checkoutLogGroup  = "arn:aws:logs:us-east-1:" + testAccount + ":log-group:/aws/vendedlogs/states/checkout:*"
+)
+
+func TestParseLogEvent(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       string
+		wantType      string
+		wantArn       string
+		wantTimestamp int64
+		wantErr       bool
+	}{
+		{
+			name:          "documented snake_case fields",
+			message:       `{"id":"1","type":"ExecutionStarted","execution_arn":"arn:x","event_timestamp":"1709287200000"}`,
+			wantType:      "ExecutionStarted",
+			wantArn:       "arn:x",
+			wantTimestamp: 1709287200000,
+		},
+		{
+			name:          "camelCase forwarder fields",
+			message:       `{"eventType":"ExecutionSucceeded","executionArn":"arn:y","timestamp":1709287201000}`,
+			wantType:      "ExecutionSucceeded",
+			wantArn:       "arn:y",
+			wantTimestamp: 1709287201000,
+		},
+		{
+			name:          "numeric timestamp wins over event_timestamp",
+			message:       `{"type":"TaskStateEntered","execution_arn":"arn:z","timestamp":5,"event_timestamp":"9"}`,
+			wantType:      "TaskStateEntered",
+			wantArn:       "arn:z",
+			wantTimestamp: 5,
+		},
+		{name: "invalid event_timestamp", message: `{"type":"ExecutionStarted","execution_arn":"arn:x","event_timestamp":"yesterday"}`, wantErr: true},
+		{name: "missing type", message: `{"execution_arn":"arn:x","event_timestamp":"1"}`, wantErr: true},
+		{name: "missing execution ARN", message: `{"type":"ExecutionStarted","event_timestamp":"1"}`, wantErr: true},
+		{name: "JSON but not an object", message: `[1, 2]`, wantErr: true},
+		{name: "not JSON", message: `START RequestId: 42`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := parseLogEvent(tt.message)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Type != tt.wantType || event.ExecutionArn != tt.wantArn || event.Timestamp != tt.wantTimestamp {
+				t.Errorf("got type %q, arn %q, timestamp %d", event.Type, event.ExecutionArn, event.Timestamp)
+			}
+		})
+	}
+}
+
+func TestToHistoryEvent(t *testing.T) {
+	event, err := parseLogEvent(`{"id":"3","previous_event_id":"2","type":"TaskScheduled","execution_arn":"arn:x","event_timestamp":"1709287200000",
+		"details":{"resource":"invoke","resourceType":"lambda","parameters":{"FunctionName":"f"},"timeoutInSeconds":"30","heartbeatInSeconds":10}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := event.toHistoryEvent()
+	want := HistoryEvent{
+		ID:               3,
+		PreviousEventID:  2,
+		Type:             "TaskScheduled",
+		Timestamp:        time.UnixMilli(1709287200000),
+		Resource:         "invoke",
+		ResourceType:     "lambda",
+		Parameters:       `{"FunctionName":"f"}`,
+		TimeoutSeconds:   30,
+		HeartbeatSeconds: 10,
+	}
+	if got != want {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// expressLogMessages logs a succeeded execution with its full history, and
+// only the failure of a second execution, as level ERROR logging would. The
+// last event matches the filter pattern but cannot be parsed.
+func expressLogMessages() []string {
+	return []string{
+		`{"id":"1","type":"ExecutionStarted","execution_arn":"` + checkoutExecution + `ok","event_timestamp":"1709287200000","details":{"input":"{}"}}`,
+		`{"id":"2","previous_event_id":"1","type":"PassStateEntered","execution_arn":"` + checkoutExecution + `ok","event_timestamp":"1709287200100","details":{"name":"Price","input":"{}"}}`,
+		`{"id":"3","previous_event_id":"2","type":"PassStateExited","execution_arn":"` + checkoutExecution + `ok","event_timestamp":"1709287200200","details":{"name":"Price","output":"{\"total\":3}"}}`,
+		`{"id":"4","previous_event_id":"3","type":"ExecutionSucceeded","execution_arn":"` + checkoutExecution + `ok","event_timestamp":"1709287212000","details":{"output":"{\"total\":3}"}}`,
+		`{"id":"5","type":"ExecutionFailed","execution_arn":"` + checkoutExecution + `failed","event_timestamp":"1709287230000","details":{"error":"States.Timeout","cause":"took too long"}}`,
+		`{"id":"6","type":"ExecutionAborted","execution_arn":"` + checkoutExecution + `aborted","event_timestamp":"soon"}`,
+	}
+}
+
+func TestListStateMachinesExpress(t *testing.T) {
+	client := &fakeSFN{stateMachines: []*sfn.DescribeStateMachineOutput{{
+		StateMachineArn: aws.String(checkoutArn),
+		Name:            aws.String("checkout"),
+		Type:            types.StateMachineTypeExpress,
+		Definition:      aws.String(`{"StartAt":"Price","States":{"Price":{"Type":"Pass","End":true}}}`),
+		RoleArn:         aws.String("arn:aws:iam::" + testAccount + ":role/checkout"),
+		CreationDate:    aws.Time(testStart.Add(-24 * time.Hour)),
+		LoggingConfiguration: &types.LoggingConfiguration{
+			Level:                types.LogLevelAll,
+			IncludeExecutionData: true,
+			Destinations: []types.LogDestination{{
+				CloudWatchLogsLogGroup: &types.CloudWatchLogsLogGroup{LogGroupArn: aws.String(checkoutLogGroup)},
+			}},
+		},
+	}}}
+	logs := &fakeLogs{messages: expressLogMessages()}
+	var warnings collectWarnings
+	f := newTestFetcher(client, logs, WithHistory(true), WithWarningSink(&warnings))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Operation != "ParseLogEvent" {
+		t.Errorf("got warnings %v, want one for the unparseable message", warnings)
+	}
+	if name := aws.ToString(logs.queries[0].LogGroupName); name != "/aws/vendedlogs/states/checkout" {
+		t.Errorf("queried log group %q", name)
+	}
+	sortForGolden(stateMachines)
+	assertGolden(t, "express.golden.json", marshalGolden(t, stateMachines))
+}
+
+func TestExpressLogLevelWarns(t *testing.T) {
+	client := &fakeSFN{stateMachines: []*sfn.DescribeStateMachineOutput{{
+		StateMachineArn: aws.String(checkoutArn),
+		Name:            aws.String("checkout"),
+		Type:            types.StateMachineTypeExpress,
+		Definition:      aws.String(`{"StartAt":"Price","States":{"Price":{"Type":"Pass","End":true}}}`),
+		RoleArn:         aws.String("arn:aws:iam::" + testAccount + ":role/checkout"),
+		CreationDate:    aws.Time(testStart.Add(-24 * time.Hour)),
+		LoggingConfiguration: &types.LoggingConfiguration{
+			Level: types.LogLevelError,
+			Destinations: []types.LogDestination{{
+				CloudWatchLogsLogGroup: &types.CloudWatchLogsLogGroup{LogGroupArn: aws.String(checkoutLogGroup)},
+			}},
+		},
+	}}}
+	var warnings collectWarnings
+	f := newTestFetcher(client, &fakeLogs{}, WithWarningSink(&warnings))
+
+	if _, err := f.ListStateMachines(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Resource != checkoutArn || warnings[0].Operation != "FilterLogEvents" {
+		t.Errorf("got warnings %v, want one for the ERROR log level", warnings)
+	}
+}
+
+func TestExpressLookbackUsesClock(t *testing.T) {
+	now := testStart.Add(time.Hour)
+	group := logGroup{Name: "/aws/vendedlogs/states/checkout"}
+	logs := &fakeLogs{messages: expressLogMessages()}
+	f := newTestFetcher(&fakeSFN{}, logs, WithClock(fixedClock(now)), WithWarningSink(&collectWarnings{}),
+		WithExpressLogQuery(ExpressLogQuery{FilterPattern: DefaultExpressFilterPattern, Limit: 50, Lookback: 2 * time.Hour}))
+
+	executionMap := make(map[string]*Execution)
+	if err := f.collectExpressExecutions(context.Background(), group, executionMap); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := aws.ToInt64(logs.queries[0].StartTime), now.Add(-2*time.Hour).UnixMilli(); got != want {
+		t.Errorf("queried from %d, want %d", got, want)
+	}
+
+	// The history query is bounded by the execution's own start and end
+	if _, err := f.getExpressExecutionHistory(context.Background(), group, *executionMap[checkoutExecution+"ok"]); err != nil {
+		t.Fatal(err)
+	}
+	history := logs.queries[1]
+	if got, want := aws.ToInt64(history.StartTime), testStart.Add(-time.Minute).UnixMilli(); got != want {
+		t.Errorf("history queried from %d, want %d", got, want)
+	}
+	if got, want := aws.ToInt64(history.EndTime), testStart.Add(12*time.Second+time.Minute).UnixMilli(); got != want {
+		t.Errorf("history queried until %d, want %d", got, want)
+	}
+}
+
+func TestListStateMachinesExpressWithoutLogging(t *testing.T) {
+	client := &fakeSFN{stateMachines: []*sfn.DescribeStateMachineOutput{{
+		StateMachineArn: aws.String(checkoutArn),
+		Name:            aws.String("checkout"),
+		Type:            types.StateMachineTypeExpress,
+		Definition:      aws.String(`{"StartAt":"Price","States":{"Price":{"Type":"Pass","End":true}}}`),
+		RoleArn:         aws.String("arn:aws:iam::" + testAccount + ":role/checkout"),
+		CreationDate:    aws.Time(testStart),
+	}}}
+	f := newTestFetcher(client, &fakeLogs{}, WithWarningSink(&collectWarnings{}))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stateMachines) != 1 || stateMachines[0].Executions[0].ExecutionArn != "N/A" {
+		t.Fatalf("got %+v, want the state machine with a placeholder execution", stateMachines)
+	}
+	if skipped := f.Skipped(); len(skipped) != 1 || skipped[0].Kind != "logging_not_configured" {
+		t.Errorf("got skipped %v", skipped)
+	}
+}
@@ -0,0 +1,100 @@
+package stepfunctions
+
+import "sort"
+
+const (
+	maxExampleCauses     = 3
+	maxExampleExecutions = 5
+)
+
+// FailureSignature groups the failed executions of a state machine that
+// failed in the same state with the same error code
+type FailureSignature struct {
+	StateMachineName string
+	StateName        string // Empty when the history was not fetched
+	Error            string
+	Count            int
+	ExampleCauses    []string
+	ExecutionArns    []string
+}
+
+// AggregateFailures ranks the failure signatures across all fetched executions,
+// most frequent first.
+func AggregateFailures(stateMachines []StateMachine) []FailureSignature {
+	type key struct{ stateMachine, state, errorCode string }
+	signatures := make(map[key]*FailureSignature)
+	var order []key
+
+	for _, sm := range stateMachines {
+		for _, exec := range sm.Executions {
+			if !IsFailedStatus(exec.Status) {
+				continue
+			}
+
+			state, errorCode, cause := failurePoint(exec)
+			k := key{sm.Name, state, errorCode}
+			sig, ok := signatures[k]
+			if !ok {
+				sig = &FailureSignature{StateMachineName: sm.Name, StateName: state, Error: errorCode}
+				signatures[k] = sig
+				order = append(order, k)
+			}
+
+			sig.Count++
+			if len(sig.ExecutionArns) < maxExampleExecutions {
+				sig.ExecutionArns = append(sig.ExecutionArns, exec.ExecutionArn)
+			}
+			if cause != "" && len(sig.ExampleCauses) < maxExampleCauses && !contains(sig.ExampleCauses, cause) {
+				sig.ExampleCauses = append(sig.ExampleCauses, cause)
+			}
+		}
+	}
+
+	result := make([]FailureSignature, 0, len(order))
+	for _, k := range order {
+		result = append(result, *signatures[k])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+	return result
+}
+
+// failurePoint finds the state, error code and cause of a failed execution,
+// preferring the last state-level error in its history over the execution summary.
+func failurePoint(exec Execution) (state, errorCode, cause string) {
+	errorCode, cause = exec.Error, exec.Cause
+	for i := len(exec.History) - 1; i >= 0; i-- {
+		event := exec.History[i]
+		if event.Error != "" && event.StateName != "" {
+			return event.StateName, event.Error, event.Cause
+		}
+		if event.Error != "" && errorCode == "" {
+			errorCode, cause = event.Error, event.Cause
+		}
+	}
+	return "", errorCode, cause
+}
+
+// failedStatuses are the statuses of executions that failed or timed out,
+// Standard (FAILED) and Express log-derived (Failed). Aborted executions were
+// stopped on purpose and are not failures.
+var failedStatuses = []string{"FAILED", "TIMED_OUT", "Failed", "TimedOut"}
+
+// FailedStatuses returns the statuses IsFailedStatus matches, e.g. to count
+// failures in a query
+func FailedStatuses() []string {
+	return append([]string(nil), failedStatuses...)
+}
+
+// IsFailedStatus matches the statuses of executions that failed or timed out
+func IsFailedStatus(status string) bool {
+	return contains(failedStatuses, status)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,274 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMain(m *testing.M) {
+	// Timestamps are formatted in local time; pin it so golden files match everywhere
+	time.Local = time.UTC
+	os.Exit(m.Run())
+}
+
+// assertGolden compares got with testdata/<name>, or rewrites it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run go test -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s does not match the output (run go test -update to accept it)\ngot:\n%s", path, got)
+	}
+}
+
+func marshalGolden(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, '\n')
+}
+
+// newTestFetcher returns a fetcher backed by fakes instead of AWS clients
+func newTestFetcher(sfnClient sfnAPI, logsClient logsAPI, opts ...Option) *Fetcher {
+	f := newFetcher(opts...)
+	f.cfg.Region = "us-east-1"
+	f.sfnClient = sfnClient
+	f.logsClient = logsClient
+	return f
+}
+
+// fakeSFN serves state machines, executions and histories from memory. Lists
+// return one item per page so pagination is exercised.
+type fakeSFN struct {
+	stateMachines []*sfn.DescribeStateMachineOutput
+	executions    map[string][]*sfn.DescribeExecutionOutput // By state machine ARN, or Map Run ARN for its children
+	histories     map[string][]types.HistoryEvent           // By execution ARN
+	started       []*sfn.StartExecutionInput
+	stopped       []*sfn.StopExecutionInput
+	redriven      []*sfn.RedriveExecutionInput
+	mapRuns       map[string][]types.MapRunListItem    // By execution ARN
+	mapRunDetails map[string]*sfn.DescribeMapRunOutput // By Map Run ARN
+	tags          map[string][]types.Tag               // By state machine ARN
+	calls         map[string]int
+}
+
+func (c *fakeSFN) called(op string) {
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[op]++
+}
+
+// page returns the item at token and the token of the next page
+func page(token *string, total int) (int, *string) {
+	i := 0
+	if token != nil {
+		i, _ = strconv.Atoi(*token)
+	}
+	if i+1 < total {
+		return i, aws.String(strconv.Itoa(i + 1))
+	}
+	return i, nil
+}
+
+func (c *fakeSFN) ListStateMachines(_ context.Context, in *sfn.ListStateMachinesInput, _ ...func(*sfn.Options)) (*sfn.ListStateMachinesOutput, error) {
+	c.called("ListStateMachines")
+	if len(c.stateMachines) == 0 {
+		return &sfn.ListStateMachinesOutput{}, nil
+	}
+	i, next := page(in.NextToken, len(c.stateMachines))
+	sm := c.stateMachines[i]
+	return &sfn.ListStateMachinesOutput{
+		StateMachines: []types.StateMachineListItem{{StateMachineArn: sm.StateMachineArn, Name: sm.Name, Type: sm.Type}},
+		NextToken:     next,
+	}, nil
+}
+
+func (c *fakeSFN) DescribeStateMachine(_ context.Context, in *sfn.DescribeStateMachineInput, _ ...func(*sfn.Options)) (*sfn.DescribeStateMachineOutput, error) {
+	c.called("DescribeStateMachine")
+	for _, sm := range c.stateMachines {
+		if aws.ToString(sm.StateMachineArn) == aws.ToString(in.StateMachineArn) {
+			return sm, nil
+		}
+	}
+	return nil, fmt.Errorf("state machine %s does not exist", aws.ToString(in.StateMachineArn))
+}
+
+func (c *fakeSFN) ListExecutions(_ context.Context, in *sfn.ListExecutionsInput, _ ...func(*sfn.Options)) (*sfn.ListExecutionsOutput, error) {
+	c.called("ListExecutions")
+	if in.MapRunArn != nil {
+		return c.listExecutions(in, c.executions[aws.ToString(in.MapRunArn)])
+	}
+	executions, ok := c.executions[aws.ToString(in.StateMachineArn)]
+	if !ok && strings.Count(aws.ToString(in.StateMachineArn), ":") > 6 {
+		// An alias or version ARN that does not exist
+		return nil, &types.ResourceNotFound{Message: aws.String("alias not found")}
+	}
+	return c.listExecutions(in, executions)
+}
+
+func (c *fakeSFN) listExecutions(in *sfn.ListExecutionsInput, executions []*sfn.DescribeExecutionOutput) (*sfn.ListExecutionsOutput, error) {
+	if in.StatusFilter != "" {
+		var matching []*sfn.DescribeExecutionOutput
+		for _, exec := range executions {
+			if exec.Status == in.StatusFilter {
+				matching = append(matching, exec)
+			}
+		}
+		executions = matching
+	}
+	if len(executions) == 0 {
+		return &sfn.ListExecutionsOutput{}, nil
+	}
+	i, next := page(in.NextToken, len(executions))
+	return &sfn.ListExecutionsOutput{
+		Executions: []types.ExecutionListItem{{
+			ExecutionArn: executions[i].ExecutionArn,
+			Name:         executions[i].Name,
+			Status:       executions[i].Status,
+			StartDate:    executions[i].StartDate,
+		}},
+		NextToken: next,
+	}, nil
+}
+
+func (c *fakeSFN) DescribeExecution(_ context.Context, in *sfn.DescribeExecutionInput, _ ...func(*sfn.Options)) (*sfn.DescribeExecutionOutput, error) {
+	c.called("DescribeExecution")
+	for _, executions := range c.executions {
+		for _, exec := range executions {
+			if aws.ToString(exec.ExecutionArn) == aws.ToString(in.ExecutionArn) {
+				return exec, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("execution %s does not exist", aws.ToString(in.ExecutionArn))
+}
+
+func (c *fakeSFN) GetExecutionHistory(_ context.Context, in *sfn.GetExecutionHistoryInput, _ ...func(*sfn.Options)) (*sfn.GetExecutionHistoryOutput, error) {
+	c.called("GetExecutionHistory")
+	return &sfn.GetExecutionHistoryOutput{Events: c.histories[aws.ToString(in.ExecutionArn)]}, nil
+}
+
+func (c *fakeSFN) StartExecution(_ context.Context, in *sfn.StartExecutionInput, _ ...func(*sfn.Options)) (*sfn.StartExecutionOutput, error) {
+	c.called("StartExecution")
+	c.started = append(c.started, in)
+	name := aws.ToString(in.Name)
+	if name == "" {
+		name = fmt.Sprintf("generated-%d", len(c.started))
+	}
+	executionArn := strings.Replace(aws.ToString(in.StateMachineArn), ":stateMachine:", ":execution:", 1) + ":" + name
+	return &sfn.StartExecutionOutput{ExecutionArn: aws.String(executionArn)}, nil
+}
+
+func (c *fakeSFN) StopExecution(_ context.Context, in *sfn.StopExecutionInput, _ ...func(*sfn.Options)) (*sfn.StopExecutionOutput, error) {
+	c.called("StopExecution")
+	c.stopped = append(c.stopped, in)
+	return &sfn.StopExecutionOutput{}, nil
+}
+
+func (c *fakeSFN) RedriveExecution(_ context.Context, in *sfn.RedriveExecutionInput, _ ...func(*sfn.Options)) (*sfn.RedriveExecutionOutput, error) {
+	c.called("RedriveExecution")
+	c.redriven = append(c.redriven, in)
+	return &sfn.RedriveExecutionOutput{RedriveDate: aws.Time(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))}, nil
+}
+
+func (c *fakeSFN) ListMapRuns(_ context.Context, in *sfn.ListMapRunsInput, _ ...func(*sfn.Options)) (*sfn.ListMapRunsOutput, error) {
+	c.called("ListMapRuns")
+	return &sfn.ListMapRunsOutput{MapRuns: c.mapRuns[aws.ToString(in.ExecutionArn)]}, nil
+}
+
+func (c *fakeSFN) DescribeMapRun(_ context.Context, in *sfn.DescribeMapRunInput, _ ...func(*sfn.Options)) (*sfn.DescribeMapRunOutput, error) {
+	c.called("DescribeMapRun")
+	if out, ok := c.mapRunDetails[aws.ToString(in.MapRunArn)]; ok {
+		return out, nil
+	}
+	return nil, fmt.Errorf("map run %s does not exist", aws.ToString(in.MapRunArn))
+}
+
+func (c *fakeSFN) ListTagsForResource(_ context.Context, in *sfn.ListTagsForResourceInput, _ ...func(*sfn.Options)) (*sfn.ListTagsForResourceOutput, error) {
+	c.called("ListTagsForResource")
+	return &sfn.ListTagsForResourceOutput{Tags: c.tags[aws.ToString(in.ResourceArn)]}, nil
+}
+
+// fakeLogs answers FilterLogEvents from a fixed set of messages: the
+// per-execution pattern selects that execution's events, any other pattern
+// the execution start and end events
+type fakeLogs struct {
+	messages []string
+	queries  []*cloudwatchlogs.FilterLogEventsInput
+}
+
+var executionPattern = regexp.MustCompile(`\$\.execution_arn = "([^"]+)"`)
+
+func (c *fakeLogs) FilterLogEvents(_ context.Context, in *cloudwatchlogs.FilterLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	c.queries = append(c.queries, in)
+	match := func(message string) bool {
+		var event struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(message), &event)
+		return strings.HasPrefix(event.Type, "Execution")
+	}
+	if m := executionPattern.FindStringSubmatch(aws.ToString(in.FilterPattern)); m != nil {
+		match = func(message string) bool { return strings.Contains(message, m[1]) }
+	}
+
+	out := &cloudwatchlogs.FilterLogEventsOutput{}
+	for _, message := range c.messages {
+		if match(message) {
+			out.Events = append(out.Events, logstypes.FilteredLogEvent{Message: aws.String(message)})
+		}
+	}
+	return out, nil
+}
+
+// fixedClock is a Clock that always returns the same time
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// collectWarnings is a WarningSink for asserting on warnings
+type collectWarnings []Warning
+
+func (c *collectWarnings) Warn(w Warning) { *c = append(*c, w) }
+
+// sortForGolden orders the map-ordered parts of fetched state machines
+func sortForGolden(stateMachines []StateMachine) {
+	for i := range stateMachines {
+		sm := &stateMachines[i]
+		sort.Slice(sm.States, func(a, b int) bool { return sm.States[a].Name < sm.States[b].Name })
+		sort.Slice(sm.Executions, func(a, b int) bool { return sm.Executions[a].ExecutionArn < sm.Executions[b].ExecutionArn })
+	}
+}
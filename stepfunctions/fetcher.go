@@ -3,55 +3,248 @@ package stepfunctions
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
 	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
 )
 
 type Fetcher struct {
-	sfnClient  *sfn.Client
-	logsClient *cloudwatchlogs.Client
+	includeHistory bool
+	expressQuery   ExpressLogQuery
+	budget         apiBudget
+	cacheDir       string // Definition cache, empty when disabled
+	failFast       bool
+	skipped        []SkippedItem
+	sinks          []RecordSink
+	keepRaw        bool
+	arns           []string // Explicit state machines; empty lists them all
+	warnings       WarningSink
+	clock          Clock
+	alias          string // Alias or version executions are limited to, see WithAlias
+	mapRuns        bool   // Fetch Distributed Map runs, see WithMapRuns
+	callbacks      bool   // Fetch the history of running executions, see WithCallbacks
+	sample         Sample // Executions fetched, see WithSample
+	sampled        []SampledCount
+	countStatuses  []string // Statuses CountExecutions counts, see WithCountStatuses
+	configOptions  []func(*config.LoadOptions) error
+	apiTimeout     time.Duration // Per operation, see WithAPITimeout
+	quotaPacing    bool
+	pacing         map[string]*tokenBucket // By Step Functions operation, see WithQuotaPacing
+
+	cfg           aws.Config
+	sfnClient     sfnAPI
+	logsClient    logsAPI
+	logsClients   map[string]logsAPI // Other regions, created on demand
+	metricsClient metricsAPI
+	cfnClient     cfnAPI
+	s3Client      s3API
+	trailClient   *cloudtrail.Client
+	iamClient     *iam.Client
+	kmsClient     *kms.Client
 }
 
-func NewFetcher(ctx context.Context, region string) (*Fetcher, error) {
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+// sfnAPI is the part of the Step Functions client the fetcher uses, so tests
+// can substitute a fake
+type sfnAPI interface {
+	sfn.ListStateMachinesAPIClient
+	sfn.ListExecutionsAPIClient
+	sfn.GetExecutionHistoryAPIClient
+	DescribeStateMachine(ctx context.Context, params *sfn.DescribeStateMachineInput, optFns ...func(*sfn.Options)) (*sfn.DescribeStateMachineOutput, error)
+	DescribeExecution(ctx context.Context, params *sfn.DescribeExecutionInput, optFns ...func(*sfn.Options)) (*sfn.DescribeExecutionOutput, error)
+	StartExecution(ctx context.Context, params *sfn.StartExecutionInput, optFns ...func(*sfn.Options)) (*sfn.StartExecutionOutput, error)
+	StopExecution(ctx context.Context, params *sfn.StopExecutionInput, optFns ...func(*sfn.Options)) (*sfn.StopExecutionOutput, error)
+	RedriveExecution(ctx context.Context, params *sfn.RedriveExecutionInput, optFns ...func(*sfn.Options)) (*sfn.RedriveExecutionOutput, error)
+	sfn.ListMapRunsAPIClient
+	DescribeMapRun(ctx context.Context, params *sfn.DescribeMapRunInput, optFns ...func(*sfn.Options)) (*sfn.DescribeMapRunOutput, error)
+	ListTagsForResource(ctx context.Context, params *sfn.ListTagsForResourceInput, optFns ...func(*sfn.Options)) (*sfn.ListTagsForResourceOutput, error)
+}
+
+// logsAPI is the part of the CloudWatch Logs client the fetcher uses
+type logsAPI interface {
+	cloudwatchlogs.FilterLogEventsAPIClient
+}
+
+// Option configures optional Fetcher behaviour
+type Option func(*Fetcher)
+
+// WithHTTPClient makes every AWS client of NewFetcher send requests through
+// client, e.g. for proxies or transport tuning. A custom CA bundle from the
+// environment or WithConfigOptions needs an *awshttp.BuildableClient.
+func WithHTTPClient(client aws.HTTPClient) Option {
+	return WithConfigOptions(config.WithHTTPClient(client))
+}
+
+// WithConfigOptions adds options for loading the AWS configuration of
+// NewFetcher, such as FIPS endpoints or a custom CA bundle
+func WithConfigOptions(opts ...func(*config.LoadOptions) error) Option {
+	return func(f *Fetcher) {
+		f.configOptions = append(f.configOptions, opts...)
+	}
+}
+
+// WithHistory makes the fetcher retrieve the full event history of every
+// execution: via GetExecutionHistory for Standard workflows and by collecting
+// all of an execution's CloudWatch Logs events for Express workflows.
+func WithHistory(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.includeHistory = enabled
+	}
+}
+
+// DefaultExpressFilterPattern matches the execution start and end events of
+// Express workflows, in both the documented and camelCase log formats
+const DefaultExpressFilterPattern = `{ $.type = "ExecutionStarted" || $.type = "ExecutionSucceeded" || $.type = "ExecutionFailed" || $.type = "ExecutionTimedOut" || $.type = "ExecutionAborted" || ` +
+	`$.eventType = "ExecutionStarted" || $.eventType = "ExecutionSucceeded" || $.eventType = "ExecutionFailed" || $.eventType = "ExecutionTimedOut" || $.eventType = "ExecutionAborted" }`
+
+// ExpressLogQuery controls how Express executions are discovered in CloudWatch Logs
+type ExpressLogQuery struct {
+	FilterPattern string        // CloudWatch Logs filter pattern
+	Limit         int32         // Maximum events returned per FilterLogEvents page
+	Lookback      time.Duration // How far back to search
+	MaxBytes      int64         // Stop paging after this many bytes of log messages (0 = unlimited)
+	MaxTime       time.Duration // Stop paging after this much time per log group (0 = unlimited)
+}
+
+// WithExpressLogQuery overrides the default Express discovery query. A zero
+// FilterPattern, Limit or Lookback keeps its default; MaxBytes and MaxTime
+// are always taken from query and have no default, so zero means unlimited.
+func WithExpressLogQuery(query ExpressLogQuery) Option {
+	return func(f *Fetcher) {
+		if query.FilterPattern != "" {
+			f.expressQuery.FilterPattern = query.FilterPattern
+		}
+		if query.Limit > 0 {
+			f.expressQuery.Limit = query.Limit
+		}
+		if query.Lookback > 0 {
+			f.expressQuery.Lookback = query.Lookback
+		}
+		f.expressQuery.MaxBytes = query.MaxBytes
+		f.expressQuery.MaxTime = query.MaxTime
+	}
+}
+
+func NewFetcher(ctx context.Context, region string, opts ...Option) (*Fetcher, error) {
+	f := newFetcher(opts...)
+	cfg, err := config.LoadDefaultConfig(ctx, append(f.configOptions, config.WithRegion(region))...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	return &Fetcher{
-		sfnClient:  sfn.NewFromConfig(cfg),
-		logsClient: cloudwatchlogs.NewFromConfig(cfg),
-	}, nil
+	// Every client, including per-region log clients, shares the call budget
+	// and is instrumented. The Service Quotas lookups that set up pacing are
+	// not part of the fetch and do not count against WithMaxAPICalls.
+	quotasCfg := cfg.Copy()
+	quotasCfg.APIOptions = append(quotasCfg.APIOptions, f.timeoutMiddleware, classifyMiddleware)
+	cfg.APIOptions = append(cfg.APIOptions, f.budget.middleware, f.timeoutMiddleware, f.pacingMiddleware, classifyMiddleware, newTelemetry().middleware)
+	f.cfg = cfg
+	f.sfnClient = sfn.NewFromConfig(cfg)
+	f.logsClient = cloudwatchlogs.NewFromConfig(cfg)
+	f.metricsClient = cloudwatch.NewFromConfig(cfg)
+	f.cfnClient = cloudformation.NewFromConfig(cfg)
+	f.s3Client = s3.NewFromConfig(cfg)
+	f.trailClient = cloudtrail.NewFromConfig(cfg)
+	f.iamClient = iam.NewFromConfig(cfg)
+	f.kmsClient = kms.NewFromConfig(cfg)
+	if f.quotaPacing {
+		f.tunePacing(ctx, servicequotas.NewFromConfig(quotasCfg))
+	}
+	return f, nil
+}
+
+// newFetcher applies the defaults and options, without creating clients
+func newFetcher(opts ...Option) *Fetcher {
+	f := &Fetcher{
+		expressQuery: ExpressLogQuery{
+			FilterPattern: DefaultExpressFilterPattern,
+			Limit:         50,
+			Lookback:      24 * time.Hour,
+		},
+		logsClients: make(map[string]logsAPI),
+		clock:       systemClock{},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
+// ListStateMachines fetches every state machine in the region, or only those
+// given with WithStateMachineARNs
 func (f *Fetcher) ListStateMachines(ctx context.Context) ([]StateMachine, error) {
+	arns, err := f.stateMachineARNs(ctx)
+	if errors.Is(err, ErrAPIBudgetExceeded) {
+		// The state machines listed so far cannot be described either
+		f.skipOverBudget(append([]string{"ListStateMachines"}, arns...), err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	var stateMachines []StateMachine
-	input := &sfn.ListStateMachinesInput{}
+	for i, smArn := range arns {
+		details, err := f.getStateMachineDetails(ctx, smArn)
+		if errors.Is(err, ErrAPIBudgetExceeded) {
+			f.skipOverBudget(arns[i:], err)
+			return stateMachines, nil
+		}
+		if err != nil {
+			f.warn(smArn, "DescribeStateMachine", err)
+			if err := f.skip(smArn, err); err != nil {
+				return stateMachines, err
+			}
+			continue
+		}
+		stateMachines = append(stateMachines, details)
+	}
+
+	return stateMachines, nil
+}
+
+// WithStateMachineARNs limits the fetch to the given state machines instead
+// of listing every state machine in the region
+func WithStateMachineARNs(arns []string) Option {
+	return func(f *Fetcher) {
+		f.arns = arns
+	}
+}
 
-	paginator := sfn.NewListStateMachinesPaginator(f.sfnClient, input)
+// stateMachineARNs returns the state machines to fetch
+func (f *Fetcher) stateMachineARNs(ctx context.Context) ([]string, error) {
+	if len(f.arns) > 0 {
+		return f.arns, nil
+	}
+
+	var arns []string
+	paginator := sfn.NewListStateMachinesPaginator(f.sfnClient, &sfn.ListStateMachinesInput{})
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list state machines: %w", err)
+			// The ARNs listed so far, for callers that report what was left out
+			return arns, fmt.Errorf("failed to list state machines: %w", err)
 		}
-
 		for _, sm := range page.StateMachines {
-			details, err := f.getStateMachineDetails(ctx, *sm.StateMachineArn)
-			if err != nil {
-				fmt.Printf("Warning: Failed to get details for %s: %v\n", *sm.StateMachineArn, err)
-				continue
-			}
-			stateMachines = append(stateMachines, details)
+			arns = append(arns, *sm.StateMachineArn)
 		}
 	}
-
-	return stateMachines, nil
+	return arns, nil
 }
 
 func (f *Fetcher) getStateMachineDetails(ctx context.Context, arn string) (StateMachine, error) {
@@ -69,169 +262,339 @@ func (f *Fetcher) getStateMachineDetails(ctx context.Context, arn string) (State
 	smType := string(result.Type)
 	fmt.Printf("Debug: State machine %s type: %s\n", *result.Name, smType)
 
-	// Parse state definitions
-	states, err := parseDefinition(*result.Definition)
-	if err != nil {
-		return StateMachine{}, fmt.Errorf("failed to parse definition for %s: %w", arn, err)
+	// Parse state definitions, unless this revision is already cached
+	revisionID := aws.ToString(result.RevisionId)
+	states, cached := f.cachedStates(arn, revisionID)
+	if !cached {
+		states, err = parseDefinition(*result.Definition)
+		if err != nil {
+			return StateMachine{}, fmt.Errorf("failed to parse definition for %s: %w", arn, err)
+		}
+		if err := f.cacheStates(arn, revisionID, states); err != nil {
+			f.warn(arn, "CacheDefinition", err)
+		}
 	}
 
 	// Fetch executions based on state machine type
 	var executions []Execution
+	var metrics *ExecutionMetrics
 	if smType == "EXPRESS" {
 		fmt.Printf("Debug: Fetching executions from CloudWatch Logs for Express Workflow %s\n", *result.Name)
 		executions, err = f.getExpressExecutions(ctx, result)
 		if err != nil {
-			fmt.Printf("Warning: Failed to fetch Express Workflow executions for %s: %v\n", *result.Name, err)
-			executions = []Execution{{
+			f.warn(arn, "FetchExpressExecutions", err)
+			placeholder := Execution{
 				ExecutionArn: "N/A",
 				Status:       "Not supported (check CloudWatch Logs configuration)",
-				StartTime:    "",
-				EndTime:      "",
-				Duration:     "N/A",
-			}}
+			}
+			// Without logs, the metrics still show how active the workflow
+			// is; only a state machine they could not cover is skipped
+			if metrics = f.expressMetricsFallback(ctx, arn, err); metrics != nil {
+				placeholder.Status = metrics.Summary()
+			} else if err := f.skip(arn, err); err != nil {
+				return StateMachine{}, err
+			}
+			executions = []Execution{placeholder}
 		}
 	} else if smType == "STANDARD" {
 		executions, err = f.getExecutions(ctx, arn)
 		if err != nil {
 			return StateMachine{}, fmt.Errorf("failed to fetch executions for %s: %w", arn, err)
 		}
+		if maps := distributedMaps(*result.Definition); f.mapRuns && len(maps) > 0 {
+			for i := range executions {
+				executions[i].MapRuns = f.getMapRuns(ctx, executions[i].ExecutionArn, maps)
+			}
+		}
 	} else {
-		fmt.Printf("Warning: Unknown state machine type %s for %s\n", smType, *result.Name)
+		f.warn(arn, "DescribeStateMachine", fmt.Errorf("unknown state machine type %s", smType))
 		executions = []Execution{{
 			ExecutionArn: "N/A",
 			Status:       fmt.Sprintf("Unknown state machine type: %s", smType),
-			StartTime:    "",
-			EndTime:      "",
-			Duration:     "N/A",
 		}}
 	}
 
-	return StateMachine{
-		Name:         *result.Name,
-		ARN:          *result.StateMachineArn,
-		RoleARN:      *result.RoleArn,
-		Definition:   *result.Definition,
-		States:       states,
-		Executions:   executions,
-		CreationDate: result.CreationDate.Format(time.RFC3339),
-		Type:         smType,
-	}, nil
+	var raw json.RawMessage
+	if f.keepRaw {
+		if raw, err = rawResponse(result); err != nil {
+			f.warn(arn, "KeepRawResponse", err)
+		}
+	}
+
+	sm := StateMachine{
+		Name:           *result.Name,
+		ARN:            *result.StateMachineArn,
+		RoleARN:        *result.RoleArn,
+		Definition:     *result.Definition,
+		DefinitionHash: DefinitionHash(*result.Definition),
+		RevisionID:     revisionID,
+		States:         states,
+		Cached:         cached,
+		Executions:     executions,
+		Metrics:        metrics,
+		CreationDate:   aws.ToTime(result.CreationDate),
+		Type:           smType,
+		Logging:        toLoggingConfiguration(result.LoggingConfiguration),
+		Tracing:        result.TracingConfiguration != nil && result.TracingConfiguration.Enabled,
+		Encryption:     toEncryptionConfiguration(result.EncryptionConfiguration),
+		RawDescribe:    raw,
+	}
+	attachConsoleURLs(&sm)
+	return sm, nil
+}
+
+func toLoggingConfiguration(cfg *types.LoggingConfiguration) LoggingConfiguration {
+	if cfg == nil {
+		return LoggingConfiguration{Level: string(types.LogLevelOff)}
+	}
+
+	logging := LoggingConfiguration{
+		Level:                string(cfg.Level),
+		IncludeExecutionData: cfg.IncludeExecutionData,
+	}
+	for _, dest := range cfg.Destinations {
+		if dest.CloudWatchLogsLogGroup != nil && dest.CloudWatchLogsLogGroup.LogGroupArn != nil {
+			logging.Destinations = append(logging.Destinations, *dest.CloudWatchLogsLogGroup.LogGroupArn)
+		}
+	}
+	return logging
+}
+
+func toEncryptionConfiguration(cfg *types.EncryptionConfiguration) EncryptionConfiguration {
+	if cfg == nil || cfg.Type == "" {
+		// State machines created before customer managed keys were supported
+		return EncryptionConfiguration{Type: string(types.EncryptionTypeAwsOwnedKey)}
+	}
+	return EncryptionConfiguration{
+		Type:                      string(cfg.Type),
+		KMSKeyID:                  aws.ToString(cfg.KmsKeyId),
+		DataKeyReusePeriodSeconds: aws.ToInt32(cfg.KmsDataKeyReusePeriodSeconds),
+	}
 }
 
 func (f *Fetcher) getExecutions(ctx context.Context, stateMachineArn string) ([]Execution, error) {
 	var executions []Execution
+	var listed []types.ExecutionListItem // Every execution, when sampling
 	input := &sfn.ListExecutionsInput{
-		StateMachineArn: aws.String(stateMachineArn),
-		MaxResults:      50, // Adjust as needed
+		StateMachineArn: aws.String(f.executionScope(stateMachineArn)),
+		MaxResults:      listExecutionsPageSize,
+	}
+	if f.sample.enabled() {
+		input.MaxResults = 1000
 	}
 
 	paginator := sfn.NewListExecutionsPaginator(f.sfnClient, input)
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
+		if errors.Is(err, ErrAPIBudgetExceeded) {
+			break
+		}
+		if f.alias != "" && isMissingScope(err) {
+			f.warn(stateMachineArn, "ListExecutions", fmt.Errorf("no alias or version %s: %w", f.alias, err))
+			return executions, nil
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to list executions: %w", err)
 		}
 
-		for _, exec := range page.Executions {
-			descInput := &sfn.DescribeExecutionInput{
-				ExecutionArn: exec.ExecutionArn,
-			}
-			descResult, err := f.sfnClient.DescribeExecution(ctx, descInput) // Fixed: f.sfnClient
-			if err != nil {
-				fmt.Printf("Warning: Failed to describe execution %s: %v\n", *exec.ExecutionArn, err)
-				continue
-			}
-
-			endTime := ""
-			duration := "N/A"
-			if descResult.StopDate != nil {
-				endTime = descResult.StopDate.Format(time.RFC3339)
-				start, _ := time.Parse(time.RFC3339, descResult.StartDate.Format(time.RFC3339))
-				end, _ := time.Parse(time.RFC3339, endTime)
-				dur := end.Sub(start)
-				duration = fmt.Sprintf("%v", dur)
-			}
-
-			executions = append(executions, Execution{
-				ExecutionArn: *descResult.ExecutionArn,
-				Status:       string(descResult.Status),
-				StartTime:    descResult.StartDate.Format(time.RFC3339),
-				EndTime:      endTime,
-				Duration:     duration,
-			})
+		if f.sample.enabled() {
+			listed = append(listed, page.Executions...)
+			continue
+		}
+		var done bool
+		if executions, done, err = f.describeExecutions(ctx, stateMachineArn, page.Executions, executions); done {
+			return executions, err
 		}
 	}
 
+	if f.sample.enabled() && len(listed) > 0 {
+		statuses := make([]string, len(listed))
+		for i, exec := range listed {
+			statuses[i] = string(exec.Status)
+		}
+		var sampled []types.ExecutionListItem
+		for _, i := range f.sample.keep(statuses) {
+			sampled = append(sampled, listed[i])
+		}
+		f.sampled = append(f.sampled, SampledCount{StateMachineARN: stateMachineArn, Kept: len(sampled), Listed: len(listed)})
+		executions, _, err := f.describeExecutions(ctx, stateMachineArn, sampled, executions)
+		return executions, err
+	}
 	return executions, nil
 }
 
-func (f *Fetcher) getExpressExecutions(ctx context.Context, sm *sfn.DescribeStateMachineOutput) ([]Execution, error) {
-	var executions []Execution
+// describeExecutions describes listed executions, with their history when
+// enabled, and appends them to executions. done is set when the API budget
+// ran out or a failure ends the fetch.
+func (f *Fetcher) describeExecutions(ctx context.Context, stateMachineArn string, listed []types.ExecutionListItem, executions []Execution) (_ []Execution, done bool, _ error) {
+	for _, exec := range listed {
+		execution, err := f.DescribeExecution(ctx, *exec.ExecutionArn)
+		if errors.Is(err, ErrAPIBudgetExceeded) {
+			return executions, true, nil
+		}
+		if err != nil {
+			f.warn(*exec.ExecutionArn, "DescribeExecution", err)
+			if err := f.skip(*exec.ExecutionArn, err); err != nil {
+				return executions, true, err
+			}
+			continue
+		}
 
-	// Check if logging is enabled
-	if sm.LoggingConfiguration == nil || len(sm.LoggingConfiguration.Destinations) == 0 {
-		return executions, fmt.Errorf("logging not enabled for Express Workflow %s", *sm.Name)
+		wantHistory := f.includeHistory || (f.callbacks && execution.Status == "RUNNING")
+		if wantHistory && !f.BudgetExceeded() {
+			history, err := f.GetExecutionHistory(ctx, execution.ExecutionArn)
+			switch {
+			case errors.Is(err, ErrAPIBudgetExceeded):
+				// Keep the execution summary without its history
+			case err != nil:
+				f.warn(execution.ExecutionArn, "GetExecutionHistory", err)
+				if err := f.skip(execution.ExecutionArn, err); err != nil {
+					return executions, true, err
+				}
+			default:
+				execution.History = history
+				execution.Transitions = countTransitions(history)
+				execution.Captured = Capture{History: true, Payloads: true}
+			}
+		}
+
+		f.emit(ctx, stateMachineArn, execution)
+		executions = append(executions, execution)
+	}
+	return executions, false, nil
+}
+
+// DescribeExecution fetches the summary of a single Standard execution
+func (f *Fetcher) DescribeExecution(ctx context.Context, executionArn string) (Execution, error) {
+	descResult, err := f.describeExecution(ctx, executionArn)
+	if err != nil {
+		return Execution{}, err
 	}
+	return toExecution(descResult), nil
+}
 
-	// Get the CloudWatch Log Group
-	logGroupArn := sm.LoggingConfiguration.Destinations[0].CloudWatchLogsLogGroup.LogGroupArn
-	if logGroupArn == nil {
-		return executions, fmt.Errorf("no CloudWatch Log Group configured for %s", *sm.Name)
+func (f *Fetcher) describeExecution(ctx context.Context, executionArn string) (*sfn.DescribeExecutionOutput, error) {
+	descInput := &sfn.DescribeExecutionInput{
+		ExecutionArn: aws.String(executionArn),
+	}
+	descResult, err := f.sfnClient.DescribeExecution(ctx, descInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe execution %s: %w", executionArn, err)
 	}
+	return descResult, nil
+}
 
-	// Extract Log Group name from ARN
-	logGroupName := strings.Split(*logGroupArn, ":log-group:")[1]
-	logGroupName = strings.Split(logGroupName, ":")[0]
-	fmt.Printf("Debug: Querying CloudWatch Log Group %s for %s\n", logGroupName, *sm.Name)
+func toExecution(descResult *sfn.DescribeExecutionOutput) Execution {
+	exec := Execution{
+		ExecutionArn: *descResult.ExecutionArn,
+		Status:       string(descResult.Status),
+		StartTime:    aws.ToTime(descResult.StartDate),
+		EndTime:      aws.ToTime(descResult.StopDate),
+		InputSize:    len(aws.ToString(descResult.Input)),
+		OutputSize:   len(aws.ToString(descResult.Output)),
+		Error:        aws.ToString(descResult.Error),
+		Cause:        aws.ToString(descResult.Cause),
+	}
+	exec.setDuration()
+	return exec
+}
 
-	// Query CloudWatch Logs for execution events
-	input := &cloudwatchlogs.FilterLogEventsInput{
-		LogGroupName:  aws.String(logGroupName),
-		FilterPattern: aws.String(`{ $.eventType = "ExecutionStarted" || $.eventType = "ExecutionSucceeded" || $.eventType = "ExecutionFailed" || $.eventType = "ExecutionTimedOut" || $.eventType = "ExecutionAborted" }`),
-		Limit:         aws.Int32(50),                                          // Adjust as needed
-		StartTime:     aws.Int64(time.Now().Add(-24 * time.Hour).UnixMilli()), // Last 24 hours
+// discoverExpressExecutions finds the executions logged to the state
+// machine's log groups, and the group each was found in
+func (f *Fetcher) discoverExpressExecutions(ctx context.Context, sm *sfn.DescribeStateMachineOutput) (map[string]*Execution, map[string]logGroup, error) {
+	if f.alias != "" {
+		// Logged execution ARNs do not record the alias or version they were started through
+		f.warn(*sm.StateMachineArn, "FilterLogEvents", fmt.Errorf("alias %s does not apply to Express executions; every logged execution is included", f.alias))
 	}
 
-	result, err := f.logsClient.FilterLogEvents(ctx, input)
-	if err != nil {
-		return executions, fmt.Errorf("failed to query CloudWatch Logs for %s: %w", *sm.Name, err)
+	// Check if logging is enabled
+	if sm.LoggingConfiguration == nil || len(sm.LoggingConfiguration.Destinations) == 0 {
+		return nil, nil, &classifiedError{kind: ErrLoggingNotConfigured, err: fmt.Errorf("logging not enabled for Express Workflow %s", *sm.Name)}
 	}
 
-	// Parse log events to extract execution details
-	type logEvent struct {
-		EventType    string `json:"eventType"`
-		ExecutionArn string `json:"executionArn"`
-		Timestamp    int64  `json:"timestamp"`
-		Status       string `json:"status,omitempty"`
+	if level := sm.LoggingConfiguration.Level; level != types.LogLevelAll {
+		f.warn(*sm.StateMachineArn, "FilterLogEvents", fmt.Errorf("logs at level %s; executions without errors are not logged and start times may be unknown", level))
 	}
 
+	smArn, _ := arn.Parse(*sm.StateMachineArn)
 	executionMap := make(map[string]*Execution)
-	for _, event := range result.Events {
-		var log logEvent
-		if err := json.Unmarshal([]byte(*event.Message), &log); err != nil {
-			fmt.Printf("Warning: Failed to parse log event for %s: %v\n", *sm.Name, err)
+	executionGroups := make(map[string]logGroup)
+	var queried int
+	for _, dest := range sm.LoggingConfiguration.Destinations {
+		if dest.CloudWatchLogsLogGroup == nil || dest.CloudWatchLogsLogGroup.LogGroupArn == nil {
+			continue
+		}
+		group, err := parseLogGroupArn(*dest.CloudWatchLogsLogGroup.LogGroupArn)
+		if err != nil {
+			f.warn(*dest.CloudWatchLogsLogGroup.LogGroupArn, "ParseLogDestination", err)
+			if err := f.skip(*dest.CloudWatchLogsLogGroup.LogGroupArn, err); err != nil {
+				return nil, nil, err
+			}
 			continue
 		}
+		group.crossAccount = group.AccountID != smArn.AccountID
+		fmt.Printf("Debug: Querying CloudWatch Log Group %s (%s) for %s\n", group.Name, group.Region, *sm.Name)
 
-		timestamp := time.UnixMilli(log.Timestamp).Format(time.RFC3339)
-		if _, exists := executionMap[log.ExecutionArn]; !exists && log.EventType == "ExecutionStarted" {
-			executionMap[log.ExecutionArn] = &Execution{
-				ExecutionArn: log.ExecutionArn,
-				Status:       "RUNNING",
-				StartTime:    timestamp,
-				EndTime:      "",
-				Duration:     "N/A",
+		if err := f.collectExpressExecutions(ctx, group, executionMap); err != nil {
+			return nil, nil, fmt.Errorf("failed to query CloudWatch Logs for %s: %w", *sm.Name, err)
+		}
+		for executionArn := range executionMap {
+			if _, ok := executionGroups[executionArn]; !ok {
+				executionGroups[executionArn] = group
 			}
-		} else if exec, exists := executionMap[log.ExecutionArn]; exists && strings.HasPrefix(log.EventType, "Execution") && log.EventType != "ExecutionStarted" {
-			exec.Status = strings.Replace(log.EventType, "Execution", "", 1)
-			exec.EndTime = timestamp
-			start, _ := time.Parse(time.RFC3339, exec.StartTime)
-			end, _ := time.Parse(time.RFC3339, exec.EndTime)
-			exec.Duration = fmt.Sprintf("%v", end.Sub(start))
 		}
+		queried++
+	}
+	if queried == 0 {
+		return nil, nil, &classifiedError{kind: ErrLoggingNotConfigured, err: fmt.Errorf("no CloudWatch Log Group configured for %s", *sm.Name)}
 	}
 
+	return executionMap, executionGroups, nil
+}
+
+func (f *Fetcher) getExpressExecutions(ctx context.Context, sm *sfn.DescribeStateMachineOutput) ([]Execution, error) {
+	var executions []Execution
+	executionMap, executionGroups, err := f.discoverExpressExecutions(ctx, sm)
+	if err != nil {
+		return executions, err
+	}
+
+	discovered := make([]*Execution, 0, len(executionMap))
 	for _, exec := range executionMap {
+		discovered = append(discovered, exec)
+	}
+	if f.sample.enabled() {
+		sort.Slice(discovered, func(i, j int) bool { return discovered[i].StartTime.After(discovered[j].StartTime) })
+		statuses := make([]string, len(discovered))
+		for i, exec := range discovered {
+			statuses[i] = exec.Status
+		}
+		var sampled []*Execution
+		for _, i := range f.sample.keep(statuses) {
+			sampled = append(sampled, discovered[i])
+		}
+		f.sampled = append(f.sampled, SampledCount{StateMachineARN: *sm.StateMachineArn, Kept: len(sampled), Listed: len(discovered)})
+		discovered = sampled
+	}
+
+	for _, exec := range discovered {
+		if f.includeHistory && !f.BudgetExceeded() {
+			history, err := f.getExpressExecutionHistory(ctx, executionGroups[exec.ExecutionArn], *exec)
+			switch {
+			case errors.Is(err, ErrAPIBudgetExceeded):
+				// Keep the execution without its log bundle
+			case err != nil:
+				f.warn(exec.ExecutionArn, "CollectLogBundle", err)
+				if err := f.skip(exec.ExecutionArn, err); err != nil {
+					return executions, err
+				}
+			default:
+				exec.History = history
+				exec.Transitions = countTransitions(history)
+				exec.Captured = expressCapture(sm.LoggingConfiguration, *exec)
+			}
+		}
+		f.emit(ctx, *sm.StateMachineArn, *exec)
 		executions = append(executions, *exec)
 	}
 
@@ -244,18 +607,104 @@ func (f *Fetcher) getExpressExecutions(ctx context.Context, sm *sfn.DescribeStat
 	return executions, nil
 }
 
+// collectExpressExecutions queries one log group for execution start and end
+// events and merges what it finds into executionMap.
+func (f *Fetcher) collectExpressExecutions(ctx context.Context, group logGroup, executionMap map[string]*Execution) error {
+	// Query CloudWatch Logs for execution events
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		FilterPattern: aws.String(f.expressQuery.FilterPattern),
+		Limit:         aws.Int32(f.expressQuery.Limit),
+		StartTime:     aws.Int64(f.clock.Now().Add(-f.expressQuery.Lookback).UnixMilli()),
+	}
+	group.setOn(input)
+
+	// Page through every matching event until the byte or time budget runs out
+	var bytesRead int64
+	started := f.clock.Now()
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(f.logsClientFor(group.Region), input)
+	for paginator.HasMorePages() {
+		if budget := f.expressQuery.MaxBytes; budget > 0 && bytesRead >= budget {
+			f.warn(group.Name, "FilterLogEvents", fmt.Errorf("stopped after %d bytes (budget %d); Express executions may be incomplete", bytesRead, budget))
+			break
+		}
+		if budget := f.expressQuery.MaxTime; budget > 0 && f.clock.Now().Sub(started) >= budget {
+			f.warn(group.Name, "FilterLogEvents", fmt.Errorf("stopped after %v (budget %v); Express executions may be incomplete", f.clock.Now().Sub(started).Round(time.Second), budget))
+			break
+		}
+
+		page, err := paginator.NextPage(ctx)
+		if errors.Is(err, ErrAPIBudgetExceeded) {
+			return nil // Keep the executions found so far
+		}
+		if err != nil {
+			return err
+		}
+		for _, event := range page.Events {
+			bytesRead += int64(len(aws.ToString(event.Message)))
+			f.mergeExecutionEvent(group, aws.ToString(event.Message), executionMap)
+		}
+	}
+
+	return nil
+}
+
+// mergeExecutionEvent applies one execution start or end event to executionMap
+func (f *Fetcher) mergeExecutionEvent(group logGroup, message string, executionMap map[string]*Execution) {
+	log, err := parseLogEvent(message)
+	if err != nil {
+		f.warn(group.Name, "ParseLogEvent", err)
+		return
+	}
+
+	at := time.UnixMilli(log.Timestamp)
+	exec, exists := executionMap[log.ExecutionArn]
+	switch {
+	case log.Type == "ExecutionStarted" && !exists:
+		executionMap[log.ExecutionArn] = &Execution{
+			ExecutionArn: log.ExecutionArn,
+			Status:       "RUNNING",
+			StartTime:    at,
+		}
+	case log.Type == "ExecutionStarted" && exec.Partial:
+		// The start arrived after the terminal event
+		exec.StartTime = at
+		exec.Partial = false
+		exec.setDuration()
+	case strings.HasPrefix(log.Type, "Execution") && log.Type != "ExecutionStarted" && exists:
+		exec.Status = strings.Replace(log.Type, "Execution", "", 1)
+		exec.EndTime = at
+		exec.setDuration()
+	case strings.HasPrefix(log.Type, "Execution") && log.Type != "ExecutionStarted":
+		// Log levels ERROR and FATAL only record terminal events, so keep the
+		// execution with an unknown start instead of dropping it
+		executionMap[log.ExecutionArn] = &Execution{
+			ExecutionArn: log.ExecutionArn,
+			Status:       strings.Replace(log.Type, "Execution", "", 1),
+			EndTime:      at,
+			Partial:      true,
+		}
+	}
+}
+
 func parseDefinition(definition string) ([]State, error) {
 	var aslDef struct {
 		States map[string]map[string]interface{} `json:"States"`
 	}
 
 	if err := json.Unmarshal([]byte(definition), &aslDef); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal ASL definition: %w", err)
+		return nil, &classifiedError{kind: ErrDefinitionParse, err: fmt.Errorf("failed to unmarshal ASL definition: %w", err)}
+	}
+
+	if len(aslDef.States) == 0 {
+		return nil, &classifiedError{kind: ErrDefinitionParse, err: errors.New("ASL definition has no States")}
 	}
 
 	var states []State
 	for name, rawDef := range aslDef.States {
 		stateType, _ := rawDef["Type"].(string)
+		if stateType == "" {
+			return nil, &classifiedError{kind: ErrDefinitionParse, err: fmt.Errorf("state %q has no Type", name)}
+		}
 		next, _ := rawDef["Next"].(string)
 		end, _ := rawDef["End"].(bool)
 		parameters, _ := rawDef["Parameters"].(map[string]interface{})
@@ -0,0 +1,441 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+const (
+	testAccount     = "123456789012"
+	ordersArn       = "arn:aws:states:us-east-1:" + testAccount + ":stateMachine:orders"
+	ordersExecution = "arn:aws:states:us-east-1:" + testAccount + ":execution:orders:"
+)
+
+var testStart = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func readDefinition(t testing.TB, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "definitions", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// standardFixture is one Standard state machine with a succeeded (with
+// history), a failed and a running execution
+func standardFixture(t testing.TB) *fakeSFN {
+	return &fakeSFN{
+		stateMachines: []*sfn.DescribeStateMachineOutput{{
+			StateMachineArn:      aws.String(ordersArn),
+			Name:                 aws.String("orders"),
+			Type:                 types.StateMachineTypeStandard,
+			Definition:           aws.String(readDefinition(t, "order.asl.json")),
+			RoleArn:              aws.String("arn:aws:iam::" + testAccount + ":role/orders"),
+			RevisionId:           aws.String("rev-1"),
+			CreationDate:         aws.Time(testStart.Add(-24 * time.Hour)),
+			TracingConfiguration: &types.TracingConfiguration{Enabled: true},
+		}},
+		executions: map[string][]*sfn.DescribeExecutionOutput{
+			ordersArn: {
+				{
+					ExecutionArn: aws.String(ordersExecution + "succeeded"),
+					Status:       types.ExecutionStatusSucceeded,
+					StartDate:    aws.Time(testStart),
+					StopDate:     aws.Time(testStart.Add(90 * time.Second)),
+					Input:        aws.String(`{"items":[1,2]}`),
+					Output:       aws.String(`{"ok":true}`),
+				},
+				{
+					ExecutionArn: aws.String(ordersExecution + "failed"),
+					Status:       types.ExecutionStatusFailed,
+					StartDate:    aws.Time(testStart),
+					StopDate:     aws.Time(testStart.Add(2 * time.Hour)),
+					Error:        aws.String("OutOfStock"),
+					Cause:        aws.String("Item unavailable"),
+				},
+				{
+					ExecutionArn: aws.String(ordersExecution + "running"),
+					Status:       types.ExecutionStatusRunning,
+					StartDate:    aws.Time(testStart),
+				},
+			},
+		},
+		histories: map[string][]types.HistoryEvent{
+			ordersExecution + "succeeded": {
+				{Id: 1, Type: types.HistoryEventTypeExecutionStarted, Timestamp: aws.Time(testStart),
+					ExecutionStartedEventDetails: &types.ExecutionStartedEventDetails{Input: aws.String(`{"items":[1,2]}`)}},
+				{Id: 2, PreviousEventId: 1, Type: types.HistoryEventTypeTaskStateEntered, Timestamp: aws.Time(testStart),
+					StateEnteredEventDetails: &types.StateEnteredEventDetails{Name: aws.String("Validate")}},
+				{Id: 3, PreviousEventId: 2, Type: types.HistoryEventTypeTaskScheduled, Timestamp: aws.Time(testStart),
+					TaskScheduledEventDetails: &types.TaskScheduledEventDetails{Resource: aws.String("invoke"), ResourceType: aws.String("lambda"), Parameters: aws.String(`{}`), Region: aws.String("us-east-1")}},
+				{Id: 4, PreviousEventId: 3, Type: types.HistoryEventTypeTaskFailed, Timestamp: aws.Time(testStart.Add(time.Second)),
+					TaskFailedEventDetails: &types.TaskFailedEventDetails{Resource: aws.String("invoke"), ResourceType: aws.String("lambda"), Error: aws.String("Lambda.TooManyRequestsException")}},
+				{Id: 5, PreviousEventId: 4, Type: types.HistoryEventTypeTaskScheduled, Timestamp: aws.Time(testStart.Add(2 * time.Second)),
+					TaskScheduledEventDetails: &types.TaskScheduledEventDetails{Resource: aws.String("invoke"), ResourceType: aws.String("lambda"), Parameters: aws.String(`{}`), Region: aws.String("us-east-1")}},
+				{Id: 6, PreviousEventId: 5, Type: types.HistoryEventTypeTaskSucceeded, Timestamp: aws.Time(testStart.Add(3 * time.Second)),
+					TaskSucceededEventDetails: &types.TaskSucceededEventDetails{Resource: aws.String("invoke"), ResourceType: aws.String("lambda"), Output: aws.String(`{"valid":true}`)}},
+				{Id: 7, PreviousEventId: 6, Type: types.HistoryEventTypeTaskStateExited, Timestamp: aws.Time(testStart.Add(3 * time.Second)),
+					StateExitedEventDetails: &types.StateExitedEventDetails{Name: aws.String("Validate"), Output: aws.String(`{"valid":true}`)}},
+				{Id: 8, PreviousEventId: 7, Type: types.HistoryEventTypeExecutionSucceeded, Timestamp: aws.Time(testStart.Add(90 * time.Second)),
+					ExecutionSucceededEventDetails: &types.ExecutionSucceededEventDetails{Output: aws.String(`{"ok":true}`)}},
+			},
+		},
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "definitions", "*.asl.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no definitions in testdata: %v", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".asl.json")
+		t.Run(name, func(t *testing.T) {
+			states, err := parseDefinition(readDefinition(t, filepath.Base(path)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sm := []StateMachine{{States: states}}
+			sortForGolden(sm)
+			assertGolden(t, filepath.Join("definitions", name+".golden.json"), marshalGolden(t, sm[0].States))
+		})
+	}
+}
+
+func TestParseDefinitionInvalid(t *testing.T) {
+	for _, definition := range []string{``, `{`, `{"States": []}`, `{"States": {"A": "Pass"}}`, `null`, `{"States": {}}`, `{"States": {"A": {"Next": "B"}}}`, `{"States": {"A": {"Type": 1}}}`} {
+		if _, err := parseDefinition(definition); !errors.Is(err, ErrDefinitionParse) {
+			t.Errorf("parseDefinition(%q) = %v, want ErrDefinitionParse", definition, err)
+		}
+	}
+}
+
+func TestToExecutionDuration(t *testing.T) {
+	tests := []struct {
+		name         string
+		stop         *time.Time
+		wantDuration time.Duration
+		wantFormat   string
+	}{
+		{"running", nil, 0, "N/A"},
+		{"seconds", aws.Time(testStart.Add(42 * time.Second)), 42 * time.Second, "42s"},
+		{"minutes", aws.Time(testStart.Add(90 * time.Second)), 90 * time.Second, "1m30s"},
+		{"sub-second precision", aws.Time(testStart.Add(1500 * time.Millisecond)), 1500 * time.Millisecond, "1.5s"},
+		{"days", aws.Time(testStart.Add(49 * time.Hour)), 49 * time.Hour, "49h0m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := toExecution(&sfn.DescribeExecutionOutput{
+				ExecutionArn: aws.String(ordersExecution + "x"),
+				Status:       types.ExecutionStatusSucceeded,
+				StartDate:    aws.Time(testStart),
+				StopDate:     tt.stop,
+				Input:        aws.String("12345"),
+			})
+			if !exec.StartTime.Equal(testStart) || !exec.EndTime.Equal(aws.ToTime(tt.stop)) || exec.Duration != tt.wantDuration {
+				t.Errorf("got start %v, end %v, duration %v; want duration %v", exec.StartTime, exec.EndTime, exec.Duration, tt.wantDuration)
+			}
+			if got := exec.FormatDuration(); got != tt.wantFormat {
+				t.Errorf("FormatDuration() = %q, want %q", got, tt.wantFormat)
+			}
+			if exec.InputSize != 5 || exec.OutputSize != 0 {
+				t.Errorf("got payload sizes %d/%d, want 5/0", exec.InputSize, exec.OutputSize)
+			}
+		})
+	}
+}
+
+func TestMergeExecutionEvent(t *testing.T) {
+	group := logGroup{Name: "/aws/vendedlogs/states/orders"}
+	event := func(eventType string, seconds int) string {
+		timestamp := testStart.Add(time.Duration(seconds) * time.Second).UnixMilli()
+		return `{"type":"` + eventType + `","execution_arn":"` + ordersExecution + `e1","event_timestamp":"` + strconv.FormatInt(timestamp, 10) + `"}`
+	}
+
+	t.Run("start then end", func(t *testing.T) {
+		var warnings collectWarnings
+		f := newFetcher(WithWarningSink(&warnings))
+		executions := map[string]*Execution{}
+		f.mergeExecutionEvent(group, event("ExecutionStarted", 0), executions)
+		f.mergeExecutionEvent(group, event("ExecutionSucceeded", 75), executions)
+		exec := executions[ordersExecution+"e1"]
+		if exec.Status != "Succeeded" || exec.Duration != 75*time.Second || exec.Partial {
+			t.Errorf("got %+v", *exec)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("unexpected warnings %v", warnings)
+		}
+	})
+
+	t.Run("end before start", func(t *testing.T) {
+		f := newFetcher()
+		executions := map[string]*Execution{}
+		f.mergeExecutionEvent(group, event("ExecutionFailed", 30), executions)
+		exec := executions[ordersExecution+"e1"]
+		if !exec.Partial || exec.DurationKnown() || !exec.StartTime.IsZero() {
+			t.Fatalf("terminal event alone: got %+v", *exec)
+		}
+		f.mergeExecutionEvent(group, event("ExecutionStarted", 0), executions)
+		if exec.Partial || exec.Status != "Failed" || exec.Duration != 30*time.Second {
+			t.Errorf("after the start arrived: got %+v", *exec)
+		}
+	})
+
+	t.Run("unparseable", func(t *testing.T) {
+		var warnings collectWarnings
+		f := newFetcher(WithWarningSink(&warnings))
+		executions := map[string]*Execution{}
+		f.mergeExecutionEvent(group, "START RequestId: not json", executions)
+		if len(executions) != 0 || len(warnings) != 1 || warnings[0].Resource != group.Name {
+			t.Errorf("got executions %v, warnings %v", executions, warnings)
+		}
+	})
+}
+
+func TestListStateMachinesStandard(t *testing.T) {
+	client := standardFixture(t)
+	var warnings collectWarnings
+	f := newTestFetcher(client, &fakeLogs{}, WithHistory(true), WithWarningSink(&warnings))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 || len(f.Skipped()) != 0 {
+		t.Errorf("unexpected warnings %v, skipped %v", warnings, f.Skipped())
+	}
+	if client.calls["ListExecutions"] != 3 {
+		t.Errorf("ListExecutions called %d times, want one per page (3)", client.calls["ListExecutions"])
+	}
+	sortForGolden(stateMachines)
+	assertGolden(t, "standard.golden.json", marshalGolden(t, stateMachines))
+}
+
+func TestListStateMachinesExplicitARNs(t *testing.T) {
+	client := standardFixture(t)
+	f := newTestFetcher(client, &fakeLogs{}, WithStateMachineARNs([]string{ordersArn}))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stateMachines) != 1 || client.calls["ListStateMachines"] != 0 {
+		t.Errorf("got %d state machines with %d ListStateMachines calls, want 1 and 0", len(stateMachines), client.calls["ListStateMachines"])
+	}
+}
+
+func TestListStateMachinesSkipsFailures(t *testing.T) {
+	missing := "arn:aws:states:us-east-1:" + testAccount + ":stateMachine:missing"
+	var warnings collectWarnings
+	f := newTestFetcher(standardFixture(t), &fakeLogs{}, WithStateMachineARNs([]string{missing, ordersArn}), WithWarningSink(&warnings))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stateMachines) != 1 || stateMachines[0].ARN != ordersArn {
+		t.Errorf("got %d state machines, want only orders", len(stateMachines))
+	}
+	if skipped := f.Skipped(); len(skipped) != 1 || skipped[0].Resource != missing {
+		t.Errorf("got skipped %v, want %s", skipped, missing)
+	}
+	if len(warnings) != 1 || warnings[0].Operation != "DescribeStateMachine" {
+		t.Errorf("got warnings %v", warnings)
+	}
+}
+
+func TestListStateMachinesFailFast(t *testing.T) {
+	missing := "arn:aws:states:us-east-1:" + testAccount + ":stateMachine:missing"
+	f := newTestFetcher(standardFixture(t), &fakeLogs{}, WithStateMachineARNs([]string{missing, ordersArn}), WithFailFast(true), WithWarningSink(&collectWarnings{}))
+
+	if _, err := f.ListStateMachines(context.Background()); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("got %v, want the error of %s", err, missing)
+	}
+}
+
+func TestListStateMachinesAlias(t *testing.T) {
+	tests := []struct {
+		alias    string
+		want     int
+		warnings int
+	}{
+		{"PROD", 1, 0},
+		{"BETA", 0, 1}, // No such alias: nothing in scope, a warning rather than an error
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			client := standardFixture(t)
+			client.executions[ordersArn+":PROD"] = client.executions[ordersArn][:1]
+			var warnings collectWarnings
+			f := newTestFetcher(client, &fakeLogs{}, WithAlias(tt.alias), WithWarningSink(&warnings))
+
+			stateMachines, err := f.ListStateMachines(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stateMachines) != 1 || len(stateMachines[0].Executions) != tt.want {
+				t.Errorf("got %+v, want %d executions", stateMachines, tt.want)
+			}
+			if len(f.Skipped()) != 0 {
+				t.Errorf("got skipped %v", f.Skipped())
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("got warnings %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+// recordingClient answers every AWS request with a non-retryable error
+type recordingClient struct{ hosts []string }
+
+func (c *recordingClient) Do(req *http.Request) (*http.Response, error) {
+	c.hosts = append(c.hosts, req.URL.Host)
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": []string{"application/x-amz-json-1.0"}},
+		Body:       io.NopCloser(strings.NewReader(`{"__type":"AccessDeniedException","message":"offline"}`)),
+		Request:    req,
+	}, nil
+}
+
+// offlineAWS points the SDK at static credentials and no config files
+func offlineAWS(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CA_BUNDLE", "") // Needs a BuildableClient
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	offlineAWS(t)
+	client := &recordingClient{}
+	ctx := context.Background()
+	f, err := NewFetcher(ctx, "us-east-1", WithHTTPClient(client), WithWarningSink(&collectWarnings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ListStateMachines(ctx); err == nil {
+		t.Fatal("expected the injected client's error")
+	}
+	if len(client.hosts) != 1 || client.hosts[0] != "states.us-east-1.amazonaws.com" {
+		t.Errorf("got requests to %v, want one to states.us-east-1.amazonaws.com", client.hosts)
+	}
+	if calls := f.APICallsByOperation(); calls["SFN.ListStateMachines"] != 1 || len(calls) != 1 {
+		t.Errorf("got API calls %v, want one SFN.ListStateMachines", calls)
+	}
+}
+
+// hangingClient never answers until the request is cancelled
+type hangingClient struct{}
+
+func (hangingClient) Do(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestWithAPITimeout(t *testing.T) {
+	offlineAWS(t)
+	ctx := context.Background()
+	f, err := NewFetcher(ctx, "us-east-1", WithHTTPClient(hangingClient{}), WithAPITimeout(50*time.Millisecond), WithWarningSink(&collectWarnings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.ListStateMachines(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrAPITimeout) || ErrorKind(err) != "timeout" {
+			t.Errorf("got %v (kind %s), want ErrAPITimeout", err, ErrorKind(err))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("call was not cut off by the API timeout")
+	}
+}
+
+// serverClient sends every request to an httptest server
+type serverClient struct{ url *url.URL }
+
+func (c serverClient) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = c.url.Scheme, c.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithAPITimeoutStreamedBody(t *testing.T) {
+	offlineAWS(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Streamed, so the body is still being read after GetObject returns
+		w.Write([]byte(`{"ResultFiles":`))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{}}`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx := context.Background()
+	f, err := NewFetcher(ctx, "us-east-1", WithHTTPClient(serverClient{serverURL}), WithAPITimeout(time.Minute), WithWarningSink(&collectWarnings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest map[string]interface{}
+	if err := f.getJSONObject(ctx, "results", "manifest.json", &manifest); err != nil {
+		t.Fatalf("getJSONObject = %v, want the body read under the API timeout", err)
+	}
+	if _, ok := manifest["ResultFiles"]; !ok {
+		t.Errorf("got %v, want the manifest", manifest)
+	}
+}
+
+// budgetSFN fails every ListStateMachines page after the first, as a spent
+// WithMaxAPICalls budget does
+type budgetSFN struct{ *fakeSFN }
+
+func (c budgetSFN) ListStateMachines(ctx context.Context, in *sfn.ListStateMachinesInput, optFns ...func(*sfn.Options)) (*sfn.ListStateMachinesOutput, error) {
+	if in.NextToken != nil {
+		return nil, fmt.Errorf("%w (1 calls)", ErrAPIBudgetExceeded)
+	}
+	return c.fakeSFN.ListStateMachines(ctx, in, optFns...)
+}
+
+func TestListStateMachinesBudgetExceededWhileListing(t *testing.T) {
+	client := standardFixture(t)
+	second := *client.stateMachines[0]
+	second.StateMachineArn = aws.String("arn:aws:states:us-east-1:" + testAccount + ":stateMachine:second")
+	client.stateMachines = append(client.stateMachines, &second)
+	f := newTestFetcher(budgetSFN{client}, &fakeLogs{}, WithWarningSink(&collectWarnings{}))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil || len(stateMachines) != 0 {
+		t.Fatalf("got %d state machines, %v", len(stateMachines), err)
+	}
+	skipped := f.Skipped()
+	if len(skipped) != 2 || skipped[0].Resource != "ListStateMachines" || skipped[1].Resource != aws.ToString(client.stateMachines[0].StateMachineArn) {
+		t.Errorf("got skipped %v, want the listing and the state machine listed before the budget ran out", skipped)
+	}
+	if skipped[0].Kind != "budget_exceeded" {
+		t.Errorf("got kind %s, want budget_exceeded", skipped[0].Kind)
+	}
+}
@@ -0,0 +1,240 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Finding severities, from most to least severe
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Rule describes one kind of finding
+type Rule struct {
+	ID          string
+	Name        string
+	Severity    string
+	Description string
+}
+
+// Rules are the checks behind findings, by ID
+var Rules = map[string]Rule{
+	"SFN001": {"SFN001", "express-logging-disabled", SeverityHigh, "Express workflows must log to CloudWatch Logs, their only execution record"},
+	"SFN002": {"SFN002", "express-logging-partial", SeverityMedium, "Express workflows should log at level ALL so every execution is recorded"},
+	"SFN003": {"SFN003", "tracing-disabled", SeverityLow, "Standard workflows should have X-Ray tracing enabled"},
+	"SFN004": {"SFN004", "aws-owned-key", SeverityLow, "State machine data should be encrypted with a customer managed KMS key"},
+	"SFN005": {"SFN005", "kms-key-misconfigured", SeverityMedium, "The customer managed KMS key should be enabled, customer managed and rotated"},
+	"SFN006": {"SFN006", "task-without-retry", SeverityMedium, "Task states should retry transient errors"},
+	"SFN007": {"SFN007", "iam-wildcard-grant", SeverityHigh, "The execution role should not allow wildcard actions or NotAction"},
+	"SFN008": {"SFN008", "workflow-type-mismatch", SeverityLow, "The observed executions would be cheaper on the other workflow type"},
+	"SFN009": {"SFN009", "cloudformation-drift", SeverityMedium, "State machines created by CloudFormation should match their stack template"},
+	"SFN010": {"SFN010", "payload-near-limit", SeverityMedium, "State inputs and outputs should stay well below the 256KB payload limit; pass large data through S3"},
+
+	// Definition validation, see ValidateDefinition
+	"ASL001": {"ASL001", "invalid-definition", SeverityHigh, "The definition must be a JSON object with StartAt and States"},
+	"ASL002": {"ASL002", "unknown-start-at", SeverityHigh, "StartAt must name a state of the same States object"},
+	"ASL003": {"ASL003", "unknown-transition", SeverityHigh, "Next, Default and Choice rule targets must name a state of the same States object"},
+	"ASL004": {"ASL004", "missing-transition", SeverityHigh, "States other than Choice, Succeed and Fail need Next or \"End\": true"},
+	"ASL005": {"ASL005", "task-without-resource", SeverityHigh, "Task states need a Resource"},
+	"ASL006": {"ASL006", "unreachable-state", SeverityMedium, "Every state should be reachable from the StartAt of its States object"},
+	"ASL007": {"ASL007", "no-terminal-path", SeverityHigh, "Every state should have a path to Succeed, Fail or a state with \"End\": true"},
+	"ASL008": {"ASL008", "unbounded-loop", SeverityMedium, "Loops should have a way out, so they do not run forever"},
+	"ASL009": {"ASL009", "busy-loop", SeverityLow, "Loops should pause in a Wait state or a .sync or .waitForTaskToken task, so they do not spin without pause"},
+}
+
+// complianceRules maps ComplianceIssue checks to rule IDs. Logging issues map
+// to SFN001 or SFN002 depending on the logging level, see ComplianceFindings.
+var complianceRules = map[string]string{
+	"tracing":    "SFN003",
+	"encryption": "SFN004",
+	"kms":        "SFN005",
+}
+
+// Finding is one result of a rule for a state machine, or one of its states
+type Finding struct {
+	RuleID           string
+	Severity         string
+	StateMachineName string
+	StateMachineARN  string
+	State            string `json:",omitempty"` // Empty for state machine wide findings
+	Line             int    `json:",omitempty"` // Line of State in the definition, when found
+	Message          string
+}
+
+// FindingsConfig suppresses findings that are accepted risks
+type FindingsConfig struct {
+	Suppress []Suppression `json:"suppress"`
+}
+
+// Suppression hides the findings of a rule. Empty fields match everything, so
+// {"rule":"SFN003"} turns a rule off and {"stateMachine":"legacy"} silences a
+// state machine.
+type Suppression struct {
+	Rule         string `json:"rule,omitempty"`
+	StateMachine string `json:"stateMachine,omitempty"` // Name or ARN
+	State        string `json:"state,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// LoadFindingsConfig reads and validates a findings config file
+func LoadFindingsConfig(path string) (FindingsConfig, error) {
+	var cfg FindingsConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read findings config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse findings config: %w", err)
+	}
+	for i, s := range cfg.Suppress {
+		if s.Rule != "" {
+			if _, ok := Rules[s.Rule]; !ok {
+				return cfg, fmt.Errorf("suppression %d: unknown rule %s", i+1, s.Rule)
+			}
+		}
+		if s.Rule == "" && s.StateMachine == "" && s.State == "" {
+			return cfg, fmt.Errorf("suppression %d matches every finding", i+1)
+		}
+	}
+	return cfg, nil
+}
+
+// Filter splits findings into the ones to report and the suppressed ones
+func (c FindingsConfig) Filter(findings []Finding) (kept, suppressed []Finding) {
+	for _, finding := range findings {
+		if c.suppresses(finding) {
+			suppressed = append(suppressed, finding)
+		} else {
+			kept = append(kept, finding)
+		}
+	}
+	return kept, suppressed
+}
+
+func (c FindingsConfig) suppresses(finding Finding) bool {
+	for _, s := range c.Suppress {
+		if (s.Rule == "" || s.Rule == finding.RuleID) &&
+			(s.StateMachine == "" || s.StateMachine == finding.StateMachineName || s.StateMachine == finding.StateMachineARN) &&
+			(s.State == "" || s.State == finding.State) {
+			return true
+		}
+	}
+	return false
+}
+
+func newFinding(ruleID string, sm StateMachine, state, format string, args ...interface{}) Finding {
+	return Finding{
+		RuleID:           ruleID,
+		Severity:         Rules[ruleID].Severity,
+		StateMachineName: sm.Name,
+		StateMachineARN:  sm.ARN,
+		State:            state,
+		Line:             definitionLine(sm.Definition, state),
+		Message:          fmt.Sprintf(format, args...),
+	}
+}
+
+// AuditStateMachine runs the checks beyond the compliance and IAM audits:
+// Task retries, observed payload sizes and the workflow type advice
+func AuditStateMachine(sm StateMachine) []Finding {
+	findings := checkTaskRetries(sm)
+	findings = append(findings, checkPayloadSizes(sm)...)
+	if advice, ok := AdviseWorkflowType(sm); ok && advice.RecommendedType != advice.CurrentType {
+		findings = append(findings, newFinding("SFN008", sm, "",
+			"Running as %s would save an estimated $%.6f over %d observed executions", advice.RecommendedType, advice.CostDelta, advice.Executions))
+	}
+	return findings
+}
+
+// ComplianceFindings converts compliance issues of sm into findings
+func ComplianceFindings(sm StateMachine, issues []ComplianceIssue) []Finding {
+	var findings []Finding
+	for _, issue := range issues {
+		ruleID, ok := complianceRules[issue.Check]
+		if issue.Check == "logging" {
+			ruleID, ok = "SFN002", true
+			if sm.Logging.Level == "" || sm.Logging.Level == "OFF" || len(sm.Logging.Destinations) == 0 {
+				ruleID = "SFN001"
+			}
+		}
+		if !ok {
+			continue
+		}
+		findings = append(findings, newFinding(ruleID, sm, "", "%s", issue.Message))
+	}
+	return findings
+}
+
+// PermissionFindings reports the wildcard grants of an execution role analysis
+func PermissionFindings(sm StateMachine, analysis PermissionAnalysis) []Finding {
+	var findings []Finding
+	for _, grant := range analysis.WildcardGrants {
+		findings = append(findings, newFinding("SFN007", sm, "", "Execution role grants %s", grant))
+	}
+	return findings
+}
+
+// DriftFindings reports a state machine that drifted from its stack, and each
+// state changed outside of CloudFormation
+func DriftFindings(sm StateMachine, drift *StackDrift) []Finding {
+	if drift == nil || (drift.Status != "MODIFIED" && drift.Status != "DELETED") {
+		return nil
+	}
+	if len(drift.DefinitionChanges) == 0 {
+		var paths []string
+		for _, diff := range drift.Differences {
+			paths = append(paths, diff.Path)
+		}
+		return []Finding{newFinding("SFN009", sm, "", "Drifted from stack %s (%s): %s", drift.StackName, drift.Status, strings.Join(paths, ", "))}
+	}
+	var findings []Finding
+	for _, change := range drift.DefinitionChanges {
+		findings = append(findings, newFinding("SFN009", sm, change.State, "State %q was %s outside of stack %s", change.State, change.Change, drift.StackName))
+	}
+	return findings
+}
+
+// checkTaskRetries flags Task states, including nested ones, without a Retry rule
+func checkTaskRetries(sm StateMachine) []Finding {
+	var findings []Finding
+	walkStates(sm.Definition, func(name string, rawDef map[string]interface{}) {
+		if stateType, _ := rawDef["Type"].(string); stateType != "Task" {
+			return
+		}
+		if retry, _ := rawDef["Retry"].([]interface{}); len(retry) == 0 {
+			findings = append(findings, newFinding("SFN006", sm, name, "Task state %q has no Retry rule", name))
+		}
+	})
+	return findings
+}
+
+var severityRank = map[string]int{SeverityHigh: 0, SeverityMedium: 1, SeverityLow: 2}
+
+// SeverityAtLeast reports whether severity is threshold or more severe
+func SeverityAtLeast(severity, threshold string) bool {
+	rank, ok := severityRank[severity]
+	return ok && rank <= severityRank[threshold]
+}
+
+// SortFindings orders findings by severity, state machine, rule and state
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.StateMachineName != b.StateMachineName {
+			return a.StateMachineName < b.StateMachineName
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.State < b.State
+	})
+}
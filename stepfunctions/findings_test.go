@@ -0,0 +1,81 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// auditFixture is an Express workflow without logging whose nested Task
+// state has no Retry rule
+func auditFixture() StateMachine {
+	return StateMachine{
+		Name: "checkout",
+		ARN:  checkoutArn,
+		Type: "EXPRESS",
+		Definition: `{"StartAt":"Price","States":{
+			"Price":{"Type":"Task","Resource":"arn:aws:states:::lambda:invoke","Retry":[{"ErrorEquals":["States.ALL"]}],"Next":"Fan"},
+			"Fan":{"Type":"Parallel","End":true,"Branches":[{"StartAt":"Charge","States":{
+				"Charge":{"Type":"Task","Resource":"arn:aws:states:::lambda:invoke","End":true}}}]}}}`,
+		Logging:    LoggingConfiguration{Level: "OFF"},
+		Encryption: EncryptionConfiguration{Type: "AWS_OWNED_KEY"},
+	}
+}
+
+func TestFindings(t *testing.T) {
+	sm := auditFixture()
+	findings := ComplianceFindings(sm, append(CheckLoggingAndTracing(sm), CheckEncryption(sm)...))
+	findings = append(findings, AuditStateMachine(sm)...)
+	findings = append(findings, PermissionFindings(sm, PermissionAnalysis{WildcardGrants: []string{"inline: lambda:* on *"}})...)
+	SortFindings(findings)
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.RuleID+"/"+finding.Severity+"/"+finding.State)
+	}
+	want := []string{"SFN001/high/", "SFN007/high/", "SFN006/medium/Charge", "SFN004/low/"}
+	if len(got) != len(want) {
+		t.Fatalf("got findings %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	var sarif bytes.Buffer
+	if err := WriteSARIF(&sarif, findings, SARIFOptions{ToolVersion: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "findings.golden.sarif", sarif.Bytes())
+}
+
+func TestFindingsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.json")
+	write := func(config string) {
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"suppress":[{"rule":"SFN006","stateMachine":"checkout","state":"Charge","reason":"idempotent"},{"rule":"SFN004"}]}`)
+	cfg, err := LoadFindingsConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := auditFixture()
+	findings := ComplianceFindings(sm, append(CheckLoggingAndTracing(sm), CheckEncryption(sm)...))
+	findings = append(findings, AuditStateMachine(sm)...)
+	kept, suppressed := cfg.Filter(findings)
+	if len(kept) != 1 || kept[0].RuleID != "SFN001" || len(suppressed) != 2 {
+		t.Errorf("got kept %v, suppressed %v", kept, suppressed)
+	}
+
+	for _, config := range []string{`{"suppress":[{"rule":"SFN999"}]}`, `{"suppress":[{"reason":"everything"}]}`} {
+		write(config)
+		if _, err := LoadFindingsConfig(path); err == nil {
+			t.Errorf("LoadFindingsConfig(%s) succeeded, want an error", config)
+		}
+	}
+}
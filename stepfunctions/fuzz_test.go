@@ -0,0 +1,63 @@
+package stepfunctions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzParseDefinition(f *testing.F) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "definitions", "*.asl.json"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+	f.Add(`{"States": {"A": {"Type": "Pass", "End": true}}}`)
+	f.Add(`{"States": {"A": {"Type": "Task", "Parameters": "not an object"}}}`)
+	f.Add(`{"States": {"A": "Pass"}}`)
+
+	f.Fuzz(func(t *testing.T, definition string) {
+		states, err := parseDefinition(definition)
+		if err != nil {
+			if !errors.Is(err, ErrDefinitionParse) {
+				t.Fatalf("got %v, want ErrDefinitionParse", err)
+			}
+			return
+		}
+		if len(states) == 0 {
+			t.Fatal("parsed a definition without states")
+		}
+		for _, state := range states {
+			if state.Type == "" || state.RawDefinition == nil {
+				t.Fatalf("parsed a partial state %+v", state)
+			}
+		}
+	})
+}
+
+func FuzzParseLogEvent(f *testing.F) {
+	for _, message := range expressLogMessages() {
+		f.Add(message)
+	}
+	f.Add(`{"eventType":"ExecutionSucceeded","executionArn":"arn:y","timestamp":1709287201000}`)
+	f.Add(`{"type":"TaskScheduled","execution_arn":"arn:x","details":{"timeoutInSeconds":"30","parameters":{"a":[1]}}}`)
+	f.Add(`{"type":"ExecutionStarted","execution_arn":"arn:x","event_timestamp":"yesterday"}`)
+
+	f.Fuzz(func(t *testing.T, message string) {
+		event, err := parseLogEvent(message)
+		if err != nil {
+			return
+		}
+		if event.Type == "" || event.ExecutionArn == "" {
+			t.Fatalf("parsed a partial event %+v", event)
+		}
+		// The conversion must not panic on whatever the details hold
+		if got := event.toHistoryEvent(); got.Type != event.Type {
+			t.Fatalf("history event type %q, want %q", got.Type, event.Type)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is the transitions of one StartAt/States scope of a definition
+type Graph struct {
+	StartAt string
+	Nodes   []GraphNode // Sorted by name
+	Edges   []GraphEdge
+}
+
+// GraphNode is a state; Parallel branches and Map processors are nested graphs
+type GraphNode struct {
+	Name     string
+	Type     string
+	End      bool    `json:",omitempty"` // "End": true
+	Children []Graph `json:",omitempty"`
+}
+
+// GraphEdge is a transition. Label is empty for Next and otherwise names the
+// rule, e.g. "Choices[0]", "Default" or "Catch States.ALL".
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string `json:",omitempty"`
+}
+
+// BuildGraph returns the state graph of a definition
+func BuildGraph(definition string) (Graph, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal([]byte(definition), &root); err != nil {
+		return Graph{}, &classifiedError{kind: ErrDefinitionParse, err: fmt.Errorf("failed to unmarshal ASL definition: %w", err)}
+	}
+	return buildGraph(root), nil
+}
+
+func buildGraph(machine map[string]interface{}) Graph {
+	g := Graph{}
+	g.StartAt, _ = machine["StartAt"].(string)
+	states, _ := machine["States"].(map[string]interface{})
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rawDef, ok := states[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		node := GraphNode{Name: name}
+		node.Type, _ = rawDef["Type"].(string)
+		node.End, _ = rawDef["End"].(bool)
+		if branches, ok := rawDef["Branches"].([]interface{}); ok {
+			for _, branch := range branches {
+				if sub, ok := branch.(map[string]interface{}); ok {
+					node.Children = append(node.Children, buildGraph(sub))
+				}
+			}
+		}
+		for _, key := range []string{"ItemProcessor", "Iterator"} {
+			if sub, ok := rawDef[key].(map[string]interface{}); ok {
+				node.Children = append(node.Children, buildGraph(sub))
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+
+		if next, ok := rawDef["Next"].(string); ok {
+			g.Edges = append(g.Edges, GraphEdge{From: name, To: next})
+		}
+		choices, _ := rawDef["Choices"].([]interface{})
+		for i, c := range choices {
+			rule, _ := c.(map[string]interface{})
+			if next, ok := rule["Next"].(string); ok {
+				g.Edges = append(g.Edges, GraphEdge{From: name, To: next, Label: fmt.Sprintf("Choices[%d]", i)})
+			}
+		}
+		if next, ok := rawDef["Default"].(string); ok {
+			g.Edges = append(g.Edges, GraphEdge{From: name, To: next, Label: "Default"})
+		}
+		catchers, _ := rawDef["Catch"].([]interface{})
+		for _, c := range catchers {
+			catcher, _ := c.(map[string]interface{})
+			next, ok := catcher["Next"].(string)
+			if !ok {
+				continue
+			}
+			var errs []string
+			errorEquals, _ := catcher["ErrorEquals"].([]interface{})
+			for _, e := range errorEquals {
+				if s, ok := e.(string); ok {
+					errs = append(errs, s)
+				}
+			}
+			g.Edges = append(g.Edges, GraphEdge{From: name, To: next, Label: "Catch " + strings.Join(errs, ",")})
+		}
+	}
+	return g
+}
@@ -0,0 +1,37 @@
+package stepfunctions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	g, err := BuildGraph(`{"StartAt":"Route","States":{
+		"Route":{"Type":"Choice","Choices":[{"Variable":"$.ok","BooleanEquals":true,"Next":"Fan"}],"Default":"Failed"},
+		"Fan":{"Type":"Parallel","Branches":[{"StartAt":"Charge","States":{"Charge":{"Type":"Task","Resource":"arn:x","End":true}}}],
+			"Catch":[{"ErrorEquals":["States.Timeout","States.TaskFailed"],"Next":"Failed"}],"Next":"Done"},
+		"Done":{"Type":"Succeed"},
+		"Failed":{"Type":"Fail"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.StartAt != "Route" || len(g.Nodes) != 4 || g.Nodes[1].Name != "Failed" {
+		t.Fatalf("got graph %+v", g)
+	}
+	if fan := g.Nodes[2]; len(fan.Children) != 1 || fan.Children[0].StartAt != "Charge" {
+		t.Errorf("got Parallel node %+v", fan)
+	}
+	want := []GraphEdge{
+		{From: "Fan", To: "Done"},
+		{From: "Fan", To: "Failed", Label: "Catch States.Timeout,States.TaskFailed"},
+		{From: "Route", To: "Fan", Label: "Choices[0]"},
+		{From: "Route", To: "Failed", Label: "Default"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Errorf("got edges %+v, want %+v", g.Edges, want)
+	}
+
+	if _, err := BuildGraph("{"); err == nil {
+		t.Error("expected an error for an invalid definition")
+	}
+}
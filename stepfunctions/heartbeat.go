@@ -0,0 +1,127 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// HeartbeatStats summarizes the heartbeat timeouts of one task resource or
+// activity, to help tune its HeartbeatSeconds
+type HeartbeatStats struct {
+	StateMachineName  string
+	Resource          string   // e.g. lambda:invoke, or the activity ARN
+	States            []string // States that scheduled the resource
+	HeartbeatSeconds  []int64  // Distinct values configured
+	Attempts          int      // Scheduled with a heartbeat
+	HeartbeatTimeouts int
+	TimeoutRate       float64 // HeartbeatTimeouts / Attempts
+	Executions        int     // Executions with at least one heartbeat timeout
+}
+
+// ComputeHeartbeatStats finds the TaskTimedOut and ActivityTimedOut events
+// caused by a missed heartbeat. States.HeartbeatTimeout is one; a
+// States.Timeout is counted too when the attempt had a heartbeat and ended
+// before its TimeoutSeconds. Resources never scheduled with a heartbeat are
+// left out.
+func ComputeHeartbeatStats(sm StateMachine) []HeartbeatStats {
+	stats := make(map[string]*HeartbeatStats)
+	states := make(map[string]map[string]bool)
+	intervals := make(map[string]map[int64]bool)
+
+	for _, exec := range sm.Executions {
+		scheduledOf := make(map[int64]HistoryEvent) // Event ID -> the Scheduled event of its attempt
+		startedAt := make(map[int64]time.Time)      // Scheduled event ID -> start of the attempt
+		timedOut := make(map[string]bool)
+		for _, event := range exec.History {
+			if event.Type == "TaskScheduled" || event.Type == "ActivityScheduled" {
+				scheduledOf[event.ID] = event
+				if event.HeartbeatSeconds == 0 {
+					continue
+				}
+				resource := heartbeatResource(event)
+				s := stats[resource]
+				if s == nil {
+					s = &HeartbeatStats{StateMachineName: sm.Name, Resource: resource}
+					stats[resource] = s
+					states[resource] = make(map[string]bool)
+					intervals[resource] = make(map[int64]bool)
+				}
+				s.Attempts++
+				states[resource][event.StateName] = true
+				intervals[resource][event.HeartbeatSeconds] = true
+				continue
+			}
+			scheduled, ok := scheduledOf[event.PreviousEventID]
+			if !ok || !(strings.HasPrefix(event.Type, "Task") || strings.HasPrefix(event.Type, "Activity")) || isStateExited(event) {
+				continue
+			}
+			scheduledOf[event.ID] = scheduled
+			switch event.Type {
+			case "TaskStarted", "ActivityStarted":
+				startedAt[scheduled.ID] = event.Timestamp
+			case "TaskTimedOut", "ActivityTimedOut":
+				start, ok := startedAt[scheduled.ID]
+				if !ok {
+					start = scheduled.Timestamp
+				}
+				if !isHeartbeatTimeout(event, scheduled, event.Timestamp.Sub(start)) {
+					continue
+				}
+				s := stats[heartbeatResource(scheduled)]
+				if s == nil {
+					continue // Scheduled without a heartbeat
+				}
+				s.HeartbeatTimeouts++
+				if !timedOut[s.Resource] {
+					timedOut[s.Resource] = true
+					s.Executions++
+				}
+			}
+		}
+	}
+
+	var result []HeartbeatStats
+	for resource, s := range stats {
+		for state := range states[resource] {
+			s.States = append(s.States, state)
+		}
+		sort.Strings(s.States)
+		for seconds := range intervals[resource] {
+			s.HeartbeatSeconds = append(s.HeartbeatSeconds, seconds)
+		}
+		sort.Slice(s.HeartbeatSeconds, func(i, j int) bool { return s.HeartbeatSeconds[i] < s.HeartbeatSeconds[j] })
+		s.TimeoutRate = float64(s.HeartbeatTimeouts) / float64(s.Attempts)
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].HeartbeatTimeouts != result[j].HeartbeatTimeouts {
+			return result[i].HeartbeatTimeouts > result[j].HeartbeatTimeouts
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result
+}
+
+// isHeartbeatTimeout reports whether a timed out attempt missed a heartbeat
+// rather than running out of TimeoutSeconds
+func isHeartbeatTimeout(event, scheduled HistoryEvent, ran time.Duration) bool {
+	switch {
+	case event.Error == "States.HeartbeatTimeout":
+		return true
+	case scheduled.HeartbeatSeconds == 0:
+		return false
+	case event.Error != "" && event.Error != "States.Timeout":
+		return false
+	}
+	return scheduled.TimeoutSeconds == 0 || ran < time.Duration(scheduled.TimeoutSeconds)*time.Second
+}
+
+// heartbeatResource names the resource of a Scheduled event: the activity
+// ARN, or the integration, e.g. lambda:invoke
+func heartbeatResource(scheduled HistoryEvent) string {
+	if scheduled.ResourceType == "" {
+		return scheduled.Resource
+	}
+	return scheduled.ResourceType + ":" + scheduled.Resource
+}
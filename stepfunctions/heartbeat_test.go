@@ -0,0 +1,48 @@
+package stepfunctions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeHeartbeatStats(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	activity := "arn:aws:states:us-east-1:123456789012:activity:pack"
+	sm := StateMachine{Name: "orders", Executions: []Execution{
+		{ExecutionArn: "arn:exec:1", History: []HistoryEvent{
+			{ID: 1, Type: "TaskStateEntered", StateName: "Ship", Timestamp: at(0)},
+			{ID: 2, PreviousEventID: 1, Type: "TaskScheduled", StateName: "Ship", Resource: "invoke", ResourceType: "lambda", HeartbeatSeconds: 30, TimeoutSeconds: 300, Timestamp: at(0)},
+			{ID: 3, PreviousEventID: 2, Type: "TaskStarted", StateName: "Ship", Timestamp: at(1)},
+			{ID: 4, PreviousEventID: 3, Type: "TaskTimedOut", StateName: "Ship", Error: "States.HeartbeatTimeout", Timestamp: at(31)},
+			// The retry ran out of TimeoutSeconds instead
+			{ID: 5, PreviousEventID: 4, Type: "TaskScheduled", StateName: "Ship", Resource: "invoke", ResourceType: "lambda", HeartbeatSeconds: 30, TimeoutSeconds: 300, Timestamp: at(40)},
+			{ID: 6, PreviousEventID: 5, Type: "TaskStarted", StateName: "Ship", Timestamp: at(40)},
+			{ID: 7, PreviousEventID: 6, Type: "TaskTimedOut", StateName: "Ship", Error: "States.Timeout", Timestamp: at(340)},
+			{ID: 8, Type: "ActivityScheduled", StateName: "Pack", Resource: activity, HeartbeatSeconds: 60, Timestamp: at(400)},
+			{ID: 9, PreviousEventID: 8, Type: "ActivityStarted", StateName: "Pack", Timestamp: at(410)},
+			{ID: 10, PreviousEventID: 9, Type: "ActivityTimedOut", StateName: "Pack", Error: "States.Timeout", Timestamp: at(470)},
+			// No heartbeat configured
+			{ID: 11, Type: "TaskScheduled", StateName: "Notify", Resource: "publish", ResourceType: "sns", Timestamp: at(500)},
+		}},
+		{ExecutionArn: "arn:exec:2", History: []HistoryEvent{
+			{ID: 1, Type: "TaskScheduled", StateName: "Ship", Resource: "invoke", ResourceType: "lambda", HeartbeatSeconds: 60, Timestamp: at(0)},
+			{ID: 2, PreviousEventID: 1, Type: "TaskSucceeded", StateName: "Ship", Timestamp: at(10)},
+		}},
+	}}
+
+	stats := ComputeHeartbeatStats(sm)
+	if len(stats) != 2 {
+		t.Fatalf("got %d resources, want 2: %+v", len(stats), stats)
+	}
+	if s := stats[0]; s.Resource != activity || s.HeartbeatTimeouts != 1 || s.Attempts != 1 || s.States[0] != "Pack" {
+		t.Errorf("got activity stats %+v", s)
+	}
+	s := stats[1]
+	if s.Resource != "lambda:invoke" || s.Attempts != 3 || s.HeartbeatTimeouts != 1 || s.Executions != 1 {
+		t.Errorf("got lambda stats %+v", s)
+	}
+	if len(s.HeartbeatSeconds) != 2 || s.HeartbeatSeconds[0] != 30 || s.HeartbeatSeconds[1] != 60 {
+		t.Errorf("got HeartbeatSeconds %v, want [30 60]", s.HeartbeatSeconds)
+	}
+}
@@ -0,0 +1,197 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// GetExecutionHistory fetches every history event of a Standard execution,
+// oldest first, with payloads included.
+func (f *Fetcher) GetExecutionHistory(ctx context.Context, executionArn string) ([]HistoryEvent, error) {
+	var events []types.HistoryEvent
+	input := &sfn.GetExecutionHistoryInput{
+		ExecutionArn:         aws.String(executionArn),
+		IncludeExecutionData: aws.Bool(true),
+		MaxResults:           1000,
+	}
+
+	paginator := sfn.NewGetExecutionHistoryPaginator(f.sfnClient, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get execution history for %s: %w", executionArn, err)
+		}
+		events = append(events, page.Events...)
+	}
+
+	return toHistoryEvents(events), nil
+}
+
+// toHistoryEvents flattens the SDK's per-type detail structs and resolves the
+// state each event belongs to by following PreviousEventId links.
+func toHistoryEvents(events []types.HistoryEvent) []HistoryEvent {
+	history := make([]HistoryEvent, 0, len(events))
+	for _, e := range events {
+		event := HistoryEvent{
+			ID:              e.Id,
+			PreviousEventID: e.PreviousEventId,
+			Type:            string(e.Type),
+			Timestamp:       aws.ToTime(e.Timestamp),
+		}
+		fillEventDetails(&event, e)
+		history = append(history, event)
+	}
+	resolveStateNames(history)
+	return history
+}
+
+// resolveStateNames fills in the state of events that do not name it (task,
+// lambda and activity events) from the event they follow.
+func resolveStateNames(history []HistoryEvent) {
+	stateByID := make(map[int64]string, len(history))
+	for i := range history {
+		event := &history[i]
+		if event.StateName == "" && !strings.HasPrefix(event.Type, "Execution") {
+			event.StateName = stateByID[event.PreviousEventID]
+		}
+		stateByID[event.ID] = event.StateName
+	}
+}
+
+// sortHistory orders events by ID, as GetExecutionHistory returns them
+func sortHistory(history []HistoryEvent) {
+	sort.SliceStable(history, func(i, j int) bool { return history[i].ID < history[j].ID })
+}
+
+func fillEventDetails(event *HistoryEvent, e types.HistoryEvent) {
+	switch {
+	case e.StateEnteredEventDetails != nil:
+		event.StateName = aws.ToString(e.StateEnteredEventDetails.Name)
+		event.Input = aws.ToString(e.StateEnteredEventDetails.Input)
+	case e.StateExitedEventDetails != nil:
+		event.StateName = aws.ToString(e.StateExitedEventDetails.Name)
+		event.Output = aws.ToString(e.StateExitedEventDetails.Output)
+	case e.ExecutionStartedEventDetails != nil:
+		event.Input = aws.ToString(e.ExecutionStartedEventDetails.Input)
+	case e.ExecutionSucceededEventDetails != nil:
+		event.Output = aws.ToString(e.ExecutionSucceededEventDetails.Output)
+	case e.ExecutionFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.ExecutionFailedEventDetails.Error), aws.ToString(e.ExecutionFailedEventDetails.Cause)
+	case e.ExecutionAbortedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.ExecutionAbortedEventDetails.Error), aws.ToString(e.ExecutionAbortedEventDetails.Cause)
+	case e.ExecutionTimedOutEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.ExecutionTimedOutEventDetails.Error), aws.ToString(e.ExecutionTimedOutEventDetails.Cause)
+	case e.TaskScheduledEventDetails != nil:
+		d := e.TaskScheduledEventDetails
+		event.Resource, event.ResourceType = aws.ToString(d.Resource), aws.ToString(d.ResourceType)
+		event.Parameters = aws.ToString(d.Parameters)
+		event.HeartbeatSeconds = aws.ToInt64(d.HeartbeatInSeconds)
+		event.TimeoutSeconds = aws.ToInt64(d.TimeoutInSeconds)
+	case e.TaskStartedEventDetails != nil:
+		event.Resource, event.ResourceType = aws.ToString(e.TaskStartedEventDetails.Resource), aws.ToString(e.TaskStartedEventDetails.ResourceType)
+	case e.TaskSubmittedEventDetails != nil:
+		d := e.TaskSubmittedEventDetails
+		event.Resource, event.ResourceType, event.Output = aws.ToString(d.Resource), aws.ToString(d.ResourceType), aws.ToString(d.Output)
+	case e.TaskSucceededEventDetails != nil:
+		d := e.TaskSucceededEventDetails
+		event.Resource, event.ResourceType, event.Output = aws.ToString(d.Resource), aws.ToString(d.ResourceType), aws.ToString(d.Output)
+	case e.TaskFailedEventDetails != nil:
+		d := e.TaskFailedEventDetails
+		event.Resource, event.ResourceType = aws.ToString(d.Resource), aws.ToString(d.ResourceType)
+		event.Error, event.Cause = aws.ToString(d.Error), aws.ToString(d.Cause)
+	case e.TaskTimedOutEventDetails != nil:
+		d := e.TaskTimedOutEventDetails
+		event.Resource, event.ResourceType = aws.ToString(d.Resource), aws.ToString(d.ResourceType)
+		event.Error, event.Cause = aws.ToString(d.Error), aws.ToString(d.Cause)
+	case e.TaskStartFailedEventDetails != nil:
+		d := e.TaskStartFailedEventDetails
+		event.Resource, event.ResourceType = aws.ToString(d.Resource), aws.ToString(d.ResourceType)
+		event.Error, event.Cause = aws.ToString(d.Error), aws.ToString(d.Cause)
+	case e.TaskSubmitFailedEventDetails != nil:
+		d := e.TaskSubmitFailedEventDetails
+		event.Resource, event.ResourceType = aws.ToString(d.Resource), aws.ToString(d.ResourceType)
+		event.Error, event.Cause = aws.ToString(d.Error), aws.ToString(d.Cause)
+	case e.LambdaFunctionScheduledEventDetails != nil:
+		d := e.LambdaFunctionScheduledEventDetails
+		event.Resource, event.Input = aws.ToString(d.Resource), aws.ToString(d.Input)
+		event.TimeoutSeconds = aws.ToInt64(d.TimeoutInSeconds)
+	case e.LambdaFunctionSucceededEventDetails != nil:
+		event.Output = aws.ToString(e.LambdaFunctionSucceededEventDetails.Output)
+	case e.LambdaFunctionFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.LambdaFunctionFailedEventDetails.Error), aws.ToString(e.LambdaFunctionFailedEventDetails.Cause)
+	case e.LambdaFunctionTimedOutEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.LambdaFunctionTimedOutEventDetails.Error), aws.ToString(e.LambdaFunctionTimedOutEventDetails.Cause)
+	case e.LambdaFunctionScheduleFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.LambdaFunctionScheduleFailedEventDetails.Error), aws.ToString(e.LambdaFunctionScheduleFailedEventDetails.Cause)
+	case e.LambdaFunctionStartFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.LambdaFunctionStartFailedEventDetails.Error), aws.ToString(e.LambdaFunctionStartFailedEventDetails.Cause)
+	case e.ActivityScheduledEventDetails != nil:
+		d := e.ActivityScheduledEventDetails
+		event.Resource, event.Input = aws.ToString(d.Resource), aws.ToString(d.Input)
+		event.HeartbeatSeconds = aws.ToInt64(d.HeartbeatInSeconds)
+		event.TimeoutSeconds = aws.ToInt64(d.TimeoutInSeconds)
+	case e.ActivitySucceededEventDetails != nil:
+		event.Output = aws.ToString(e.ActivitySucceededEventDetails.Output)
+	case e.ActivityFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.ActivityFailedEventDetails.Error), aws.ToString(e.ActivityFailedEventDetails.Cause)
+	case e.ActivityTimedOutEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.ActivityTimedOutEventDetails.Error), aws.ToString(e.ActivityTimedOutEventDetails.Cause)
+	case e.ActivityScheduleFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.ActivityScheduleFailedEventDetails.Error), aws.ToString(e.ActivityScheduleFailedEventDetails.Cause)
+	case e.MapRunStartedEventDetails != nil:
+		event.MapRunArn = aws.ToString(e.MapRunStartedEventDetails.MapRunArn)
+	case e.MapRunFailedEventDetails != nil:
+		event.Error, event.Cause = aws.ToString(e.MapRunFailedEventDetails.Error), aws.ToString(e.MapRunFailedEventDetails.Cause)
+	case e.EvaluationFailedEventDetails != nil:
+		d := e.EvaluationFailedEventDetails
+		event.StateName = aws.ToString(d.State)
+		event.Error, event.Cause = aws.ToString(d.Error), aws.ToString(d.Cause)
+	}
+}
+
+// isRetryEvent reports whether a scheduling event was caused by a Retry rule,
+// i.e. it directly follows a failure or timeout of the same state.
+func isRetryEvent(event HistoryEvent, byID map[int64]HistoryEvent) bool {
+	if !strings.HasSuffix(event.Type, "Scheduled") {
+		return false
+	}
+	prev, ok := byID[event.PreviousEventID]
+	return ok && (strings.HasSuffix(prev.Type, "Failed") || strings.HasSuffix(prev.Type, "TimedOut"))
+}
+
+// isStateEntered matches the per-type entry events (TaskStateEntered, ChoiceStateEntered, ...)
+func isStateEntered(event HistoryEvent) bool {
+	return strings.HasSuffix(event.Type, "StateEntered")
+}
+
+// isStateExited matches the per-type exit events (TaskStateExited, ChoiceStateExited, ...)
+func isStateExited(event HistoryEvent) bool {
+	return strings.HasSuffix(event.Type, "StateExited")
+}
+
+func indexEvents(history []HistoryEvent) map[int64]HistoryEvent {
+	byID := make(map[int64]HistoryEvent, len(history))
+	for _, event := range history {
+		byID[event.ID] = event
+	}
+	return byID
+}
+
+// countTransitions counts the billed state transitions in a history: every
+// state entered plus every retry attempt.
+func countTransitions(history []HistoryEvent) int {
+	byID := indexEvents(history)
+	transitions := 0
+	for _, event := range history {
+		if isStateEntered(event) || isRetryEvent(event, byID) {
+			transitions++
+		}
+	}
+	return transitions
+}
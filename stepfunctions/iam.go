@@ -0,0 +1,333 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// iamServicePrefixes maps aws-sdk integration service names to their IAM prefix where they differ
+var iamServicePrefixes = map[string]string{
+	"sfn":                    "states",
+	"cloudwatchlogs":         "logs",
+	"eventbridge":            "events",
+	"sesv2":                  "ses",
+	"elasticloadbalancingv2": "elasticloadbalancing",
+}
+
+// integrationActions covers optimized integrations whose IAM action is not
+// simply the capitalized API name.
+var integrationActions = map[string][]string{
+	"lambda:invoke":     {"lambda:InvokeFunction"},
+	"http:invoke":       {"states:InvokeHTTPEndpoint", "events:RetrieveConnectionCredentials"},
+	"apigateway:invoke": {"execute-api:Invoke"},
+	"s3:listObjectsV2":  {"s3:ListBucket"},
+}
+
+// syncActions are the extra actions the run-a-job (.sync) pattern needs to poll and stop the job
+var syncActions = map[string][]string{
+	"ecs":       {"ecs:StopTask", "ecs:DescribeTasks", "events:PutTargets", "events:PutRule", "events:DescribeRule"},
+	"states":    {"states:DescribeExecution", "states:StopExecution", "events:PutTargets", "events:PutRule", "events:DescribeRule"},
+	"batch":     {"batch:DescribeJobs", "batch:TerminateJob", "events:PutTargets", "events:PutRule", "events:DescribeRule"},
+	"glue":      {"glue:GetJobRun", "glue:GetJobRuns", "glue:BatchStopJobRun"},
+	"codebuild": {"codebuild:StopBuild", "codebuild:BatchGetBuilds", "events:PutTargets", "events:PutRule", "events:DescribeRule"},
+}
+
+// AnalyzeRolePermissions fetches the policies attached to the state machine's
+// execution role and compares the granted actions with those its definition needs.
+func (f *Fetcher) AnalyzeRolePermissions(ctx context.Context, sm StateMachine) (PermissionAnalysis, error) {
+	policies, err := f.getRolePolicies(ctx, sm.RoleARN)
+	if err != nil {
+		return PermissionAnalysis{}, err
+	}
+
+	required, err := requiredActions(sm.Definition)
+	if err != nil {
+		return PermissionAnalysis{}, fmt.Errorf("failed to derive required actions for %s: %w", sm.ARN, err)
+	}
+
+	analysis := analyzePermissions(required, policies)
+	analysis.StateMachineARN = sm.ARN
+	analysis.RoleARN = sm.RoleARN
+	return analysis, nil
+}
+
+func (f *Fetcher) getRolePolicies(ctx context.Context, roleArn string) ([]RolePolicy, error) {
+	roleName := roleArn[strings.LastIndex(roleArn, "/")+1:]
+	var policies []RolePolicy
+
+	attached := iam.NewListAttachedRolePoliciesPaginator(f.iamClient, &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	for attached.HasMorePages() {
+		page, err := attached.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list attached policies for role %s: %w", roleName, err)
+		}
+
+		for _, p := range page.AttachedPolicies {
+			policy, err := f.getManagedPolicy(ctx, *p.PolicyArn)
+			if err != nil {
+				return nil, err
+			}
+			policy.Name = aws.ToString(p.PolicyName)
+			policies = append(policies, policy)
+		}
+	}
+
+	inline := iam.NewListRolePoliciesPaginator(f.iamClient, &iam.ListRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	for inline.HasMorePages() {
+		page, err := inline.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list inline policies for role %s: %w", roleName, err)
+		}
+
+		for _, name := range page.PolicyNames {
+			result, err := f.iamClient.GetRolePolicy(ctx, &iam.GetRolePolicyInput{
+				RoleName:   aws.String(roleName),
+				PolicyName: aws.String(name),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get inline policy %s for role %s: %w", name, roleName, err)
+			}
+
+			document, err := decodePolicyDocument(aws.ToString(result.PolicyDocument))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode inline policy %s: %w", name, err)
+			}
+			policies = append(policies, RolePolicy{Name: name, Inline: true, Document: document})
+		}
+	}
+
+	return policies, nil
+}
+
+// getManagedPolicy fetches the default version of a managed policy
+func (f *Fetcher) getManagedPolicy(ctx context.Context, policyArn string) (RolePolicy, error) {
+	policy, err := f.iamClient.GetPolicy(ctx, &iam.GetPolicyInput{PolicyArn: aws.String(policyArn)})
+	if err != nil {
+		return RolePolicy{}, fmt.Errorf("failed to get policy %s: %w", policyArn, err)
+	}
+
+	version, err := f.iamClient.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(policyArn),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return RolePolicy{}, fmt.Errorf("failed to get default version of policy %s: %w", policyArn, err)
+	}
+
+	document, err := decodePolicyDocument(aws.ToString(version.PolicyVersion.Document))
+	if err != nil {
+		return RolePolicy{}, fmt.Errorf("failed to decode policy %s: %w", policyArn, err)
+	}
+
+	return RolePolicy{ARN: policyArn, Document: document}, nil
+}
+
+// decodePolicyDocument parses the URL-encoded JSON document returned by the IAM API
+func decodePolicyDocument(encoded string) (map[string]interface{}, error) {
+	decoded, err := url.QueryUnescape(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(decoded), &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// requiredActions derives the IAM actions the execution role needs for the
+// Task states (and Distributed Map readers/writers) in a definition.
+func requiredActions(definition string) ([]string, error) {
+	actions := make(map[string]bool)
+	err := walkStates(definition, func(name string, rawDef map[string]interface{}) {
+		if resource, ok := rawDef["Resource"].(string); ok {
+			for _, action := range actionsForResource(resource) {
+				actions[action] = true
+			}
+		}
+
+		processor, _ := rawDef["ItemProcessor"].(map[string]interface{})
+		config, _ := processor["ProcessorConfig"].(map[string]interface{})
+		if mode, _ := config["Mode"].(string); mode == "DISTRIBUTED" {
+			for _, action := range []string{"states:StartExecution", "states:DescribeExecution", "states:StopExecution"} {
+				actions[action] = true
+			}
+		}
+		for _, key := range []string{"ItemReader", "ResultWriter"} {
+			block, _ := rawDef[key].(map[string]interface{})
+			if resource, ok := block["Resource"].(string); ok {
+				for _, action := range actionsForResource(resource) {
+					actions[action] = true
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for action := range actions {
+		result = append(result, action)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// actionsForResource maps a Task Resource ARN to the IAM actions it implies
+func actionsForResource(resource string) []string {
+	parts := strings.Split(resource, ":")
+	if len(parts) < 6 {
+		return nil
+	}
+
+	switch parts[2] {
+	case "lambda":
+		return []string{"lambda:InvokeFunction"}
+	case "states":
+		if parts[5] == "activity" {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	// Service integrations: arn:aws:states:::service:api[.pattern][:version]
+	integration := parts[5:]
+	service, api := integration[0], ""
+	if service == "aws-sdk" && len(integration) >= 3 {
+		service, api = integration[1], integration[2]
+		if prefix, ok := iamServicePrefixes[service]; ok {
+			service = prefix
+		}
+	} else if len(integration) >= 2 {
+		api = integration[1]
+	}
+	if api == "" {
+		return nil
+	}
+
+	api, pattern, _ := strings.Cut(api, ".")
+	var actions []string
+	if mapped, ok := integrationActions[service+":"+api]; ok {
+		actions = append(actions, mapped...)
+	} else {
+		actions = append(actions, service+":"+strings.ToUpper(api[:1])+api[1:])
+	}
+	if pattern == "sync" {
+		actions = append(actions, syncActions[service]...)
+	}
+	return actions
+}
+
+// analyzePermissions compares required actions against the Allow statements in
+// the role's policies. Resource scoping and conditions are not evaluated.
+func analyzePermissions(required []string, policies []RolePolicy) PermissionAnalysis {
+	analysis := PermissionAnalysis{
+		Policies:        policies,
+		RequiredActions: required,
+	}
+
+	granted := make(map[string]string) // lowercased action -> action as written
+	var patterns []string
+	for _, policy := range policies {
+		policyName := policy.Name
+		for _, stmt := range policyStatements(policy.Document) {
+			if effect, _ := stmt["Effect"].(string); effect != "Allow" {
+				continue
+			}
+
+			resources := stringOrSlice(stmt["Resource"])
+			if notActions := stringOrSlice(stmt["NotAction"]); len(notActions) > 0 {
+				analysis.WildcardGrants = append(analysis.WildcardGrants,
+					fmt.Sprintf("%s: NotAction %s on %s", policyName, strings.Join(notActions, ","), strings.Join(resources, ",")))
+				patterns = append(patterns, "*")
+				continue
+			}
+
+			for _, action := range stringOrSlice(stmt["Action"]) {
+				if strings.Contains(action, "*") {
+					analysis.WildcardGrants = append(analysis.WildcardGrants,
+						fmt.Sprintf("%s: %s on %s", policyName, action, strings.Join(resources, ",")))
+					patterns = append(patterns, action)
+					continue
+				}
+				granted[strings.ToLower(action)] = action
+			}
+		}
+	}
+
+	needed := make(map[string]bool)
+	for _, action := range required {
+		needed[strings.ToLower(action)] = true
+		if _, ok := granted[strings.ToLower(action)]; ok {
+			continue
+		}
+		if !matchesAnyPattern(patterns, action) {
+			analysis.MissingActions = append(analysis.MissingActions, action)
+		}
+	}
+
+	for key, action := range granted {
+		if !needed[key] {
+			analysis.UnusedActions = append(analysis.UnusedActions, action)
+		}
+	}
+	sort.Strings(analysis.UnusedActions)
+
+	return analysis
+}
+
+func policyStatements(document map[string]interface{}) []map[string]interface{} {
+	var statements []map[string]interface{}
+	switch stmt := document["Statement"].(type) {
+	case map[string]interface{}:
+		statements = append(statements, stmt)
+	case []interface{}:
+		for _, s := range stmt {
+			if m, ok := s.(map[string]interface{}); ok {
+				statements = append(statements, m)
+			}
+		}
+	}
+	return statements
+}
+
+// stringOrSlice normalizes IAM fields that may be a single string or a list
+func stringOrSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func matchesAnyPattern(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(action)); ok {
+			return true
+		}
+	}
+	return false
+}
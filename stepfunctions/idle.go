@@ -0,0 +1,110 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// IdleTimeStats separates productive time from intentional waiting for the
+// executions of a state machine
+type IdleTimeStats struct {
+	StateMachineName  string
+	Executions        int
+	TotalSeconds      float64
+	ProductiveSeconds float64 // Some non-waiting state was active
+	WaitSeconds       float64 // Only Wait states were active
+	CallbackSeconds   float64 // Only .waitForTaskToken tasks (and Wait states) were active
+	PerExecution      []ExecutionIdleTime
+}
+
+// ExecutionIdleTime is the idle-time breakdown of one execution
+type ExecutionIdleTime struct {
+	ExecutionArn      string
+	TotalSeconds      float64
+	ProductiveSeconds float64
+	WaitSeconds       float64
+	CallbackSeconds   float64
+}
+
+type interval struct{ start, end time.Time }
+
+// ComputeIdleTime classifies the leaf state spans of each execution as
+// productive, Wait or callback and measures the time covered by each class.
+// Time counts as idle only when no productive state overlaps it, so a Wait in
+// one parallel branch does not hide work in another.
+func ComputeIdleTime(sm StateMachine) (IdleTimeStats, bool) {
+	callbackStates := make(map[string]bool)
+	walkStates(sm.Definition, func(name string, rawDef map[string]interface{}) {
+		if resource, _ := rawDef["Resource"].(string); strings.HasSuffix(resource, ".waitForTaskToken") {
+			callbackStates[name] = true
+		}
+	})
+
+	stats := IdleTimeStats{StateMachineName: sm.Name}
+	for _, exec := range sm.Executions {
+		spans := BuildTimeline(exec.History)
+		if len(spans) == 0 {
+			continue
+		}
+
+		var productive, waits, callbacks []interval
+		for _, span := range spans {
+			iv := interval{span.Start, span.End}
+			switch {
+			case span.StateType == "Parallel" || span.StateType == "Map":
+				continue // Containers; their children are classified individually
+			case span.StateType == "Wait":
+				waits = append(waits, iv)
+			case callbackStates[span.StateName]:
+				callbacks = append(callbacks, iv)
+			default:
+				productive = append(productive, iv)
+			}
+		}
+
+		start, end := exec.History[0].Timestamp, exec.History[len(exec.History)-1].Timestamp
+		productiveTime := unionLength(productive)
+		waitTime := unionLength(append(productive, waits...)) - productiveTime
+		callbackTime := unionLength(append(append(productive, waits...), callbacks...)) - productiveTime - waitTime
+
+		execStats := ExecutionIdleTime{
+			ExecutionArn:      exec.ExecutionArn,
+			TotalSeconds:      end.Sub(start).Seconds(),
+			ProductiveSeconds: productiveTime.Seconds(),
+			WaitSeconds:       waitTime.Seconds(),
+			CallbackSeconds:   callbackTime.Seconds(),
+		}
+		stats.Executions++
+		stats.TotalSeconds += execStats.TotalSeconds
+		stats.ProductiveSeconds += execStats.ProductiveSeconds
+		stats.WaitSeconds += execStats.WaitSeconds
+		stats.CallbackSeconds += execStats.CallbackSeconds
+		stats.PerExecution = append(stats.PerExecution, execStats)
+	}
+
+	return stats, stats.Executions > 0
+}
+
+// unionLength measures the total time covered by possibly overlapping intervals
+func unionLength(intervals []interval) time.Duration {
+	if len(intervals) == 0 {
+		return 0
+	}
+	sorted := append([]interval(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start.Before(sorted[j].start) })
+
+	var total time.Duration
+	current := sorted[0]
+	for _, iv := range sorted[1:] {
+		if iv.start.After(current.end) {
+			total += current.end.Sub(current.start)
+			current = iv
+			continue
+		}
+		if iv.end.After(current.end) {
+			current.end = iv.end
+		}
+	}
+	return total + current.end.Sub(current.start)
+}
@@ -0,0 +1,64 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// AuditKMSKey checks the customer managed key of a state machine: it must be
+// enabled, customer managed and have automatic rotation turned on. State
+// machines on AWS-owned keys have nothing to audit.
+func (f *Fetcher) AuditKMSKey(ctx context.Context, sm StateMachine) ([]ComplianceIssue, error) {
+	keyID := sm.Encryption.KMSKeyID
+	if sm.Encryption.Type != "CUSTOMER_MANAGED_KMS_KEY" || keyID == "" {
+		return nil, nil
+	}
+
+	var issues []ComplianceIssue
+	issue := func(format string, args ...interface{}) {
+		issues = append(issues, ComplianceIssue{
+			StateMachineName: sm.Name,
+			StateMachineARN:  sm.ARN,
+			Check:            "kms",
+			Message:          fmt.Sprintf(format, args...),
+		})
+	}
+
+	client := f.kmsClientFor(keyID)
+	key, err := client.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe KMS key %s: %w", keyID, err)
+	}
+	if key.KeyMetadata.KeyState != kmstypes.KeyStateEnabled {
+		issue("KMS key %s is %s; executions cannot start", keyID, key.KeyMetadata.KeyState)
+	}
+	if key.KeyMetadata.KeyManager != kmstypes.KeyManagerTypeCustomer {
+		issue("KMS key %s is AWS managed, not customer managed", keyID)
+	}
+
+	rotation, err := client.GetKeyRotationStatus(ctx, &kms.GetKeyRotationStatusInput{KeyId: key.KeyMetadata.Arn})
+	if err != nil {
+		return issues, fmt.Errorf("failed to get rotation status of KMS key %s: %w", keyID, err)
+	}
+	if !rotation.KeyRotationEnabled {
+		issue("KMS key %s does not have automatic rotation enabled", keyID)
+	}
+
+	return issues, nil
+}
+
+// kmsClientFor returns a client in the key's region when keyID is an ARN
+func (f *Fetcher) kmsClientFor(keyID string) *kms.Client {
+	parsed, err := arn.Parse(keyID)
+	if err != nil || parsed.Region == "" || parsed.Region == f.cfg.Region {
+		return f.kmsClient
+	}
+	cfg := f.cfg.Copy()
+	cfg.Region = parsed.Region
+	return kms.NewFromConfig(cfg)
+}
@@ -0,0 +1,104 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// LambdaInvocation is one attempt of a Task state invoking a Lambda function.
+// The New Relic fields are set when it is correlated with the function's
+// telemetry.
+type LambdaInvocation struct {
+	State       string
+	FunctionArn string // Unqualified function ARN
+	RequestID   string `json:",omitempty"` // Lambda request ID, from the lambda:invoke output
+	Start       time.Time
+	End         time.Time
+	Failed      bool   `json:",omitempty"`
+	TraceID     string `json:",omitempty"`
+	EntityGUID  string `json:",omitempty"`
+	EntityURL   string `json:",omitempty"`
+}
+
+// LambdaInvocations lists the completed Lambda invocations in an execution's
+// history, through the lambda:invoke integration or a function ARN resource
+func LambdaInvocations(exec Execution) []LambdaInvocation {
+	var invocations []LambdaInvocation
+	scheduledOf := make(map[int64]HistoryEvent) // Event ID -> the Scheduled event of its attempt
+	for _, event := range exec.History {
+		if isLambdaScheduled(event) {
+			scheduledOf[event.ID] = event
+			continue
+		}
+		scheduled, ok := scheduledOf[event.PreviousEventID]
+		if !ok {
+			continue
+		}
+		scheduledOf[event.ID] = scheduled
+		failed := strings.HasSuffix(event.Type, "Failed") || strings.HasSuffix(event.Type, "TimedOut")
+		if !strings.HasSuffix(event.Type, "Succeeded") && !failed {
+			continue
+		}
+		invocations = append(invocations, LambdaInvocation{
+			State:       scheduled.StateName,
+			FunctionArn: lambdaFunctionArn(scheduled, exec.ExecutionArn),
+			RequestID:   lambdaRequestID(event.Output),
+			Start:       scheduled.Timestamp,
+			End:         event.Timestamp,
+			Failed:      failed,
+		})
+	}
+	return invocations
+}
+
+func isLambdaScheduled(event HistoryEvent) bool {
+	if event.Type == "LambdaFunctionScheduled" {
+		return true
+	}
+	return event.Type == "TaskScheduled" && event.ResourceType == "lambda" && strings.HasPrefix(event.Resource, "invoke")
+}
+
+// lambdaFunctionArn returns the unqualified ARN of the function a Scheduled
+// event calls. Function names and partial ARNs are completed with the region
+// and account of the execution.
+func lambdaFunctionArn(scheduled HistoryEvent, executionArn string) string {
+	function := scheduled.Resource
+	if scheduled.Type == "TaskScheduled" {
+		var params struct{ FunctionName string }
+		json.Unmarshal([]byte(scheduled.Parameters), &params)
+		function = params.FunctionName
+	}
+	if parsed, err := arn.Parse(function); err == nil {
+		// arn:aws:lambda:region:account:function:name[:qualifier]
+		parts := strings.SplitN(parsed.Resource, ":", 3)
+		if len(parts) >= 2 {
+			parsed.Resource = parts[0] + ":" + parts[1]
+		}
+		return parsed.String()
+	}
+	exec, err := arn.Parse(executionArn)
+	if err != nil || function == "" {
+		return function
+	}
+	// name[:qualifier] or account:function:name[:qualifier]
+	parts := strings.Split(function, ":")
+	name, account := parts[0], exec.AccountID
+	if len(parts) >= 3 && parts[1] == "function" {
+		name, account = parts[2], parts[0]
+	}
+	return arn.ARN{Partition: exec.Partition, Service: "lambda", Region: exec.Region, AccountID: account, Resource: "function:" + name}.String()
+}
+
+// lambdaRequestID reads the request ID from a lambda:invoke task output
+func lambdaRequestID(output string) string {
+	var result struct {
+		SdkResponseMetadata struct{ RequestId string }
+	}
+	if json.Unmarshal([]byte(output), &result) != nil {
+		return ""
+	}
+	return result.SdkResponseMetadata.RequestId
+}
@@ -0,0 +1,42 @@
+package stepfunctions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLambdaInvocations(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	exec := Execution{
+		ExecutionArn: "arn:aws:states:us-east-1:123456789012:execution:orders:run-1",
+		History: []HistoryEvent{
+			{ID: 1, Type: "TaskScheduled", StateName: "Charge", Resource: "invoke", ResourceType: "lambda",
+				Parameters: `{"FunctionName":"arn:aws:lambda:us-east-1:123456789012:function:charge:live","Payload":{}}`, Timestamp: at(0)},
+			{ID: 2, PreviousEventID: 1, Type: "TaskStarted", Timestamp: at(0)},
+			{ID: 3, PreviousEventID: 2, Type: "TaskSucceeded", Output: `{"Payload":{},"SdkResponseMetadata":{"RequestId":"req-1"}}`, Timestamp: at(2)},
+			{ID: 4, Type: "TaskScheduled", StateName: "Notify", Resource: "invoke", ResourceType: "lambda", Parameters: `{"FunctionName":"notify"}`, Timestamp: at(3)},
+			{ID: 5, PreviousEventID: 4, Type: "TaskFailed", Timestamp: at(4)},
+			{ID: 6, Type: "LambdaFunctionScheduled", StateName: "Legacy", Resource: "arn:aws:lambda:us-east-1:123456789012:function:legacy", Timestamp: at(5)},
+			{ID: 7, PreviousEventID: 6, Type: "LambdaFunctionSucceeded", Timestamp: at(6)},
+			// Not Lambda
+			{ID: 8, Type: "TaskScheduled", StateName: "Queue", Resource: "sendMessage", ResourceType: "sqs", Timestamp: at(7)},
+			{ID: 9, PreviousEventID: 8, Type: "TaskSucceeded", Timestamp: at(8)},
+		},
+	}
+
+	got := LambdaInvocations(exec)
+	want := []LambdaInvocation{
+		{State: "Charge", FunctionArn: "arn:aws:lambda:us-east-1:123456789012:function:charge", RequestID: "req-1", Start: at(0), End: at(2)},
+		{State: "Notify", FunctionArn: "arn:aws:lambda:us-east-1:123456789012:function:notify", Start: at(3), End: at(4), Failed: true},
+		{State: "Legacy", FunctionArn: "arn:aws:lambda:us-east-1:123456789012:function:legacy", Start: at(5), End: at(6)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("invocation %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
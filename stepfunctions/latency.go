@@ -0,0 +1,139 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ResourceLatency aggregates the task calls to one downstream resource
+// across every fetched state machine, from scheduling to completion
+type ResourceLatency struct {
+	Resource      string   // Function, task definition, queue, ... ARN when the parameters name it
+	Integration   string   // e.g. lambda:invoke, ecs:runTask.sync; empty for Lambda and activity ARNs
+	StateMachines []string // State machines calling the resource
+	Calls         int      // Completed attempts
+	Failed        int      // Attempts that failed or timed out
+	TotalSeconds  float64  // Time spent waiting on the resource
+	AvgSeconds    float64
+	P50Seconds    float64
+	P90Seconds    float64
+	P99Seconds    float64
+	MaxSeconds    float64
+}
+
+type latencySamples struct {
+	latency       ResourceLatency
+	durations     []float64
+	stateMachines map[string]bool
+}
+
+// targetParameters are the task parameters that name the resource a service
+// integration calls, in the order they are tried
+var targetParameters = []string{
+	"FunctionName", "TaskDefinition", "QueueUrl", "TopicArn", "TableName",
+	"StateMachineArn", "JobDefinition", "JobName", "ApiEndpoint", "EventBusName",
+}
+
+// ComputeResourceLatency measures every Scheduled to Succeeded, Failed or
+// TimedOut task attempt in the fetched histories and groups them by the
+// resource called, slowest in total first, to show which downstream service
+// the workflows wait on most.
+func ComputeResourceLatency(stateMachines []StateMachine) []ResourceLatency {
+	samples := make(map[string]*latencySamples)
+	for _, sm := range stateMachines {
+		for _, exec := range sm.Executions {
+			scheduledOf := make(map[int64]HistoryEvent) // Event ID -> the Scheduled event of its attempt
+			for _, event := range exec.History {
+				if strings.HasSuffix(event.Type, "Scheduled") {
+					scheduledOf[event.ID] = event
+					continue
+				}
+				scheduled, ok := scheduledOf[event.PreviousEventID]
+				if !ok {
+					continue
+				}
+				scheduledOf[event.ID] = scheduled
+				failed := strings.HasSuffix(event.Type, "Failed") || strings.HasSuffix(event.Type, "TimedOut")
+				if !strings.HasSuffix(event.Type, "Succeeded") && !failed {
+					continue
+				}
+
+				resource, integration := latencyTarget(scheduled)
+				key := integration + " " + resource
+				s := samples[key]
+				if s == nil {
+					s = &latencySamples{
+						latency:       ResourceLatency{Resource: resource, Integration: integration},
+						stateMachines: make(map[string]bool),
+					}
+					samples[key] = s
+				}
+				s.latency.Calls++
+				if failed {
+					s.latency.Failed++
+				}
+				s.durations = append(s.durations, event.Timestamp.Sub(scheduled.Timestamp).Seconds())
+				s.stateMachines[sm.Name] = true
+			}
+		}
+	}
+
+	var result []ResourceLatency
+	for _, s := range samples {
+		l := s.latency
+		sort.Float64s(s.durations)
+		for _, d := range s.durations {
+			l.TotalSeconds += d
+		}
+		l.AvgSeconds = l.TotalSeconds / float64(len(s.durations))
+		l.P50Seconds = percentile(s.durations, 50)
+		l.P90Seconds = percentile(s.durations, 90)
+		l.P99Seconds = percentile(s.durations, 99)
+		l.MaxSeconds = s.durations[len(s.durations)-1]
+		for name := range s.stateMachines {
+			l.StateMachines = append(l.StateMachines, name)
+		}
+		sort.Strings(l.StateMachines)
+		result = append(result, l)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalSeconds != result[j].TotalSeconds {
+			return result[i].TotalSeconds > result[j].TotalSeconds
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result
+}
+
+// latencyTarget names the resource a Scheduled event calls: the Lambda
+// function or activity ARN, or for service integrations the target in the
+// parameters, falling back to the integration itself
+func latencyTarget(scheduled HistoryEvent) (resource, integration string) {
+	if scheduled.ResourceType == "" {
+		return scheduled.Resource, ""
+	}
+	integration = scheduled.ResourceType + ":" + scheduled.Resource
+	var params map[string]interface{}
+	if json.Unmarshal([]byte(scheduled.Parameters), &params) == nil {
+		for _, key := range targetParameters {
+			if target, ok := params[key].(string); ok && target != "" {
+				return target, integration
+			}
+		}
+	}
+	return integration, integration
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
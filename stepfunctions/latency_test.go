@@ -0,0 +1,48 @@
+package stepfunctions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeResourceLatency(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	fn := "arn:aws:lambda:us-east-1:123456789012:function:charge"
+	call := func(id int64, from, to int, outcome string) []HistoryEvent {
+		return []HistoryEvent{
+			{ID: id, Type: "TaskScheduled", Resource: "invoke", ResourceType: "lambda", Parameters: `{"FunctionName":"` + fn + `","Payload":{}}`, Timestamp: at(from)},
+			{ID: id + 1, PreviousEventID: id, Type: "TaskStarted", Timestamp: at(from)},
+			{ID: id + 2, PreviousEventID: id + 1, Type: outcome, Timestamp: at(to)},
+		}
+	}
+	var history []HistoryEvent
+	history = append(history, call(1, 0, 2, "TaskSucceeded")...)
+	history = append(history, call(4, 2, 6, "TaskFailed")...)
+	history = append(history,
+		HistoryEvent{ID: 7, Type: "LambdaFunctionScheduled", Resource: fn, Timestamp: at(10)},
+		HistoryEvent{ID: 8, PreviousEventID: 7, Type: "LambdaFunctionStarted", Timestamp: at(10)},
+		HistoryEvent{ID: 9, PreviousEventID: 8, Type: "LambdaFunctionSucceeded", Timestamp: at(11)},
+		// Still running, so not measured
+		HistoryEvent{ID: 10, Type: "TaskScheduled", Resource: "sendMessage", ResourceType: "sqs", Timestamp: at(12)},
+	)
+	stateMachines := []StateMachine{
+		{Name: "orders", Executions: []Execution{{History: history}}},
+		{Name: "refunds", Executions: []Execution{{History: call(1, 0, 30, "TaskSucceeded")}}},
+	}
+
+	latency := ComputeResourceLatency(stateMachines)
+	if len(latency) != 2 {
+		t.Fatalf("got %d resources, want 2: %+v", len(latency), latency)
+	}
+	l := latency[0]
+	if l.Resource != fn || l.Integration != "lambda:invoke" || l.Calls != 3 || l.Failed != 1 {
+		t.Errorf("got %+v", l)
+	}
+	if l.TotalSeconds != 36 || l.P50Seconds != 4 || l.MaxSeconds != 30 || len(l.StateMachines) != 2 {
+		t.Errorf("got timings %+v", l)
+	}
+	if l := latency[1]; l.Resource != fn || l.Integration != "" || l.Calls != 1 || l.AvgSeconds != 1 {
+		t.Errorf("got direct Lambda latency %+v", l)
+	}
+}
@@ -0,0 +1,95 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// consoleHosts are the console hosts of the partitions without per-region
+// console hosts
+var consoleHosts = map[string]string{
+	"aws-us-gov": "console.amazonaws-us-gov.com",
+	"aws-cn":     "console.amazonaws.cn",
+}
+
+// consoleURL links to a console page of service in a partition and region,
+// e.g. consoleURL("aws", "us-east-1", "states", "#/statemachines")
+func consoleURL(partition, region, service, fragment string) string {
+	host, ok := consoleHosts[partition]
+	if !ok {
+		host = region + ".console.aws.amazon.com"
+	}
+	return fmt.Sprintf("https://%s/%s/home?region=%s%s", host, service, region, fragment)
+}
+
+// ExecutionConsoleURL links to an execution in the Step Functions console of
+// its partition. Express executions, whose ARNs have an "express" resource,
+// use the express-executions view.
+func ExecutionConsoleURL(executionArn string) string {
+	parsed, err := arn.Parse(executionArn)
+	if err != nil {
+		return ""
+	}
+	view := "v2/executions"
+	if strings.HasPrefix(parsed.Resource, "express:") {
+		view = "express-executions"
+	}
+	return consoleURL(parsed.Partition, parsed.Region, "states", fmt.Sprintf("#/%s/details/%s", view, executionArn))
+}
+
+// StateMachineConsoleURL links to a state machine in the Step Functions
+// console of its partition
+func StateMachineConsoleURL(stateMachineArn string) string {
+	parsed, err := arn.Parse(stateMachineArn)
+	if err != nil {
+		return ""
+	}
+	return consoleURL(parsed.Partition, parsed.Region, "states", "#/v2/statemachines/view/"+stateMachineArn)
+}
+
+// MapRunConsoleURL links to a Distributed Map run in the Step Functions console
+func MapRunConsoleURL(mapRunArn string) string {
+	parsed, err := arn.Parse(mapRunArn)
+	if err != nil {
+		return ""
+	}
+	return consoleURL(parsed.Partition, parsed.Region, "states", "#/v2/map-run/details/"+mapRunArn)
+}
+
+// LogGroupConsoleURL links to a log group, given by its ARN as in a logging
+// destination, in the CloudWatch console. The console escapes the name twice,
+// with $ in place of the second %.
+func LogGroupConsoleURL(logGroupArn string) string {
+	group, err := parseLogGroupArn(logGroupArn)
+	if err != nil {
+		return ""
+	}
+	parsed, _ := arn.Parse(logGroupArn)
+	escaped := strings.ReplaceAll(url.QueryEscape(group.Name), "%", "$25")
+	return consoleURL(parsed.Partition, group.Region, "cloudwatch", "#logsV2:log-groups/log-group/"+escaped)
+}
+
+// attachConsoleURLs sets the console links of a fetched state machine, its
+// log groups, executions and their Map Runs
+func attachConsoleURLs(sm *StateMachine) {
+	sm.ConsoleURL = StateMachineConsoleURL(sm.ARN)
+	sm.Logging.ConsoleURLs = nil
+	for _, destination := range sm.Logging.Destinations {
+		if link := LogGroupConsoleURL(destination); link != "" {
+			sm.Logging.ConsoleURLs = append(sm.Logging.ConsoleURLs, link)
+		}
+	}
+	for i := range sm.Executions {
+		attachExecutionConsoleURLs(&sm.Executions[i])
+	}
+}
+
+func attachExecutionConsoleURLs(exec *Execution) {
+	exec.ConsoleURL = ExecutionConsoleURL(exec.ExecutionArn)
+	for i := range exec.MapRuns {
+		exec.MapRuns[i].ConsoleURL = MapRunConsoleURL(exec.MapRuns[i].MapRunArn)
+	}
+}
@@ -0,0 +1,60 @@
+package stepfunctions
+
+import "testing"
+
+func TestExecutionConsoleURL(t *testing.T) {
+	for _, tt := range []struct {
+		executionArn string
+		want         string
+	}{
+		{
+			"arn:aws:states:us-east-1:123456789012:execution:orders:run-1",
+			"https://us-east-1.console.aws.amazon.com/states/home?region=us-east-1#/v2/executions/details/arn:aws:states:us-east-1:123456789012:execution:orders:run-1",
+		},
+		{
+			"arn:aws:states:us-east-1:123456789012:express:checkout:run-1:abc",
+			"https://us-east-1.console.aws.amazon.com/states/home?region=us-east-1#/express-executions/details/arn:aws:states:us-east-1:123456789012:express:checkout:run-1:abc",
+		},
+		{
+			"arn:aws-us-gov:states:us-gov-west-1:123456789012:execution:orders:run-1",
+			"https://console.amazonaws-us-gov.com/states/home?region=us-gov-west-1#/v2/executions/details/arn:aws-us-gov:states:us-gov-west-1:123456789012:execution:orders:run-1",
+		},
+		{
+			"arn:aws-cn:states:cn-north-1:123456789012:execution:orders:run-1",
+			"https://console.amazonaws.cn/states/home?region=cn-north-1#/v2/executions/details/arn:aws-cn:states:cn-north-1:123456789012:execution:orders:run-1",
+		},
+		{"not-an-arn", ""},
+	} {
+		if got := ExecutionConsoleURL(tt.executionArn); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.executionArn, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleURLs(t *testing.T) {
+	sm := StateMachine{
+		ARN:     "arn:aws:states:us-east-1:123456789012:stateMachine:orders",
+		Logging: LoggingConfiguration{Destinations: []string{"arn:aws:logs:us-east-1:123456789012:log-group:/aws/vendedlogs/states/orders:*"}},
+		Executions: []Execution{
+			{
+				ExecutionArn: "arn:aws:states:us-east-1:123456789012:execution:orders:run-1",
+				MapRuns:      []MapRun{{MapRunArn: "arn:aws:states:us-east-1:123456789012:mapRun:orders/Rows:1234"}},
+			},
+			{ExecutionArn: "N/A"},
+		},
+	}
+	attachConsoleURLs(&sm)
+
+	const console = "https://us-east-1.console.aws.amazon.com/"
+	for _, tt := range []struct{ name, got, want string }{
+		{"state machine", sm.ConsoleURL, console + "states/home?region=us-east-1#/v2/statemachines/view/" + sm.ARN},
+		{"log group", sm.Logging.ConsoleURLs[0], console + "cloudwatch/home?region=us-east-1#logsV2:log-groups/log-group/$252Faws$252Fvendedlogs$252Fstates$252Forders"},
+		{"execution", sm.Executions[0].ConsoleURL, ExecutionConsoleURL(sm.Executions[0].ExecutionArn)},
+		{"map run", sm.Executions[0].MapRuns[0].ConsoleURL, console + "states/home?region=us-east-1#/v2/map-run/details/arn:aws:states:us-east-1:123456789012:mapRun:orders/Rows:1234"},
+		{"placeholder", sm.Executions[1].ConsoleURL, ""},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
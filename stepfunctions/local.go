@@ -0,0 +1,21 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LocalStateMachine builds a STANDARD state machine from a definition read
+// from disk, so definitions can be validated and analyzed without AWS. On a
+// parse error the state machine is still returned with its Definition, which
+// ValidateDefinition reports on.
+func LocalStateMachine(name, definition string) (StateMachine, error) {
+	sm := StateMachine{Name: name, Type: "STANDARD", Definition: definition}
+	states, err := parseDefinition(definition)
+	if err != nil {
+		return sm, fmt.Errorf("failed to parse definition for %s: %w", name, err)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	sm.States = states
+	return sm, nil
+}
@@ -0,0 +1,69 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// logGroup is a CloudWatch Logs destination of a state machine
+type logGroup struct {
+	ARN          string // Without the trailing ":*"
+	Name         string
+	Region       string
+	AccountID    string
+	crossAccount bool
+}
+
+// parseLogGroupArn splits arn:<partition>:logs:<region>:<account>:log-group:<name>[:*]
+func parseLogGroupArn(logGroupArn string) (logGroup, error) {
+	parsed, err := arn.Parse(logGroupArn)
+	if err != nil {
+		return logGroup{}, fmt.Errorf("invalid log group ARN %q: %w", logGroupArn, err)
+	}
+	if parsed.Service != "logs" || !strings.HasPrefix(parsed.Resource, "log-group:") {
+		return logGroup{}, fmt.Errorf("%q is not a CloudWatch Logs log group ARN", logGroupArn)
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(parsed.Resource, "log-group:"), ":*")
+	if name == "" {
+		return logGroup{}, fmt.Errorf("log group ARN %q has no name", logGroupArn)
+	}
+
+	return logGroup{
+		ARN:       strings.TrimSuffix(logGroupArn, ":*"),
+		Name:      name,
+		Region:    parsed.Region,
+		AccountID: parsed.AccountID,
+	}, nil
+}
+
+// setOn addresses the log group in a FilterLogEvents call. Log groups in
+// another account (shared via CloudWatch cross-account observability) must
+// be referenced by ARN.
+func (g logGroup) setOn(input *cloudwatchlogs.FilterLogEventsInput) {
+	if g.crossAccount {
+		input.LogGroupIdentifier = aws.String(g.ARN)
+	} else {
+		input.LogGroupName = aws.String(g.Name)
+	}
+}
+
+// logsClientFor returns a CloudWatch Logs client for the log group's region
+func (f *Fetcher) logsClientFor(region string) logsAPI {
+	if region == "" || region == f.cfg.Region {
+		return f.logsClient
+	}
+	if client, ok := f.logsClients[region]; ok {
+		return client
+	}
+
+	cfg := f.cfg.Copy()
+	cfg.Region = region
+	client := cloudwatchlogs.NewFromConfig(cfg)
+	f.logsClients[region] = client
+	return client
+}
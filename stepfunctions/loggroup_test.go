@@ -0,0 +1,19 @@
+package stepfunctions
+
+import "testing"
+
+func TestParseLogGroupArnPartitions(t *testing.T) {
+	for _, logGroupArn := range []string{
+		"arn:aws:logs:us-east-1:123456789012:log-group:/aws/vendedlogs/states/orders:*",
+		"arn:aws-us-gov:logs:us-gov-west-1:123456789012:log-group:/aws/vendedlogs/states/orders:*",
+		"arn:aws-cn:logs:cn-north-1:123456789012:log-group:/aws/vendedlogs/states/orders",
+	} {
+		group, err := parseLogGroupArn(logGroupArn)
+		if err != nil {
+			t.Fatalf("%s: %v", logGroupArn, err)
+		}
+		if group.Name != "/aws/vendedlogs/states/orders" || group.AccountID != "123456789012" || group.Region == "" {
+			t.Errorf("%s: got %+v", logGroupArn, group)
+		}
+	}
+}
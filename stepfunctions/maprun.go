@@ -0,0 +1,200 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+)
+
+// maxFailedItems caps the failed items kept per Map Run
+const maxFailedItems = 1000
+
+// maxResultFileBytes caps how much of one ResultWriter file is read
+const maxResultFileBytes = 64 * 1024 * 1024
+
+// s3API is the part of the S3 client the fetcher uses
+type s3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// MapRun is a Distributed Map run started by an execution
+type MapRun struct {
+	MapRunArn                  string
+	ConsoleURL                 string `json:",omitempty"` // Step Functions console page
+	MapState                   string // Label of the Map state, its name unless Label is set
+	Status                     string
+	StartTime                  time.Time
+	EndTime                    time.Time
+	Items                      MapRunItemCounts
+	ToleratedFailureCount      int64   `json:",omitempty"`
+	ToleratedFailurePercentage float32 `json:",omitempty"`
+	// ResultWriter is where the run's results were written, s3://bucket/prefix/<run ID>/
+	ResultWriter         string            `json:",omitempty"`
+	FailedItems          []json.RawMessage `json:",omitempty"` // Child executions of the FAILED result files
+	FailedItemsTruncated bool              `json:",omitempty"`
+}
+
+// MapRunItemCounts counts the items of a Map Run by status
+type MapRunItemCounts struct {
+	Total, Pending, Running, Succeeded, Failed, TimedOut, Aborted, ResultsWritten int64
+}
+
+// WithMapRuns makes the fetcher list the Distributed Map runs of Standard
+// executions whose definition has a distributed Map state, and read the
+// items that failed from the runs' ResultWriter output in S3
+func WithMapRuns(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.mapRuns = enabled
+	}
+}
+
+// resultWriter is the S3 location a distributed Map state writes results to;
+// a zero value means the state has no ResultWriter or its location is only
+// known at run time
+type resultWriter struct {
+	Bucket string
+	Prefix string
+}
+
+// distributedMaps returns the distributed Map states of a definition by label
+func distributedMaps(definition string) map[string]resultWriter {
+	maps := make(map[string]resultWriter)
+	walkStates(definition, func(name string, rawDef map[string]interface{}) {
+		if stateType, _ := rawDef["Type"].(string); stateType != "Map" {
+			return
+		}
+		processor, _ := rawDef["ItemProcessor"].(map[string]interface{})
+		config, _ := processor["ProcessorConfig"].(map[string]interface{})
+		if mode, _ := config["Mode"].(string); mode != "DISTRIBUTED" {
+			return
+		}
+		label, _ := rawDef["Label"].(string)
+		if label == "" {
+			label = name
+		}
+		var writer resultWriter
+		resultWriterDef, _ := rawDef["ResultWriter"].(map[string]interface{})
+		for _, key := range []string{"Parameters", "Arguments"} {
+			if params, ok := resultWriterDef[key].(map[string]interface{}); ok {
+				writer.Bucket, _ = params["Bucket"].(string)
+				writer.Prefix, _ = params["Prefix"].(string)
+			}
+		}
+		maps[label] = writer
+	})
+	return maps
+}
+
+// getMapRuns lists the Map Runs of an execution. Failures are warnings, so
+// the execution is still exported.
+func (f *Fetcher) getMapRuns(ctx context.Context, executionArn string, maps map[string]resultWriter) []MapRun {
+	var runs []MapRun
+	paginator := sfn.NewListMapRunsPaginator(f.sfnClient, &sfn.ListMapRunsInput{ExecutionArn: aws.String(executionArn)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			f.warn(executionArn, "ListMapRuns", err)
+			return runs
+		}
+		for _, item := range page.MapRuns {
+			desc, err := f.sfnClient.DescribeMapRun(ctx, &sfn.DescribeMapRunInput{MapRunArn: item.MapRunArn})
+			if err != nil {
+				f.warn(aws.ToString(item.MapRunArn), "DescribeMapRun", err)
+				continue
+			}
+			smArn := aws.ToString(item.StateMachineArn)
+			run := MapRun{
+				MapRunArn:                  aws.ToString(desc.MapRunArn),
+				MapState:                   smArn[strings.LastIndex(smArn, "/")+1:],
+				Status:                     string(desc.Status),
+				StartTime:                  aws.ToTime(desc.StartDate),
+				EndTime:                    aws.ToTime(desc.StopDate),
+				ToleratedFailureCount:      desc.ToleratedFailureCount,
+				ToleratedFailurePercentage: desc.ToleratedFailurePercentage,
+			}
+			if counts := desc.ItemCounts; counts != nil {
+				run.Items = MapRunItemCounts{
+					Total:          counts.Total,
+					Pending:        counts.Pending,
+					Running:        counts.Running,
+					Succeeded:      counts.Succeeded,
+					Failed:         counts.Failed,
+					TimedOut:       counts.TimedOut,
+					Aborted:        counts.Aborted,
+					ResultsWritten: counts.ResultsWritten,
+				}
+			}
+			if writer := maps[run.MapState]; writer.Bucket != "" && run.Items.Failed+run.Items.TimedOut+run.Items.Aborted > 0 {
+				runID := run.MapRunArn[strings.LastIndex(run.MapRunArn, ":")+1:]
+				prefix := path.Join(writer.Prefix, runID)
+				run.ResultWriter = fmt.Sprintf("s3://%s/%s/", writer.Bucket, prefix)
+				if err := f.readFailedItems(ctx, &run, writer.Bucket, path.Join(prefix, "manifest.json")); err != nil {
+					f.warn(run.MapRunArn, "GetObject", err)
+				}
+			}
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// readFailedItems reads the FAILED result files listed in a Map Run's
+// manifest. A missing manifest means the run has not finished writing.
+func (f *Fetcher) readFailedItems(ctx context.Context, run *MapRun, bucket, manifestKey string) error {
+	var manifest struct {
+		DestinationBucket string
+		ResultFiles       map[string][]struct{ Key string }
+	}
+	err := f.getJSONObject(ctx, bucket, manifestKey, &manifest)
+	var noSuchKey *s3types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		fmt.Printf("Debug: No ResultWriter manifest for %s yet\n", run.MapRunArn)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if manifest.DestinationBucket != "" {
+		bucket = manifest.DestinationBucket
+	}
+	for _, file := range manifest.ResultFiles["FAILED"] {
+		var items []json.RawMessage
+		if err := f.getJSONObject(ctx, bucket, file.Key, &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if len(run.FailedItems) == maxFailedItems {
+				run.FailedItemsTruncated = true
+				return nil
+			}
+			run.FailedItems = append(run.FailedItems, item)
+		}
+	}
+	return nil
+}
+
+func (f *Fetcher) getJSONObject(ctx context.Context, bucket, key string, v interface{}) error {
+	out, err := f.s3Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(out.Body, maxResultFileBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// fakeS3 serves objects by bucket/key
+type fakeS3 struct {
+	objects map[string]string
+}
+
+func (c *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := c.objects[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)]
+	if !ok {
+		return nil, &s3types.NoSuchKey{Message: aws.String("not found")}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(data)))}, nil
+}
+
+const distributedMapDefinition = `{"StartAt":"Import","States":{
+	"Import":{"Type":"Map","Label":"Rows","ItemProcessor":{"ProcessorConfig":{"Mode":"DISTRIBUTED","ExecutionType":"STANDARD"},
+		"StartAt":"Load","States":{"Load":{"Type":"Pass","End":true}}},
+		"ResultWriter":{"Resource":"arn:aws:states:::s3:putObject","Parameters":{"Bucket":"results","Prefix":"imports"}},"Next":"Inline"},
+	"Inline":{"Type":"Map","ItemProcessor":{"StartAt":"Noop","States":{"Noop":{"Type":"Pass","End":true}}},"End":true}}}`
+
+func TestDistributedMaps(t *testing.T) {
+	maps := distributedMaps(distributedMapDefinition)
+	if len(maps) != 1 || maps["Rows"] != (resultWriter{Bucket: "results", Prefix: "imports"}) {
+		t.Errorf("got distributed maps %+v", maps)
+	}
+}
+
+func TestGetMapRuns(t *testing.T) {
+	executionArn := "arn:aws:states:us-east-1:" + testAccount + ":execution:orders:run-1"
+	mapRunArn := "arn:aws:states:us-east-1:" + testAccount + ":mapRun:orders/Rows:1234"
+	client := &fakeSFN{
+		mapRuns: map[string][]types.MapRunListItem{executionArn: {{
+			ExecutionArn:    aws.String(executionArn),
+			MapRunArn:       aws.String(mapRunArn),
+			StateMachineArn: aws.String(ordersArn + "/Rows"),
+		}}},
+		mapRunDetails: map[string]*sfn.DescribeMapRunOutput{mapRunArn: {
+			MapRunArn:  aws.String(mapRunArn),
+			Status:     types.MapRunStatusFailed,
+			ItemCounts: &types.MapRunItemCounts{Total: 3, Succeeded: 1, Failed: 2},
+		}},
+	}
+	f := newTestFetcher(client, &fakeLogs{})
+	f.s3Client = &fakeS3{objects: map[string]string{
+		"results/imports/1234/manifest.json": `{"DestinationBucket":"results","ResultFiles":{"FAILED":[{"Key":"imports/1234/FAILED_0.json","Size":1}],"SUCCEEDED":[]}}`,
+		"results/imports/1234/FAILED_0.json": `[{"Name":"a","Status":"FAILED","Input":"{\"row\":1}"},{"Name":"b","Status":"FAILED","Input":"{\"row\":2}"}]`,
+	}}
+
+	runs := f.getMapRuns(context.Background(), executionArn, distributedMaps(distributedMapDefinition))
+	if len(runs) != 1 {
+		t.Fatalf("got map runs %+v", runs)
+	}
+	run := runs[0]
+	if run.MapState != "Rows" || run.Status != "FAILED" || run.Items.Failed != 2 || run.ResultWriter != "s3://results/imports/1234/" {
+		t.Errorf("got map run %+v", run)
+	}
+	if len(run.FailedItems) != 2 || !strings.Contains(string(run.FailedItems[1]), `"b"`) {
+		t.Errorf("got failed items %s", run.FailedItems)
+	}
+
+	// Without a manifest the run is still reported
+	f.s3Client = &fakeS3{}
+	runs = f.getMapRuns(context.Background(), executionArn, distributedMaps(distributedMapDefinition))
+	if len(runs) != 1 || runs[0].FailedItems != nil {
+		t.Errorf("got map runs %+v", runs)
+	}
+}
@@ -0,0 +1,129 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// metricsAPI is the part of the CloudWatch client the fetcher uses
+type metricsAPI interface {
+	cloudwatch.GetMetricDataAPIClient
+}
+
+// ExecutionMetrics are the execution counts of a state machine from the
+// AWS/States CloudWatch metrics. They stand in for the executions of Express
+// workflows that don't log to CloudWatch Logs.
+type ExecutionMetrics struct {
+	Source   string // Always "CloudWatch metrics (AWS/States)"
+	Start    time.Time
+	End      time.Time
+	Started  int
+	ByStatus map[string]int // SUCCEEDED, FAILED, TIMED_OUT, ABORTED
+}
+
+// MetricsSource annotates data derived from CloudWatch metrics
+const MetricsSource = "CloudWatch metrics (AWS/States)"
+
+// executionMetricNames maps the AWS/States metrics to the statuses they count
+var executionMetricNames = map[string]string{
+	"ExecutionsStarted":   "",
+	"ExecutionsSucceeded": "SUCCEEDED",
+	"ExecutionsFailed":    "FAILED",
+	"ExecutionsTimedOut":  "TIMED_OUT",
+	"ExecutionsAborted":   "ABORTED",
+}
+
+// Summary is a one-line description of the counts, for placeholder rows
+func (m *ExecutionMetrics) Summary() string {
+	return fmt.Sprintf("No logs: %d started, %d succeeded, %d failed, %d timed out, %d aborted in %v (%s)",
+		m.Started, m.ByStatus["SUCCEEDED"], m.ByStatus["FAILED"], m.ByStatus["TIMED_OUT"], m.ByStatus["ABORTED"],
+		m.End.Sub(m.Start), m.Source)
+}
+
+// getExecutionMetrics sums the execution metrics of a state machine over the
+// Express lookback window. Five-minute periods keep the window exact for
+// lookbacks CloudWatch stores at that resolution; older windows use hours.
+func (f *Fetcher) getExecutionMetrics(ctx context.Context, smArn string) (*ExecutionMetrics, error) {
+	if f.metricsClient == nil {
+		return nil, errors.New("no CloudWatch client")
+	}
+	end := f.clock.Now()
+	metrics := &ExecutionMetrics{
+		Source:   MetricsSource,
+		Start:    end.Add(-f.expressQuery.Lookback),
+		End:      end,
+		ByStatus: make(map[string]int),
+	}
+	period := int32(300)
+	if f.expressQuery.Lookback > 15*24*time.Hour {
+		period = 3600
+	}
+
+	var queries []cwtypes.MetricDataQuery
+	for name := range executionMetricNames {
+		queries = append(queries, cwtypes.MetricDataQuery{
+			Id: aws.String(strings.ToLower(name)),
+			MetricStat: &cwtypes.MetricStat{
+				Metric: &cwtypes.Metric{
+					Namespace:  aws.String("AWS/States"),
+					MetricName: aws.String(name),
+					Dimensions: []cwtypes.Dimension{{Name: aws.String("StateMachineArn"), Value: aws.String(smArn)}},
+				},
+				Period: aws.Int32(period),
+				Stat:   aws.String("Sum"),
+			},
+		})
+	}
+
+	paginator := cloudwatch.NewGetMetricDataPaginator(f.metricsClient, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: queries,
+		StartTime:         aws.Time(metrics.Start),
+		EndTime:           aws.Time(metrics.End),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get execution metrics for %s: %w", smArn, err)
+		}
+		for _, result := range page.MetricDataResults {
+			sum := 0
+			for _, value := range result.Values {
+				sum += int(value)
+			}
+			for name, status := range executionMetricNames {
+				if strings.ToLower(name) != aws.ToString(result.Id) {
+					continue
+				}
+				if status == "" {
+					metrics.Started += sum
+				} else {
+					metrics.ByStatus[status] += sum
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// expressMetricsFallback returns the execution metrics of an Express workflow
+// whose executions could not be read from logs, or nil when err has another
+// cause or the metrics are unavailable
+func (f *Fetcher) expressMetricsFallback(ctx context.Context, smArn string, err error) *ExecutionMetrics {
+	if !errors.Is(err, ErrLoggingNotConfigured) {
+		return nil
+	}
+	fmt.Printf("Debug: Falling back to CloudWatch metrics for %s\n", smArn)
+	metrics, err := f.getExecutionMetrics(ctx, smArn)
+	if err != nil {
+		f.warn(smArn, "GetMetricData", err)
+		return nil
+	}
+	return metrics
+}
@@ -0,0 +1,122 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// fakeMetrics serves metric values by query ID, split over two pages
+type fakeMetrics struct {
+	values map[string][]float64
+	inputs []*cloudwatch.GetMetricDataInput
+}
+
+func (c *fakeMetrics) GetMetricData(_ context.Context, in *cloudwatch.GetMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	c.inputs = append(c.inputs, in)
+	out := &cloudwatch.GetMetricDataOutput{}
+	for _, query := range in.MetricDataQueries {
+		values := c.values[aws.ToString(query.Id)]
+		half := len(values) / 2
+		if in.NextToken == nil {
+			values = values[:half]
+		} else {
+			values = values[half:]
+		}
+		out.MetricDataResults = append(out.MetricDataResults, cwtypes.MetricDataResult{Id: query.Id, Values: values})
+	}
+	if in.NextToken == nil {
+		out.NextToken = aws.String("2")
+	}
+	return out, nil
+}
+
+func TestListStateMachinesExpressMetricsFallback(t *testing.T) {
+	client := &fakeSFN{stateMachines: []*sfn.DescribeStateMachineOutput{{
+		StateMachineArn: aws.String(checkoutArn),
+		Name:            aws.String("checkout"),
+		Type:            types.StateMachineTypeExpress,
+		Definition:      aws.String(`{"StartAt":"Price","States":{"Price":{"Type":"Pass","End":true}}}`),
+		RoleArn:         aws.String("arn:aws:iam::" + testAccount + ":role/checkout"),
+		CreationDate:    aws.Time(testStart),
+	}}}
+	metrics := &fakeMetrics{values: map[string][]float64{
+		"executionsstarted":   {4, 3, 2, 1},
+		"executionssucceeded": {4, 2, 1, 0},
+		"executionsfailed":    {0, 1, 0, 1},
+		"executionstimedout":  {0, 0, 1, 0},
+	}}
+	now := testStart.Add(time.Hour)
+	f := newTestFetcher(client, &fakeLogs{}, WithWarningSink(&collectWarnings{}), WithClock(fixedClock(now)))
+	f.metricsClient = metrics
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := stateMachines[0].Metrics
+	if got == nil {
+		t.Fatal("got no metrics")
+	}
+	if got.Started != 10 || got.ByStatus["SUCCEEDED"] != 7 || got.ByStatus["FAILED"] != 2 || got.ByStatus["TIMED_OUT"] != 1 || got.ByStatus["ABORTED"] != 0 {
+		t.Errorf("got metrics %+v", got)
+	}
+	if !got.Start.Equal(now.Add(-24*time.Hour)) || !got.End.Equal(now) {
+		t.Errorf("got window %v to %v", got.Start, got.End)
+	}
+	if status := stateMachines[0].Executions[0].Status; !strings.Contains(status, "10 started") || !strings.Contains(status, MetricsSource) {
+		t.Errorf("got placeholder status %q", status)
+	}
+	query := metrics.inputs[0].MetricDataQueries[0].MetricStat
+	if dim := query.Metric.Dimensions[0]; aws.ToString(dim.Value) != checkoutArn || aws.ToString(query.Metric.Namespace) != "AWS/States" {
+		t.Errorf("got query %+v", query.Metric)
+	}
+
+	// The metrics covered the state machine, so it is not skipped
+	if skipped := f.Skipped(); len(skipped) != 0 {
+		t.Errorf("got skipped %v, want none", skipped)
+	}
+
+	counts, err := f.CountExecutions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts[0].Source != MetricsSource || counts[0].Total != 10 || counts[0].ByStatus["FAILED"] != 2 {
+		t.Errorf("got counts %+v", counts)
+	}
+}
+
+type failingMetrics struct{}
+
+func (failingMetrics) GetMetricData(context.Context, *cloudwatch.GetMetricDataInput, ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	return nil, errors.New("metrics unavailable")
+}
+
+func TestListStateMachinesExpressMetricsFallbackFails(t *testing.T) {
+	client := &fakeSFN{stateMachines: []*sfn.DescribeStateMachineOutput{{
+		StateMachineArn: aws.String(checkoutArn),
+		Name:            aws.String("checkout"),
+		Type:            types.StateMachineTypeExpress,
+		Definition:      aws.String(`{"StartAt":"Price","States":{"Price":{"Type":"Pass","End":true}}}`),
+		RoleArn:         aws.String("arn:aws:iam::" + testAccount + ":role/checkout"),
+		CreationDate:    aws.Time(testStart),
+	}}}
+	f := newTestFetcher(client, &fakeLogs{}, WithWarningSink(&collectWarnings{}))
+	f.metricsClient = failingMetrics{}
+
+	if _, err := f.ListStateMachines(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Neither logs nor metrics covered the state machine
+	if skipped := f.Skipped(); len(skipped) != 1 || skipped[0].Kind != "logging_not_configured" {
+		t.Errorf("got skipped %v", skipped)
+	}
+}
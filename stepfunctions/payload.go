@@ -0,0 +1,126 @@
+package stepfunctions
+
+import "sort"
+
+// PayloadLimitBytes is the Step Functions limit on state input and output
+const PayloadLimitBytes = 256 * 1024
+
+const (
+	// payloadNearLimit is the share of the limit at which a state is flagged
+	payloadNearLimit = 0.8
+	// payloadOffloadShare is the share of the limit at which offloading the
+	// payload to S3 is recommended
+	payloadOffloadShare = 0.5
+)
+
+// PayloadStats measures the input and output sizes of one state across the
+// fetched histories
+type PayloadStats struct {
+	StateMachineName string
+	StateName        string
+	Samples          int // State entries measured
+	AvgInputBytes    int
+	MaxInputBytes    int
+	AvgOutputBytes   int
+	MaxOutputBytes   int
+	LimitShare       float64 // Largest payload / PayloadLimitBytes
+	NearLimit        bool    // LimitShare of at least 80%
+}
+
+// OffloadCandidate is a state machine whose payloads are large enough that
+// passing S3 references, or a Distributed Map with ItemReader/ResultWriter,
+// would keep it clear of the payload limit
+type OffloadCandidate struct {
+	StateMachineName string
+	States           []string // States with a payload of at least half the limit
+	MaxBytes         int
+}
+
+// ComputePayloadStats measures state inputs from StateEntered events and
+// outputs from StateExited events, largest first. Events without data, such
+// as Express logs without execution data, are not measured.
+func ComputePayloadStats(sm StateMachine) []PayloadStats {
+	stats := make(map[string]*PayloadStats)
+	inputs := make(map[string]int)
+	outputs := make(map[string]int)
+	for _, exec := range sm.Executions {
+		for _, event := range exec.History {
+			entered, exited := isStateEntered(event), isStateExited(event)
+			if !entered && !exited {
+				continue
+			}
+			s := stats[event.StateName]
+			if s == nil {
+				s = &PayloadStats{StateMachineName: sm.Name, StateName: event.StateName}
+				stats[event.StateName] = s
+			}
+			switch {
+			case entered && event.Input != "":
+				s.Samples++
+				s.AvgInputBytes += len(event.Input)
+				inputs[event.StateName]++
+				s.MaxInputBytes = max(s.MaxInputBytes, len(event.Input))
+			case exited && event.Output != "":
+				s.AvgOutputBytes += len(event.Output)
+				outputs[event.StateName]++
+				s.MaxOutputBytes = max(s.MaxOutputBytes, len(event.Output))
+			}
+		}
+	}
+
+	var result []PayloadStats
+	for name, s := range stats {
+		if inputs[name]+outputs[name] == 0 {
+			continue
+		}
+		if inputs[name] > 0 {
+			s.AvgInputBytes /= inputs[name]
+		}
+		if outputs[name] > 0 {
+			s.AvgOutputBytes /= outputs[name]
+		}
+		s.LimitShare = float64(max(s.MaxInputBytes, s.MaxOutputBytes)) / PayloadLimitBytes
+		s.NearLimit = s.LimitShare >= payloadNearLimit
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].LimitShare != result[j].LimitShare {
+			return result[i].LimitShare > result[j].LimitShare
+		}
+		return result[i].StateName < result[j].StateName
+	})
+	return result
+}
+
+// AdviseOffload reports whether a state machine's payloads are large enough
+// to move to S3, from its ComputePayloadStats
+func AdviseOffload(sm StateMachine, stats []PayloadStats) (OffloadCandidate, bool) {
+	candidate := OffloadCandidate{StateMachineName: sm.Name}
+	for _, s := range stats {
+		if s.LimitShare >= payloadOffloadShare {
+			candidate.States = append(candidate.States, s.StateName)
+			candidate.MaxBytes = max(candidate.MaxBytes, s.MaxInputBytes, s.MaxOutputBytes)
+		}
+	}
+	sort.Strings(candidate.States)
+	return candidate, len(candidate.States) > 0
+}
+
+// checkPayloadSizes flags states whose observed payloads come within 20% of
+// the payload limit
+func checkPayloadSizes(sm StateMachine) []Finding {
+	var findings []Finding
+	for _, s := range ComputePayloadStats(sm) {
+		if !s.NearLimit {
+			continue
+		}
+		direction := "input"
+		size := s.MaxInputBytes
+		if s.MaxOutputBytes > size {
+			direction, size = "output", s.MaxOutputBytes
+		}
+		findings = append(findings, newFinding("SFN010", sm, s.StateName,
+			"Largest %s of %d bytes is %.0f%% of the %dKB payload limit", direction, size, s.LimitShare*100, PayloadLimitBytes/1024))
+	}
+	return findings
+}
@@ -0,0 +1,45 @@
+package stepfunctions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputePayloadStats(t *testing.T) {
+	big := `"` + strings.Repeat("x", 220*1024) + `"`
+	medium := `"` + strings.Repeat("x", 140*1024) + `"`
+	sm := StateMachine{Name: "orders", Definition: `{"StartAt":"Load","States":{"Load":{"Type":"Pass","Next":"Ship"},"Ship":{"Type":"Pass","End":true}}}`, Executions: []Execution{
+		{History: []HistoryEvent{
+			{ID: 1, Type: "PassStateEntered", StateName: "Load", Input: `{}`},
+			{ID: 2, Type: "PassStateExited", StateName: "Load", Output: big},
+			{ID: 3, Type: "PassStateEntered", StateName: "Ship", Input: medium},
+			{ID: 4, Type: "PassStateExited", StateName: "Ship", Output: `{}`},
+		}},
+		// Logged without execution data
+		{History: []HistoryEvent{
+			{ID: 1, Type: "PassStateEntered", StateName: "Load"},
+			{ID: 2, Type: "PassStateExited", StateName: "Load"},
+		}},
+	}}
+
+	stats := ComputePayloadStats(sm)
+	if len(stats) != 2 {
+		t.Fatalf("got %d states, want 2: %+v", len(stats), stats)
+	}
+	load := stats[0]
+	if load.StateName != "Load" || load.Samples != 1 || load.MaxOutputBytes != len(big) || load.AvgInputBytes != 2 || !load.NearLimit {
+		t.Errorf("got %+v", load)
+	}
+	if ship := stats[1]; ship.StateName != "Ship" || ship.NearLimit || ship.LimitShare < 0.5 {
+		t.Errorf("got %+v", ship)
+	}
+
+	candidate, ok := AdviseOffload(sm, stats)
+	if !ok || len(candidate.States) != 2 || candidate.MaxBytes != len(big) {
+		t.Errorf("got offload advice %+v, %v", candidate, ok)
+	}
+	findings := checkPayloadSizes(sm)
+	if len(findings) != 1 || findings[0].RuleID != "SFN010" || findings[0].State != "Load" || !strings.Contains(findings[0].Message, "output") {
+		t.Errorf("got findings %+v", findings)
+	}
+}
@@ -0,0 +1,159 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+)
+
+// listExecutionsPageSize is the page size getExecutions requests
+const listExecutionsPageSize = 50
+
+// RunPlan estimates the API calls and files a full run would make
+type RunPlan struct {
+	StateMachines []PlannedStateMachine
+	APICalls      map[string]int // Operation -> estimated calls, across all state machines
+	Files         int
+}
+
+// PlannedStateMachine is the estimate for one state machine
+type PlannedStateMachine struct {
+	Name       string
+	ARN        string
+	Type       string
+	States     int
+	Executions int // -1 when unknown (Express executions are only found in logs)
+	APICalls   map[string]int
+	Files      int
+}
+
+// TotalAPICalls sums the estimated calls across operations
+func (p PlannedStateMachine) TotalAPICalls() int {
+	total := 0
+	for _, calls := range p.APICalls {
+		total += calls
+	}
+	return total
+}
+
+// PlanRun lists the state machines a run would process and estimates its API
+// calls using only read-only listing and describe calls: executions of
+// Standard workflows are counted with the largest ListExecutions page size.
+// Nothing is fetched per execution and nothing is written.
+func (f *Fetcher) PlanRun(ctx context.Context) (RunPlan, error) {
+	plan := RunPlan{APICalls: map[string]int{"ListStateMachines": 0}, Files: 1} // state_machines.json
+
+	arns := f.arns
+	if len(arns) == 0 {
+		paginator := sfn.NewListStateMachinesPaginator(f.sfnClient, &sfn.ListStateMachinesInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return plan, fmt.Errorf("failed to list state machines: %w", err)
+			}
+			plan.APICalls["ListStateMachines"]++
+			for _, item := range page.StateMachines {
+				arns = append(arns, *item.StateMachineArn)
+			}
+		}
+	}
+
+	for _, smArn := range arns {
+		planned, err := f.planStateMachine(ctx, smArn)
+		if err != nil {
+			f.warn(smArn, "PlanStateMachine", err)
+			continue
+		}
+		for op, calls := range planned.APICalls {
+			plan.APICalls[op] += calls
+		}
+		plan.Files += planned.Files
+		plan.StateMachines = append(plan.StateMachines, planned)
+	}
+
+	return plan, nil
+}
+
+func (f *Fetcher) planStateMachine(ctx context.Context, arn string) (PlannedStateMachine, error) {
+	result, err := f.sfnClient.DescribeStateMachine(ctx, &sfn.DescribeStateMachineInput{StateMachineArn: aws.String(arn)})
+	if err != nil {
+		return PlannedStateMachine{}, fmt.Errorf("failed to describe state machine %s: %w", arn, err)
+	}
+
+	planned := PlannedStateMachine{
+		Name:       aws.ToString(result.Name),
+		ARN:        arn,
+		Type:       string(result.Type),
+		Executions: -1,
+		APICalls:   map[string]int{"DescribeStateMachine": 1},
+	}
+	if states, err := parseDefinition(aws.ToString(result.Definition)); err == nil {
+		planned.States = len(states)
+	}
+	planned.Files = 1 + planned.States // <name>.asl.json and one file per state
+
+	switch planned.Type {
+	case "STANDARD":
+		byStatus, err := f.countExecutions(ctx, arn)
+		if err != nil {
+			return planned, err
+		}
+		pageSize := float64(listExecutionsPageSize)
+		if f.sample.enabled() {
+			pageSize = 1000 // Every execution is listed before sampling
+		}
+		planned.APICalls["ListExecutions"] = int(math.Max(1, math.Ceil(float64(sumCounts(byStatus))/pageSize)))
+		count := 0
+		for _, n := range byStatus {
+			count += f.sample.size(n)
+		}
+		planned.Executions = count
+		planned.APICalls["DescribeExecution"] = count
+		if f.includeHistory {
+			planned.APICalls["GetExecutionHistory"] = count // At least one page each
+		}
+		planned.Files += count
+	case "EXPRESS":
+		destinations := 0
+		if result.LoggingConfiguration != nil {
+			destinations = len(result.LoggingConfiguration.Destinations)
+		}
+		planned.APICalls["FilterLogEvents"] = destinations // At least one page per log group
+	}
+
+	return planned, nil
+}
+
+// countExecutions pages through ListExecutions with the maximum page size and
+// counts the executions by status
+func (f *Fetcher) countExecutions(ctx context.Context, stateMachineArn string) (map[string]int, error) {
+	byStatus := make(map[string]int)
+	paginator := sfn.NewListExecutionsPaginator(f.sfnClient, &sfn.ListExecutionsInput{
+		StateMachineArn: aws.String(f.executionScope(stateMachineArn)),
+		MaxResults:      1000,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if f.alias != "" && isMissingScope(err) {
+			return byStatus, nil // No executions in scope
+		}
+		if err != nil {
+			return byStatus, fmt.Errorf("failed to count executions for %s: %w", stateMachineArn, err)
+		}
+		for _, exec := range page.Executions {
+			byStatus[string(exec.Status)]++
+		}
+	}
+	return byStatus, nil
+}
+
+func sumCounts(byStatus map[string]int) int {
+	total := 0
+	for _, count := range byStatus {
+		total += count
+	}
+	return total
+}
@@ -0,0 +1,203 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// quotaHeadroom is the share of each throttling quota the fetcher uses,
+// leaving the rest to the account's other callers
+const quotaHeadroom = 0.8
+
+// APIRate is a Step Functions throttling quota: a token bucket of Burst
+// calls refilled at PerSecond
+type APIRate struct {
+	Burst     float64
+	PerSecond float64
+}
+
+// defaultAPIRates are the published Step Functions throttling quotas of the
+// smaller regions, used for operations Service Quotas does not report
+var defaultAPIRates = map[string]APIRate{
+	"ListStateMachines":    {Burst: 100, PerSecond: 5},
+	"DescribeStateMachine": {Burst: 200, PerSecond: 20},
+	"ListExecutions":       {Burst: 100, PerSecond: 2},
+	"DescribeExecution":    {Burst: 250, PerSecond: 15},
+	"GetExecutionHistory":  {Burst: 250, PerSecond: 5},
+	"StartExecution":       {Burst: 800, PerSecond: 150},
+	"StopExecution":        {Burst: 500, PerSecond: 25},
+}
+
+// quotasAPI is the part of the Service Quotas client the fetcher uses
+type quotasAPI interface {
+	servicequotas.ListServiceQuotasAPIClient
+	servicequotas.ListAWSDefaultServiceQuotasAPIClient
+}
+
+// WithQuotaPacing paces Step Functions calls to stay under the account's API
+// throttling quotas, read from Service Quotas when the fetcher is created,
+// instead of running into ThrottlingException retries that the account's
+// other callers would share. The Service Quotas calls are not counted by
+// WithMaxAPICalls.
+func WithQuotaPacing(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.quotaPacing = enabled
+	}
+}
+
+// APIRates returns the rates Step Functions calls are paced at, by
+// operation; nil without WithQuotaPacing
+func (f *Fetcher) APIRates() map[string]APIRate {
+	if f.pacing == nil {
+		return nil
+	}
+	rates := make(map[string]APIRate, len(f.pacing))
+	for operation, bucket := range f.pacing {
+		rates[operation] = APIRate{Burst: bucket.burst, PerSecond: bucket.perSecond}
+	}
+	return rates
+}
+
+// tunePacing reads the account's quotas and sets the pacing under them,
+// falling back to the defaults when Service Quotas cannot be read. APIRates
+// reports the result.
+func (f *Fetcher) tunePacing(ctx context.Context, client quotasAPI) {
+	quotas, err := readAPIRates(ctx, client)
+	if err != nil {
+		f.warn("states", "ListServiceQuotas", fmt.Errorf("%w; pacing at the default quotas", err))
+	}
+	f.pacing = make(map[string]*tokenBucket, len(defaultAPIRates))
+	for operation, rate := range defaultAPIRates {
+		if quota, ok := quotas[operation]; ok {
+			rate = quota
+		}
+		f.pacing[operation] = newTokenBucket(max(1, rate.Burst*quotaHeadroom), rate.PerSecond*quotaHeadroom)
+	}
+}
+
+// readAPIRates reads the Step Functions throttling quotas applied to the
+// account, or the AWS defaults when none were changed. A quota name holds the
+// operation and whether it is the bucket size or the refill rate.
+func readAPIRates(ctx context.Context, client quotasAPI) (map[string]APIRate, error) {
+	var names []string
+	values := make(map[string]float64)
+	applied := servicequotas.NewListServiceQuotasPaginator(client, &servicequotas.ListServiceQuotasInput{ServiceCode: aws.String("states")})
+	for applied.HasMorePages() {
+		page, err := applied.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Step Functions quotas: %w", err)
+		}
+		for _, quota := range page.Quotas {
+			names = append(names, aws.ToString(quota.QuotaName))
+			values[aws.ToString(quota.QuotaName)] = aws.ToFloat64(quota.Value)
+		}
+	}
+	if len(names) == 0 {
+		defaults := servicequotas.NewListAWSDefaultServiceQuotasPaginator(client, &servicequotas.ListAWSDefaultServiceQuotasInput{ServiceCode: aws.String("states")})
+		for defaults.HasMorePages() {
+			page, err := defaults.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list default Step Functions quotas: %w", err)
+			}
+			for _, quota := range page.Quotas {
+				names = append(names, aws.ToString(quota.QuotaName))
+				values[aws.ToString(quota.QuotaName)] = aws.ToFloat64(quota.Value)
+			}
+		}
+	}
+
+	rates := make(map[string]APIRate)
+	for _, name := range names {
+		value := values[name]
+		if value <= 0 {
+			continue
+		}
+		words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+		lower := strings.ToLower(name)
+		for _, word := range words {
+			if _, ok := defaultAPIRates[word]; !ok {
+				continue
+			}
+			rate, ok := rates[word]
+			if !ok {
+				rate = defaultAPIRates[word]
+			}
+			switch {
+			case strings.Contains(lower, "refill"):
+				rate.PerSecond = value
+			case strings.Contains(lower, "bucket"):
+				rate.Burst = value
+			default:
+				continue
+			}
+			rates[word] = rate
+		}
+	}
+	return rates, nil
+}
+
+// pacingMiddleware waits for a token before each paced Step Functions
+// operation, outside the API timeout so waiting is not mistaken for a hang
+func (f *Fetcher) pacingMiddleware(stack *middleware.Stack) error {
+	if f.pacing == nil {
+		return nil
+	}
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("QuotaPacing",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			if middleware.GetServiceID(ctx) == "SFN" {
+				if bucket, ok := f.pacing[middleware.GetOperationName(ctx)]; ok {
+					if err := bucket.wait(ctx); err != nil {
+						return middleware.InitializeOutput{}, middleware.Metadata{}, err
+					}
+				}
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.Before)
+}
+
+// tokenBucket allows burst calls at once and perSecond after that
+type tokenBucket struct {
+	burst     float64
+	perSecond float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(burst, perSecond float64) *tokenBucket {
+	return &tokenBucket{burst: burst, perSecond: perSecond, tokens: burst, last: time.Now()}
+}
+
+// wait takes a token, sleeping until one is available
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+)
+
+// fakeQuotas answers with applied quotas, falling back to the defaults, or
+// fails when err is set
+type fakeQuotas struct {
+	applied, defaults map[string]float64
+	err               error
+}
+
+func quotaList(values map[string]float64) []types.ServiceQuota {
+	var quotas []types.ServiceQuota
+	for name, value := range values {
+		quotas = append(quotas, types.ServiceQuota{QuotaName: aws.String(name), Value: aws.Float64(value)})
+	}
+	return quotas
+}
+
+func (q *fakeQuotas) ListServiceQuotas(ctx context.Context, params *servicequotas.ListServiceQuotasInput, optFns ...func(*servicequotas.Options)) (*servicequotas.ListServiceQuotasOutput, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	return &servicequotas.ListServiceQuotasOutput{Quotas: quotaList(q.applied)}, nil
+}
+
+func (q *fakeQuotas) ListAWSDefaultServiceQuotas(ctx context.Context, params *servicequotas.ListAWSDefaultServiceQuotasInput, optFns ...func(*servicequotas.Options)) (*servicequotas.ListAWSDefaultServiceQuotasOutput, error) {
+	return &servicequotas.ListAWSDefaultServiceQuotasOutput{Quotas: quotaList(q.defaults)}, nil
+}
+
+func TestTunePacing(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		quotas   *fakeQuotas
+		want     APIRate
+		warnings int
+	}{
+		{"applied", &fakeQuotas{applied: map[string]float64{
+			"DescribeExecution throttle token bucket size":       500,
+			"DescribeExecution throttle token refill rate":       50,
+			"DescribeStateMachineForExecution token refill rate": 1,
+		}}, APIRate{Burst: 400, PerSecond: 40}, 0},
+		{"defaults", &fakeQuotas{defaults: map[string]float64{
+			"DescribeExecution throttle token refill rate": 20,
+		}}, APIRate{Burst: 200, PerSecond: 16}, 0},
+		{"unreadable", &fakeQuotas{err: errors.New("AccessDenied")}, APIRate{Burst: 200, PerSecond: 12}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings := &collectWarnings{}
+			f := newFetcher(WithWarningSink(warnings))
+			f.tunePacing(context.Background(), tc.quotas)
+			rates := f.APIRates()
+			if got := rates["DescribeExecution"]; got != tc.want {
+				t.Errorf("got DescribeExecution %+v, want %+v", got, tc.want)
+			}
+			if got := rates["DescribeStateMachine"]; got.PerSecond != 16 {
+				t.Errorf("got DescribeStateMachine %+v, want the default paced at 16/s", got)
+			}
+			if len(*warnings) != tc.warnings {
+				t.Errorf("got warnings %v, want %d", *warnings, tc.warnings)
+			}
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2, 100)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := bucket.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two calls from the burst, then one every 10ms
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("4 calls took %v, want at least 20ms", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := bucket.wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v waiting with a cancelled context, want context.Canceled", err)
+	}
+}
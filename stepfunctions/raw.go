@@ -0,0 +1,29 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WithRawResponses keeps each DescribeStateMachine response, as returned by
+// the API, in StateMachine.RawDescribe
+func WithRawResponses(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.keepRaw = enabled
+	}
+}
+
+// rawResponse encodes an SDK output struct as JSON, without the SDK's
+// per-request ResultMetadata
+func rawResponse(output interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal raw response: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal raw response: %w", err)
+	}
+	delete(fields, "ResultMetadata")
+	return json.MarshalIndent(fields, "", "  ")
+}
@@ -0,0 +1,155 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+)
+
+// flow checks the transitions of a scope and its nested scopes: states
+// StartAt does not reach (ASL006), states with no path to an end (ASL007),
+// loops without a way out (ASL008) and loops that never pause in a Wait state
+// or a task waiting for a job or callback (ASL009). Transitions to unknown
+// states are left to ASL002 and ASL003.
+func (v *validator) flow(g Graph) {
+	for _, node := range g.Nodes {
+		for _, child := range node.Children {
+			v.flow(child)
+		}
+	}
+	nodes := make(map[string]GraphNode, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes[node.Name] = node
+	}
+	if _, ok := nodes[g.StartAt]; !ok {
+		return
+	}
+	next := make(map[string][]string)
+	previous := make(map[string][]string)
+	hasDefault := make(map[string]bool)
+	for _, edge := range g.Edges {
+		if _, ok := nodes[edge.To]; !ok {
+			continue
+		}
+		next[edge.From] = append(next[edge.From], edge.To)
+		previous[edge.To] = append(previous[edge.To], edge.From)
+		hasDefault[edge.From] = hasDefault[edge.From] || edge.Label == "Default"
+	}
+	// A state ends the scope when it ends it explicitly, fails a Choice that
+	// no rule matches, or has no transition, which ASL004 reports
+	ends := func(node GraphNode) bool {
+		return node.End || node.Type == "Succeed" || node.Type == "Fail" ||
+			(node.Type == "Choice" && !hasDefault[node.Name]) || len(next[node.Name]) == 0
+	}
+
+	reachable := walk([]string{g.StartAt}, next)
+	var terminal []string
+	for _, node := range g.Nodes {
+		if ends(node) {
+			terminal = append(terminal, node.Name)
+		}
+	}
+	terminates := walk(terminal, previous)
+	for _, node := range g.Nodes {
+		switch {
+		case !reachable[node.Name]:
+			v.add("ASL006", node.Name, "State %q is not reachable from StartAt %q", node.Name, g.StartAt)
+		case !terminates[node.Name]:
+			v.add("ASL007", node.Name, "No path from state %q reaches Succeed, Fail or an End state", node.Name)
+		}
+	}
+
+	for _, loop := range loops(g.Nodes, next) {
+		members := make(map[string]bool, len(loop))
+		exits, waits := false, false
+		for _, name := range loop {
+			members[name] = true
+		}
+		for _, name := range loop {
+			waits = waits || nodes[name].Type == "Wait" || v.waits[name]
+			exits = exits || ends(nodes[name])
+			for _, to := range next[name] {
+				exits = exits || !members[to]
+			}
+		}
+		if !reachable[loop[0]] {
+			continue
+		}
+		states := strings.Join(loop, ", ")
+		switch {
+		case !exits:
+			v.add("ASL008", loop[0], "States %s loop with no transition out of the loop", states)
+		case !waits:
+			v.add("ASL009", loop[0], "States %s loop without a Wait state or a task waiting for a job or callback, so each iteration starts immediately", states)
+		}
+	}
+}
+
+// walk returns the states reached from start through edges
+func walk(start []string, edges map[string][]string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), start...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		queue = append(queue, edges[name]...)
+	}
+	return seen
+}
+
+// loops returns the cycles of the graph as its strongly connected components
+// with more than one state or a transition to itself, each sorted by name
+func loops(nodes []GraphNode, next map[string][]string) [][]string {
+	// Tarjan's algorithm
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var found [][]string
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, to := range next[name] {
+			if _, visited := index[to]; !visited {
+				connect(to)
+				low[name] = min(low[name], low[to])
+			} else if onStack[to] {
+				low[name] = min(low[name], index[to])
+			}
+		}
+		if low[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+		selfLoop := false
+		for _, to := range next[name] {
+			selfLoop = selfLoop || to == name
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			found = append(found, component)
+		}
+	}
+	for _, node := range nodes {
+		if _, visited := index[node.Name]; !visited {
+			connect(node.Name)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i][0] < found[j][0] })
+	return found
+}
@@ -0,0 +1,86 @@
+package stepfunctions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// redriveStatuses are the execution statuses RedriveExecution accepts
+var redriveStatuses = []types.ExecutionStatus{types.ExecutionStatusFailed, types.ExecutionStatusTimedOut, types.ExecutionStatusAborted}
+
+// RedriveCandidate is a failed, timed out or aborted execution and whether
+// Step Functions allows redriving it
+type RedriveCandidate struct {
+	ExecutionArn string
+	Status       string
+	StartTime    time.Time
+	RedriveCount int
+	Redrivable   bool
+	Reason       string `json:",omitempty"` // Why it cannot be redriven
+}
+
+// RedriveCandidates lists the failed, timed out and aborted executions of a
+// Standard state machine started since the given time, newest first per
+// status, with their redrive status from DescribeExecution
+func (f *Fetcher) RedriveCandidates(ctx context.Context, stateMachineArn string, since time.Time) ([]RedriveCandidate, error) {
+	var candidates []RedriveCandidate
+	for _, status := range redriveStatuses {
+		paginator := sfn.NewListExecutionsPaginator(f.sfnClient, &sfn.ListExecutionsInput{
+			StateMachineArn: aws.String(stateMachineArn),
+			StatusFilter:    status,
+			MaxResults:      listExecutionsPageSize,
+		})
+	pages:
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return candidates, fmt.Errorf("failed to list %s executions of %s: %w", status, stateMachineArn, err)
+			}
+			for _, item := range page.Executions {
+				if item.Status != status {
+					continue
+				}
+				// Executions are listed newest first
+				if aws.ToTime(item.StartDate).Before(since) {
+					break pages
+				}
+				desc, err := f.describeExecution(ctx, aws.ToString(item.ExecutionArn))
+				if err != nil {
+					return candidates, err
+				}
+				candidates = append(candidates, RedriveCandidate{
+					ExecutionArn: aws.ToString(desc.ExecutionArn),
+					Status:       string(desc.Status),
+					StartTime:    aws.ToTime(desc.StartDate),
+					RedriveCount: int(aws.ToInt32(desc.RedriveCount)),
+					Redrivable:   desc.RedriveStatus == types.ExecutionRedriveStatusRedrivable,
+					Reason:       aws.ToString(desc.RedriveStatusReason),
+				})
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// RedriveExecution restarts a candidate from its failed states and returns the
+// redrive time. The client token is derived from the execution and its redrive
+// count, so retrying a redrive that may have gone through does not redrive twice.
+func (f *Fetcher) RedriveExecution(ctx context.Context, candidate RedriveCandidate) (time.Time, error) {
+	token := sha256.Sum256([]byte(candidate.ExecutionArn + "#" + strconv.Itoa(candidate.RedriveCount)))
+	out, err := f.sfnClient.RedriveExecution(ctx, &sfn.RedriveExecutionInput{
+		ExecutionArn: aws.String(candidate.ExecutionArn),
+		ClientToken:  aws.String(hex.EncodeToString(token[:])),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to redrive execution %s: %w", candidate.ExecutionArn, err)
+	}
+	return aws.ToTime(out.RedriveDate), nil
+}
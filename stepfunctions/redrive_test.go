@@ -0,0 +1,55 @@
+package stepfunctions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+func TestRedriveCandidates(t *testing.T) {
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	execution := func(name string, status types.ExecutionStatus, started time.Time, redrive types.ExecutionRedriveStatus) *sfn.DescribeExecutionOutput {
+		return &sfn.DescribeExecutionOutput{
+			ExecutionArn:        aws.String("arn:aws:states:us-east-1:" + testAccount + ":execution:orders:" + name),
+			StateMachineArn:     aws.String(ordersArn),
+			Status:              status,
+			StartDate:           aws.Time(started),
+			RedriveStatus:       redrive,
+			RedriveStatusReason: aws.String("Execution is RUNNING and cannot be redriven"),
+		}
+	}
+	// Newest first, like ListExecutions
+	client := &fakeSFN{executions: map[string][]*sfn.DescribeExecutionOutput{ordersArn: {
+		execution("running", types.ExecutionStatusRunning, now.Add(-time.Hour), types.ExecutionRedriveStatusNotRedrivable),
+		execution("failed", types.ExecutionStatusFailed, now.Add(-2*time.Hour), types.ExecutionRedriveStatusRedrivable),
+		execution("old", types.ExecutionStatusFailed, now.Add(-48*time.Hour), types.ExecutionRedriveStatusRedrivable),
+	}}}
+	f := newTestFetcher(client, &fakeLogs{})
+
+	candidates, err := f.RedriveCandidates(context.Background(), ordersArn, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || !candidates[0].Redrivable || candidates[0].Status != "FAILED" {
+		t.Fatalf("got candidates %+v", candidates)
+	}
+
+	if _, err := f.RedriveExecution(context.Background(), candidates[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.RedriveExecution(context.Background(), candidates[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.redriven) != 2 || aws.ToString(client.redriven[0].ClientToken) != aws.ToString(client.redriven[1].ClientToken) {
+		t.Errorf("retried redrives should share a client token: %+v", client.redriven)
+	}
+	candidates[0].RedriveCount++
+	f.RedriveExecution(context.Background(), candidates[0])
+	if aws.ToString(client.redriven[2].ClientToken) == aws.ToString(client.redriven[0].ClientToken) {
+		t.Error("a later redrive reused the client token")
+	}
+}
@@ -0,0 +1,131 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+)
+
+// ParseExportedExecution reads an execution file written by a run (an
+// Execution) or by fetch-execution (an ExecutionDetail). The input is taken
+// from the detail or, failing that, the ExecutionStarted history event, and
+// is empty when neither was saved.
+func ParseExportedExecution(data []byte) (ExecutionDetail, error) {
+	var detail ExecutionDetail
+	if _, err := DocumentVersion(data); err != nil {
+		return ExecutionDetail{}, err
+	}
+	if err := json.Unmarshal(data, &detail); err != nil {
+		return ExecutionDetail{}, fmt.Errorf("failed to parse execution: %w", err)
+	}
+	if detail.Execution.ExecutionArn == "" {
+		if err := json.Unmarshal(data, &detail.Execution); err != nil {
+			return ExecutionDetail{}, fmt.Errorf("failed to parse execution: %w", err)
+		}
+	}
+	if detail.Execution.ExecutionArn == "" {
+		return ExecutionDetail{}, errors.New("failed to parse execution: no ExecutionArn")
+	}
+	if detail.StateMachineARN == "" {
+		smArn, _, err := stateMachineForExecution(detail.Execution.ExecutionArn)
+		if err != nil {
+			return ExecutionDetail{}, err
+		}
+		detail.StateMachineARN = smArn
+	}
+	if detail.Input == "" {
+		for _, event := range detail.Execution.History {
+			if event.Type == "ExecutionStarted" {
+				detail.Input = event.Input
+				break
+			}
+		}
+	}
+	return detail, nil
+}
+
+// ExecutionInput returns the input of an execution from DescribeExecution.
+// Standard executions are kept for 90 days after they close.
+func (f *Fetcher) ExecutionInput(ctx context.Context, executionArn string) (string, error) {
+	desc, err := f.describeExecution(ctx, executionArn)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(desc.Input), nil
+}
+
+// StartExecution starts an execution of the state machine and returns its
+// ARN. An empty name lets Step Functions generate one.
+func (f *Fetcher) StartExecution(ctx context.Context, stateMachineArn, name, input string) (string, error) {
+	in := &sfn.StartExecutionInput{
+		StateMachineArn: aws.String(stateMachineArn),
+		Input:           aws.String(input),
+	}
+	if name != "" {
+		in.Name = aws.String(name)
+	}
+	out, err := f.sfnClient.StartExecution(ctx, in)
+	if err != nil {
+		return "", fmt.Errorf("failed to start execution of %s: %w", stateMachineArn, err)
+	}
+	return aws.ToString(out.ExecutionArn), nil
+}
+
+// MergePatch applies a JSON merge patch (RFC 7386) to a JSON document: patch
+// members replace the document's, null members delete them, and objects are
+// merged recursively. A patch that is not an object replaces the document.
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if len(document) > 0 {
+		if err := unmarshalNumbers(document, &target); err != nil {
+			return nil, fmt.Errorf("failed to parse document: %w", err)
+		}
+	}
+	if err := unmarshalNumbers(patch, &changes); err != nil {
+		return nil, fmt.Errorf("failed to parse merge patch: %w", err)
+	}
+	merged, err := json.Marshal(mergePatch(target, changes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patched document: %w", err)
+	}
+	return merged, nil
+}
+
+// unmarshalNumbers is json.Unmarshal keeping numbers as json.Number, so large
+// integers such as IDs and epoch milliseconds are not rounded to a float64
+func unmarshalNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after the top-level value")
+	}
+	return nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergePatch(object[key], value)
+	}
+	return object
+}
@@ -0,0 +1,66 @@
+package stepfunctions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseExportedExecution(t *testing.T) {
+	// A run's execution file, with the input in its history
+	detail, err := ParseExportedExecution([]byte(`{"ExecutionArn":"arn:aws:states:us-east-1:123456789012:execution:orders:run-1","Status":"FAILED",
+		"History":[{"ID":1,"Type":"ExecutionStarted","Input":"{\"id\":1}"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.StateMachineARN != "arn:aws:states:us-east-1:123456789012:stateMachine:orders" || detail.Input != `{"id":1}` || detail.Execution.Status != "FAILED" {
+		t.Errorf("got %+v", detail)
+	}
+
+	// A fetch-execution file
+	detail, err = ParseExportedExecution([]byte(`{"StateMachineARN":"arn:sm","Input":"{\"id\":2}","Execution":{"ExecutionArn":"arn:exec"}}`))
+	if err != nil || detail.StateMachineARN != "arn:sm" || detail.Input != `{"id":2}` {
+		t.Errorf("got %+v, %v", detail, err)
+	}
+
+	if _, err := ParseExportedExecution([]byte(`{"Status":"FAILED"}`)); err == nil {
+		t.Error("expected an error without an execution ARN")
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct{ document, patch, want string }{
+		{`{"a":1,"b":{"c":2,"d":3}}`, `{"b":{"c":null,"e":4},"f":true}`, `{"a":1,"b":{"d":3,"e":4},"f":true}`},
+		{`{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{`"text"`, `{"a":1}`, `{"a":1}`},
+		{`{"a":1}`, `[1]`, `[1]`},
+		{``, `{"a":1}`, `{"a":1}`},
+		// Large integers keep every digit
+		{`{"id":9007199254740993,"at":1760000000123}`, `{"n":12345678901234567890}`, `{"at":1760000000123,"id":9007199254740993,"n":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.document), []byte(tt.patch))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("MergePatch(%s, %s) = %s, %v, want %s", tt.document, tt.patch, got, err, tt.want)
+		}
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Error("expected an error for an invalid patch")
+	}
+}
+
+func TestStartExecution(t *testing.T) {
+	client := &fakeSFN{}
+	f := newTestFetcher(client, &fakeLogs{})
+	executionArn, err := f.StartExecution(context.Background(), ordersArn, "replay-1", `{"id":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.started) != 1 || aws.ToString(client.started[0].Input) != `{"id":1}` || aws.ToString(client.started[0].Name) != "replay-1" {
+		t.Errorf("started %+v", client.started)
+	}
+	if executionArn == "" {
+		t.Error("got no execution ARN")
+	}
+}
@@ -0,0 +1,115 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+)
+
+// RetryStats summarizes how a task state's Retry rules behave in practice
+type RetryStats struct {
+	StateMachineName string
+	StateName        string
+	Entries          int            // Times the state scheduled work
+	RetriedEntries   int            // Entries that needed at least one retry
+	Retries          int            // Total retry attempts
+	RetryRate        float64        // RetriedEntries / Entries
+	AvgAttempts      float64        // Attempts per retried entry, including the first
+	RecoveredEntries int            // Retried entries that eventually succeeded
+	RecoveryRate     float64        // RecoveredEntries / RetriedEntries
+	RetryErrors      map[string]int // Errors that triggered a retry
+}
+
+type stateEntry struct {
+	state     string
+	attempts  int
+	succeeded bool
+}
+
+// ComputeRetryStats follows each task state entry in the fetched histories,
+// counting scheduling attempts and whether the state eventually exited.
+func ComputeRetryStats(sm StateMachine) []RetryStats {
+	stats := make(map[string]*RetryStats)
+
+	for _, exec := range sm.Executions {
+		if len(exec.History) == 0 {
+			continue
+		}
+
+		byID := indexEvents(exec.History)
+		entryOf := make(map[int64]int64) // event ID -> ID of the StateEntered event it belongs to
+		entries := make(map[int64]*stateEntry)
+		var order []int64
+		for _, event := range exec.History {
+			if isStateEntered(event) {
+				entryOf[event.ID] = event.ID
+				entries[event.ID] = &stateEntry{state: event.StateName}
+				order = append(order, event.ID)
+				continue
+			}
+			entryID, ok := entryOf[event.PreviousEventID]
+			if !ok {
+				continue
+			}
+			entryOf[event.ID] = entryID
+			entry := entries[entryID]
+
+			switch {
+			case strings.HasSuffix(event.Type, "Scheduled"):
+				entry.attempts++
+				if isRetryEvent(event, byID) {
+					s := statsFor(stats, sm.Name, entry.state)
+					s.RetryErrors[byID[event.PreviousEventID].Error]++
+				}
+			case isStateExited(event):
+				entry.succeeded = true
+			}
+		}
+
+		for _, id := range order {
+			entry := entries[id]
+			if entry.attempts == 0 {
+				continue // Not a task-like state
+			}
+			s := statsFor(stats, sm.Name, entry.state)
+			s.Entries++
+			if entry.attempts > 1 {
+				s.RetriedEntries++
+				s.Retries += entry.attempts - 1
+				s.AvgAttempts += float64(entry.attempts)
+				if entry.succeeded {
+					s.RecoveredEntries++
+				}
+			}
+		}
+	}
+
+	var result []RetryStats
+	for _, s := range stats {
+		if s.Entries > 0 {
+			s.RetryRate = float64(s.RetriedEntries) / float64(s.Entries)
+		}
+		if s.RetriedEntries > 0 {
+			s.AvgAttempts /= float64(s.RetriedEntries)
+			s.RecoveryRate = float64(s.RecoveredEntries) / float64(s.RetriedEntries)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Retries != result[j].Retries {
+			return result[i].Retries > result[j].Retries
+		}
+		return result[i].StateName < result[j].StateName
+	})
+	return result
+}
+
+func statsFor(stats map[string]*RetryStats, smName, state string) *RetryStats {
+	if stats[state] == nil {
+		stats[state] = &RetryStats{
+			StateMachineName: smName,
+			StateName:        state,
+			RetryErrors:      make(map[string]int),
+		}
+	}
+	return stats[state]
+}
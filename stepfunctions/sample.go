@@ -0,0 +1,114 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sample selects the executions of a state machine to fetch, stratified by
+// status so rare outcomes such as failures stay represented. The zero Sample
+// fetches every execution.
+type Sample struct {
+	Percent   float64 // Share of the executions of each status kept, (0, 100]
+	PerStatus int     // Latest executions kept of each status
+}
+
+// ParseSample parses the --sample flag: "10%", "latest-per-status" for the
+// latest execution of each status, or "latest-per-status:5"
+func ParseSample(value string) (Sample, error) {
+	switch {
+	case value == "":
+		return Sample{}, nil
+	case strings.HasSuffix(value, "%"):
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return Sample{}, fmt.Errorf("invalid sample %q: the percentage must be in (0, 100]", value)
+		}
+		return Sample{Percent: percent}, nil
+	case value == "latest-per-status":
+		return Sample{PerStatus: 1}, nil
+	case strings.HasPrefix(value, "latest-per-status:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(value, "latest-per-status:"))
+		if err != nil || n < 1 {
+			return Sample{}, fmt.Errorf("invalid sample %q: the count must be a positive integer", value)
+		}
+		return Sample{PerStatus: n}, nil
+	}
+	return Sample{}, fmt.Errorf("invalid sample %q: want a percentage such as 10%% or latest-per-status[:N]", value)
+}
+
+// WithSample makes the fetcher list every execution of a state machine first
+// and describe, and fetch the history of, only the sampled ones
+func WithSample(sample Sample) Option {
+	return func(f *Fetcher) {
+		f.sample = sample
+	}
+}
+
+// SampledCount records how many of a state machine's executions WithSample kept
+type SampledCount struct {
+	StateMachineARN string
+	Kept            int
+	Listed          int
+}
+
+// Sampled returns the executions kept of each sampled state machine, in the
+// order they were fetched
+func (f *Fetcher) Sampled() []SampledCount {
+	return f.sampled
+}
+
+func (s Sample) enabled() bool {
+	return s.Percent > 0 || s.PerStatus > 0
+}
+
+func (s Sample) String() string {
+	if s.PerStatus > 0 {
+		return fmt.Sprintf("latest %d per status", s.PerStatus)
+	}
+	return strconv.FormatFloat(s.Percent, 'f', -1, 64) + "%"
+}
+
+// keep returns the indices of the sampled executions, given their statuses
+// newest first. Percentages keep executions evenly spaced over each status,
+// so the sample is reproducible and covers the whole listed period.
+func (s Sample) keep(statuses []string) []int {
+	if !s.enabled() {
+		indices := make([]int, len(statuses))
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices
+	}
+	byStatus := make(map[string][]int)
+	for i, status := range statuses {
+		byStatus[status] = append(byStatus[status], i)
+	}
+	var indices []int
+	for _, group := range byStatus {
+		n := s.size(len(group))
+		for i := 0; i < n; i++ {
+			if s.PerStatus > 0 {
+				indices = append(indices, group[i])
+			} else {
+				indices = append(indices, group[i*len(group)/n])
+			}
+		}
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// size is the number of executions kept out of count of one status
+func (s Sample) size(count int) int {
+	switch {
+	case s.PerStatus > 0:
+		return min(count, s.PerStatus)
+	case s.Percent > 0:
+		return min(count, int(math.Ceil(float64(count)*s.Percent/100)))
+	}
+	return count
+}
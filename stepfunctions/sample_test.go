@@ -0,0 +1,89 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Sample
+		wantErr bool
+	}{
+		{"", Sample{}, false},
+		{"10%", Sample{Percent: 10}, false},
+		{"0.5%", Sample{Percent: 0.5}, false},
+		{"latest-per-status", Sample{PerStatus: 1}, false},
+		{"latest-per-status:5", Sample{PerStatus: 5}, false},
+		{"0%", Sample{}, true},
+		{"150%", Sample{}, true},
+		{"latest-per-status:0", Sample{}, true},
+		{"10", Sample{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSample(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseSample(%q) = %+v, %v, want %+v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSampleKeep(t *testing.T) {
+	// Newest first: eight successes and two failures
+	statuses := []string{"SUCCEEDED", "FAILED", "SUCCEEDED", "SUCCEEDED", "SUCCEEDED", "SUCCEEDED", "FAILED", "SUCCEEDED", "SUCCEEDED", "SUCCEEDED"}
+	tests := []struct {
+		sample Sample
+		want   []int
+	}{
+		{Sample{}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{Sample{PerStatus: 1}, []int{0, 1}},
+		{Sample{PerStatus: 3}, []int{0, 1, 2, 3, 6}},
+		// Failures stay represented: ceil(10% of 2) of them
+		{Sample{Percent: 10}, []int{0, 1}},
+		{Sample{Percent: 50}, []int{0, 1, 3, 5, 8}},
+	}
+	for _, tt := range tests {
+		if got := tt.sample.keep(statuses); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v keep = %v, want %v", tt.sample, got, tt.want)
+		}
+	}
+}
+
+func TestListStateMachinesSample(t *testing.T) {
+	client := standardFixture(t)
+	executions := client.executions[ordersArn]
+	for i := 0; i < 20; i++ {
+		executions = append(executions, &sfn.DescribeExecutionOutput{
+			ExecutionArn: aws.String(fmt.Sprintf("%ssucceeded-%d", ordersExecution, i)),
+			Status:       types.ExecutionStatusSucceeded,
+			StartDate:    aws.Time(testStart),
+		})
+	}
+	client.executions[ordersArn] = executions
+	f := newTestFetcher(client, &fakeLogs{}, WithSample(Sample{PerStatus: 1}))
+
+	stateMachines, err := f.ListStateMachines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	byStatus := make(map[string]int)
+	for _, exec := range stateMachines[0].Executions {
+		byStatus[exec.Status]++
+	}
+	if want := map[string]int{"SUCCEEDED": 1, "FAILED": 1, "RUNNING": 1}; !reflect.DeepEqual(byStatus, want) {
+		t.Errorf("got %v, want %v", byStatus, want)
+	}
+	if got := client.calls["DescribeExecution"]; got != 3 {
+		t.Errorf("got %d DescribeExecution calls, want 3", got)
+	}
+	if want := []SampledCount{{StateMachineARN: ordersArn, Kept: 3, Listed: len(executions)}}; !reflect.DeepEqual(f.Sampled(), want) {
+		t.Errorf("got sampled %+v, want %+v", f.Sampled(), want)
+	}
+}
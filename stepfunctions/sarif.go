@@ -0,0 +1,156 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// SARIFOptions describes the run recorded in a SARIF log
+type SARIFOptions struct {
+	ToolVersion string
+	// ArtifactURI returns the exported definition file, relative to the SARIF
+	// log, that a finding is reported against; nil or "" reports no file
+	ArtifactURI func(Finding) string
+}
+
+// Minimal SARIF 2.1.0 object model, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleDefaults `json:"defaultConfiguration"`
+}
+
+type sarifRuleDefaults struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevels maps finding severities to SARIF result levels
+var sarifLevels = map[string]string{
+	SeverityHigh:   "error",
+	SeverityMedium: "warning",
+	SeverityLow:    "note",
+}
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log for code scanning tools.
+// Every rule is listed, so results that disappear are reported as fixed.
+func WriteSARIF(w io.Writer, findings []Finding, opts SARIFOptions) error {
+	ids := make([]string, 0, len(Rules))
+	for id := range Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	driver := sarifDriver{Name: "stepfunction-fetcher", Version: opts.ToolVersion}
+	ruleIndex := make(map[string]int)
+	for i, id := range ids {
+		rule := Rules[id]
+		ruleIndex[id] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			Name:                 rule.Name,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifRuleDefaults{Level: sarifLevels[rule.Severity]},
+		})
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	for _, finding := range findings {
+		location := sarifLocation{LogicalLocations: []sarifLogicalLocation{{
+			Name:               finding.StateMachineName,
+			FullyQualifiedName: finding.StateMachineARN,
+			Kind:               "resource",
+		}}}
+		if finding.State != "" {
+			location.LogicalLocations = append(location.LogicalLocations, sarifLogicalLocation{
+				Name:               finding.State,
+				FullyQualifiedName: finding.StateMachineARN + "#" + finding.State,
+				Kind:               "member",
+			})
+		}
+		if opts.ArtifactURI != nil {
+			if uri := opts.ArtifactURI(finding); uri != "" {
+				// State machine wide findings point at the top of the file
+				line := max(finding.Line, 1)
+				location.PhysicalLocation = &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region:           sarifRegion{StartLine: line},
+				}
+			}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    finding.RuleID,
+			RuleIndex: ruleIndex[finding.RuleID],
+			Level:     sarifLevels[finding.Severity],
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,95 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// SchemaVersion is the version of the JSON documents the fetcher writes.
+// Version 1 documents carry no schemaVersion, and list documents such as
+// state_machines.json were bare arrays; version 2 documents are objects that
+// start with "schemaVersion": "2", lists being under a named key. Definitions
+// and other AWS formats (state files, raw responses, templates) are passed
+// through unversioned.
+const SchemaVersion = "2"
+
+// MarshalDocument marshals v as an indented, versioned output document.
+// Objects get schemaVersion as their first key; anything else is wrapped in
+// an object under listKey.
+func MarshalDocument(v interface{}, listKey string) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	version := []byte(`"schemaVersion": ` + strconv.Quote(SchemaVersion))
+	if bytes.HasPrefix(data, []byte("{")) {
+		if bytes.Equal(data, []byte("{}")) {
+			return append(append([]byte("{\n  "), version...), "\n}"...), nil
+		}
+		return append(append([]byte("{\n  "), version...), append([]byte(","), data[1:]...)...), nil
+	}
+	return MarshalDocument(map[string]json.RawMessage{listKey: data}, "")
+}
+
+// DocumentVersion returns the schemaVersion of an output document, "1" for
+// documents written before versioning. Documents newer than SchemaVersion are
+// an error, as their fields may have changed meaning.
+func DocumentVersion(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return "1", nil
+	}
+	var header struct {
+		SchemaVersion string `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", fmt.Errorf("failed to parse document: %w", err)
+	}
+	if header.SchemaVersion == "" {
+		return "1", nil
+	}
+	version, err := strconv.Atoi(header.SchemaVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid schemaVersion %q", header.SchemaVersion)
+	}
+	if current, _ := strconv.Atoi(SchemaVersion); version > current {
+		return "", fmt.Errorf("schemaVersion %s is newer than %s; upgrade stepfunction-fetcher to read it", header.SchemaVersion, SchemaVersion)
+	}
+	return header.SchemaVersion, nil
+}
+
+// ParseStateMachines reads state_machines.json of any schema version
+func ParseStateMachines(data []byte) ([]StateMachine, error) {
+	version, err := DocumentVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	var stateMachines []StateMachine
+	if version == "1" {
+		err = json.Unmarshal(data, &stateMachines)
+	} else {
+		var document struct {
+			StateMachines []StateMachine `json:"stateMachines"`
+		}
+		err = json.Unmarshal(data, &document)
+		stateMachines = document.StateMachines
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state machines: %w", err)
+	}
+	return stateMachines, nil
+}
+
+// ParseExecution reads an execution document of any schema version
+func ParseExecution(data []byte) (Execution, error) {
+	var exec Execution
+	if _, err := DocumentVersion(data); err != nil {
+		return exec, err
+	}
+	if err := json.Unmarshal(data, &exec); err != nil {
+		return exec, fmt.Errorf("failed to parse execution: %w", err)
+	}
+	return exec, nil
+}
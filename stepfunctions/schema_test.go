@@ -0,0 +1,53 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalDocument(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{map[string]int{"a": 1}, "{\n  \"schemaVersion\": \"2\",\n  \"a\": 1\n}"},
+		{struct{}{}, "{\n  \"schemaVersion\": \"2\"\n}"},
+		{[]int{1, 2}, "{\n  \"schemaVersion\": \"2\",\n  \"items\": [\n    1,\n    2\n  ]\n}"},
+	}
+	for _, tt := range tests {
+		got, err := MarshalDocument(tt.v, "items")
+		if err != nil || string(got) != tt.want {
+			t.Errorf("MarshalDocument(%v) = %s, %v, want %s", tt.v, got, err, tt.want)
+		}
+		if !json.Valid(got) {
+			t.Errorf("MarshalDocument(%v) is not valid JSON: %s", tt.v, got)
+		}
+	}
+}
+
+func TestParseStateMachines(t *testing.T) {
+	v2, err := MarshalDocument([]StateMachine{{Name: "orders", Executions: []Execution{{ExecutionArn: "arn:exec", Status: "FAILED"}}}}, "stateMachines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"version 1": []byte(`[{"Name":"orders","Executions":[{"ExecutionArn":"arn:exec","Status":"FAILED","StartTime":"","EndTime":"","Duration":"N/A"}]}]`),
+		"version 2": v2,
+	} {
+		stateMachines, err := ParseStateMachines(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(stateMachines) != 1 || stateMachines[0].Name != "orders" || stateMachines[0].Executions[0].Status != "FAILED" {
+			t.Errorf("%s: got %+v", name, stateMachines)
+		}
+	}
+
+	if _, err := ParseStateMachines([]byte(`{"schemaVersion":"3","stateMachines":[]}`)); err == nil {
+		t.Error("expected an error for a newer schema version")
+	}
+	if _, err := ParseExecution([]byte(`{"schemaVersion":"x"}`)); err == nil {
+		t.Error("expected an error for an invalid schema version")
+	}
+}
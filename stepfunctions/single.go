@@ -0,0 +1,122 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+)
+
+// ExecutionDetail is everything fetched for a single execution, payloads included
+type ExecutionDetail struct {
+	StateMachineARN string
+	Execution       Execution // With History
+	Input           string
+	Output          string
+	Source          string // "api" for Standard, "logs" for Express
+}
+
+// FetchExecution fetches one execution without crawling its state machine:
+// DescribeExecution and the full history for Standard executions, and every
+// CloudWatch Logs event of the execution for Express ones.
+func (f *Fetcher) FetchExecution(ctx context.Context, executionArn string) (ExecutionDetail, error) {
+	stateMachineArn, express, err := stateMachineForExecution(executionArn)
+	if err != nil {
+		return ExecutionDetail{}, err
+	}
+	if express {
+		return f.fetchExpressExecution(ctx, stateMachineArn, executionArn)
+	}
+
+	desc, err := f.describeExecution(ctx, executionArn)
+	if err != nil {
+		return ExecutionDetail{}, err
+	}
+	exec := toExecution(desc)
+	if exec.History, err = f.GetExecutionHistory(ctx, executionArn); err != nil {
+		return ExecutionDetail{}, err
+	}
+	exec.Transitions = countTransitions(exec.History)
+	exec.Captured = Capture{History: true, Payloads: true}
+	attachExecutionConsoleURLs(&exec)
+
+	return ExecutionDetail{
+		StateMachineARN: aws.ToString(desc.StateMachineArn),
+		Execution:       exec,
+		Input:           aws.ToString(desc.Input),
+		Output:          aws.ToString(desc.Output),
+		Source:          "api",
+	}, nil
+}
+
+// stateMachineForExecution derives the state machine ARN from an execution
+// ARN. Express execution ARNs look like
+// arn:aws:states:region:account:express:machine:name:id.
+func stateMachineForExecution(executionArn string) (string, bool, error) {
+	parsed, err := arn.Parse(executionArn)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid execution ARN %s: %w", executionArn, err)
+	}
+	parts := strings.Split(parsed.Resource, ":")
+	if len(parts) < 3 || (parts[0] != "execution" && parts[0] != "express") {
+		return "", false, fmt.Errorf("%s is not an execution ARN", executionArn)
+	}
+	parsed.Resource = "stateMachine:" + parts[1]
+	return parsed.String(), parts[0] == "express", nil
+}
+
+// fetchExpressExecution rebuilds an Express execution from its log events
+func (f *Fetcher) fetchExpressExecution(ctx context.Context, stateMachineArn, executionArn string) (ExecutionDetail, error) {
+	sm, err := f.sfnClient.DescribeStateMachine(ctx, &sfn.DescribeStateMachineInput{StateMachineArn: aws.String(stateMachineArn)})
+	if err != nil {
+		return ExecutionDetail{}, fmt.Errorf("failed to describe state machine %s: %w", stateMachineArn, err)
+	}
+	logging := toLoggingConfiguration(sm.LoggingConfiguration)
+	if len(logging.Destinations) == 0 {
+		return ExecutionDetail{}, &classifiedError{kind: ErrLoggingNotConfigured, err: fmt.Errorf("logging not enabled for Express Workflow %s", aws.ToString(sm.Name))}
+	}
+	group, err := parseLogGroupArn(logging.Destinations[0])
+	if err != nil {
+		return ExecutionDetail{}, err
+	}
+	smArn, _ := arn.Parse(stateMachineArn)
+	group.crossAccount = group.AccountID != smArn.AccountID
+
+	// Without known start and end times, search the whole discovery lookback
+	exec := Execution{
+		ExecutionArn: executionArn,
+		StartTime:    f.clock.Now().Add(-f.expressQuery.Lookback),
+	}
+	history, err := f.getExpressExecutionHistory(ctx, group, exec)
+	if err != nil {
+		return ExecutionDetail{}, err
+	}
+	if len(history) == 0 {
+		return ExecutionDetail{}, fmt.Errorf("no log events found for %s in %s within %v", executionArn, group.Name, f.expressQuery.Lookback)
+	}
+
+	detail := ExecutionDetail{StateMachineARN: stateMachineArn, Source: "logs"}
+	exec = Execution{ExecutionArn: executionArn, Status: "RUNNING", Partial: true, History: history}
+	for _, event := range history {
+		switch {
+		case event.Type == "ExecutionStarted":
+			exec.StartTime, exec.Partial = event.Timestamp, false
+			detail.Input = event.Input
+		case strings.HasPrefix(event.Type, "Execution"):
+			exec.Status = strings.Replace(event.Type, "Execution", "", 1)
+			exec.EndTime = event.Timestamp
+			exec.Error, exec.Cause = event.Error, event.Cause
+			detail.Output = event.Output
+		}
+	}
+	exec.setDuration()
+	exec.InputSize, exec.OutputSize = len(detail.Input), len(detail.Output)
+	exec.Transitions = countTransitions(history)
+	exec.Captured = expressCapture(sm.LoggingConfiguration, exec)
+	attachExecutionConsoleURLs(&exec)
+	detail.Execution = exec
+	return detail, nil
+}
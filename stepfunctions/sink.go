@@ -0,0 +1,61 @@
+package stepfunctions
+
+import (
+	"context"
+)
+
+// Record is one line of the NDJSON stream written to a RecordSink or hook
+type Record struct {
+	RecordType      string        `json:"recordType"` // "stateMachine", "execution" or "historyEvent"
+	StateMachineArn string        `json:"stateMachineArn"`
+	ExecutionArn    string        `json:"executionArn,omitempty"`
+	StateMachine    *StateMachine `json:"stateMachine,omitempty"` // Without Executions; they are separate records
+	Execution       *Execution    `json:"execution,omitempty"`    // Without History; events are separate records
+	Event           *HistoryEvent `json:"event,omitempty"`
+}
+
+// RecordSink receives execution and history records as they are fetched
+type RecordSink interface {
+	Put(ctx context.Context, records []Record) error
+	// Close flushes anything buffered
+	Close(ctx context.Context) error
+}
+
+// WithRecordSink streams every fetched execution, followed by its history
+// events, to sink
+func WithRecordSink(sink RecordSink) Option {
+	return func(f *Fetcher) {
+		f.sinks = append(f.sinks, sink)
+	}
+}
+
+// emit writes one execution and its history to every sink. Sink failures are
+// warnings; the execution is still returned to the caller.
+func (f *Fetcher) emit(ctx context.Context, stateMachineArn string, exec Execution) {
+	if len(f.sinks) == 0 || exec.ExecutionArn == "N/A" {
+		return
+	}
+
+	summary := exec
+	summary.History = nil
+	records := []Record{{
+		RecordType:      "execution",
+		StateMachineArn: stateMachineArn,
+		ExecutionArn:    exec.ExecutionArn,
+		Execution:       &summary,
+	}}
+	for i := range exec.History {
+		records = append(records, Record{
+			RecordType:      "historyEvent",
+			StateMachineArn: stateMachineArn,
+			ExecutionArn:    exec.ExecutionArn,
+			Event:           &exec.History[i],
+		})
+	}
+
+	for _, sink := range f.sinks {
+		if err := sink.Put(ctx, records); err != nil {
+			f.warn(exec.ExecutionArn, "StreamRecords", err)
+		}
+	}
+}
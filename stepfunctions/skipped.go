@@ -0,0 +1,54 @@
+package stepfunctions
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrFailedFast marks the error that ended a WithFailFast run. The failed
+// resource is already in Skipped, so callers pass it on instead of recording
+// it again.
+var ErrFailedFast = errors.New("failing fast")
+
+// SkippedItem records a resource the fetcher could not fetch and moved past
+type SkippedItem struct {
+	Resource string // ARN or log group
+	Kind     string // See ErrorKind
+	Error    string
+}
+
+// WithFailFast makes the fetcher return the first error instead of skipping
+// the failed state machine, execution or log group and continuing.
+func WithFailFast(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.failFast = enabled
+	}
+}
+
+// skip records a skipped resource. It returns err when failing fast so the
+// caller can stop, and nil when the caller should carry on.
+func (f *Fetcher) skip(resource string, err error) error {
+	if errors.Is(err, ErrFailedFast) {
+		return err
+	}
+	f.skipped = append(f.skipped, SkippedItem{Resource: resource, Kind: ErrorKind(err), Error: err.Error()})
+	if f.failFast {
+		return &classifiedError{kind: ErrFailedFast, err: fmt.Errorf("%s: %w", resource, err)}
+	}
+	return nil
+}
+
+// skipOverBudget records the state machines a spent WithMaxAPICalls budget
+// left out, and "ListStateMachines" when the listing itself was cut short, so
+// a partial run shows what is missing. It never fails fast: the budget is a
+// limit the caller set, not an error.
+func (f *Fetcher) skipOverBudget(resources []string, err error) {
+	for _, resource := range resources {
+		f.skipped = append(f.skipped, SkippedItem{Resource: resource, Kind: ErrorKind(err), Error: err.Error()})
+	}
+}
+
+// Skipped returns everything skipped so far because of an error
+func (f *Fetcher) Skipped() []SkippedItem {
+	return f.skipped
+}
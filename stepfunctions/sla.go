@@ -0,0 +1,122 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SLAConfig maps state machine names or ARNs to thresholds. The "*" entry
+// applies to state machines without their own entry.
+type SLAConfig struct {
+	StateMachines map[string]SLAThreshold `json:"stateMachines"`
+}
+
+// SLAThreshold bounds execution duration and failure rate over the fetched window
+type SLAThreshold struct {
+	MaxDuration    string   `json:"maxDuration,omitempty"`    // Go duration, e.g. "5m"
+	MaxFailureRate *float64 `json:"maxFailureRate,omitempty"` // Fraction of completed executions, e.g. 0.05
+}
+
+// SLAViolation is a threshold breached by the executions of a state machine
+type SLAViolation struct {
+	StateMachineName string
+	StateMachineARN  string
+	Check            string // "maxDuration" or "maxFailureRate"
+	Threshold        string
+	Actual           string
+	ExecutionArns    []string // Offending executions
+}
+
+// LoadSLAConfig reads and validates an SLA config file
+func LoadSLAConfig(path string) (SLAConfig, error) {
+	var cfg SLAConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read SLA config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse SLA config: %w", err)
+	}
+
+	for name, threshold := range cfg.StateMachines {
+		if threshold.MaxDuration != "" {
+			if _, err := time.ParseDuration(threshold.MaxDuration); err != nil {
+				return cfg, fmt.Errorf("invalid maxDuration for %s: %w", name, err)
+			}
+		}
+		if rate := threshold.MaxFailureRate; rate != nil && (*rate < 0 || *rate > 1) {
+			return cfg, fmt.Errorf("maxFailureRate for %s must be between 0 and 1", name)
+		}
+	}
+	return cfg, nil
+}
+
+// thresholdFor returns the thresholds for a state machine, by ARN, name, then "*"
+func (c SLAConfig) thresholdFor(sm StateMachine) (SLAThreshold, bool) {
+	for _, key := range []string{sm.ARN, sm.Name, "*"} {
+		if threshold, ok := c.StateMachines[key]; ok {
+			return threshold, true
+		}
+	}
+	return SLAThreshold{}, false
+}
+
+// EvaluateSLA checks the completed executions of each state machine against its thresholds
+func EvaluateSLA(cfg SLAConfig, stateMachines []StateMachine) []SLAViolation {
+	var violations []SLAViolation
+	for _, sm := range stateMachines {
+		threshold, ok := cfg.thresholdFor(sm)
+		if !ok {
+			continue
+		}
+
+		maxDuration, _ := time.ParseDuration(threshold.MaxDuration)
+		var completed, failed int
+		var worst time.Duration
+		var slow, failedArns []string
+		for _, exec := range sm.Executions {
+			if !exec.DurationKnown() {
+				continue // Still running
+			}
+			duration := exec.Duration
+			completed++
+			if IsFailedStatus(exec.Status) || exec.Status == "ABORTED" || exec.Status == "Aborted" {
+				failed++
+				failedArns = append(failedArns, exec.ExecutionArn)
+			}
+			if maxDuration > 0 && duration > maxDuration {
+				slow = append(slow, exec.ExecutionArn)
+				if duration > worst {
+					worst = duration
+				}
+			}
+		}
+
+		if len(slow) > 0 {
+			violations = append(violations, SLAViolation{
+				StateMachineName: sm.Name,
+				StateMachineARN:  sm.ARN,
+				Check:            "maxDuration",
+				Threshold:        maxDuration.String(),
+				Actual:           fmt.Sprintf("%d execution(s) over, worst %v", len(slow), worst),
+				ExecutionArns:    slow,
+			})
+		}
+		if threshold.MaxFailureRate != nil && completed > 0 {
+			rate := float64(failed) / float64(completed)
+			if rate > *threshold.MaxFailureRate {
+				violations = append(violations, SLAViolation{
+					StateMachineName: sm.Name,
+					StateMachineARN:  sm.ARN,
+					Check:            "maxFailureRate",
+					Threshold:        fmt.Sprintf("%.2f%%", *threshold.MaxFailureRate*100),
+					Actual:           fmt.Sprintf("%.2f%% (%d/%d)", rate*100, failed, completed),
+					ExecutionArns:    failedArns,
+				})
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,80 @@
+package stepfunctions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Execution sort keys accepted by ParseExecutionSort
+const (
+	SortByStartTime = "start-time"
+	SortByDuration  = "duration"
+	SortByStatus    = "status"
+)
+
+// ExecutionSort orders the executions of a state machine
+type ExecutionSort struct {
+	Key        string // SortByStartTime, SortByDuration or SortByStatus
+	Descending bool
+}
+
+// ParseExecutionSort reads "key" or "key:asc|desc". Start times and
+// durations default to descending (newest and slowest first), status to
+// ascending.
+func ParseExecutionSort(spec string) (ExecutionSort, error) {
+	key, order, hasOrder := strings.Cut(spec, ":")
+	s := ExecutionSort{Key: key}
+	switch key {
+	case SortByStartTime, SortByDuration:
+		s.Descending = true
+	case SortByStatus:
+	default:
+		return s, fmt.Errorf("unknown sort key %q (supported: %s, %s, %s)", key, SortByStartTime, SortByDuration, SortByStatus)
+	}
+	if hasOrder {
+		switch order {
+		case "asc":
+			s.Descending = false
+		case "desc":
+			s.Descending = true
+		default:
+			return s, fmt.Errorf("unknown sort order %q (supported: asc, desc)", order)
+		}
+	}
+	return s, nil
+}
+
+// Apply sorts executions in place. Executions missing the key (unknown start
+// or duration) go last in either order, and ties keep the newest first.
+func (s ExecutionSort) Apply(executions []Execution) {
+	var known func(Execution) bool
+	var less func(a, b Execution) bool
+	switch s.Key {
+	case SortByStartTime:
+		known = func(e Execution) bool { return !e.StartTime.IsZero() }
+		less = func(a, b Execution) bool { return a.StartTime.Before(b.StartTime) }
+	case SortByDuration:
+		known = Execution.DurationKnown
+		less = func(a, b Execution) bool { return a.Duration < b.Duration }
+	case SortByStatus:
+		known = func(Execution) bool { return true }
+		less = func(a, b Execution) bool { return a.Status < b.Status }
+	default:
+		return
+	}
+
+	sort.SliceStable(executions, func(i, j int) bool {
+		a, b := executions[i], executions[j]
+		if known(a) != known(b) {
+			return known(a)
+		}
+		if !known(a) || (!less(a, b) && !less(b, a)) {
+			return a.StartTime.After(b.StartTime)
+		}
+		if s.Descending {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
@@ -0,0 +1,69 @@
+package stepfunctions
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecutionSort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ExecutionSort
+		wantErr bool
+	}{
+		{spec: "start-time", want: ExecutionSort{Key: SortByStartTime, Descending: true}},
+		{spec: "start-time:asc", want: ExecutionSort{Key: SortByStartTime}},
+		{spec: "duration", want: ExecutionSort{Key: SortByDuration, Descending: true}},
+		{spec: "status", want: ExecutionSort{Key: SortByStatus}},
+		{spec: "status:desc", want: ExecutionSort{Key: SortByStatus, Descending: true}},
+		{spec: "name", wantErr: true},
+		{spec: "status:up", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExecutionSort(tt.spec)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ParseExecutionSort(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestExecutionSortApply(t *testing.T) {
+	at := func(minutes int) time.Time { return testStart.Add(time.Duration(minutes) * time.Minute) }
+	executions := func() []Execution {
+		return []Execution{
+			{ExecutionArn: "a", Status: "SUCCEEDED", StartTime: at(1), EndTime: at(2), Duration: time.Minute},
+			{ExecutionArn: "b", Status: "FAILED", StartTime: at(3), EndTime: at(8), Duration: 5 * time.Minute},
+			{ExecutionArn: "c", Status: "Failed", EndTime: at(4), Partial: true},
+			{ExecutionArn: "d", Status: "RUNNING", StartTime: at(5)},
+			{ExecutionArn: "e", Status: "FAILED", StartTime: at(6), EndTime: at(7), Duration: time.Minute},
+		}
+	}
+	// Executions without the key go last: c has no start, c and d no duration
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"start-time", "edbac"},
+		{"start-time:asc", "abdec"},
+		{"duration", "beadc"},
+		{"duration:asc", "eabdc"},
+		{"status", "ebcda"}, // Ties newest first
+		{"status:desc", "adceb"},
+	}
+	for _, tt := range tests {
+		s, err := ParseExecutionSort(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted := executions()
+		s.Apply(sorted)
+		var got strings.Builder
+		for _, exec := range sorted {
+			got.WriteString(exec.ExecutionArn)
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.spec, got.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package stepfunctions
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// RunningFilter selects running executions, e.g. the ones to stop
+type RunningFilter struct {
+	StartedBefore time.Time // Zero matches every start time
+	NamePattern   string    // path.Match pattern on the execution name, empty matches every name
+}
+
+// RunningExecutions lists the RUNNING executions of a Standard state machine
+// that match the filter. It uses only ListExecutions, so the executions have
+// no payload sizes.
+func (f *Fetcher) RunningExecutions(ctx context.Context, stateMachineArn string, filter RunningFilter) ([]Execution, error) {
+	if filter.NamePattern != "" {
+		if _, err := path.Match(filter.NamePattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", filter.NamePattern, err)
+		}
+	}
+	var executions []Execution
+	paginator := sfn.NewListExecutionsPaginator(f.sfnClient, &sfn.ListExecutionsInput{
+		StateMachineArn: aws.String(stateMachineArn),
+		StatusFilter:    types.ExecutionStatusRunning,
+		MaxResults:      listExecutionsPageSize,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return executions, fmt.Errorf("failed to list running executions of %s: %w", stateMachineArn, err)
+		}
+		for _, item := range page.Executions {
+			if item.Status != types.ExecutionStatusRunning {
+				continue
+			}
+			executionArn := aws.ToString(item.ExecutionArn)
+			name := aws.ToString(item.Name)
+			if name == "" {
+				name = executionArn[strings.LastIndex(executionArn, ":")+1:]
+			}
+			started := aws.ToTime(item.StartDate)
+			if !filter.StartedBefore.IsZero() && !started.Before(filter.StartedBefore) {
+				continue
+			}
+			if filter.NamePattern != "" {
+				if ok, _ := path.Match(filter.NamePattern, name); !ok {
+					continue
+				}
+			}
+			executions = append(executions, Execution{ExecutionArn: executionArn, Status: string(item.Status), StartTime: started})
+		}
+	}
+	return executions, nil
+}
+
+// StopExecution stops a running Standard execution with an error code and cause
+func (f *Fetcher) StopExecution(ctx context.Context, executionArn, errorCode, cause string) error {
+	_, err := f.sfnClient.StopExecution(ctx, &sfn.StopExecutionInput{
+		ExecutionArn: aws.String(executionArn),
+		Error:        aws.String(errorCode),
+		Cause:        aws.String(cause),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stop execution %s: %w", executionArn, err)
+	}
+	return nil
+}
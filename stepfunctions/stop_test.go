@@ -0,0 +1,52 @@
+package stepfunctions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+func TestRunningExecutions(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	execution := func(name string, status types.ExecutionStatus, started time.Time) *sfn.DescribeExecutionOutput {
+		return &sfn.DescribeExecutionOutput{
+			ExecutionArn: aws.String("arn:aws:states:us-east-1:" + testAccount + ":execution:orders:" + name),
+			Name:         aws.String(name),
+			Status:       status,
+			StartDate:    aws.Time(started),
+		}
+	}
+	client := &fakeSFN{executions: map[string][]*sfn.DescribeExecutionOutput{ordersArn: {
+		execution("import-1", types.ExecutionStatusRunning, start),
+		execution("import-2", types.ExecutionStatusRunning, start.Add(2*time.Hour)),
+		execution("import-3", types.ExecutionStatusSucceeded, start),
+		execution("manual-1", types.ExecutionStatusRunning, start),
+	}}}
+	f := newTestFetcher(client, &fakeLogs{})
+
+	executions, err := f.RunningExecutions(context.Background(), ordersArn, RunningFilter{
+		StartedBefore: start.Add(time.Hour),
+		NamePattern:   "import-*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(executions) != 1 || executions[0].ExecutionArn != aws.ToString(client.executions[ordersArn][0].ExecutionArn) {
+		t.Fatalf("got executions %+v", executions)
+	}
+
+	if err := f.StopExecution(context.Background(), executions[0].ExecutionArn, "OperatorStopped", "incident"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.stopped) != 1 || aws.ToString(client.stopped[0].Cause) != "incident" {
+		t.Errorf("stopped %+v", client.stopped)
+	}
+
+	if _, err := f.RunningExecutions(context.Background(), ordersArn, RunningFilter{NamePattern: "["}); err == nil {
+		t.Error("expected an error for an invalid name pattern")
+	}
+}
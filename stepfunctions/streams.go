@@ -0,0 +1,167 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	fhtypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kintypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// Batch limits shared by PutRecords and PutRecordBatch (Firehose allows 4 MiB
+// per batch, Kinesis 5 MiB; the lower one is used for both)
+const (
+	streamBatchRecords = 500
+	streamBatchBytes   = 4 * 1024 * 1024
+	streamRecordBytes  = 1000 * 1024
+	streamPutAttempts  = 3
+)
+
+// streamEntry is one encoded NDJSON line and the key it is partitioned by
+type streamEntry struct {
+	key  string
+	data []byte
+}
+
+// DeadLetterFunc receives the encoded records a sink gave up on, and why
+type DeadLetterFunc func(records []json.RawMessage, err error)
+
+// streamBatcher buffers encoded records and hands full batches to put, which
+// returns the entries that failed and should be retried
+type streamBatcher struct {
+	entries    []streamEntry
+	size       int
+	put        func(ctx context.Context, entries []streamEntry) ([]streamEntry, error)
+	deadLetter DeadLetterFunc // Optional
+}
+
+// Put buffers records, sending full batches. Records over the stream record
+// limit are dead-lettered and reported in the returned error once the rest are
+// buffered.
+func (b *streamBatcher) Put(ctx context.Context, records []Record) error {
+	var dropped []string
+	var oversized []json.RawMessage
+	var errs []error
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal record: %w", err)
+		}
+		data = append(data, '\n')
+		if len(data) > streamRecordBytes {
+			dropped = append(dropped, fmt.Sprintf("%s (%d bytes)", record.RecordType, len(data)))
+			oversized = append(oversized, data[:len(data)-1])
+			continue
+		}
+
+		if len(b.entries) == streamBatchRecords || b.size+len(data) > streamBatchBytes {
+			// A failed batch is dead-lettered; the rest are still buffered
+			errs = append(errs, b.flush(ctx))
+		}
+		b.entries = append(b.entries, streamEntry{key: record.ExecutionArn, data: data})
+		b.size += len(data)
+	}
+	if len(dropped) > 0 {
+		err := fmt.Errorf("dropped records over the %d-byte stream record limit: %s", streamRecordBytes, strings.Join(dropped, ", "))
+		b.dropped(oversized, err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (b *streamBatcher) Close(ctx context.Context) error {
+	return b.flush(ctx)
+}
+
+func (b *streamBatcher) flush(ctx context.Context) error {
+	pending := b.entries
+	b.entries, b.size = nil, 0
+	for attempt := 0; attempt < streamPutAttempts && len(pending) > 0; attempt++ {
+		failed, err := b.put(ctx, pending)
+		if err != nil {
+			b.droppedEntries(pending, err)
+			return err
+		}
+		pending = failed
+	}
+	if len(pending) > 0 {
+		err := fmt.Errorf("%d records were rejected after %d attempts", len(pending), streamPutAttempts)
+		b.droppedEntries(pending, err)
+		return err
+	}
+	return nil
+}
+
+func (b *streamBatcher) droppedEntries(entries []streamEntry, err error) {
+	records := make([]json.RawMessage, len(entries))
+	for i, entry := range entries {
+		records[i] = bytes.TrimSuffix(entry.data, []byte("\n"))
+	}
+	b.dropped(records, err)
+}
+
+func (b *streamBatcher) dropped(records []json.RawMessage, err error) {
+	if b.deadLetter != nil && len(records) > 0 {
+		b.deadLetter(records, err)
+	}
+}
+
+// NewKinesisSink writes records to a Kinesis data stream, partitioned by
+// execution ARN so each execution's records stay in order. Records it cannot
+// write are passed to deadLetter, when not nil.
+func NewKinesisSink(client *kinesis.Client, stream string, deadLetter DeadLetterFunc) RecordSink {
+	return &streamBatcher{deadLetter: deadLetter, put: func(ctx context.Context, entries []streamEntry) ([]streamEntry, error) {
+		input := &kinesis.PutRecordsInput{StreamName: aws.String(stream)}
+		if strings.HasPrefix(stream, "arn:") {
+			input.StreamName, input.StreamARN = nil, aws.String(stream)
+		}
+		for _, entry := range entries {
+			input.Records = append(input.Records, kintypes.PutRecordsRequestEntry{
+				Data:         entry.data,
+				PartitionKey: aws.String(entry.key),
+			})
+		}
+
+		out, err := client.PutRecords(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put records to Kinesis stream %s: %w", stream, err)
+		}
+		var failed []streamEntry
+		for i, result := range out.Records {
+			if result.ErrorCode != nil {
+				failed = append(failed, entries[i])
+			}
+		}
+		return failed, nil
+	}}
+}
+
+// NewFirehoseSink writes records to a Firehose delivery stream. Records it
+// cannot write are passed to deadLetter, when not nil.
+func NewFirehoseSink(client *firehose.Client, stream string, deadLetter DeadLetterFunc) RecordSink {
+	return &streamBatcher{deadLetter: deadLetter, put: func(ctx context.Context, entries []streamEntry) ([]streamEntry, error) {
+		input := &firehose.PutRecordBatchInput{DeliveryStreamName: aws.String(stream)}
+		for _, entry := range entries {
+			input.Records = append(input.Records, fhtypes.Record{Data: entry.data})
+		}
+
+		out, err := client.PutRecordBatch(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put records to Firehose stream %s: %w", stream, err)
+		}
+		var failed []streamEntry
+		for i, result := range out.RequestResponses {
+			if result.ErrorCode != nil {
+				failed = append(failed, entries[i])
+			}
+		}
+		return failed, nil
+	}}
+}
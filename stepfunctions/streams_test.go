@@ -0,0 +1,63 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamBatcherDeadLetters(t *testing.T) {
+	var dead []json.RawMessage
+	attempts := 0
+	b := &streamBatcher{
+		put: func(_ context.Context, entries []streamEntry) ([]streamEntry, error) {
+			attempts++
+			var rejected []streamEntry // Only execution a is accepted
+			for _, entry := range entries {
+				if entry.key != "a" {
+					rejected = append(rejected, entry)
+				}
+			}
+			return rejected, nil
+		},
+		deadLetter: func(records []json.RawMessage, err error) { dead = append(dead, records...) },
+	}
+	ctx := context.Background()
+	records := []Record{
+		{RecordType: "execution", ExecutionArn: "a"},
+		{RecordType: "execution", ExecutionArn: "b"},
+		{RecordType: "historyEvent", ExecutionArn: "c", Event: &HistoryEvent{Cause: strings.Repeat("x", streamRecordBytes)}},
+	}
+	if err := b.Put(ctx, records); err == nil {
+		t.Fatal("expected the oversized record to be reported")
+	}
+	if err := b.Close(ctx); err == nil {
+		t.Fatal("expected rejected records to be reported")
+	}
+	if attempts != streamPutAttempts || len(dead) != 2 {
+		t.Fatalf("got %d attempts and %d dead-lettered records, want %d and 2", attempts, len(dead), streamPutAttempts)
+	}
+	var first Record
+	if err := json.Unmarshal(dead[0], &first); err != nil || first.RecordType != "historyEvent" {
+		t.Errorf("got first dead-lettered record %s (%v), want the oversized history event", dead[0], err)
+	}
+	var second Record
+	if err := json.Unmarshal(dead[1], &second); err != nil || second.ExecutionArn != "b" {
+		t.Errorf("got second dead-lettered record %s (%v), want the rejected execution b", dead[1], err)
+	}
+}
+
+func TestStreamBatcherDeadLettersOnError(t *testing.T) {
+	var dead []json.RawMessage
+	b := &streamBatcher{
+		put:        func(context.Context, []streamEntry) ([]streamEntry, error) { return nil, errors.New("stream deleted") },
+		deadLetter: func(records []json.RawMessage, err error) { dead = append(dead, records...) },
+	}
+	ctx := context.Background()
+	b.Put(ctx, []Record{{RecordType: "execution", ExecutionArn: "a"}})
+	if err := b.Close(ctx); err == nil || len(dead) != 1 {
+		t.Errorf("got error %v and %d dead-lettered records, want an error and 1", err, len(dead))
+	}
+}
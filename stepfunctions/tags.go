@@ -0,0 +1,31 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+)
+
+// AttachTags looks up the tags of every state machine. State machines whose
+// tags cannot be read are left untagged with a warning.
+func (f *Fetcher) AttachTags(ctx context.Context, stateMachines []StateMachine) error {
+	for i := range stateMachines {
+		sm := &stateMachines[i]
+		result, err := f.sfnClient.ListTagsForResource(ctx, &sfn.ListTagsForResourceInput{ResourceArn: aws.String(sm.ARN)})
+		if errors.Is(err, ErrAPIBudgetExceeded) {
+			return err
+		}
+		if err != nil {
+			f.warn(sm.ARN, "ListTagsForResource", fmt.Errorf("failed to list tags: %w", err))
+			continue
+		}
+		sm.Tags = make(map[string]string, len(result.Tags))
+		for _, tag := range result.Tags {
+			sm.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return nil
+}
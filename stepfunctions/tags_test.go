@@ -0,0 +1,27 @@
+package stepfunctions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+func TestAttachTags(t *testing.T) {
+	client := &fakeSFN{tags: map[string][]types.Tag{
+		ordersArn: {{Key: aws.String("team"), Value: aws.String("payments")}},
+	}}
+	f := newTestFetcher(client, &fakeLogs{})
+	stateMachines := []StateMachine{{Name: "orders", ARN: ordersArn}, {Name: "untagged", ARN: ordersArn + "-2"}}
+
+	if err := f.AttachTags(context.Background(), stateMachines); err != nil {
+		t.Fatal(err)
+	}
+	if got := stateMachines[0].Tags["team"]; got != "payments" {
+		t.Errorf("got team tag %q, want payments", got)
+	}
+	if stateMachines[1].Tags == nil || len(stateMachines[1].Tags) != 0 {
+		t.Errorf("got tags %v for an untagged state machine, want none", stateMachines[1].Tags)
+	}
+}
@@ -0,0 +1,77 @@
+package stepfunctions
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies the fetcher's spans and metrics
+const InstrumentationName = "stepfunction-fetcher"
+
+// telemetry records a client span and metrics for every AWS operation through
+// the global OpenTelemetry providers. Until the application installs
+// providers (otel.SetTracerProvider, otel.SetMeterProvider) it is a no-op.
+type telemetry struct {
+	tracer   trace.Tracer
+	calls    metric.Int64Counter
+	errors   metric.Int64Counter
+	pages    metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+func newTelemetry() *telemetry {
+	meter := otel.Meter(InstrumentationName)
+	t := &telemetry{tracer: otel.Tracer(InstrumentationName)}
+	// Instrument errors only occur for invalid names; the no-op instruments
+	// returned alongside keep the fetcher working
+	t.calls, _ = meter.Int64Counter("fetcher.api.calls", metric.WithDescription("AWS API operations, including those refused by the call budget"))
+	t.errors, _ = meter.Int64Counter("fetcher.api.errors", metric.WithDescription("AWS API operations that failed after retries"))
+	t.pages, _ = meter.Int64Counter("fetcher.api.pages", metric.WithDescription("Pages fetched from paginated AWS APIs"))
+	t.duration, _ = meter.Float64Histogram("fetcher.api.duration", metric.WithUnit("s"), metric.WithDescription("AWS API operation latency, including retries"))
+	return t
+}
+
+// middleware wraps each operation, outside the call budget and error
+// classification, so refused and classified failures are both recorded
+func (t *telemetry) middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FetcherTelemetry",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			service, operation := middleware.GetServiceID(ctx), middleware.GetOperationName(ctx)
+			attrs := []attribute.KeyValue{
+				attribute.String("rpc.system", "aws-api"),
+				attribute.String("rpc.service", service),
+				attribute.String("rpc.method", operation),
+			}
+
+			ctx, span := t.tracer.Start(ctx, service+"."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				attrs = append(attrs, attribute.String("error.kind", ErrorKind(err)))
+				t.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+			} else if isPaginated(operation) {
+				t.pages.Add(ctx, 1, metric.WithAttributes(attrs...))
+			}
+			t.calls.Add(ctx, 1, metric.WithAttributes(attrs...))
+			t.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+			return out, metadata, err
+		}), middleware.Before)
+}
+
+// isPaginated matches the operations the fetcher pages through
+func isPaginated(operation string) bool {
+	return strings.HasPrefix(operation, "List") || operation == "GetExecutionHistory" ||
+		operation == "FilterLogEvents" || operation == "LookupEvents"
+}
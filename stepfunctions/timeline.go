@@ -0,0 +1,94 @@
+package stepfunctions
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimelineSpan is the time one state spent between entry and exit
+type TimelineSpan struct {
+	StateName string
+	StateType string // Task, Wait, Parallel, Map, ...
+	Start     time.Time
+	End       time.Time
+	Lane      int    // Concurrent spans (parallel branches, map iterations) get separate lanes
+	Status    string // SUCCEEDED, FAILED or RUNNING
+}
+
+// Duration returns how long the state was active
+func (s TimelineSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// BuildTimeline pairs state entry and exit events from an execution history
+// into spans and assigns overlapping spans to separate lanes. States that never
+// exited end at the last event and are marked FAILED, or RUNNING when the
+// execution has not finished.
+func BuildTimeline(history []HistoryEvent) []TimelineSpan {
+	if len(history) == 0 {
+		return nil
+	}
+
+	byID := indexEvents(history)
+	last := history[len(history)-1]
+	finished := strings.HasPrefix(last.Type, "Execution") && last.Type != "ExecutionStarted"
+
+	exited := make(map[int64]HistoryEvent) // StateEntered ID -> StateExited event
+	for _, event := range history {
+		if !isStateExited(event) {
+			continue
+		}
+		for prev, ok := byID[event.PreviousEventID]; ok; prev, ok = byID[prev.PreviousEventID] {
+			if isStateEntered(prev) && prev.StateName == event.StateName {
+				exited[prev.ID] = event
+				break
+			}
+		}
+	}
+
+	var spans []TimelineSpan
+	for _, event := range history {
+		if !isStateEntered(event) {
+			continue
+		}
+		span := TimelineSpan{
+			StateName: event.StateName,
+			StateType: strings.TrimSuffix(event.Type, "StateEntered"),
+			Start:     event.Timestamp,
+		}
+		if exit, ok := exited[event.ID]; ok {
+			span.End, span.Status = exit.Timestamp, "SUCCEEDED"
+		} else if finished {
+			span.End, span.Status = last.Timestamp, "FAILED"
+		} else {
+			span.End, span.Status = last.Timestamp, "RUNNING"
+		}
+		spans = append(spans, span)
+	}
+
+	assignLanes(spans)
+	return spans
+}
+
+// assignLanes places each span in the first lane that is free at its start time
+func assignLanes(spans []TimelineSpan) {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+
+	var laneEnds []time.Time
+	for i := range spans {
+		lane := -1
+		for l, end := range laneEnds {
+			if !spans[i].Start.Before(end) {
+				lane = l
+				break
+			}
+		}
+		if lane == -1 {
+			lane = len(laneEnds)
+			laneEnds = append(laneEnds, time.Time{})
+		}
+		spans[i].Lane = lane
+		laneEnds[lane] = spans[i].End
+	}
+}
@@ -0,0 +1,56 @@
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// WithAPITimeout bounds each AWS operation, retries included, so one hung
+// call (a slow FilterLogEvents page, say) fails with ErrAPITimeout and is
+// skipped like any other failure instead of stalling the fetch. 0 leaves
+// operations unbounded.
+func WithAPITimeout(timeout time.Duration) Option {
+	return func(f *Fetcher) {
+		f.apiTimeout = timeout
+	}
+}
+
+// timeoutMiddleware gives every operation its own deadline
+func (f *Fetcher) timeoutMiddleware(stack *middleware.Stack) error {
+	if f.apiTimeout <= 0 {
+		return nil
+	}
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("APITimeout",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			callCtx, cancel := context.WithTimeout(ctx, f.apiTimeout)
+			out, metadata, err := next.HandleInitialize(callCtx, in)
+			if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+				err = &classifiedError{kind: ErrAPITimeout, err: fmt.Errorf("timed out after %v: %w", f.apiTimeout, err)}
+			}
+			// A streamed body is read after the operation returns, under
+			// the same deadline; the context ends when the body is closed
+			if result, ok := out.Result.(*s3.GetObjectOutput); ok && err == nil && result.Body != nil {
+				result.Body = &cancelOnClose{ReadCloser: result.Body, cancel: cancel}
+			} else {
+				cancel()
+			}
+			return out, metadata, err
+		}), middleware.Before)
+}
+
+// cancelOnClose ends an operation's context when its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,89 @@
+package stepfunctions
+
+import "sort"
+
+// loopHeavyFactor flags workflows whose executions enter states this many
+// times more often than the number of distinct states they visit
+const loopHeavyFactor = 3.0
+
+// TransitionStats breaks down the billed state transitions of a state machine
+type TransitionStats struct {
+	StateMachineName   string
+	StateMachineARN    string
+	Executions         int // Executions with history
+	TotalTransitions   int
+	AvgTransitions     float64
+	MaxTransitions     int
+	MaxTransitionsArn  string
+	Retries            int
+	EstimatedCost      float64        // Standard pricing for the counted transitions
+	TransitionsByState map[string]int // State entries per state name
+	LoopFactor         float64        // Avg state entries per distinct state visited
+	LoopHeavy          bool
+}
+
+// ComputeTransitionStats aggregates transition counts over the executions of a
+// state machine whose history was fetched. It returns false when none was.
+func ComputeTransitionStats(sm StateMachine) (TransitionStats, bool) {
+	stats := TransitionStats{
+		StateMachineName:   sm.Name,
+		StateMachineARN:    sm.ARN,
+		TransitionsByState: make(map[string]int),
+	}
+
+	var loopFactors float64
+	for _, exec := range sm.Executions {
+		if len(exec.History) == 0 {
+			continue
+		}
+		stats.Executions++
+		stats.TotalTransitions += exec.Transitions
+		if exec.Transitions > stats.MaxTransitions {
+			stats.MaxTransitions = exec.Transitions
+			stats.MaxTransitionsArn = exec.ExecutionArn
+		}
+
+		byID := indexEvents(exec.History)
+		visited := make(map[string]bool)
+		entries := 0
+		for _, event := range exec.History {
+			if isStateEntered(event) {
+				stats.TransitionsByState[event.StateName]++
+				visited[event.StateName] = true
+				entries++
+			} else if isRetryEvent(event, byID) {
+				stats.Retries++
+			}
+		}
+		if len(visited) > 0 {
+			loopFactors += float64(entries) / float64(len(visited))
+		}
+	}
+	if stats.Executions == 0 {
+		return stats, false
+	}
+
+	stats.AvgTransitions = float64(stats.TotalTransitions) / float64(stats.Executions)
+	stats.EstimatedCost = float64(stats.TotalTransitions) * standardPricePerTransition
+	stats.LoopFactor = loopFactors / float64(stats.Executions)
+	stats.LoopHeavy = stats.LoopFactor >= loopHeavyFactor
+	return stats, true
+}
+
+// TopStates returns the n state names with the most entries, busiest first
+func (s TransitionStats) TopStates(n int) []string {
+	var names []string
+	for name := range s.TransitionsByState {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if s.TransitionsByState[names[i]] != s.TransitionsByState[names[j]] {
+			return s.TransitionsByState[names[i]] > s.TransitionsByState[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
@@ -0,0 +1,156 @@
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+// ExecutionTree is an execution and, recursively, the child executions it
+// started: the child workflows of its Distributed Map runs and the
+// executions started by its states:startExecution tasks
+type ExecutionTree struct {
+	ExecutionDetail
+	ParentArn         string          `json:",omitempty"`
+	ParentState       string          `json:",omitempty"` // State of the parent that started the execution
+	MapRunArn         string          `json:",omitempty"` // Set for the child workflows of a Map Run
+	Children          []ExecutionTree `json:",omitempty"`
+	ChildrenTruncated bool            `json:",omitempty"` // Some Map Run children were left out, see TreeOptions.MaxChildren
+}
+
+// TreeOptions bound how much of an execution tree is fetched
+type TreeOptions struct {
+	MaxDepth    int // Levels of children below the root; 0 fetches the root only
+	MaxChildren int // Child workflows fetched per Map Run
+}
+
+// childRef is a child execution, or a Map Run of child executions, found in
+// a parent's history
+type childRef struct {
+	executionArn string
+	mapRunArn    string
+	state        string
+}
+
+// FetchExecutionTree fetches an execution like FetchExecution and follows
+// its child executions down to opts.MaxDepth. A child that cannot be fetched
+// is a warning and is left out of the tree.
+func (f *Fetcher) FetchExecutionTree(ctx context.Context, executionArn string, opts TreeOptions) (ExecutionTree, error) {
+	detail, err := f.FetchExecution(ctx, executionArn)
+	if err != nil {
+		return ExecutionTree{}, err
+	}
+	tree := ExecutionTree{ExecutionDetail: detail}
+	f.fetchChildren(ctx, &tree, opts, opts.MaxDepth)
+	return tree, nil
+}
+
+func (f *Fetcher) fetchChildren(ctx context.Context, parent *ExecutionTree, opts TreeOptions, depth int) {
+	if depth <= 0 {
+		return
+	}
+	var refs []childRef
+	for _, ref := range childExecutions(parent.Execution.History) {
+		if ref.mapRunArn == "" {
+			refs = append(refs, ref)
+			continue
+		}
+		arns, truncated := f.mapRunChildren(ctx, ref.mapRunArn, opts.MaxChildren)
+		parent.ChildrenTruncated = parent.ChildrenTruncated || truncated
+		for _, arn := range arns {
+			refs = append(refs, childRef{executionArn: arn, mapRunArn: ref.mapRunArn, state: ref.state})
+		}
+	}
+
+	for _, ref := range refs {
+		detail, err := f.FetchExecution(ctx, ref.executionArn)
+		if err != nil {
+			f.warn(ref.executionArn, "FetchExecution", err)
+			continue
+		}
+		child := ExecutionTree{
+			ExecutionDetail: detail,
+			ParentArn:       parent.Execution.ExecutionArn,
+			ParentState:     ref.state,
+			MapRunArn:       ref.mapRunArn,
+		}
+		f.fetchChildren(ctx, &child, opts, depth-1)
+		parent.Children = append(parent.Children, child)
+	}
+}
+
+// childExecutions finds the Map Runs and the states:startExecution children
+// in a history, in the order they were started
+func childExecutions(history []HistoryEvent) []childRef {
+	var refs []childRef
+	seen := make(map[string]bool)
+	for _, event := range history {
+		var ref childRef
+		switch {
+		case event.Type == "MapRunStarted" && event.MapRunArn != "":
+			ref = childRef{mapRunArn: event.MapRunArn, state: event.StateName}
+		case event.ResourceType == "states" && strings.HasPrefix(event.Resource, "startExecution") && event.Output != "":
+			// The output of the task, or of its submission for .sync
+			// integrations, names the child
+			var output struct{ ExecutionArn string }
+			if json.Unmarshal([]byte(event.Output), &output) != nil || output.ExecutionArn == "" {
+				continue
+			}
+			ref = childRef{executionArn: output.ExecutionArn, state: event.StateName}
+		default:
+			continue
+		}
+		key := ref.executionArn + ref.mapRunArn
+		if !seen[key] {
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// mapRunChildStatuses lists unsuccessful child workflows first, so the ones
+// worth looking at survive MaxChildren
+var mapRunChildStatuses = []types.ExecutionStatus{
+	types.ExecutionStatusFailed,
+	types.ExecutionStatusTimedOut,
+	types.ExecutionStatusAborted,
+	"", // Any status
+}
+
+// mapRunChildren lists up to max child executions of a Map Run and reports
+// whether there were more
+func (f *Fetcher) mapRunChildren(ctx context.Context, mapRunArn string, max int) ([]string, bool) {
+	var arns []string
+	seen := make(map[string]bool)
+	for _, status := range mapRunChildStatuses {
+		input := &sfn.ListExecutionsInput{MapRunArn: aws.String(mapRunArn)}
+		if status != "" {
+			input.StatusFilter = status
+		}
+		paginator := sfn.NewListExecutionsPaginator(f.sfnClient, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				f.warn(mapRunArn, "ListExecutions", err)
+				return arns, false
+			}
+			for _, item := range page.Executions {
+				arn := aws.ToString(item.ExecutionArn)
+				if seen[arn] {
+					continue
+				}
+				if len(arns) == max {
+					return arns, true
+				}
+				seen[arn] = true
+				arns = append(arns, arn)
+			}
+		}
+	}
+	return arns, false
+}
@@ -0,0 +1,82 @@
+package stepfunctions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
+)
+
+func TestFetchExecutionTree(t *testing.T) {
+	const (
+		parentArn  = "arn:aws:states:us-east-1:123456789012:execution:orders:run-1"
+		childArn   = "arn:aws:states:us-east-1:123456789012:execution:checkout:child-1"
+		grandArn   = "arn:aws:states:us-east-1:123456789012:execution:checkout:grandchild-1"
+		mapRunArn  = "arn:aws:states:us-east-1:123456789012:mapRun:orders/Items:run-a"
+		itemOKArn  = "arn:aws:states:us-east-1:123456789012:execution:orders/Items:item-ok"
+		itemBadArn = "arn:aws:states:us-east-1:123456789012:execution:orders/Items:item-bad"
+	)
+	execution := func(arn string, status types.ExecutionStatus) *sfn.DescribeExecutionOutput {
+		return &sfn.DescribeExecutionOutput{ExecutionArn: aws.String(arn), StateMachineArn: aws.String(ordersArn), Status: status}
+	}
+	startsChild := func(state, child string) []types.HistoryEvent {
+		return []types.HistoryEvent{
+			{Id: 1, Type: types.HistoryEventTypeExecutionStarted, ExecutionStartedEventDetails: &types.ExecutionStartedEventDetails{}},
+			{Id: 2, PreviousEventId: 1, Type: types.HistoryEventTypeTaskStateEntered, StateEnteredEventDetails: &types.StateEnteredEventDetails{Name: aws.String(state)}},
+			{Id: 3, PreviousEventId: 2, Type: types.HistoryEventTypeTaskSubmitted, TaskSubmittedEventDetails: &types.TaskSubmittedEventDetails{
+				Resource: aws.String("startExecution.sync:2"), ResourceType: aws.String("states"), Output: aws.String(`{"ExecutionArn":"` + child + `"}`),
+			}},
+			{Id: 4, PreviousEventId: 3, Type: types.HistoryEventTypeTaskSucceeded, TaskSucceededEventDetails: &types.TaskSucceededEventDetails{
+				Resource: aws.String("startExecution.sync:2"), ResourceType: aws.String("states"), Output: aws.String(`{"ExecutionArn":"` + child + `","Output":{}}`),
+			}},
+		}
+	}
+	parentHistory := append(startsChild("Checkout", childArn),
+		types.HistoryEvent{Id: 5, PreviousEventId: 4, Type: types.HistoryEventTypeMapStateEntered, StateEnteredEventDetails: &types.StateEnteredEventDetails{Name: aws.String("Items")}},
+		types.HistoryEvent{Id: 6, PreviousEventId: 5, Type: types.HistoryEventTypeMapRunStarted, MapRunStartedEventDetails: &types.MapRunStartedEventDetails{MapRunArn: aws.String(mapRunArn)}},
+	)
+	client := &fakeSFN{
+		executions: map[string][]*sfn.DescribeExecutionOutput{
+			ordersArn: {execution(parentArn, types.ExecutionStatusFailed), execution(childArn, types.ExecutionStatusSucceeded), execution(grandArn, types.ExecutionStatusSucceeded)},
+			mapRunArn: {execution(itemOKArn, types.ExecutionStatusSucceeded), execution(itemBadArn, types.ExecutionStatusFailed)},
+		},
+		histories: map[string][]types.HistoryEvent{
+			parentArn: parentHistory,
+			childArn:  startsChild("Audit", grandArn),
+		},
+	}
+	f := newTestFetcher(client, &fakeLogs{})
+
+	tree, err := f.FetchExecutionTree(context.Background(), parentArn, TreeOptions{MaxDepth: 1, MaxChildren: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 2 || !tree.ChildrenTruncated {
+		t.Fatalf("got children %+v, truncated %v", tree.Children, tree.ChildrenTruncated)
+	}
+	child := tree.Children[0]
+	if child.Execution.ExecutionArn != childArn || child.ParentArn != parentArn || child.ParentState != "Checkout" || child.MapRunArn != "" {
+		t.Errorf("got child %+v", child)
+	}
+	if len(child.Children) != 0 {
+		t.Errorf("followed %d grandchildren beyond MaxDepth", len(child.Children))
+	}
+	// The failed item is kept over the one that succeeded
+	item := tree.Children[1]
+	if item.Execution.ExecutionArn != itemBadArn || item.MapRunArn != mapRunArn || item.ParentState != "Items" {
+		t.Errorf("got Map Run child %+v", item)
+	}
+
+	tree, err = f.FetchExecutionTree(context.Background(), parentArn, TreeOptions{MaxDepth: 2, MaxChildren: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 3 || tree.ChildrenTruncated {
+		t.Fatalf("got %d children, truncated %v", len(tree.Children), tree.ChildrenTruncated)
+	}
+	if grandchildren := tree.Children[0].Children; len(grandchildren) != 1 || grandchildren[0].ParentArn != childArn {
+		t.Errorf("got grandchildren %+v", grandchildren)
+	}
+}
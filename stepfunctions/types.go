@@ -1,15 +1,54 @@
 package stepfunctions
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // StateMachine represents a Step Functions state machine
 type StateMachine struct {
 	Name         string
 	ARN          string
+	ConsoleURL   string `json:",omitempty"` // Step Functions console page
 	RoleARN      string
 	Definition   string
+	RevisionID   string `json:",omitempty"`
 	States       []State
+	Cached       bool `json:"-"` // Definition unchanged since the last run
 	Executions   []Execution
-	CreationDate string
+	Metrics      *ExecutionMetrics `json:",omitempty"` // Express executions counted from metrics when not logged
+	CreationDate time.Time
 	Type         string
+	Logging      LoggingConfiguration
+	Tracing      bool // X-Ray tracing enabled
+	Encryption   EncryptionConfiguration
+	Tags         map[string]string `json:",omitempty"` // Set by AttachTags
+	RawDescribe  json.RawMessage   `json:"-"`          // DescribeStateMachine response, with WithRawResponses
+
+	// DefinitionHash is the DefinitionHash of the definition, and
+	// DefinitionUpdated when a run first saw it, from the revisions/ history;
+	// nil when no history was recorded
+	DefinitionHash    string     `json:",omitempty"`
+	DefinitionUpdated *time.Time `json:",omitempty"`
+	// DefinitionFile names the .asl.json file a definition too large to keep
+	// inline was moved to, relative to the export; Definition is then empty
+	DefinitionFile string `json:",omitempty"`
+}
+
+// LoggingConfiguration captures the CloudWatch Logs settings of a state machine
+type LoggingConfiguration struct {
+	Level                string // ALL, ERROR, FATAL or OFF
+	IncludeExecutionData bool
+	Destinations         []string // CloudWatch Logs log group ARNs
+	ConsoleURLs          []string `json:",omitempty"` // CloudWatch console pages of the Destinations
+}
+
+// EncryptionConfiguration captures how a state machine encrypts its data at rest
+type EncryptionConfiguration struct {
+	Type                      string // AWS_OWNED_KEY or CUSTOMER_MANAGED_KMS_KEY
+	KMSKeyID                  string `json:",omitempty"`
+	DataKeyReusePeriodSeconds int32  `json:",omitempty"`
 }
 
 // State represents an individual state in the state machine
@@ -25,8 +64,185 @@ type State struct {
 // Execution represents an execution of a state machine
 type Execution struct {
 	ExecutionArn string
+	ConsoleURL   string `json:",omitempty"` // Step Functions console page
 	Status       string
-	StartTime    string
-	EndTime      string
-	Duration     string // Human-readable duration (e.g., "1m30s")
+	StartTime    time.Time          // Zero when unknown, see Partial
+	EndTime      time.Time          // Zero while running
+	Duration     time.Duration      // Zero unless both StartTime and EndTime are known
+	InputSize    int                // Input payload size in bytes, when known
+	OutputSize   int                // Output payload size in bytes, when known
+	Error        string             `json:",omitempty"` // Error code of a failed execution
+	Cause        string             `json:",omitempty"`
+	Partial      bool               `json:",omitempty"` // Express execution whose start was not logged; StartTime and Duration are unknown
+	Transitions  int                // Billed state transitions, counted from History
+	History      []HistoryEvent     `json:",omitempty"` // Set when history fetching is enabled
+	Initiator    *Initiator         `json:",omitempty"` // Set when CloudTrail attribution is enabled
+	MapRuns      []MapRun           `json:",omitempty"` // Distributed Map runs, with WithMapRuns
+	Lambdas      []LambdaInvocation `json:",omitempty"` // Lambda invocations correlated with New Relic telemetry
+	Captured     Capture            // What was fetched, see Capture
+}
+
+// Capture records what was fetched for an execution, so consumers can tell an
+// execution without failures from one whose history was never fetched. All
+// false means the summary only.
+type Capture struct {
+	History   bool // The event history was fetched
+	Payloads  bool // History events carry state input and output
+	Truncated bool // Some history events are missing: not logged, or filtered out on export
+}
+
+// String describes the capture, e.g. "summary" or "history+payloads"
+func (c Capture) String() string {
+	level := "summary"
+	if c.History {
+		level = "history"
+		if c.Payloads {
+			level += "+payloads"
+		}
+	}
+	if c.Truncated {
+		level += " (truncated)"
+	}
+	return level
+}
+
+// DurationKnown reports whether both ends of the execution are known
+func (e Execution) DurationKnown() bool {
+	return !e.StartTime.IsZero() && !e.EndTime.IsZero()
+}
+
+// FormatDuration returns the duration for display, e.g. "1m30.5s", or "N/A"
+// while it is unknown
+func (e Execution) FormatDuration() string {
+	if !e.DurationKnown() {
+		return "N/A"
+	}
+	return e.Duration.String()
+}
+
+// setDuration derives Duration from StartTime and EndTime
+func (e *Execution) setDuration() {
+	e.Duration = 0
+	if e.DurationKnown() {
+		e.Duration = e.EndTime.Sub(e.StartTime)
+	}
+}
+
+// executionJSON overrides the typed fields of Execution with the strings
+// written by earlier versions: "" for unknown times and "N/A" for an unknown
+// duration
+type executionJSON struct {
+	*executionFields
+	StartTime string
+	EndTime   string
+	Duration  string
+}
+
+type executionFields Execution
+
+// MarshalJSON writes times in RFC 3339 and the duration as e.g. "1m30s"
+func (e Execution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(executionJSON{
+		executionFields: (*executionFields)(&e),
+		StartTime:       formatJSONTime(e.StartTime),
+		EndTime:         formatJSONTime(e.EndTime),
+		Duration:        e.FormatDuration(),
+	})
+}
+
+// UnmarshalJSON reads what MarshalJSON writes
+func (e *Execution) UnmarshalJSON(data []byte) error {
+	v := executionJSON{executionFields: (*executionFields)(e)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var err error
+	if e.StartTime, err = parseJSONTime(v.StartTime); err != nil {
+		return err
+	}
+	if e.EndTime, err = parseJSONTime(v.EndTime); err != nil {
+		return err
+	}
+	e.Duration = 0
+	if v.Duration != "" && v.Duration != "N/A" {
+		if e.Duration, err = time.ParseDuration(v.Duration); err != nil {
+			return fmt.Errorf("invalid execution duration %q: %w", v.Duration, err)
+		}
+	}
+	return nil
+}
+
+func formatJSONTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseJSONTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid execution time %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// HistoryEvent is a single event from an execution's history, with the
+// type-specific details flattened into common fields
+type HistoryEvent struct {
+	ID               int64
+	PreviousEventID  int64
+	Type             string
+	Timestamp        time.Time
+	StateName        string `json:",omitempty"` // State the event belongs to
+	Resource         string `json:",omitempty"`
+	ResourceType     string `json:",omitempty"`
+	Parameters       string `json:",omitempty"`
+	Input            string `json:",omitempty"`
+	Output           string `json:",omitempty"`
+	Error            string `json:",omitempty"`
+	Cause            string `json:",omitempty"`
+	HeartbeatSeconds int64  `json:",omitempty"`
+	TimeoutSeconds   int64  `json:",omitempty"`
+	MapRunArn        string `json:",omitempty"`
+}
+
+// Initiator identifies the principal that started an execution, as recorded by CloudTrail
+type Initiator struct {
+	PrincipalARN  string
+	PrincipalType string // e.g., "AssumedRole", "IAMUser", "AWSService"
+	RoleARN       string // Issuing role for assumed-role sessions
+	SourceIP      string
+	UserAgent     string
+	EventTime     string
+}
+
+// RolePolicy is a managed or inline IAM policy on a state machine's execution role
+type RolePolicy struct {
+	Name     string
+	ARN      string `json:",omitempty"` // Empty for inline policies
+	Inline   bool
+	Document map[string]interface{}
+}
+
+// PermissionAnalysis compares an execution role's grants with the actions its definition uses
+type PermissionAnalysis struct {
+	StateMachineARN string
+	RoleARN         string
+	Policies        []RolePolicy
+	RequiredActions []string // Actions implied by the Task resources in the definition
+	MissingActions  []string // Required actions no Allow statement grants
+	WildcardGrants  []string // Allow statements using "*" actions or NotAction
+	UnusedActions   []string // Explicitly granted actions the definition never uses
+}
+
+// ComplianceIssue is a configuration gap found while auditing a state machine
+type ComplianceIssue struct {
+	StateMachineName string
+	StateMachineARN  string
+	Check            string // e.g., "logging", "tracing", "encryption"
+	Message          string
 }
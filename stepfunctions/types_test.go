@@ -0,0 +1,75 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutionJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		exec Execution
+		want string // Fragment of the encoded execution
+	}{
+		{
+			name: "finished",
+			exec: Execution{ExecutionArn: "arn:x", StartTime: testStart, EndTime: testStart.Add(1500 * time.Millisecond), Duration: 1500 * time.Millisecond},
+			want: `"StartTime":"2024-03-01T10:00:00Z","EndTime":"2024-03-01T10:00:01.5Z","Duration":"1.5s"`,
+		},
+		{
+			name: "running",
+			exec: Execution{ExecutionArn: "arn:x", StartTime: testStart},
+			want: `"StartTime":"2024-03-01T10:00:00Z","EndTime":"","Duration":"N/A"`,
+		},
+		{
+			name: "partial",
+			exec: Execution{ExecutionArn: "arn:x", EndTime: testStart, Partial: true},
+			want: `"StartTime":"","EndTime":"2024-03-01T10:00:00Z","Duration":"N/A"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.exec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("got %s, want it to contain %s", data, tt.want)
+			}
+			var got Execution
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.StartTime.Equal(tt.exec.StartTime) || !got.EndTime.Equal(tt.exec.EndTime) || got.Duration != tt.exec.Duration || got.Partial != tt.exec.Partial {
+				t.Errorf("round trip: got %+v, want %+v", got, tt.exec)
+			}
+		})
+	}
+}
+
+func TestExecutionJSONInvalid(t *testing.T) {
+	for _, data := range []string{`{"StartTime":"yesterday"}`, `{"EndTime":1}`, `{"Duration":"long"}`} {
+		var exec Execution
+		if err := json.Unmarshal([]byte(data), &exec); err == nil {
+			t.Errorf("decoding %s: got %+v, want an error", data, exec)
+		}
+	}
+}
+
+func TestCaptureString(t *testing.T) {
+	for _, tt := range []struct {
+		capture Capture
+		want    string
+	}{
+		{Capture{}, "summary"},
+		{Capture{History: true}, "history"},
+		{Capture{History: true, Payloads: true}, "history+payloads"},
+		{Capture{History: true, Truncated: true}, "history (truncated)"},
+	} {
+		if got := tt.capture.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.capture, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateDefinition checks the structure of a state machine's definition:
+// start states, transition targets and Task resources, then the flow between
+// states (see flow), in every Parallel branch and Map processor as well as at
+// the top level
+func ValidateDefinition(sm StateMachine) []Finding {
+	var root map[string]interface{}
+	if err := json.Unmarshal([]byte(sm.Definition), &root); err != nil {
+		return []Finding{newFinding("ASL001", sm, "", "Definition is not a JSON object: %v", err)}
+	}
+	v := &validator{sm: sm, waits: make(map[string]bool)}
+	v.machine(root, "")
+	v.flow(buildGraph(root))
+	return v.findings
+}
+
+type validator struct {
+	sm       StateMachine
+	findings []Finding
+	// waits are the Task states that wait for a job (.sync) or a callback
+	// (.waitForTaskToken), which pause a loop like a Wait state
+	waits map[string]bool
+}
+
+// machine validates one StartAt/States scope; parent names the state that
+// contains it, empty at the top level
+func (v *validator) machine(machine map[string]interface{}, parent string) {
+	states, ok := machine["States"].(map[string]interface{})
+	if !ok || len(states) == 0 {
+		v.add("ASL001", parent, "States is missing or empty")
+		return
+	}
+	if start, _ := machine["StartAt"].(string); states[start] == nil {
+		v.add("ASL002", parent, "StartAt %q is not a state", start)
+	}
+
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rawDef, ok := states[name].(map[string]interface{})
+		if !ok {
+			v.add("ASL001", name, "State %q is not an object", name)
+			continue
+		}
+		v.state(name, rawDef, states)
+	}
+}
+
+func (v *validator) state(name string, rawDef map[string]interface{}, scope map[string]interface{}) {
+	target := func(field, next string) {
+		if scope[next] == nil {
+			v.add("ASL003", name, "%s %q of state %q is not a state in its scope", field, next, name)
+		}
+	}
+
+	stateType, _ := rawDef["Type"].(string)
+	next, hasNext := rawDef["Next"].(string)
+	if hasNext {
+		target("Next", next)
+	}
+	switch stateType {
+	case "Choice":
+		choices, _ := rawDef["Choices"].([]interface{})
+		for _, choice := range choices {
+			if rule, ok := choice.(map[string]interface{}); ok {
+				if next, ok := rule["Next"].(string); ok {
+					target("Choice rule Next", next)
+				}
+			}
+		}
+		if def, ok := rawDef["Default"].(string); ok {
+			target("Default", def)
+		}
+	case "Succeed", "Fail":
+	default:
+		if end, _ := rawDef["End"].(bool); !hasNext && !end {
+			v.add("ASL004", name, "State %q has neither Next nor \"End\": true", name)
+		}
+	}
+	if resource, _ := rawDef["Resource"].(string); stateType == "Task" {
+		if resource == "" {
+			v.add("ASL005", name, "Task state %q has no Resource", name)
+		}
+		v.waits[name] = strings.Contains(resource, ".sync") || strings.Contains(resource, ".waitForTaskToken")
+	}
+
+	if branches, ok := rawDef["Branches"].([]interface{}); ok {
+		for _, branch := range branches {
+			if sub, ok := branch.(map[string]interface{}); ok {
+				v.machine(sub, name)
+			}
+		}
+	}
+	for _, key := range []string{"ItemProcessor", "Iterator"} {
+		if sub, ok := rawDef[key].(map[string]interface{}); ok {
+			v.machine(sub, name)
+		}
+	}
+}
+
+func (v *validator) add(ruleID, state, format string, args ...interface{}) {
+	v.findings = append(v.findings, newFinding(ruleID, v.sm, state, format, args...))
+}
+
+// definitionLine returns the 1-based line of the definition on which state is
+// declared, or 0 when it isn't found. State names are unique across a state
+// machine, nested scopes included, so the first matching key is the state.
+func definitionLine(definition, state string) int {
+	if state == "" {
+		return 0
+	}
+	key, err := json.Marshal(state)
+	if err != nil {
+		return 0
+	}
+	loc := regexp.MustCompile(regexp.QuoteMeta(string(key)) + `\s*:\s*\{`).FindStringIndex(definition)
+	if loc == nil {
+		return 0
+	}
+	return strings.Count(definition[:loc[0]], "\n") + 1
+}
@@ -0,0 +1,98 @@
+package stepfunctions
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValidateDefinition(t *testing.T) {
+	if findings := ValidateDefinition(StateMachine{Definition: readDefinition(t, "order.asl.json")}); len(findings) != 0 {
+		t.Errorf("order.asl.json: got findings %v, want none", findings)
+	}
+
+	sm := StateMachine{
+		Name: "broken",
+		ARN:  "arn:aws:states:us-east-1:123456789012:stateMachine:broken",
+		Definition: `{
+  "StartAt": "Lookup",
+  "States": {
+    "Lookup": {"Type": "Task", "Next": "Route"},
+    "Route": {
+      "Type": "Choice",
+      "Choices": [{"Variable": "$.ok", "BooleanEquals": true, "Next": "Fan"}],
+      "Default": "Missing"
+    },
+    "Fan": {
+      "Type": "Parallel",
+      "End": true,
+      "Branches": [{"StartAt": "Nowhere", "States": {"Leg": {"Type": "Pass"}}}]
+    }
+  }
+}`,
+	}
+	findings := ValidateDefinition(sm)
+	want := []struct {
+		rule, state string
+		line        int
+	}{
+		{"ASL002", "Fan", 10},
+		{"ASL004", "Leg", 13},
+		{"ASL005", "Lookup", 4},
+		{"ASL003", "Route", 5},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("got findings %v, want %d", findings, len(want))
+	}
+	for i, w := range want {
+		if got := findings[i]; got.RuleID != w.rule || got.State != w.state || got.Line != w.line {
+			t.Errorf("finding %d = %s %s line %d, want %s %s line %d", i, got.RuleID, got.State, got.Line, w.rule, w.state, w.line)
+		}
+	}
+
+	var sarif bytes.Buffer
+	opts := SARIFOptions{ArtifactURI: func(f Finding) string { return f.StateMachineName + ".asl.json" }}
+	if err := WriteSARIF(&sarif, findings, opts); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "validation.golden.sarif", sarif.Bytes())
+
+	if findings := ValidateDefinition(StateMachine{Definition: "[]"}); len(findings) != 1 || findings[0].RuleID != "ASL001" {
+		t.Errorf("got findings %v for a non-object definition", findings)
+	}
+}
+
+func TestValidateFlow(t *testing.T) {
+	sm := StateMachine{Name: "jobs", Definition: `{
+  "StartAt": "Submit",
+  "States": {
+    "Submit": {"Type": "Task", "Resource": "arn:x", "Next": "Poll", "Catch": [{"ErrorEquals": ["States.ALL"], "Next": "Spin"}]},
+    "Poll": {"Type": "Task", "Resource": "arn:x", "Next": "Done?"},
+    "Done?": {"Type": "Choice", "Choices": [{"Variable": "$.done", "BooleanEquals": false, "Next": "Poll"}], "Default": "Build"},
+    "Rows": {
+      "Type": "Map",
+      "End": true,
+      "ItemProcessor": {"StartAt": "Row", "States": {"Row": {"Type": "Pass", "End": true}, "Stray": {"Type": "Pass", "End": true}}}
+    },
+    "Spin": {"Type": "Pass", "Next": "Spin"},
+    "Orphan": {"Type": "Succeed"},
+    "Build": {"Type": "Task", "Resource": "arn:aws:states:::codebuild:startBuild.sync", "Next": "Built?"},
+    "Built?": {"Type": "Choice", "Choices": [{"Variable": "$.retry", "BooleanEquals": true, "Next": "Build"}], "Default": "Rows"}
+  }
+}`}
+	want := []struct{ rule, state string }{
+		{"ASL006", "Stray"},
+		{"ASL006", "Orphan"},
+		{"ASL007", "Spin"},
+		{"ASL009", "Done?"}, // Poll and Done? loop without waiting
+		{"ASL008", "Spin"},  // Spin never leaves; Build and Built? pause in the .sync task
+	}
+	findings := ValidateDefinition(sm)
+	if len(findings) != len(want) {
+		t.Fatalf("got findings %v, want %d", findings, len(want))
+	}
+	for i, w := range want {
+		if got := findings[i]; got.RuleID != w.rule || got.State != w.state {
+			t.Errorf("finding %d = %s %s (%s), want %s %s", i, got.RuleID, got.State, got.Message, w.rule, w.state)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Warning is a problem the fetcher worked around: the result is still
+// returned, but may be missing what Resource would have contributed
+type Warning struct {
+	Resource  string // ARN, log group or CloudTrail event the warning is about
+	Operation string // What the fetcher was doing, usually the AWS operation
+	Err       error
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s %s: %v", w.Operation, w.Resource, w.Err)
+}
+
+// WarningSink receives warnings as they happen
+type WarningSink interface {
+	Warn(w Warning)
+}
+
+// WithWarningSink sends warnings to sink instead of printing them
+func WithWarningSink(sink WarningSink) Option {
+	return func(f *Fetcher) {
+		f.warnings = sink
+	}
+}
+
+func (f *Fetcher) warn(resource, operation string, err error) {
+	w := Warning{Resource: resource, Operation: operation, Err: err}
+	if f.warnings == nil {
+		fmt.Printf("Warning: %s\n", w)
+		return
+	}
+	f.warnings.Warn(w)
+}
+
+// MarshalJSON writes the error as its message and kind (see ErrorKind)
+func (w Warning) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Resource  string `json:"resource"`
+		Operation string `json:"operation"`
+		Kind      string `json:"kind"`
+		Error     string `json:"error"`
+	}{w.Resource, w.Operation, ErrorKind(w.Err), w.Err.Error()})
+}
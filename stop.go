@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// runStop stops the running executions of the given state machines that match
+// the filters, for cleaning up runaway workflows during an incident. Without
+// --confirm it only lists what it would stop.
+func runStop(args []string) {
+	fs := flag.NewFlagSet("stop", flag.ExitOnError)
+	region := fs.String("region", "", "AWS region (defaults to the region in the state machine ARN)")
+	stateMachineArns := fs.String("state-machine-arn", "", "Comma-separated Standard state machine ARNs whose executions to stop (required)")
+	startedBefore := fs.String("started-before", "", "Only executions started before this RFC 3339 time, or longer ago than this duration (e.g. 2h)")
+	namePattern := fs.String("name-pattern", "", "Only executions whose name matches this glob pattern (e.g. 'import-*')")
+	errorCode := fs.String("error", "OperatorStopped", "Error code recorded on the stopped executions")
+	cause := fs.String("cause", "Stopped with stepfunction-fetcher stop", "Cause recorded on the stopped executions")
+	maxStop := fs.Int("max", 100, "Refuse to stop more than this many executions")
+	confirm := fs.Bool("confirm", false, "Stop the listed executions; without it the command only lists them")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher stop --state-machine-arn <arn>[,<arn>...] [filters] [--confirm]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	initColor(*noColor)
+	if *stateMachineArns == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	now := time.Now()
+	filter := stepfunctions.RunningFilter{NamePattern: *namePattern}
+	if *startedBefore != "" {
+		var err error
+		if filter.StartedBefore, err = parseStartedBefore(*startedBefore, now); err != nil {
+			log.Fatalf("Invalid --started-before: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	arns := strings.Split(*stateMachineArns, ",")
+	fetcher, err := stepfunctions.NewFetcher(ctx, regionFor(strings.TrimSpace(arns[0]), *region))
+	if err != nil {
+		log.Fatalf("Failed to create fetcher: %v", err)
+	}
+	var matched []stepfunctions.Execution
+	for _, smArn := range arns {
+		executions, err := fetcher.RunningExecutions(ctx, strings.TrimSpace(smArn), filter)
+		if err != nil {
+			log.Fatalf("Failed to list executions: %v", err)
+		}
+		matched = append(matched, executions...)
+	}
+
+	stopTable := newTable()
+	stopTable.SetHeader([]string{"Execution", "Started", "Running For"})
+	for _, exec := range matched {
+		stopTable.Append([]string{exec.ExecutionArn, formatTime(exec.StartTime), now.Sub(exec.StartTime).Round(time.Second).String()})
+	}
+	stopTable.Render()
+
+	switch {
+	case len(matched) == 0:
+		fmt.Println("No running executions match")
+		return
+	case len(matched) > *maxStop:
+		log.Fatalf("%d executions match, more than --max %d; narrow the filters or raise --max", len(matched), *maxStop)
+	case !*confirm:
+		fmt.Printf("Dry run: %d execution(s) would be stopped; re-run with --confirm to stop them\n", len(matched))
+		return
+	}
+
+	failed := 0
+	for _, exec := range matched {
+		if err := fetcher.StopExecution(ctx, exec.ExecutionArn, *errorCode, *cause); err != nil {
+			log.Printf("%v", err)
+			failed++
+			continue
+		}
+		fmt.Printf("Stopped %s\n", exec.ExecutionArn)
+	}
+	fmt.Printf("Stopped %d of %d execution(s)\n", len(matched)-failed, len(matched))
+	if failed > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// parseStartedBefore accepts an RFC 3339 time or a duration before now
+func parseStartedBefore(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", value)
+	}
+	return t, nil
+}
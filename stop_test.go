@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStartedBefore(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2h":                   now.Add(-2 * time.Hour),
+		"2024-03-01T09:30:00Z": time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		if got, err := parseStartedBefore(value, now); err != nil || !got.Equal(want) {
+			t.Errorf("parseStartedBefore(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseStartedBefore("yesterday", now); err == nil {
+		t.Error("expected an error for an unparseable value")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// runSummary describes the outcome of a run for webhooks and other consumers
+type runSummary struct {
+	GeneratedAt      time.Time                   `json:"generatedAt"`
+	Region           string                      `json:"region"`
+	OutputDir        string                      `json:"outputDir"`
+	ExitCode         int                         `json:"exitCode"`
+	APICalls         int                         `json:"apiCalls"`
+	BudgetExceeded   bool                        `json:"budgetExceeded"`
+	Skipped          int                         `json:"skipped"`
+	Executions       int                         `json:"executions"`
+	FailedExecutions int                         `json:"failedExecutions"`
+	StateMachines    []stateMachineSummary       `json:"stateMachines"`
+	Errors           []stepfunctions.SkippedItem `json:"errors,omitempty"`
+	Warnings         []stepfunctions.Warning     `json:"warnings,omitempty"`
+}
+
+type stateMachineSummary struct {
+	Name             string `json:"name"`
+	ARN              string `json:"arn"`
+	Type             string `json:"type"`
+	Executions       int    `json:"executions"`
+	FailedExecutions int    `json:"failedExecutions"`
+}
+
+func buildRunSummary(cfg runConfig, fetcher *stepfunctions.Fetcher, stateMachines []stepfunctions.StateMachine, errs *runErrors, warnings *runWarnings, exitCode int) runSummary {
+	summary := runSummary{
+		GeneratedAt:    time.Now().UTC(),
+		Region:         cfg.region,
+		OutputDir:      cfg.outputDir,
+		ExitCode:       exitCode,
+		APICalls:       fetcher.APICalls(),
+		BudgetExceeded: fetcher.BudgetExceeded(),
+		Skipped:        len(errs.items),
+		StateMachines:  []stateMachineSummary{},
+		Errors:         errs.items,
+		Warnings:       warnings.items,
+	}
+	for _, sm := range stateMachines {
+		smSummary := stateMachineSummary{Name: sm.Name, ARN: sm.ARN, Type: sm.Type}
+		for _, exec := range sm.Executions {
+			if exec.ExecutionArn == "N/A" {
+				continue
+			}
+			smSummary.Executions++
+			if stepfunctions.IsFailedStatus(exec.Status) {
+				smSummary.FailedExecutions++
+			}
+		}
+		summary.Executions += smSummary.Executions
+		summary.FailedExecutions += smSummary.FailedExecutions
+		summary.StateMachines = append(summary.StateMachines, smSummary)
+	}
+	return summary
+}
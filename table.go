@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// tableLayout holds the --columns, --max-width, --wrap, --page-size and
+// --state-view settings
+type tableLayout struct {
+	columns  map[string]bool // Selected column keys; empty keeps every column
+	maxWidth int             // Cell width limit, 0 = unlimited
+	wrap     bool            // Wrap cells at maxWidth instead of truncating them
+	pageSize int             // Executions printed per state machine, 0 = all
+	// stateTree prints each state machine's flow with renderStateTree
+	// instead of the state table
+	stateTree bool
+}
+
+var layout tableLayout
+
+func parseColumns(list string) map[string]bool {
+	columns := make(map[string]bool)
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(strings.ToLower(key)); key != "" {
+			columns[key] = true
+		}
+	}
+	return columns
+}
+
+// table is a tablewriter table on stdout that applies the layout settings
+type table struct {
+	*tablewriter.Table
+	keep   []int                       // Indexes of the selected columns, nil for all
+	colors map[int]func(string) string // Cell colors by column index, applied after truncation
+}
+
+func newTable() *table {
+	t := &table{Table: tablewriter.NewWriter(os.Stdout)}
+	switch {
+	case layout.wrap:
+		t.SetAutoWrapText(true)
+		if layout.maxWidth > 0 {
+			t.SetColWidth(layout.maxWidth)
+		}
+	case layout.maxWidth > 0:
+		t.SetAutoWrapText(false)
+	}
+	return t
+}
+
+// SetColumns names the table's columns in header order so --columns can
+// select among them. When none of the keys was selected, all columns stay.
+func (t *table) SetColumns(keys ...string) {
+	var keep []int
+	for i, key := range keys {
+		if layout.columns[key] {
+			keep = append(keep, i)
+		}
+	}
+	t.keep = keep
+}
+
+// SetColumnColor colors the cells of column i (in header order)
+func (t *table) SetColumnColor(i int, color func(string) string) {
+	if t.colors == nil {
+		t.colors = make(map[int]func(string) string)
+	}
+	t.colors[i] = color
+}
+
+func (t *table) SetHeader(headers []string) {
+	t.Table.SetHeader(t.pick(headers))
+}
+
+func (t *table) Append(row []string) {
+	row = append([]string(nil), row...)
+	for i, cell := range row {
+		if layout.maxWidth > 0 && !layout.wrap {
+			cell = truncateCell(cell, layout.maxWidth)
+		}
+		if color, ok := t.colors[i]; ok {
+			cell = color(cell)
+		}
+		row[i] = cell
+	}
+	t.Table.Append(t.pick(row))
+}
+
+func (t *table) pick(cells []string) []string {
+	if t.keep == nil {
+		return append([]string(nil), cells...)
+	}
+	picked := make([]string, 0, len(t.keep))
+	for _, i := range t.keep {
+		if i < len(cells) {
+			picked = append(picked, cells[i])
+		}
+	}
+	return picked
+}
+
+// formatTime renders a time cell, leaving unknown times empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// truncateCell shortens each line of a cell to width runes
+func truncateCell(cell string, width int) string {
+	lines := strings.Split(cell, "\n")
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > width {
+			if width > 3 {
+				lines[i] = string(runes[:width-3]) + "..."
+			} else {
+				lines[i] = string(runes[:width])
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
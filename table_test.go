@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// captureStdout returns what fn printed to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+func TestPageSize(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	sm := stepfunctions.StateMachine{
+		Name: "orders",
+		ARN:  "arn:aws:states:us-east-1:123456789012:stateMachine:orders",
+		Type: "STANDARD",
+	}
+	for i := 1; i <= 5; i++ {
+		sm.Executions = append(sm.Executions, stepfunctions.Execution{
+			ExecutionArn: fmt.Sprintf("arn:aws:states:us-east-1:123456789012:execution:orders:run-%d", i),
+			Status:       "SUCCEEDED",
+			StartTime:    start,
+			EndTime:      start.Add(time.Second),
+		})
+	}
+
+	tests := []struct {
+		pageSize int
+		printed  int
+		more     string
+	}{
+		{0, 5, ""},
+		{2, 2, "... 3 more executions not shown (--page-size 2)"},
+		{5, 5, ""},
+		{10, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.pageSize), func(t *testing.T) {
+			previous := layout
+			layout = tableLayout{pageSize: tt.pageSize}
+			t.Cleanup(func() { layout = previous })
+
+			dir := t.TempDir()
+			errs := &runErrors{outputDir: dir}
+			output := captureStdout(t, func() {
+				processStateMachines(context.Background(), []stepfunctions.StateMachine{sm}, []Exporter{newFileExporter(dir)}, historyFilter{}, errs)
+			})
+			if len(errs.items) != 0 {
+				t.Fatalf("got errors %v", errs.items)
+			}
+
+			if printed := strings.Count(output, "execution:orders:run-"); printed != tt.printed {
+				t.Errorf("printed %d executions, want %d:\n%s", printed, tt.printed, output)
+			}
+			if hasMore := strings.Contains(output, "more executions not shown"); hasMore != (tt.more != "") || !strings.Contains(output, tt.more) {
+				t.Errorf("got output\n%s\nwant note %q", output, tt.more)
+			}
+			exported, err := filepath.Glob(filepath.Join(dir, "orders_execution_*.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The page size only limits the table; every execution is exported
+			if len(exported) != len(sm.Executions) {
+				t.Errorf("exported %d executions, want %d", len(exported), len(sm.Executions))
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// setupTelemetry installs OTLP/HTTP trace and metric providers for the
+// fetcher's own operation. The exporters follow the standard OTEL_EXPORTER_OTLP_*
+// variables; without an endpoint, the New Relic license key sends to the
+// OTLP endpoint of its region. Spans are batched, compressed and retried
+// with the httpSink policy; the OTLP exporters drop what they cannot send
+// rather than dead-letter it. The returned function flushes and must be
+// called before exiting.
+func setupTelemetry(ctx context.Context, nr newRelicConfig) (func(context.Context) error, error) {
+	traceOpts := []otlptracehttp.Option{
+		otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: sinkRetryBackoff,
+			MaxInterval:     sinkMaxBackoff,
+			MaxElapsedTime:  sinkMaxAttempts * sinkMaxBackoff,
+		}),
+	}
+	metricOpts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression),
+		otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: sinkRetryBackoff,
+			MaxInterval:     sinkMaxBackoff,
+			MaxElapsedTime:  sinkMaxAttempts * sinkMaxBackoff,
+		}),
+	}
+	tlsConfig, err := network.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		traceOpts = append(traceOpts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithTLSClientConfig(tlsConfig))
+	}
+	if network.proxy != "" {
+		traceOpts = append(traceOpts, otlptracehttp.WithProxy(network.proxyFunc()))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithProxy(network.proxyFunc()))
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		if nr.licenseKey == "" {
+			return nil, fmt.Errorf("set OTEL_EXPORTER_OTLP_ENDPOINT or NEW_RELIC_LICENSE_KEY")
+		}
+		endpoint := nr.endpoints().otlpEndpoint
+		headers := map[string]string{"api-key": nr.licenseKey}
+		traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithHeaders(headers))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithEndpoint(endpoint), otlpmetrichttp.WithHeaders(headers))
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", stepfunctions.InstrumentationName),
+		attribute.String("service.version", version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter, sdktrace.WithMaxExportBatchSize(sinkBatchItems), sdktrace.WithBatchTimeout(sinkFlushInterval)), sdktrace.WithResource(res))
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)), sdkmetric.WithResource(res))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+func flushTelemetry(ctx context.Context, shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		log.Printf("Failed to flush telemetry: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// templateData is the root object templates are executed against
+type templateData struct {
+	GeneratedAt   time.Time
+	Region        string
+	StateMachines []stepfunctions.StateMachine
+}
+
+// templateFuncs are available to --template files in addition to the
+// text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":       strings.Join,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"replace":    strings.ReplaceAll,
+	"hasPrefix":  strings.HasPrefix,
+	"consoleURL": stepfunctions.ExecutionConsoleURL,
+	"formatTime": formatTime, // RFC 3339, empty for unknown times
+	// shortArn returns the last segment of an ARN: the execution or state machine name
+	"shortArn": func(arn string) string { return arn[strings.LastIndex(arn, ":")+1:] },
+	// csv quotes a value for a CSV field
+	"csv": func(s string) string {
+		if strings.ContainsAny(s, "\",\n\r") {
+			return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+		}
+		return s
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// loadTemplate parses a --template file up front so mistakes fail before fetching
+func loadTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// processTemplate renders the template over the fetched state machines and
+// saves the result next to the other output, named after the template
+// without its .tmpl extension (runbook.md.tmpl -> runbook.md)
+func processTemplate(tmpl *template.Template, region string, stateMachines []stepfunctions.StateMachine, outputDir string) {
+	var out bytes.Buffer
+	data := templateData{GeneratedAt: time.Now().UTC(), Region: region, StateMachines: stateMachines}
+	if err := tmpl.Execute(&out, data); err != nil {
+		log.Printf("Failed to execute template %s: %v", tmpl.Name(), err)
+		return
+	}
+
+	name := strings.TrimSuffix(tmpl.Name(), ".tmpl")
+	if name == tmpl.Name() {
+		name += ".out"
+	}
+	path := filepath.Join(outputDir, name)
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		log.Printf("Failed to save template output: %v", err)
+		return
+	}
+	fmt.Printf("Template output saved to %s\n", path)
+}
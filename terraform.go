@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// writeTerraform renders the state machines as aws_sfn_state_machine
+// resources in dir:
+//
+//	main.tf                            one resource per state machine
+//	definitions/<name>.asl.json.tftpl  the definitions, with the account ID and region templated
+//	import.sh                          terraform import commands for the existing state machines
+func writeTerraform(stateMachines []stepfunctions.StateMachine, dir string, _ iacOptions) error {
+	if err := os.MkdirAll(filepath.Join(dir, "definitions"), 0755); err != nil {
+		return fmt.Errorf("failed to create terraform directory: %w", err)
+	}
+	sort.Slice(stateMachines, func(i, j int) bool { return stateMachines[i].Name < stateMachines[j].Name })
+
+	var tf, imports strings.Builder
+	tf.WriteString("# Generated by stepfunction-fetcher export terraform\n\n")
+	tf.WriteString("data \"aws_caller_identity\" \"current\" {}\n\ndata \"aws_region\" \"current\" {}\n")
+	imports.WriteString("#!/bin/sh\n# Adopt the existing state machines into the Terraform state\nset -e\n")
+	resources := make(map[string]bool)
+	for _, sm := range stateMachines {
+		resource := uniqueName(resources, terraformName(sm.Name), "_")
+		template := filepath.ToSlash(filepath.Join("definitions", sm.Name+".asl.json.tftpl"))
+		if err := os.WriteFile(filepath.Join(dir, template), []byte(templateDefinition(sm)), 0644); err != nil {
+			return fmt.Errorf("failed to save definition template for %s: %w", sm.Name, err)
+		}
+		tf.WriteString("\n" + terraformResource(sm, resource, template))
+		fmt.Fprintf(&imports, "terraform import aws_sfn_state_machine.%s %s\n", resource, shellQuote(sm.ARN))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(tf.String()), 0644); err != nil {
+		return fmt.Errorf("failed to save main.tf: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "import.sh"), []byte(imports.String()), 0755); err != nil {
+		return fmt.Errorf("failed to save import.sh: %w", err)
+	}
+	return nil
+}
+
+func terraformResource(sm stepfunctions.StateMachine, resource, template string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource \"aws_sfn_state_machine\" %s {\n", hclString(resource))
+	fmt.Fprintf(&b, "  name     = %s\n", hclString(sm.Name))
+	fmt.Fprintf(&b, "  role_arn = %s\n", hclString(sm.RoleARN))
+	fmt.Fprintf(&b, "  type     = %s\n", hclString(sm.Type))
+	fmt.Fprintf(&b, "  definition = templatefile(\"${path.module}/%s\", {\n", template)
+	b.WriteString("    account_id = data.aws_caller_identity.current.account_id\n")
+	b.WriteString("    region     = data.aws_region.current.name\n")
+	b.WriteString("  })\n")
+
+	if sm.Logging.Level != "" && sm.Logging.Level != "OFF" {
+		b.WriteString("\n  logging_configuration {\n")
+		fmt.Fprintf(&b, "    level                  = %s\n", hclString(sm.Logging.Level))
+		fmt.Fprintf(&b, "    include_execution_data = %t\n", sm.Logging.IncludeExecutionData)
+		for _, dest := range sm.Logging.Destinations {
+			fmt.Fprintf(&b, "    log_destination        = %s\n", hclString(dest))
+		}
+		b.WriteString("  }\n")
+	}
+	if sm.Tracing {
+		b.WriteString("\n  tracing_configuration {\n    enabled = true\n  }\n")
+	}
+	if sm.Encryption.Type == "CUSTOMER_MANAGED_KMS_KEY" {
+		b.WriteString("\n  encryption_configuration {\n")
+		b.WriteString("    type                              = \"CUSTOMER_MANAGED_KMS_KEY\"\n")
+		fmt.Fprintf(&b, "    kms_key_id                        = %s\n", hclString(sm.Encryption.KMSKeyID))
+		if sm.Encryption.DataKeyReusePeriodSeconds > 0 {
+			fmt.Fprintf(&b, "    kms_data_key_reuse_period_seconds = %d\n", sm.Encryption.DataKeyReusePeriodSeconds)
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// templateDefinition escapes the definition for templatefile and replaces the
+// state machine's account ID and region with the template variables
+func templateDefinition(sm stepfunctions.StateMachine) string {
+	definition := strings.NewReplacer("${", "$${", "%{", "%%{").Replace(sm.Definition)
+	parsed, err := arn.Parse(sm.ARN)
+	if err != nil {
+		return definition
+	}
+	if parsed.AccountID != "" {
+		definition = strings.ReplaceAll(definition, ":"+parsed.AccountID+":", ":${account_id}:")
+	}
+	if parsed.Region != "" {
+		definition = strings.ReplaceAll(definition, ":"+parsed.Region+":", ":${region}:")
+	}
+	return definition
+}
+
+var invalidTerraformChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// terraformName turns a state machine name into a resource name
+func terraformName(name string) string {
+	name = invalidTerraformChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// uniqueName returns name, or name with the first free numeric suffix after
+// separator when it is already taken, and marks the result as taken.
+// Sanitized names collide: a-b and a_b are both a_b.
+func uniqueName(taken map[string]bool, name, separator string) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s%s%d", name, separator, i)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences
+func hclString(s string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(strconv.Quote(s))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
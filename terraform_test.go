@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// iacFixture is a logged, traced and KMS-encrypted state machine whose
+// definition refers to its own account and region
+func iacFixture() []stepfunctions.StateMachine {
+	return []stepfunctions.StateMachine{{
+		Name:       "order-flow",
+		ARN:        "arn:aws:states:us-east-1:123456789012:stateMachine:order-flow",
+		RoleARN:    "arn:aws:iam::123456789012:role/order-flow",
+		Type:       "STANDARD",
+		Definition: `{"StartAt":"Charge","States":{"Charge":{"Type":"Task","Resource":"arn:aws:lambda:us-east-1:123456789012:function:charge","Comment":"${not} a %{template}","End":true}}}`,
+		Logging: stepfunctions.LoggingConfiguration{
+			Level:                "ERROR",
+			IncludeExecutionData: true,
+			Destinations:         []string{"arn:aws:logs:us-east-1:123456789012:log-group:/aws/states/order-flow:*"},
+		},
+		Tracing: true,
+		Encryption: stepfunctions.EncryptionConfiguration{
+			Type:                      "CUSTOMER_MANAGED_KMS_KEY",
+			KMSKeyID:                  "arn:aws:kms:us-east-1:123456789012:key/1234",
+			DataKeyReusePeriodSeconds: 300,
+		},
+	}}
+}
+
+func TestWriteTerraform(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTerraform(iacFixture(), dir, iacOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.tf", "import.sh", "definitions/order-flow.asl.json.tftpl"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, filepath.Join("terraform", name), data)
+	}
+}
+
+func TestTerraformName(t *testing.T) {
+	tests := map[string]string{
+		"orders":     "orders",
+		"order-flow": "order_flow",
+		"2fa.check":  "_2fa_check",
+	}
+	for name, want := range tests {
+		if got := terraformName(name); got != want {
+			t.Errorf("terraformName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteTerraformCollidingNames(t *testing.T) {
+	dir := t.TempDir()
+	first, second := iacFixture()[0], iacFixture()[0]
+	second.Name, second.ARN = "order_flow", "arn:aws:states:us-east-1:123456789012:stateMachine:order_flow"
+	if err := writeTerraform([]stepfunctions.StateMachine{first, second}, dir, iacOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"aws_sfn_state_machine" "order_flow"`, `"aws_sfn_state_machine" "order_flow_2"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("main.tf lacks %s:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+#!/bin/sh
+# Adopt the existing state machines into the Terraform state
+set -e
+terraform import aws_sfn_state_machine.order_flow 'arn:aws:states:us-east-1:123456789012:stateMachine:order-flow'
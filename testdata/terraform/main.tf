@@ -0,0 +1,31 @@
+# Generated by stepfunction-fetcher export terraform
+
+data "aws_caller_identity" "current" {}
+
+data "aws_region" "current" {}
+
+resource "aws_sfn_state_machine" "order_flow" {
+  name     = "order-flow"
+  role_arn = "arn:aws:iam::123456789012:role/order-flow"
+  type     = "STANDARD"
+  definition = templatefile("${path.module}/definitions/order-flow.asl.json.tftpl", {
+    account_id = data.aws_caller_identity.current.account_id
+    region     = data.aws_region.current.name
+  })
+
+  logging_configuration {
+    level                  = "ERROR"
+    include_execution_data = true
+    log_destination        = "arn:aws:logs:us-east-1:123456789012:log-group:/aws/states/order-flow:*"
+  }
+
+  tracing_configuration {
+    enabled = true
+  }
+
+  encryption_configuration {
+    type                              = "CUSTOMER_MANAGED_KMS_KEY"
+    kms_key_id                        = "arn:aws:kms:us-east-1:123456789012:key/1234"
+    kms_data_key_reuse_period_seconds = 300
+  }
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// treeNode is an execution tree without histories and payloads, for
+// finding the failed execution in a deep tree
+type treeNode struct {
+	ExecutionArn      string
+	StateMachineARN   string
+	Status            string
+	StartTime         string `json:",omitempty"`
+	Duration          string
+	ParentState       string     `json:",omitempty"`
+	MapRunArn         string     `json:",omitempty"`
+	Error             string     `json:",omitempty"`
+	Cause             string     `json:",omitempty"`
+	Children          []treeNode `json:",omitempty"`
+	ChildrenTruncated bool       `json:",omitempty"`
+}
+
+func toTreeNode(tree stepfunctions.ExecutionTree) treeNode {
+	exec := tree.Execution
+	node := treeNode{
+		ExecutionArn:      exec.ExecutionArn,
+		StateMachineARN:   tree.StateMachineARN,
+		Status:            exec.Status,
+		StartTime:         formatTime(exec.StartTime),
+		Duration:          exec.FormatDuration(),
+		ParentState:       tree.ParentState,
+		MapRunArn:         tree.MapRunArn,
+		Error:             exec.Error,
+		Cause:             exec.Cause,
+		ChildrenTruncated: tree.ChildrenTruncated,
+	}
+	for _, child := range tree.Children {
+		node.Children = append(node.Children, toTreeNode(child))
+	}
+	return node
+}
+
+// renderTree writes the tree indented, one execution per line with its
+// status, duration, the parent state that started it and any error, e.g.
+//
+//	run-1  FAILED  2m0s
+//	├── child-1  SUCCEEDED  30s  (Checkout)
+//	└── item-7  FAILED  1s  (Items, Map Run)  States.TaskFailed
+func renderTree(w io.Writer, node treeNode) {
+	fmt.Fprintln(w, treeLine(node))
+	renderChildren(w, node, "")
+}
+
+func renderChildren(w io.Writer, node treeNode, indent string) {
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 && !node.ChildrenTruncated {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintln(w, indent+branch+treeLine(child))
+		renderChildren(w, child, indent+next)
+	}
+	if node.ChildrenTruncated {
+		fmt.Fprintln(w, indent+"└── … more Map Run children not fetched")
+	}
+}
+
+func treeLine(node treeNode) string {
+	parts := []string{
+		node.ExecutionArn[strings.LastIndex(node.ExecutionArn, ":")+1:],
+		colorStatus(node.Status),
+		node.Duration,
+	}
+	switch {
+	case node.MapRunArn != "":
+		parts = append(parts, fmt.Sprintf("(%s, Map Run)", node.ParentState))
+	case node.ParentState != "":
+		parts = append(parts, fmt.Sprintf("(%s)", node.ParentState))
+	}
+	if node.Error != "" {
+		parts = append(parts, node.Error)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// executionTreeFile names the tree saved next to an execution file
+func executionTreeFile(smName, executionArn string) string {
+	return strings.TrimSuffix(executionFile(smName, executionArn), ".json") + ".tree.json"
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+func TestRenderTree(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	execution := func(name, status string, duration time.Duration) stepfunctions.ExecutionDetail {
+		exec := stepfunctions.Execution{
+			ExecutionArn: "arn:aws:states:us-east-1:123456789012:execution:orders:" + name,
+			Status:       status,
+			StartTime:    start,
+			EndTime:      start.Add(duration),
+			Duration:     duration,
+		}
+		if status == "FAILED" {
+			exec.Error, exec.Cause = "States.TaskFailed", "card declined"
+		}
+		return stepfunctions.ExecutionDetail{StateMachineARN: "arn:aws:states:us-east-1:123456789012:stateMachine:orders", Execution: exec}
+	}
+	mapRunArn := "arn:aws:states:us-east-1:123456789012:mapRun:orders/Items:run-a"
+	tree := stepfunctions.ExecutionTree{
+		ExecutionDetail: execution("run-1", "FAILED", 2*time.Minute),
+		Children: []stepfunctions.ExecutionTree{
+			{
+				ExecutionDetail: execution("child-1", "SUCCEEDED", 30*time.Second),
+				ParentState:     "Checkout",
+				Children: []stepfunctions.ExecutionTree{
+					{ExecutionDetail: execution("grandchild-1", "SUCCEEDED", 10*time.Second), ParentState: "Audit"},
+				},
+			},
+			{ExecutionDetail: execution("item-7", "FAILED", time.Second), ParentState: "Items", MapRunArn: mapRunArn},
+		},
+		ChildrenTruncated: true,
+	}
+
+	node := toTreeNode(tree)
+	var out bytes.Buffer
+	renderTree(&out, node)
+	assertGolden(t, "tree/run-1.txt", out.Bytes())
+
+	data, err := json.MarshalIndent(node, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "tree/run-1.tree.json", append(data, '\n'))
+}
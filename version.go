@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildInfo returns the version, commit and build date, falling back to
+// what the Go toolchain embeds when the ldflags were not set
+func buildInfo() (v, rev, date string) {
+	rev, date, modVersion := commit, buildDate, ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		modVersion = info.Main.Version
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && rev == "":
+				rev = setting.Value
+			case setting.Key == "vcs.time" && date == "":
+				date = setting.Value
+			}
+		}
+	}
+
+	v = version
+	if v == "dev" && modVersion != "" && modVersion != "(devel)" {
+		v = modVersion
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return v, rev, date
+}
+
+// printVersion prints the build information
+func printVersion() {
+	v, rev, date := buildInfo()
+	fmt.Printf("stepfunction-fetcher %s\n", v)
+	fmt.Printf("  commit:     %s\n", rev)
+	fmt.Printf("  built:      %s\n", date)
+	fmt.Printf("  go version: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"stepfunction-fetcher/stepfunctions"
+)
+
+// runWarnings collects the fetcher's warnings so they are shown together at
+// the end of the run instead of scrolling past between tables
+type runWarnings struct {
+	items []stepfunctions.Warning
+}
+
+func (w *runWarnings) Warn(warning stepfunctions.Warning) {
+	w.items = append(w.items, warning)
+}
+
+// render prints the warnings table and saves warnings.json
+func (w *runWarnings) render(outputDir string) {
+	if len(w.items) == 0 {
+		return
+	}
+	warningTable := newTable()
+	warningTable.SetColumnColor(2, colorFinding)
+	warningTable.SetHeader([]string{"Operation", "Resource", "Warning"})
+	for _, warning := range w.items {
+		warningTable.Append([]string{warning.Operation, warning.Resource, warning.Err.Error()})
+	}
+	fmt.Printf("Warnings (%d):\n", len(w.items))
+	warningTable.Render()
+	fmt.Println()
+
+	data, err := stepfunctions.MarshalDocument(w.items, "warnings")
+	if err != nil {
+		log.Printf("Failed to marshal warnings: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "warnings.json"), data, 0644); err != nil {
+		log.Printf("Failed to save warnings: %v", err)
+	}
+}
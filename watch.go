@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"stepfunction-fetcher/stepfunctions"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// runWatch consumes execution status change events from an SQS queue
+// subscribed to EventBridge and saves each finished execution as it arrives.
+// The EventBridge rule should match:
+//
+//	{"source": ["aws.states"], "detail-type": ["Step Functions Execution Status Change"]}
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	region := fs.String("region", "us-west-2", "AWS region")
+	queueURL := fs.String("queue-url", "", "URL of the SQS queue that receives the EventBridge events")
+	outputDir := fs.String("output-dir", "stepfunctions_state_definitions", "Directory to save execution definitions")
+	includeHistory := fs.Bool("include-history", false, "Also fetch the full event history of every execution")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	wait := fs.Duration("wait", 20*time.Second, "SQS long-poll wait time (max 20s)")
+	otelEnabled := fs.Bool("otel", false, "Export the watcher's own traces and metrics over OTLP (see --otel of the main command)")
+	fs.Parse(args)
+	initColor(*noColor)
+	if *queueURL == "" {
+		fmt.Fprintln(fs.Output(), "Usage: stepfunction-fetcher watch --queue-url <url> [flags]")
+		fs.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *otelEnabled {
+		nr, err := newRelicFromEnv(newRelicConfig{}).resolve(ctx, *region)
+		if err != nil {
+			log.Fatalf("Failed to read the New Relic keys: %v", err)
+		}
+		shutdown, err := setupTelemetry(ctx, nr)
+		if err != nil {
+			log.Fatalf("Failed to set up telemetry: %v", err)
+		}
+		// ctx is cancelled by then; flush with a fresh one
+		defer flushTelemetry(context.Background(), shutdown)
+	}
+
+	fetcher, err := stepfunctions.NewFetcher(ctx, *region)
+	if err != nil {
+		log.Fatalf("Failed to create fetcher: %v", err)
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		log.Fatalf("Failed to load AWS config: %v", err)
+	}
+	queue := sqs.NewFromConfig(cfg)
+	createOutputDirectory(*outputDir)
+
+	fmt.Printf("Watching %s for execution status changes (Ctrl-C to stop)\n", *queueURL)
+	for ctx.Err() == nil {
+		out, err := queue.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            queueURL,
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     int32(wait.Seconds()),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("Failed to receive messages: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, msg := range out.Messages {
+			// Messages that fail stay on the queue and are retried (or
+			// dead-lettered) according to the queue's redrive policy
+			if err := handleStatusChange(ctx, fetcher, aws.ToString(msg.Body), *outputDir, *includeHistory); err != nil {
+				log.Printf("Failed to handle message %s: %v", aws.ToString(msg.MessageId), err)
+				continue
+			}
+			if _, err := queue.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      queueURL,
+				ReceiptHandle: msg.ReceiptHandle,
+			}); err != nil {
+				log.Printf("Failed to delete message %s: %v", aws.ToString(msg.MessageId), err)
+			}
+		}
+	}
+	fmt.Println("Stopped.")
+}
+
+// handleStatusChange saves the execution an event refers to once it has
+// finished; events for running executions are acknowledged and ignored
+func handleStatusChange(ctx context.Context, fetcher *stepfunctions.Fetcher, body, outputDir string, includeHistory bool) error {
+	event, err := stepfunctions.ParseStatusChangeEvent([]byte(body))
+	if err != nil {
+		return err
+	}
+	if !event.Terminal() {
+		return nil
+	}
+
+	exec, err := fetcher.DescribeExecution(ctx, event.ExecutionArn)
+	if err != nil {
+		return err
+	}
+	if includeHistory {
+		if exec.History, err = fetcher.GetExecutionHistory(ctx, event.ExecutionArn); err != nil {
+			return err
+		}
+	}
+
+	data, err := stepfunctions.MarshalDocument(exec, "")
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution %s: %w", exec.ExecutionArn, err)
+	}
+	if err := saveExecutionDefinition(outputDir, event.StateMachineName(), exec.ExecutionArn, data); err != nil {
+		return fmt.Errorf("failed to save execution %s: %w", exec.ExecutionArn, err)
+	}
+	fmt.Printf("%s %s %s (%s)\n", formatTime(exec.EndTime), event.StateMachineName(), colorStatus(exec.Status), exec.ExecutionArn)
+	return nil
+}
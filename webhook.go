@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// postWebhook POSTs the run summary as JSON. With a secret, the request
+// carries X-Fetcher-Timestamp and
+//
+//	X-Fetcher-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// so receivers can verify the sender and reject replays. The body is not
+// compressed, and is retried and dead-lettered to dlqPath like the other
+// HTTP exporters (see httpSink).
+func postWebhook(ctx context.Context, webhookURL, secret string, summary runSummary, dlqPath string) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if parsed.Scheme != "https" && parsed.Hostname() != "localhost" && parsed.Hostname() != "127.0.0.1" {
+		return fmt.Errorf("webhook URL must use https")
+	}
+
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run summary: %w", err)
+	}
+
+	return newWebhookSink(webhookURL, secret, dlqPath).deliver(ctx, body, []json.RawMessage{body})
+}
+
+// newWebhookSink returns the sink webhook requests are sent through
+func newWebhookSink(webhookURL, secret, dlqPath string) *httpSink {
+	sink := newHTTPSink("webhook", webhookURL, dlqPath)
+	sink.gzip = false
+	sink.client.Timeout = 10 * time.Second
+	if secret != "" {
+		sink.sign = func(req *http.Request, body []byte) {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set("X-Fetcher-Timestamp", timestamp)
+			req.Header.Set("X-Fetcher-Signature", "sha256="+signPayload(secret, timestamp, body))
+		}
+	}
+	return sink
+}
+
+func signPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}